@@ -2,17 +2,59 @@ package calltypes
 
 import "time"
 
+// UserStatus is the lifecycle state of a user account. Only active users can
+// log in, refresh tokens and use their sessions.
+type UserStatus string
+
+const (
+	// UserStatusPending is an account that has not been activated yet.
+	UserStatusPending UserStatus = "pending"
+	// UserStatusActive is an account in good standing.
+	UserStatusActive UserStatus = "active"
+	// UserStatusSuspended is an account deactivated by an administrator.
+	UserStatusSuspended UserStatus = "suspended"
+	// UserStatusDeleted is an account removed at the request of its owner.
+	UserStatusDeleted UserStatus = "deleted"
+)
+
+// Valid reports whether the status is one of the known ones.
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserStatusPending, UserStatusActive, UserStatusSuspended, UserStatusDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// SessionState is what authenticating a request needs to know about its user.
+type SessionState struct {
+	Status UserStatus
+	// RevokedAt is when sessions of the user were last revoked, zero if never.
+	// Access tokens issued until then are refused.
+	RevokedAt time.Time
+}
+
+// Revoked reports whether a token issued at issuedAt, in Unix seconds, has been
+// revoked. Since iat has a precision of seconds, tokens issued in the second
+// of the revocation are revoked too.
+func (s SessionState) Revoked(issuedAt int64) bool {
+	return !s.RevokedAt.IsZero() && issuedAt <= s.RevokedAt.Unix()
+}
+
 // User provides structure to hold users
 // @Description info about user.
 type User struct {
-	ID        int       `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"firstName,omitempty"`
-	LastName  string    `json:"lastName,omitempty"`
-	Password  string    `json:"-"`
-	Active    int       `json:"active"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID            int        `json:"id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"emailVerified"`
+	FirstName     string     `json:"firstName,omitempty"`
+	LastName      string     `json:"lastName,omitempty"`
+	Password      string     `json:"-"`
+	Status        UserStatus `enums:"pending,active,suspended,deleted" example:"active" json:"status"`
+	Version       int        `json:"-"` // increased by every change, backs the ETag; read by GetOne only
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
 }
 
 // LoginRequest represents user login request
@@ -29,5 +71,614 @@ type RegisterRequest struct {
 	FirstName string `example:"John"                json:"firstName"`
 	LastName  string `example:"Doe"                 json:"lastName"`
 	Password  string `example:"securePassword123"   json:"password"`
-	Active    int    `example:"1"                   json:"active,omitempty"`
+}
+
+// ImportUser represents a user migrated from a legacy system together with the
+// password hash that system stored
+// @name ImportUser.
+type ImportUser struct {
+	Email        string     `example:"user@example.com"                      json:"email"`
+	FirstName    string     `example:"John"                                  json:"firstName"`
+	LastName     string     `example:"Doe"                                   json:"lastName"`
+	PasswordHash string     `example:"$pbkdf2-sha256$i=10000$c2FsdA$aGFzaA" json:"passwordHash"`
+	Status       UserStatus `example:"active"                                json:"status,omitempty"`
+}
+
+// ImportRequest represents bulk user import request
+// @name ImportRequest.
+type ImportRequest struct {
+	Users []ImportUser `json:"users"`
+}
+
+// ImportResult represents outcome of importing one user
+// @name ImportResult.
+type ImportResult struct {
+	Email string `example:"user@example.com" json:"email"`
+	ID    int    `example:"1"                json:"id,omitempty"`
+	Error string `example:""                 json:"error,omitempty"`
+}
+
+// LoginFailures holds failed login counters for an account or a source IP.
+type LoginFailures struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// TOTP holds TOTP enrollment of a user. Secret is encrypted. PendingSecret,
+// also encrypted, replaces Secret of a confirmed TOTP once confirmed itself.
+type TOTP struct {
+	UserID        int
+	Secret        string
+	PendingSecret string
+	Confirmed     bool
+	LastUsedStep  int64
+}
+
+// MFAChallenge is a pending login that passed the password check and waits for
+// the second factor. ID is a hash of the token handed to the client.
+type MFAChallenge struct {
+	ID     string
+	UserID int
+	IP     string
+	// AMR lists the authentication methods of the first factor.
+	AMR       []string
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+// TOTPEnrollment represents TOTP secret issued at enrollment
+// @name TOTPEnrollment.
+type TOTPEnrollment struct {
+	Secret string `example:"JBSWY3DPEHPK3PXP"                                      json:"secret"`
+	URI    string `example:"otpauth://totp/medods:user@example.com?secret=JBSWY3DPEHPK3PXP" json:"uri"`
+}
+
+// TOTPCodeRequest represents request carrying TOTP code
+// @name TOTPCodeRequest.
+type TOTPCodeRequest struct {
+	Code string `example:"123456" json:"code"`
+}
+
+// MFACodeRequest carries either a TOTP code or one of the recovery codes
+// @name MFACodeRequest.
+type MFACodeRequest struct {
+	Code         string `example:"123456"      json:"code,omitempty"`
+	RecoveryCode string `example:"ABCDE-FGHIJ" json:"recoveryCode,omitempty"`
+}
+
+// MFAChallengeResponse is returned by Authenticate instead of tokens when the
+// user has MFA enabled
+// @name MFAChallengeResponse.
+type MFAChallengeResponse struct {
+	MFARequired bool      `example:"true"                 json:"mfaRequired"`
+	Challenge   string    `example:"c2VjcmV0LWNoYWxsZW5nZQ" json:"challenge"`
+	Methods     []string  `example:"totp"                 json:"methods"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// MFALoginRequest represents second step of login. Either TOTP code or one of
+// the recovery codes must be provided
+// @name MFALoginRequest.
+type MFALoginRequest struct {
+	Challenge    string `example:"c2VjcmV0LWNoYWxsZW5nZQ" json:"challenge"`
+	Code         string `example:"123456"                 json:"code,omitempty"`
+	RecoveryCode string `example:"ABCDE-FGHIJ"            json:"recoveryCode,omitempty"`
+}
+
+// AuditEvent is an entry of the audit trail. UserID is the subject of the action
+// and ActorID is who performed it, both zero when unknown.
+type AuditEvent struct {
+	ID        int64                  `json:"id"`
+	UserID    int                    `json:"userId,omitempty"`
+	ActorID   int                    `json:"actorId,omitempty"`
+	Action    string                 `json:"action"`
+	IP        string                 `json:"ip,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// RecoveryCodes represents MFA recovery codes, shown only once
+// @name RecoveryCodes.
+type RecoveryCodes struct {
+	RecoveryCodes []string `example:"ABCDE-FGHIJ" json:"recoveryCodes"`
+}
+
+// PasskeyRegistrationBeginRequest confirms the registration of a passkey. The
+// body may be omitted when the session was authenticated with a second factor
+// or a user-verified passkey
+// @name PasskeyRegistrationBeginRequest.
+type PasskeyRegistrationBeginRequest struct {
+	Password string `example:"securePassword123" json:"password"`
+}
+
+// WebAuthnCredential is a passkey registered by a user. ID is the base64url
+// encoded credential ID and PublicKey is the COSE encoded key
+// @name WebAuthnCredential.
+type WebAuthnCredential struct {
+	ID             string     `example:"q83vEjRWeJA"      json:"id"`
+	UserID         int        `json:"-"`
+	Name           string     `example:"MacBook Touch ID" json:"name"`
+	PublicKey      []byte     `json:"-"`
+	SignCount      uint32     `example:"0"                json:"signCount"`
+	AAGUID         string     `json:"aaguid"`
+	Transports     []string   `example:"internal"         json:"transports,omitempty"`
+	BackupEligible bool       `example:"true"             json:"backupEligible"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// WebAuthnSession is a pending WebAuthn ceremony. ID is a hash of the challenge
+// and UserID is zero for logins with discoverable credentials.
+type WebAuthnSession struct {
+	ID        string
+	UserID    int
+	Ceremony  string
+	ExpiresAt time.Time
+}
+
+// PasskeyLoginRequest starts passkey login. Without email any discoverable
+// credential is accepted
+// @name PasskeyLoginRequest.
+type PasskeyLoginRequest struct {
+	Email string `example:"user@example.com" json:"email,omitempty"`
+}
+
+// EmailLogin is a pending passwordless login. ID is sent to the browser in the
+// binding cookie and BrowserHash is a hash of the secret half of that cookie.
+// CodeHash is empty for magic links.
+type EmailLogin struct {
+	ID          string
+	UserID      int
+	Method      string
+	CodeHash    string
+	BrowserHash string
+	Attempts    int
+	ExpiresAt   time.Time
+}
+
+// EmailLoginRequest starts passwordless login by email
+// @name EmailLoginRequest.
+type EmailLoginRequest struct {
+	Email  string `example:"user@example.com" json:"email"`
+	Method string `enums:"code,link"          example:"code"  json:"method"`
+}
+
+// EmailCodeRequest completes passwordless login with the code from the email
+// @name EmailCodeRequest.
+type EmailCodeRequest struct {
+	Code string `example:"123456" json:"code"`
+}
+
+// OAuthClient is an application registered to obtain tokens from the OAuth
+// authorization server. SecretHash is empty for public clients.
+type OAuthClient struct {
+	ID                      string
+	Name                    string
+	SecretHash              string
+	RedirectURIs            []string
+	GrantTypes              []string
+	Scopes                  []string
+	TokenEndpointAuthMethod string
+	// JWKS holds public keys of clients using private_key_jwt.
+	JWKS []JSONWebKey
+	// ExchangeAudiences lists audiences the client may exchange tokens for.
+	ExchangeAudiences []string
+	// FirstParty clients are operated by the owner of the service. Only they
+	// may use the authorization endpoint, which does not ask users for consent.
+	FirstParty bool
+	CreatedAt  time.Time
+}
+
+// OAuthAuthorizationCode is an issued authorization code waiting to be
+// exchanged. CodeHash is a hash of the code handed to the client. Nonce of an
+// OpenID Connect request is returned in the ID token.
+type OAuthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      string
+	UserID        int
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	ExpiresAt     time.Time
+}
+
+// OAuthRefreshToken is a refresh token issued to an OAuth client. TokenHash is
+// a hash of the token handed to the client.
+type OAuthRefreshToken struct {
+	TokenHash string
+	ClientID  string
+	UserID    int
+	Scope     string
+	ExpiresAt time.Time
+}
+
+// OAuthDeviceCode is a pending device authorization (RFC 8628).
+// DeviceCodeHash is a hash of the code polled by the device, UserCode is
+// entered by the user. UserID is zero until the user decides; Denied reports
+// the decision. LastPolledAt is zero before the first poll.
+type OAuthDeviceCode struct {
+	DeviceCodeHash string
+	UserCode       string
+	ClientID       string
+	Scope          string
+	UserID         int
+	Denied         bool
+	Interval       time.Duration
+	LastPolledAt   time.Time
+	ExpiresAt      time.Time
+}
+
+// OAuthClientRequest registers an OAuth client. Public clients use
+// token_endpoint_auth_method "none" and get no secret, clients using
+// private_key_jwt register their public keys instead of getting a secret
+// @name OAuthClientRequest.
+type OAuthClientRequest struct {
+	Name                    string         `example:"Mobile app"                              json:"name"`
+	RedirectURIs            []string       `example:"https://app.example.com/callback"        json:"redirectUris"`
+	GrantTypes              []string       `enums:"authorization_code,refresh_token,client_credentials,urn:ietf:params:oauth:grant-type:device_code,urn:ietf:params:oauth:grant-type:token-exchange" example:"authorization_code,refresh_token" json:"grantTypes,omitempty"`
+	Scopes                  []string       `example:"profile"                                 json:"scopes,omitempty"`
+	TokenEndpointAuthMethod string         `enums:"client_secret_basic,client_secret_post,private_key_jwt,none" example:"client_secret_basic" json:"tokenEndpointAuthMethod,omitempty"`
+	JWKS                    *JSONWebKeySet `json:"jwks,omitempty"`
+	ExchangeAudiences       []string       `example:"https://billing.internal"               json:"exchangeAudiences,omitempty"`
+	FirstParty              bool           `example:"true"                                    json:"firstParty,omitempty"`
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517). RSA keys use N and E,
+// elliptic curve keys use Crv, X and Y
+// @name JSONWebKey.
+type JSONWebKey struct {
+	Kty string `example:"EC"      json:"kty"`
+	Kid string `example:"key-1"   json:"kid,omitempty"`
+	Use string `example:"sig"     json:"use,omitempty"`
+	Alg string `example:"ES256"   json:"alg,omitempty"`
+	Crv string `example:"P-256"   json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `example:"AQAB"    json:"e,omitempty"`
+}
+
+// JSONWebKeySet is a set of public keys
+// @name JSONWebKeySet.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// OAuthClientCredentials represents a registered OAuth client. The secret is
+// shown only once
+// @name OAuthClientCredentials.
+type OAuthClientCredentials struct {
+	ClientID     string `example:"pQ2x7bOe9Wm4JtFz1kYdVg"   json:"clientId"`
+	ClientSecret string `example:"c2VjcmV0LWNsaWVudC1zZWNyZXQ" json:"clientSecret,omitempty"`
+}
+
+// OAuthTokenResponse is a successful response of the token endpoint (RFC 6749
+// section 5.1). IDToken is issued for the openid scope, IssuedTokenType is set
+// by token exchange (RFC 8693 section 2.2.1)
+// @name OAuthTokenResponse.
+type OAuthTokenResponse struct {
+	AccessToken     string `example:"eyJhbGciOiJIUzUxMiJ9..."                         json:"access_token"`
+	IssuedTokenType string `example:"urn:ietf:params:oauth:token-type:access_token" json:"issued_token_type,omitempty"`
+	TokenType       string `example:"Bearer"                                          json:"token_type"`
+	ExpiresIn       int    `example:"900"                                             json:"expires_in"`
+	RefreshToken    string `example:"dGhpcyBpcyBhIHRva2Vu"                            json:"refresh_token,omitempty"`
+	Scope           string `example:"openid email"                                    json:"scope,omitempty"`
+	IDToken         string `example:"eyJhbGciOiJSUzI1NiJ9..."                         json:"id_token,omitempty"`
+}
+
+// OAuthDeviceAuthorizationResponse is a response of the device authorization
+// endpoint (RFC 8628 section 3.2)
+// @name OAuthDeviceAuthorizationResponse.
+type OAuthDeviceAuthorizationResponse struct {
+	DeviceCode              string `example:"GmRhmhcxhwAzkoEqiMEg_DnyEysNkuNhszIySk9eS" json:"device_code"`
+	UserCode                string `example:"WDJB-MJHT"                                  json:"user_code"`
+	VerificationURI         string `example:"https://auth.example.com/device"            json:"verification_uri"`
+	VerificationURIComplete string `example:"https://auth.example.com/device?user_code=WDJB-MJHT" json:"verification_uri_complete"`
+	ExpiresIn               int    `example:"600"                                        json:"expires_in"`
+	Interval                int    `example:"5"                                          json:"interval"`
+}
+
+// OAuthDeviceRequest describes a pending device authorization to the user
+// approving it
+// @name OAuthDeviceRequest.
+type OAuthDeviceRequest struct {
+	UserCode   string `example:"WDJB-MJHT"   json:"userCode"`
+	ClientID   string `example:"pQ2x7bOe9Wm4JtFz1kYdVg" json:"clientId"`
+	ClientName string `example:"Deploy CLI"  json:"clientName"`
+	Scope      string `example:"users:read"  json:"scope,omitempty"`
+}
+
+// OAuthDeviceDecision approves or denies a device authorization
+// @name OAuthDeviceDecision.
+type OAuthDeviceDecision struct {
+	UserCode string `example:"WDJB-MJHT" json:"userCode"`
+	Approve  bool   `example:"true"      json:"approve"`
+}
+
+// OAuthErrorResponse is an error response of the token endpoint (RFC 6749
+// section 5.2)
+// @name OAuthErrorResponse.
+type OAuthErrorResponse struct {
+	Error            string `example:"invalid_grant"            json:"error"`
+	ErrorDescription string `example:"invalid or expired grant" json:"error_description,omitempty"`
+}
+
+// OpenIDConfiguration is the OpenID Provider metadata served at
+// /.well-known/openid-configuration (OpenID Connect Discovery 1.0)
+// @name OpenIDConfiguration.
+type OpenIDConfiguration struct {
+	Issuer                                string   `example:"https://auth.example.com"               json:"issuer"`
+	AuthorizationEndpoint                 string   `example:"https://auth.example.com/oauth/authorize" json:"authorization_endpoint"`
+	TokenEndpoint                         string   `example:"https://auth.example.com/oauth/token"   json:"token_endpoint"`
+	UserInfoEndpoint                      string   `example:"https://auth.example.com/userinfo"      json:"userinfo_endpoint"`
+	JWKSURI                               string   `example:"https://auth.example.com/oauth/jwks"    json:"jwks_uri"`
+	DeviceAuthorizationEndpoint           string   `example:"https://auth.example.com/oauth/device_authorization" json:"device_authorization_endpoint"`
+	ScopesSupported                       []string `example:"openid,email,profile"                   json:"scopes_supported"`
+	ResponseTypesSupported                []string `example:"code"                                   json:"response_types_supported"`
+	ResponseModesSupported                []string `example:"query"                                  json:"response_modes_supported"`
+	GrantTypesSupported                   []string `example:"authorization_code,refresh_token"       json:"grant_types_supported"`
+	SubjectTypesSupported                 []string `example:"public"                                 json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported      []string `example:"RS256"                                  json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported     []string `example:"client_secret_basic"                    json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgsSupported []string `example:"ES256,RS256"                            json:"token_endpoint_auth_signing_alg_values_supported"`
+	ClaimsSupported                       []string `example:"sub,email,email_verified,name"          json:"claims_supported"`
+	CodeChallengeMethodsSupported         []string `example:"S256"                                   json:"code_challenge_methods_supported"`
+	AuthorizationResponseISSSupported     bool     `example:"true"                                   json:"authorization_response_iss_parameter_supported"`
+}
+
+// UserInfo holds claims about the user released for the granted scopes: email
+// and email_verified for "email", names for "profile"
+// @name UserInfo.
+type UserInfo struct {
+	Sub           string `example:"42"               json:"sub"`
+	Email         string `example:"user@example.com" json:"email,omitempty"`
+	EmailVerified *bool  `example:"true"             json:"email_verified,omitempty"`
+	Name          string `example:"John Doe"         json:"name,omitempty"`
+	GivenName     string `example:"John"             json:"given_name,omitempty"`
+	FamilyName    string `example:"Doe"              json:"family_name,omitempty"`
+}
+
+// FederatedLogin is a pending login through an external identity provider.
+// StateHash is a hash of the state parameter, which is also kept in the
+// browser, and CodeVerifier is the PKCE verifier of the authorization request.
+type FederatedLogin struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// FederatedIdentity links the subject of an external identity provider to a
+// user.
+type FederatedIdentity struct {
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	UserID      int       `json:"-"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
+}
+
+// SAMLRequest is an AuthnRequest sent to a SAML identity provider. ID is the
+// request ID the response must refer to, which is also kept in the browser.
+type SAMLRequest struct {
+	ID        string
+	Provider  string
+	ExpiresAt time.Time
+}
+
+// APIKey is a long-lived credential of a user (personal key) or of an
+// integration (service key, without a user). Only a hash of the secret is
+// stored. RateLimit overrides the default quota of the key
+// @name APIKey.
+type APIKey struct {
+	ID         string     `example:"3f9a1c0d7b2e4a65" json:"id"`
+	Kind       string     `enums:"personal,service"  example:"personal" json:"kind"`
+	UserID     int        `json:"-"`
+	Name       string     `example:"Backup script"    json:"name"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `example:"users:read"       json:"scopes,omitempty"`
+	RateLimit  string     `example:"100/1m"           json:"rateLimit,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// APIKeyRequest creates an API key. Without expiresAt the key does not expire.
+// Only service keys may set rateLimit, personal keys get the default quota
+// @name APIKeyRequest.
+type APIKeyRequest struct {
+	Name      string     `example:"Backup script" json:"name"`
+	Scopes    []string   `example:"users:read"    json:"scopes,omitempty"`
+	RateLimit string     `example:"100/1m"        json:"rateLimit,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// APIKeyCredentials is a created API key, sent in the X-API-Key header. The
+// key is shown only once
+// @name APIKeyCredentials.
+type APIKeyCredentials struct {
+	ID  string `example:"3f9a1c0d7b2e4a65"                                     json:"id"`
+	Key string `example:"mdk_3f9a1c0d7b2e4a65_hV0W4m5i2Zq7cXrT8yKp3nB6sLd1fGjA" json:"key"`
+}
+
+// Role grants its permissions, such as users:read, to the users it is
+// assigned to
+// @name Role.
+type Role struct {
+	Name        string   `example:"support"             json:"name"`
+	Description string   `example:"Reads user profiles" json:"description,omitempty"`
+	Permissions []string `example:"users:read"          json:"permissions"`
+}
+
+// RoleRequest creates a role or replaces its description and permissions
+// @name RoleRequest.
+type RoleRequest struct {
+	Description string   `example:"Reads user profiles" json:"description,omitempty"`
+	Permissions []string `example:"users:read"          json:"permissions"`
+}
+
+// UserFilter selects users listed to administrators. Query matches the email
+// and names, Status filters by the status unless empty.
+type UserFilter struct {
+	Query  string
+	Status UserStatus
+	Limit  int
+	Offset int
+}
+
+// UserPage is a page of users matching a filter
+// @name UserPage.
+type UserPage struct {
+	Users  []*User `json:"users"`
+	Total  int     `example:"42" json:"total"`
+	Limit  int     `example:"50" json:"limit"`
+	Offset int     `example:"0"  json:"offset"`
+}
+
+// CreateUserRequest creates a user on behalf of an administrator
+// @name CreateUserRequest.
+type CreateUserRequest struct {
+	Email         string `example:"user@example.com"  json:"email"`
+	FirstName     string `example:"John"              json:"firstName"`
+	LastName      string `example:"Doe"               json:"lastName"`
+	Password      string `example:"securePassword123" json:"password"`
+	EmailVerified bool   `example:"true"              json:"emailVerified"`
+}
+
+// UpdateUserRequest replaces the email and names of a user
+// @name UpdateUserRequest.
+type UpdateUserRequest struct {
+	Email     string `example:"user@example.com" json:"email"`
+	FirstName string `example:"John"             json:"firstName"`
+	LastName  string `example:"Doe"              json:"lastName"`
+}
+
+// ProfilePatch is a JSON merge patch (RFC 7396) of the profile of the current
+// user. Null removes a name
+// @name ProfilePatch.
+type ProfilePatch struct {
+	FirstName *string `example:"John" json:"firstName,omitempty"`
+	LastName  *string `example:"Doe"  json:"lastName,omitempty"`
+}
+
+// PasswordReset is a pending password reset. Only a hash of its token is
+// stored.
+type PasswordReset struct {
+	TokenHash string
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// PasswordResetRequest sets a new password with the token from a reset email
+// @name PasswordResetRequest.
+type PasswordResetRequest struct {
+	Token    string `example:"hV0W4m5i2Zq7cXrT8yKp3nB6sLd1fGjA" json:"token"`
+	Password string `example:"newSecurePassword123"             json:"password"`
+}
+
+// EmailChange is a pending change of the email of a user. Only hashes of its
+// confirmation and cancellation tokens are stored.
+type EmailChange struct {
+	TokenHash  string
+	CancelHash string
+	UserID     int
+	NewEmail   string
+	ExpiresAt  time.Time
+}
+
+// EmailChangeRequest asks to change the email of the current user
+// @name EmailChangeRequest.
+type EmailChangeRequest struct {
+	Email string `example:"new@example.com" json:"email"`
+	// Password is the current password. It is not needed when the session was
+	// authenticated with a second factor or a user-verified passkey.
+	Password string `example:"securePassword123" json:"password,omitempty"`
+}
+
+// PendingEmailChange is an email change awaiting confirmation
+// @name PendingEmailChange.
+type PendingEmailChange struct {
+	Email     string    `example:"new@example.com"      json:"email"`
+	ExpiresAt time.Time `example:"2025-09-22T10:00:00Z" json:"expiresAt"`
+}
+
+// EmailChangeTokenRequest confirms or cancels an email change with the token
+// from an email
+// @name EmailChangeTokenRequest.
+type EmailChangeTokenRequest struct {
+	Token string `example:"hV0W4m5i2Zq7cXrT8yKp3nB6sLd1fGjA" json:"token"`
+}
+
+// AccountDeletionRequest confirms the deletion of the account of the current
+// user. The body may be omitted when the session was authenticated with a
+// second factor or a user-verified passkey
+// @name AccountDeletionRequest.
+type AccountDeletionRequest struct {
+	Password string `example:"securePassword123" json:"password"`
+}
+
+// AccountDeletion is a scheduled deletion of the account of a user. The account
+// is erased at EraseAt unless the user cancels the deletion
+// @name AccountDeletion.
+type AccountDeletion struct {
+	UserID      int       `json:"-"`
+	RequestedAt time.Time `example:"2025-09-29T10:00:00Z" json:"requestedAt"`
+	EraseAt     time.Time `example:"2025-10-29T10:00:00Z" json:"eraseAt"`
+}
+
+// DataExport is an export of the personal data of a user. It is pending until
+// a background job generates its file, which is removed at ExpiresAt. Ready
+// exports are downloaded through DownloadURL, a short-lived signed link
+// @name DataExport.
+type DataExport struct {
+	ID          string     `example:"9f86d081884c7d659a2feaa0c55ad015" json:"id"`
+	UserID      int        `json:"-"`
+	Format      string     `enums:"json,zip"                          example:"zip"     json:"format"`
+	Status      string     `enums:"pending,running,ready,failed"      example:"ready"   json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+// DataExportRequest asks for an export of the personal data of the current user
+// @name DataExportRequest.
+type DataExportRequest struct {
+	Format string `enums:"json,zip" example:"zip" json:"format"`
+}
+
+// Session is a session of a user: the web session of the service, which has no
+// client, or a refresh token held by an OAuth client.
+type Session struct {
+	ClientID  string     `json:"clientId,omitempty"`
+	Scope     string     `json:"scope,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
+
+// OAuthConsent is an OAuth client the user has let act on its behalf.
+type OAuthConsent struct {
+	ClientID   string    `json:"clientId"`
+	ClientName string    `json:"clientName"`
+	Scope      string    `json:"scope,omitempty"`
+	GrantedAt  time.Time `json:"grantedAt"`
+}
+
+// PersonalData is everything the service holds about a user, as exported to
+// the user.
+type PersonalData struct {
+	GeneratedAt         time.Time             `json:"generatedAt"`
+	Profile             *User                 `json:"profile"`
+	Roles               []*Role               `json:"roles"`
+	Sessions            []*Session            `json:"sessions"`
+	Consents            []*OAuthConsent       `json:"consents"`
+	Passkeys            []*WebAuthnCredential `json:"passkeys"`
+	APIKeys             []*APIKey             `json:"apiKeys"`
+	FederatedIdentities []*FederatedIdentity  `json:"federatedIdentities"`
+	SecurityEvents      []*AuditEvent         `json:"securityEvents"`
 }
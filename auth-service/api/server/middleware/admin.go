@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// Admin middleware checks the shared admin token sent in the X-Admin-Token header.
+// An empty configured token disables the admin API entirely.
+func Admin(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get("X-Admin-Token")
+
+			if adminToken == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+				handleForbidden(w, "invalid admin token")
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleForbidden handle errors from authorization middlewares.
+func handleForbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   true,
+		"message": "Access denied: " + message,
+	})
+
+	if err != nil {
+		return
+	}
+}
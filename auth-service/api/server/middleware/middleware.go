@@ -1,48 +1,363 @@
 package middleware
 
 import (
+	"auth-service/api/calltypes"
 	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
 	"context"
 	"encoding/json"
-	"fmt"
+	"net"
 	"net/http"
 	"strings"
 )
 
-// Auth middleware checks JWT token from cookies.
-func Auth() func(http.Handler) http.Handler {
+type contextKey string
+
+const (
+	userIDKey      contextKey = "userID"
+	amrKey         contextKey = "amr"
+	clientIDKey    contextKey = "clientID"
+	scopesKey      contextKey = "scopes"
+	apiKeyKey      contextKey = "apiKey"
+	rolesKey       contextKey = "roles"
+	permissionsKey contextKey = "permissions"
+	claimsKey      contextKey = "claims"
+)
+
+// APIKeyHeader carries API keys.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates API keys.
+type APIKeyAuthenticator interface {
+	Authenticate(key string) (*calltypes.APIKey, error)
+}
+
+// SessionStateReader reads statuses of users and when their sessions were
+// revoked.
+type SessionStateReader interface {
+	SessionState(id int) (*calltypes.SessionState, error)
+}
+
+// PermissionReader reads the permissions granted to users by their roles.
+type PermissionReader interface {
+	Permissions(userID int) ([]string, error)
+}
+
+// UserIDFromContext returns ID of the user authenticated by Auth middleware.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(userIDKey).(int)
+
+	return id, ok
+}
+
+// AMRFromContext returns authentication methods the access token was issued for.
+func AMRFromContext(ctx context.Context) []string {
+	amr, _ := ctx.Value(amrKey).([]string)
+
+	return amr
+}
+
+// ClientIDFromContext returns ID of the OAuth client the access token was
+// issued to. It reports false for user sessions.
+func ClientIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(clientIDKey).(string)
+
+	return id, ok
+}
+
+// ScopesFromContext returns scopes granted to the OAuth client or the API key.
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey).([]string)
+
+	return scopes
+}
+
+// RolesFromContext returns roles of the user the session access token was
+// issued to.
+func RolesFromContext(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey).([]string)
+
+	return roles
+}
+
+// PermissionsFromContext returns permissions of the user the session access
+// token was issued to or, for tokens OAuth clients got on behalf of a user, the
+// current permissions of that user.
+func PermissionsFromContext(ctx context.Context) []string {
+	permissions, _ := ctx.Value(permissionsKey).([]string)
+
+	return permissions
+}
+
+// ClaimsFromContext returns claims of the access token. Requests authenticated
+// with an API key get claims describing the key: api_key, kind, scope and, for
+// personal keys, sub.
+func ClaimsFromContext(ctx context.Context) map[string]interface{} {
+	claims, _ := ctx.Value(claimsKey).(map[string]interface{})
+
+	return claims
+}
+
+// APIKeyFromContext returns the API key the request was authenticated with.
+func APIKeyFromContext(ctx context.Context) (*calltypes.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey).(*calltypes.APIKey)
+
+	return key, ok
+}
+
+// ClientIP returns the IP of the client without the port. IPv6 addresses come
+// without brackets.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Auth middleware checks JWT token from the Authorization header or cookies.
+// Besides user sessions it accepts tokens issued to OAuth clients, including
+// clients acting on their own behalf, which have no user in the context, and
+// API keys sent in the X-API-Key header. Service keys have no user either.
+// Requests acting for a user who is not active are refused, as are access
+// tokens issued before sessions of the user were revoked.
+func Auth(keys APIKeyAuthenticator, users SessionStateReader, permissions PermissionReader) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			accessCookie, err := r.Cookie("accessToken")
-			if err != nil {
-				handleAuthError(w, "missing access token")
+			if presented := r.Header.Get(APIKeyHeader); presented != "" {
+				key, err := keys.Authenticate(presented)
+				if err != nil {
+					handleAuthError(w, err.Error())
+
+					return
+				}
+
+				ctx := withAPIKey(r, key)
+				if err := requireActive(ctx, users); err != nil {
+					handleAuthError(w, err.Error())
+
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(ctx))
 
 				return
 			}
 
-			ip := r.RemoteAddr
-			if strings.Contains(ip, ":") {
-				ip = strings.Split(ip, ":")[0]
-			}
+			accessToken, ok := accessTokenFromRequest(r)
+			if !ok {
+				handleAuthError(w, "missing access token")
 
-			tokenService := token.NewTokenService()
+				return
+			}
 
-			claims, err := tokenService.ValidateAccessToken(accessCookie.Value)
+			ctx, err := authenticate(r, accessToken)
 			if err != nil {
-				fmt.Println("Validation error details:", err)
 				handleAuthError(w, "invalid access token: "+err.Error())
 
 				return
 			}
 
-			fmt.Println("Successful validation, claims:", claims)
+			if err := requireActive(ctx, users); err != nil {
+				handleAuthError(w, err.Error())
+
+				return
+			}
+
+			if ctx, err = withDelegatedPermissions(ctx, permissions); err != nil {
+				handleAuthError(w, err.Error())
+
+				return
+			}
 
-			ctx := context.WithValue(r.Context(), "clientIP", ip) //nolint: revive,staticcheck
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// OptionalAuth middleware is Auth that lets anonymous requests through. Only
+// user sessions of active users are accepted. Handlers tell them apart with
+// UserIDFromContext.
+func OptionalAuth(users SessionStateReader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if accessCookie, err := r.Cookie("accessToken"); err == nil {
+				if ctx, err := authenticate(r, accessCookie.Value); err == nil {
+					_, isClient := ClientIDFromContext(ctx)
+					if !isClient && requireActive(ctx, users) == nil {
+						r = r.WithContext(ctx)
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate validates the access token and returns the request context
+// carrying the user and authentication methods.
+func authenticate(r *http.Request, accessToken string) (context.Context, error) {
+	ip := ClientIP(r)
+
+	tokenService := token.NewTokenService()
+
+	claims, err := tokenService.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	// Tokens exchanged for a downstream audience are not accepted here.
+	if _, ok := claims["aud"]; ok {
+		return nil, errormsg.ErrForeignAudience
+	}
+
+	ctx := context.WithValue(r.Context(), "clientIP", ip) //nolint: revive,staticcheck
+	ctx = context.WithValue(ctx, claimsKey, map[string]interface{}(claims))
+
+	if sub, ok := claims["sub"].(float64); ok {
+		ctx = context.WithValue(ctx, userIDKey, int(sub))
+	}
+
+	if methods, ok := claims["amr"].([]interface{}); ok {
+		amr := make([]string, 0, len(methods))
+
+		for _, method := range methods {
+			if name, ok := method.(string); ok {
+				amr = append(amr, name)
+			}
+		}
+
+		ctx = context.WithValue(ctx, amrKey, amr)
+	}
+
+	scope, _ := claims["scope"].(string)
+
+	if clientID, ok := claims["client_id"].(string); ok {
+		ctx = context.WithValue(ctx, clientIDKey, clientID)
+		ctx = context.WithValue(ctx, scopesKey, strings.Fields(scope))
+
+		return ctx, nil
+	}
+
+	// The scope of session tokens lists permissions granted by the roles.
+	ctx = context.WithValue(ctx, permissionsKey, strings.Fields(scope))
+
+	if names, ok := claims["roles"].([]interface{}); ok {
+		roles := make([]string, 0, len(names))
+
+		for _, name := range names {
+			if role, ok := name.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+
+		ctx = context.WithValue(ctx, rolesKey, roles)
+	}
+
+	return ctx, nil
+}
+
+// requireActive checks that the user in the context, if any, is active and
+// that the access token was issued after sessions of the user were revoked.
+func requireActive(ctx context.Context, users SessionStateReader) error {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	state, err := users.SessionState(userID)
+	if err != nil {
+		return err //nolint: wrapcheck
+	}
+
+	if state.Status != calltypes.UserStatusActive {
+		return errormsg.ErrUserInactive
+	}
+
+	if _, isAPIKey := APIKeyFromContext(ctx); isAPIKey {
+		return nil
+	}
+
+	if issuedAt, _ := ClaimsFromContext(ctx)["iat"].(float64); state.Revoked(int64(issuedAt)) {
+		return errormsg.ErrSessionRevoked
+	}
+
+	return nil
+}
+
+// withDelegatedPermissions adds the current permissions of the user to the
+// context of a token an OAuth client got on behalf of the user, so that the
+// scopes of the client cannot exceed them.
+func withDelegatedPermissions(ctx context.Context, permissions PermissionReader) (context.Context, error) {
+	if !delegated(ctx) {
+		return ctx, nil
+	}
+
+	userID, _ := UserIDFromContext(ctx)
+
+	granted, err := permissions.Permissions(userID)
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	return context.WithValue(ctx, permissionsKey, granted), nil
+}
+
+// withAPIKey returns the request context carrying the API key, its scopes and
+// claims and, for personal keys, the user.
+func withAPIKey(r *http.Request, key *calltypes.APIKey) context.Context {
+	ctx := context.WithValue(r.Context(), apiKeyKey, key)
+	ctx = context.WithValue(ctx, scopesKey, key.Scopes)
+
+	claims := map[string]interface{}{
+		"api_key": key.ID,
+		"kind":    key.Kind,
+		"scope":   strings.Join(key.Scopes, " "),
+	}
+
+	if key.UserID != 0 {
+		ctx = context.WithValue(ctx, userIDKey, key.UserID)
+		claims["sub"] = float64(key.UserID)
+	}
+
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// scoped reports whether the request is limited to granted scopes, which is
+// the case for OAuth clients and API keys.
+func scoped(ctx context.Context) bool {
+	_, isClient := ClientIDFromContext(ctx)
+	_, isAPIKey := APIKeyFromContext(ctx)
+
+	return isClient || isAPIKey
+}
+
+// delegated reports whether the request carries a token an OAuth client got on
+// behalf of a user.
+func delegated(ctx context.Context) bool {
+	_, isClient := ClientIDFromContext(ctx)
+	_, hasUser := UserIDFromContext(ctx)
+
+	return isClient && hasUser
+}
+
+// accessTokenFromRequest returns a bearer token of the Authorization header,
+// falling back to the accessToken cookie.
+func accessTokenFromRequest(r *http.Request) (string, bool) {
+	if scheme, accessToken, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return accessToken, true
+	}
+
+	accessCookie, err := r.Cookie("accessToken")
+	if err != nil {
+		return "", false
+	}
+
+	return accessCookie.Value, true
+}
+
 // handleAuthError handle errors from Auth middleware.
 func handleAuthError(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
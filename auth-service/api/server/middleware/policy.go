@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"auth-service/internal/policy"
+	"auth-service/pkg/errormsg"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const policyAllowedKey contextKey = "policyAllowed"
+
+// PolicyEngine decides on requests with policy rules.
+type PolicyEngine interface {
+	Evaluate(input policy.Input, resource policy.ResourceFunc) policy.Decision
+}
+
+// ResourceLoader loads the resource targeted by the request, e.g. the user in
+// the URL, for policy rules.
+type ResourceLoader func(r *http.Request) (map[string]interface{}, error)
+
+// Authorize middleware evaluates policy rules covering the route against the
+// token claims, the route and the target resource. Requests the policy denies
+// are rejected, requests it allows skip RequirePermission and RequireOwner of
+// the route. Routes without rules are left to those checks. It must be used
+// after Auth.
+func Authorize(engine PolicyEngine, resources ResourceLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input := policy.Input{
+				Claims: ClaimsFromContext(r.Context()),
+				Route: policy.Route{
+					Method: r.Method,
+					Path:   r.URL.Path,
+				},
+			}
+
+			if routeContext := chi.RouteContext(r.Context()); routeContext != nil {
+				input.Route.Pattern = routeContext.RoutePattern()
+				input.Route.Params = make(map[string]string, len(routeContext.URLParams.Keys))
+
+				for i, key := range routeContext.URLParams.Keys {
+					input.Route.Params[key] = routeContext.URLParams.Values[i]
+				}
+			}
+
+			decision := engine.Evaluate(input, func() (map[string]interface{}, error) {
+				return resources(r)
+			})
+
+			switch {
+			case decision.Denied():
+				handleForbidden(w, errormsg.ErrPolicyDenied.Error())
+
+				return
+			case decision.Allowed():
+				r = r.WithContext(context.WithValue(r.Context(), policyAllowedKey, true))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// policyAllowed reports whether a policy rule allowed the request.
+func policyAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(policyAllowedKey).(bool)
+
+	return allowed
+}
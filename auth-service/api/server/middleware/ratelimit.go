@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"auth-service/api/server/httputils"
+	"auth-service/internal/ratelimit"
+	"auth-service/pkg/errormsg"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc returns the rate limit key of the request.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP limits requests per client IP.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// KeyByUser limits requests per authenticated user and falls back to the client
+// IP for anonymous requests. It must run after Auth middleware.
+func KeyByUser(r *http.Request) string {
+	if id, ok := UserIDFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(id)
+	}
+
+	return KeyByIP(r)
+}
+
+// KeyByAPIKey limits requests per API key sent in the X-API-Key header and falls
+// back to the client IP. The key itself is hashed so it never reaches the store.
+func KeyByAPIKey(r *http.Request) string {
+	apiKey := r.Header.Get(APIKeyHeader)
+	if apiKey == "" {
+		return KeyByIP(r)
+	}
+
+	sum := sha256.Sum256([]byte(apiKey))
+
+	return "key:" + hex.EncodeToString(sum[:])
+}
+
+// KeyFuncByName returns KeyFunc by its configuration name: "ip", "user" or "apikey".
+func KeyFuncByName(name string) (KeyFunc, bool) {
+	switch name {
+	case "ip":
+		return KeyByIP, true
+	case "user":
+		return KeyByUser, true
+	case "apikey":
+		return KeyByAPIKey, true
+	default:
+		return nil, false
+	}
+}
+
+// RateLimit middleware limits requests to the route named name with a token bucket
+// per key. It sets RateLimit-* headers and answers 429 with Retry-After once the
+// bucket is empty. Store failures let the request through.
+func RateLimit(store ratelimit.Store, name string, rule ratelimit.Rule, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(name+"|"+key(r), rule)
+			if err != nil {
+				log.Println("rate limit store failed: ", err)
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				httputils.ErrorJSON(w, errormsg.ErrRateLimited, http.StatusTooManyRequests)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyQuota middleware gives every API key its own token bucket, with the
+// rate limit of the key or fallback. Other requests pass. It must be used after
+// Auth.
+func APIKeyQuota(store ratelimit.Store, fallback ratelimit.Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := APIKeyFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			rule := fallback
+
+			if key.RateLimit != "" {
+				custom, err := ratelimit.ParseRule(key.RateLimit)
+				if err != nil {
+					log.Printf("API key %s has invalid rate limit: %v", key.ID, err)
+				} else {
+					rule = custom
+				}
+			}
+
+			RateLimit(store, "apikey", rule, func(*http.Request) string { return key.ID })(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
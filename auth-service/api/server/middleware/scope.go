@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"auth-service/pkg/errormsg"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequirePermission middleware lets user sessions through only if roles of the
+// user grant the permission, and OAuth clients and API keys only if they were
+// granted it as a scope; clients acting for a user also need the user to hold
+// it. Requests allowed by a policy rule pass. It must be used after Auth.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !permitted(w, r, permission) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireOwner middleware lets user sessions through if the user ID in the URL
+// parameter is their own, and otherwise only with the permission, as
+// RequirePermission does. OAuth clients and API keys need the permission as a
+// scope even for their own user. It must be used after Auth.
+func RequireOwner(param, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !owner(r, param) && !permitted(w, r, permission) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSelf middleware lets through only user sessions of the user in the
+// URL parameter; no permission stands in for ownership. It must be used after
+// Auth.
+func RequireSelf(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !owner(r, param) {
+				handleForbidden(w, errormsg.ErrPermissionDenied.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// permitted reports whether the request holds the permission, writing a 403
+// response otherwise.
+func permitted(w http.ResponseWriter, r *http.Request, permission string) bool {
+	if policyAllowed(r.Context()) {
+		return true
+	}
+
+	if scoped(r.Context()) {
+		if !slices.Contains(ScopesFromContext(r.Context()), permission) {
+			handleForbidden(w, errormsg.ErrInsufficientScope.Error()+": "+permission)
+
+			return false
+		}
+
+		// A client acting for a user gets no more than the user holds.
+		if delegated(r.Context()) && !slices.Contains(PermissionsFromContext(r.Context()), permission) {
+			handleForbidden(w, errormsg.ErrPermissionDenied.Error()+": "+permission)
+
+			return false
+		}
+	} else if !slices.Contains(PermissionsFromContext(r.Context()), permission) {
+		handleForbidden(w, errormsg.ErrPermissionDenied.Error()+": "+permission)
+
+		return false
+	}
+
+	return true
+}
+
+// owner reports whether the request is a user session of the user in the URL
+// parameter.
+func owner(r *http.Request, param string) bool {
+	if scoped(r.Context()) {
+		return false
+	}
+
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		return false
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(chi.URLParam(r, param)))
+
+	return err == nil && id == userID
+}
+
+// SessionOnly middleware rejects access tokens issued to OAuth clients and API
+// keys, so that account management stays out of reach of third-party
+// applications and scripts. It must be used after Auth.
+func SessionOnly() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scoped(r.Context()) {
+				handleForbidden(w, errormsg.ErrSessionRequired.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
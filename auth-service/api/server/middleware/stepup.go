@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// strongFactors are authentication methods that satisfy step-up: a completed
+// second factor. Passkey logins with user verification carry it too; a passkey
+// without user verification proves possession only and does not count.
+var strongFactors = []string{token.AMRMFA} //nolint: gochecknoglobals
+
+// StepUp middleware lets through only requests whose access token was issued
+// after a strong authentication. It must be used after Auth. Rejected requests
+// get the insufficient_user_authentication challenge of RFC 9470.
+func StepUp() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !SteppedUp(r.Context()) {
+				handleStepUpRequired(w)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SteppedUp reports whether the access token of the request was issued after
+// a strong authentication.
+func SteppedUp(ctx context.Context) bool {
+	amr := AMRFromContext(ctx)
+
+	return slices.ContainsFunc(strongFactors, func(method string) bool {
+		return slices.Contains(amr, method)
+	})
+}
+
+func handleStepUpRequired(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication", `+
+		`error_description="a second factor or a user-verified passkey is required"`)
+	w.WriteHeader(http.StatusUnauthorized)
+
+	err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   true,
+		"message": errormsg.ErrStepUpRequired.Error(),
+	})
+	if err != nil {
+		return
+	}
+}
@@ -1,10 +1,71 @@
 package network
 
 import (
+	"auth-service/api/server/middleware"
+	"auth-service/internal/dataexport"
+	"auth-service/internal/emailchange"
+	"auth-service/internal/emaillogin"
+	"auth-service/internal/erasure"
+	"auth-service/internal/federation"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mfa"
+	"auth-service/internal/notify"
+	"auth-service/internal/oauth"
+	"auth-service/internal/passwordreset"
+	"auth-service/internal/policy"
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/saml"
+	"auth-service/internal/webauthn"
+	"auth-service/pkg/consts"
 	"auth-service/pkg/errormsg"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	RateLimitMemory   = "memory"
+	RateLimitPostgres = "postgres"
+)
+
+const (
+	MailSMTP = "smtp"
+	// MailLog prints messages to the log without their bodies. It is meant
+	// for development only.
+	MailLog = "log"
+)
+
+// RouteLimit is the rate limit of one route.
+type RouteLimit struct {
+	Rule ratelimit.Rule
+	// Key is the name of the key function: "ip", "user" or "apikey".
+	Key string
+}
+
+// rateLimitedRoutes lists routes with rate limits and their defaults.
+var rateLimitedRoutes = map[string]string{ //nolint: gochecknoglobals
+	"authenticate":           consts.RateLimitAuthenticate,
+	"registrate":             consts.RateLimitRegistrate,
+	"provide":                consts.RateLimitProvide,
+	"authenticate_mfa":       consts.RateLimitAuthMFA,
+	"authenticate_passkey":   consts.RateLimitAuthPasskey,
+	"authenticate_email":     consts.RateLimitAuthEmail,
+	"oauth_token":            consts.RateLimitOAuthToken,
+	"oauth_device":           consts.RateLimitOAuthDevice,
+	"authenticate_federated": consts.RateLimitAuthFederated,
+	"password_reset":         consts.RateLimitPassReset,
+	"email_change":           consts.RateLimitEmailChange,
+	"data_export":            consts.RateLimitDataExport,
+}
+
+// rateLimitKeys lists routes limited by other keys than the client IP by
+// default.
+var rateLimitKeys = map[string]string{ //nolint: gochecknoglobals
+	"data_export": "user",
+}
+
 type Config struct {
 	DB struct {
 		DSN string
@@ -15,6 +76,58 @@ type Config struct {
 	JWT struct {
 		Secret string
 	}
+	Admin struct {
+		Token string
+	}
+	Mail struct {
+		// Backend is either "smtp" or "log".
+		Backend string
+		SMTP    notify.SMTPConfig
+	}
+	Email struct {
+		// LowercaseLocal stores emails fully lowercased. Their domain is
+		// lowercased either way.
+		LowercaseLocal bool
+	}
+	Lockout   lockout.Policy
+	RateLimit struct {
+		// Backend is either "memory" or "postgres".
+		Backend string
+		Routes  map[string]RouteLimit
+		// APIKey is the quota of every API key without its own rate limit.
+		APIKey ratelimit.Rule
+	}
+	MFA struct {
+		mfa.Config
+		// EncryptionKey encrypts TOTP secrets at rest. MFA is disabled without it.
+		EncryptionKey string
+	}
+	// WebAuthn configures passkeys. Passkeys are disabled without RPID.
+	WebAuthn webauthn.Config
+	// EmailLogin configures passwordless login. It is disabled without Secret.
+	EmailLogin emaillogin.Config
+	// OAuth configures the authorization server. It is disabled without Issuer,
+	// OpenID Connect is disabled without SigningKey.
+	OAuth oauth.Config
+	// Federation configures login through external OpenID providers. It is
+	// disabled without providers.
+	Federation federation.Config
+	// SAML configures login through SAML identity providers. It is disabled
+	// without providers.
+	SAML saml.Config
+	// Policy configures the policy engine. It is disabled without Dir.
+	Policy policy.Config
+	// PasswordReset configures password reset links sent on behalf of
+	// administrators.
+	PasswordReset passwordreset.Config
+	// EmailChange configures the links sent when users change their email.
+	EmailChange emailchange.Config
+	// AccountDeletion configures the deletion of accounts on request of their
+	// owners.
+	AccountDeletion erasure.Config
+	// DataExport configures personal data exports. It is disabled without
+	// Secret.
+	DataExport dataexport.Config
 }
 
 func Load() (*Config, error) {
@@ -22,6 +135,7 @@ func Load() (*Config, error) {
 
 	cfg.DB.DSN = os.Getenv("DSN")
 	cfg.Server.Port = os.Getenv("PORT")
+	cfg.Admin.Token = os.Getenv("ADMIN_TOKEN")
 
 	if cfg.DB.DSN == "" {
 		return nil, errormsg.ErrDSNRequired
@@ -31,5 +145,544 @@ func Load() (*Config, error) {
 		return nil, errormsg.ErrServerPortRequired
 	}
 
+	if err := loadMail(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadEmail(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadLockout(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadRateLimit(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadMFA(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadWebAuthn(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadEmailLogin(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadOAuth(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadFederation(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadSAML(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadPolicy(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadPasswordReset(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadEmailChange(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadAccountDeletion(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadDataExport(cfg); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+func loadMail(cfg *Config) error {
+	var err error
+
+	cfg.Mail.Backend = envString("MAIL_BACKEND", MailSMTP)
+	if cfg.Mail.Backend == MailLog {
+		return nil
+	}
+
+	if cfg.Mail.Backend != MailSMTP {
+		return fmt.Errorf("%w: MAIL_BACKEND", errormsg.ErrInvalidConfig)
+	}
+
+	cfg.Mail.SMTP.Host = os.Getenv("SMTP_HOST")
+	cfg.Mail.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	cfg.Mail.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	cfg.Mail.SMTP.From = os.Getenv("MAIL_FROM")
+
+	if cfg.Mail.SMTP.Port, err = envInt("SMTP_PORT", consts.SMTPPort); err != nil {
+		return err
+	}
+
+	if cfg.Mail.SMTP.Host == "" {
+		return fmt.Errorf("%w: SMTP_HOST", errormsg.ErrInvalidConfig)
+	}
+
+	if cfg.Mail.SMTP.From == "" {
+		return fmt.Errorf("%w: MAIL_FROM", errormsg.ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+func loadEmail(cfg *Config) error {
+	var err error
+
+	if cfg.Email.LowercaseLocal, err = envBool("EMAIL_LOWERCASE_LOCAL", false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func loadLockout(cfg *Config) error {
+	var err error
+
+	if cfg.Lockout.MaxAccountFailures, err = envInt("LOCKOUT_MAX_FAILURES", consts.LockoutMaxFailures); err != nil {
+		return err
+	}
+
+	if cfg.Lockout.MaxIPFailures, err = envInt("LOCKOUT_MAX_IP_FAILURES", consts.LockoutMaxIPFailures); err != nil {
+		return err
+	}
+
+	if cfg.Lockout.LockDuration, err = envDuration("LOCKOUT_DURATION", consts.LockoutDuration); err != nil {
+		return err
+	}
+
+	if cfg.Lockout.BaseDelay, err = envDuration("LOCKOUT_BASE_DELAY", consts.LockoutBaseDelay); err != nil {
+		return err
+	}
+
+	if cfg.Lockout.MaxDelay, err = envDuration("LOCKOUT_MAX_DELAY", consts.LockoutMaxDelay); err != nil {
+		return err
+	}
+
+	if cfg.Lockout.FailureWindow, err = envDuration("LOCKOUT_FAILURE_WINDOW", consts.LockoutFailureWindow); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadRateLimit reads RATE_LIMIT_<ROUTE> rules such as "10/1m:20",
+// RATE_LIMIT_<ROUTE>_KEY key function names and the default API key quota
+// RATE_LIMIT_APIKEY.
+func loadRateLimit(cfg *Config) error {
+	cfg.RateLimit.Backend = envString("RATE_LIMIT_BACKEND", RateLimitMemory)
+	if cfg.RateLimit.Backend != RateLimitMemory && cfg.RateLimit.Backend != RateLimitPostgres {
+		return fmt.Errorf("%w: RATE_LIMIT_BACKEND", errormsg.ErrInvalidConfig)
+	}
+
+	cfg.RateLimit.Routes = make(map[string]RouteLimit, len(rateLimitedRoutes))
+
+	for name, fallback := range rateLimitedRoutes {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(name)
+
+		rule, err := ratelimit.ParseRule(envString(prefix, fallback))
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", errormsg.ErrInvalidConfig, prefix, err)
+		}
+
+		defaultKey, ok := rateLimitKeys[name]
+		if !ok {
+			defaultKey = "ip"
+		}
+
+		key := envString(prefix+"_KEY", defaultKey)
+		if _, ok := middleware.KeyFuncByName(key); !ok {
+			return fmt.Errorf("%w: %s_KEY", errormsg.ErrInvalidConfig, prefix)
+		}
+
+		cfg.RateLimit.Routes[name] = RouteLimit{Rule: rule, Key: key}
+	}
+
+	rule, err := ratelimit.ParseRule(envString("RATE_LIMIT_APIKEY", consts.RateLimitAPIKey))
+	if err != nil {
+		return fmt.Errorf("%w: RATE_LIMIT_APIKEY: %w", errormsg.ErrInvalidConfig, err)
+	}
+
+	cfg.RateLimit.APIKey = rule
+
+	return nil
+}
+
+func loadMFA(cfg *Config) error {
+	var err error
+
+	cfg.MFA.EncryptionKey = os.Getenv("MFA_ENCRYPTION_KEY")
+	cfg.MFA.Issuer = envString("MFA_ISSUER", consts.MFAIssuer)
+
+	if cfg.MFA.Skew, err = envInt("MFA_TOTP_SKEW", consts.MFATOTPSkew); err != nil {
+		return err
+	}
+
+	if cfg.MFA.ChallengeTTL, err = envDuration("MFA_CHALLENGE_TTL", consts.MFAChallengeTTL); err != nil {
+		return err
+	}
+
+	if cfg.MFA.MaxChallengeAttempts, err = envInt("MFA_CHALLENGE_ATTEMPTS", consts.MFAChallengeAttempts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadWebAuthn reads relying party settings. WEBAUTHN_ORIGINS is a comma
+// separated list and defaults to https://<WEBAUTHN_RP_ID>.
+func loadWebAuthn(cfg *Config) error {
+	var err error
+
+	cfg.WebAuthn.RPID = os.Getenv("WEBAUTHN_RP_ID")
+	cfg.WebAuthn.RPName = envString("WEBAUTHN_RP_NAME", consts.WebAuthnRPName)
+
+	for _, origin := range strings.Split(envString("WEBAUTHN_ORIGINS", "https://"+cfg.WebAuthn.RPID), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.WebAuthn.Origins = append(cfg.WebAuthn.Origins, origin)
+		}
+	}
+
+	if cfg.WebAuthn.Timeout, err = envDuration("WEBAUTHN_TIMEOUT", consts.WebAuthnTimeout); err != nil {
+		return err
+	}
+
+	cfg.WebAuthn.UserVerification = envString("WEBAUTHN_USER_VERIFICATION", consts.WebAuthnVerification)
+
+	switch cfg.WebAuthn.UserVerification {
+	case webauthn.VerificationRequired, webauthn.VerificationPreferred, webauthn.VerificationDiscouraged:
+		return nil
+	default:
+		return fmt.Errorf("%w: WEBAUTHN_USER_VERIFICATION", errormsg.ErrInvalidConfig)
+	}
+}
+
+func loadEmailLogin(cfg *Config) error {
+	var err error
+
+	cfg.EmailLogin.Secret = os.Getenv("EMAIL_LOGIN_SECRET")
+	cfg.EmailLogin.LinkURL = envString("EMAIL_LOGIN_LINK_URL", "http://localhost:"+cfg.Server.Port+"/authenticate/email/link")
+
+	if cfg.EmailLogin.CodeTTL, err = envDuration("EMAIL_LOGIN_CODE_TTL", consts.EmailLoginCodeTTL); err != nil {
+		return err
+	}
+
+	if cfg.EmailLogin.LinkTTL, err = envDuration("EMAIL_LOGIN_LINK_TTL", consts.EmailLoginLinkTTL); err != nil {
+		return err
+	}
+
+	if cfg.EmailLogin.MaxAttempts, err = envInt("EMAIL_LOGIN_ATTEMPTS", consts.EmailLoginAttempts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func loadOAuth(cfg *Config) error {
+	var err error
+
+	cfg.OAuth.Issuer = os.Getenv("OAUTH_ISSUER")
+	cfg.OAuth.LoginURL = os.Getenv("OAUTH_LOGIN_URL")
+
+	if cfg.OAuth.CodeTTL, err = envDuration("OAUTH_CODE_TTL", consts.OAuthCodeTTL); err != nil {
+		return err
+	}
+
+	if cfg.OAuth.RefreshTokenTTL, err = envDuration("OAUTH_REFRESH_TOKEN_TTL", consts.OAuthRefreshTokenTTL); err != nil {
+		return err
+	}
+
+	if cfg.OAuth.DeviceCodeTTL, err = envDuration("OAUTH_DEVICE_CODE_TTL", consts.OAuthDeviceCodeTTL); err != nil {
+		return err
+	}
+
+	cfg.OAuth.DeviceVerificationURL = os.Getenv("OAUTH_DEVICE_VERIFICATION_URL")
+
+	if path := os.Getenv("OIDC_SIGNING_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read OIDC_SIGNING_KEY_FILE: %w", err)
+		}
+
+		if cfg.OAuth.SigningKey, err = oauth.ParseSigningKey(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadFederation reads FEDERATION_PROVIDERS, a comma separated list of provider
+// names, and FEDERATION_<NAME>_* settings of every provider.
+func loadFederation(cfg *Config) error {
+	var err error
+
+	cfg.Federation.BaseURL = envString("FEDERATION_BASE_URL", "http://localhost:"+cfg.Server.Port+"/federation")
+
+	if cfg.Federation.LoginTTL, err = envDuration("FEDERATION_LOGIN_TTL", consts.FederationLoginTTL); err != nil {
+		return err
+	}
+
+	names, err := providerNames("FEDERATION_PROVIDERS")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		prefix := "FEDERATION_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+		provider := federation.ProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "_ISSUER"),
+			ClientID:     os.Getenv(prefix + "_CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "_CLIENT_SECRET"),
+			Scope:        envString(prefix+"_SCOPE", federation.DefaultScope),
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return fmt.Errorf("%w: %s_ISSUER and %s_CLIENT_ID are required", errormsg.ErrInvalidConfig, prefix, prefix)
+		}
+
+		if provider.TrustEmail, err = envBool(prefix+"_TRUST_EMAIL", false); err != nil {
+			return err
+		}
+
+		cfg.Federation.Providers = append(cfg.Federation.Providers, provider)
+	}
+
+	return nil
+}
+
+// loadSAML reads SAML_PROVIDERS, a comma separated list of identity provider
+// names, and SAML_<NAME>_* settings of every provider.
+func loadSAML(cfg *Config) error {
+	var err error
+
+	cfg.SAML.BaseURL = envString("SAML_BASE_URL", "http://localhost:"+cfg.Server.Port+"/saml")
+	cfg.SAML.EntityID = os.Getenv("SAML_ENTITY_ID")
+
+	if cfg.SAML.RequestTTL, err = envDuration("SAML_REQUEST_TTL", consts.SAMLRequestTTL); err != nil {
+		return err
+	}
+
+	if cfg.SAML.ClockSkew, err = envDuration("SAML_CLOCK_SKEW", consts.SAMLClockSkew); err != nil {
+		return err
+	}
+
+	names, err := providerNames("SAML_PROVIDERS")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		prefix := "SAML_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+		provider := saml.ProviderConfig{
+			Name:               name,
+			EntityID:           os.Getenv(prefix + "_ENTITY_ID"),
+			SSOURL:             os.Getenv(prefix + "_SSO_URL"),
+			EmailAttribute:     envString(prefix+"_EMAIL_ATTRIBUTE", saml.AttributeEmail),
+			FirstNameAttribute: envString(prefix+"_FIRST_NAME_ATTRIBUTE", saml.AttributeFirstName),
+			LastNameAttribute:  envString(prefix+"_LAST_NAME_ATTRIBUTE", saml.AttributeLastName),
+		}
+
+		for _, domain := range strings.Split(os.Getenv(prefix+"_EMAIL_DOMAINS"), ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				provider.EmailDomains = append(provider.EmailDomains, domain)
+			}
+		}
+
+		if provider.TrustEmail, err = envBool(prefix+"_TRUST_EMAIL", false); err != nil {
+			return err
+		}
+
+		path := os.Getenv(prefix + "_CERTIFICATE_FILE")
+		if provider.EntityID == "" || provider.SSOURL == "" || path == "" {
+			return fmt.Errorf("%w: %s_ENTITY_ID, %s_SSO_URL and %s_CERTIFICATE_FILE are required",
+				errormsg.ErrInvalidConfig, prefix, prefix, prefix)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_CERTIFICATE_FILE: %w", prefix, err)
+		}
+
+		if provider.Certificate, err = saml.ParseCertificate(data); err != nil {
+			return err
+		}
+
+		cfg.SAML.Providers = append(cfg.SAML.Providers, provider)
+	}
+
+	return nil
+}
+
+func loadPolicy(cfg *Config) error {
+	var err error
+
+	cfg.Policy.Dir = os.Getenv("POLICY_DIR")
+	cfg.Policy.DecisionLog = os.Getenv("POLICY_DECISION_LOG")
+
+	if cfg.Policy.ReloadInterval, err = envDuration("POLICY_RELOAD_INTERVAL", consts.PolicyReloadInterval); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func loadPasswordReset(cfg *Config) error {
+	var err error
+
+	cfg.PasswordReset.URL = envString("PASSWORD_RESET_URL", "http://localhost:"+cfg.Server.Port+"/password/reset")
+
+	if cfg.PasswordReset.TTL, err = envDuration("PASSWORD_RESET_TTL", consts.PasswordResetTTL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func loadEmailChange(cfg *Config) error {
+	var err error
+
+	cfg.EmailChange.ConfirmURL = envString("EMAIL_CHANGE_CONFIRM_URL", "http://localhost:"+cfg.Server.Port+"/email-change/confirm")
+	cfg.EmailChange.CancelURL = envString("EMAIL_CHANGE_CANCEL_URL", "http://localhost:"+cfg.Server.Port+"/email-change/cancel")
+
+	if cfg.EmailChange.TTL, err = envDuration("EMAIL_CHANGE_TTL", consts.EmailChangeTTL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func loadAccountDeletion(cfg *Config) error {
+	var err error
+
+	if cfg.AccountDeletion.GracePeriod, err = envDuration("ACCOUNT_DELETION_GRACE_PERIOD", consts.AccountDeletionGrace); err != nil {
+		return err
+	}
+
+	if cfg.AccountDeletion.Interval, err = envDuration("ACCOUNT_ERASURE_INTERVAL", consts.AccountErasureInterval); err != nil {
+		return err
+	}
+
+	if cfg.AccountDeletion.Interval <= 0 {
+		return fmt.Errorf("%w: ACCOUNT_ERASURE_INTERVAL", errormsg.ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+func loadDataExport(cfg *Config) error {
+	var err error
+
+	cfg.DataExport.Secret = os.Getenv("EXPORT_SECRET")
+	cfg.DataExport.Dir = envString("EXPORT_DIR", consts.DataExportDir)
+	cfg.DataExport.DownloadURL = envString("EXPORT_DOWNLOAD_URL", "http://localhost:"+cfg.Server.Port+"/exports")
+
+	if cfg.DataExport.LinkTTL, err = envDuration("EXPORT_LINK_TTL", consts.DataExportLinkTTL); err != nil {
+		return err
+	}
+
+	if cfg.DataExport.Retention, err = envDuration("EXPORT_RETENTION", consts.DataExportRetention); err != nil {
+		return err
+	}
+
+	if cfg.DataExport.Interval, err = envDuration("EXPORT_INTERVAL", consts.DataExportInterval); err != nil {
+		return err
+	}
+
+	if cfg.DataExport.Interval <= 0 {
+		return fmt.Errorf("%w: EXPORT_INTERVAL", errormsg.ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// providerNames reads a comma separated list of identity provider names.
+func providerNames(key string) ([]string, error) {
+	var names []string
+
+	for _, name := range strings.Split(os.Getenv(key), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		// Names appear in URLs and variable names.
+		if strings.ContainsFunc(name, func(c rune) bool { return (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' }) {
+			return nil, fmt.Errorf("%w: %s: invalid name %q", errormsg.ErrInvalidConfig, key, name)
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// envString reads a variable, returning fallback when it is not set.
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// envInt reads an integer variable, returning fallback when it is not set.
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%w: %s", errormsg.ErrInvalidConfig, key)
+	}
+
+	return number, nil
+}
+
+// envBool reads a boolean variable, returning fallback when it is not set.
+func envBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", errormsg.ErrInvalidConfig, key)
+	}
+
+	return flag, nil
+}
+
+// envDuration reads a duration variable such as "15m", returning fallback when it is not set.
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%w: %s", errormsg.ErrInvalidConfig, key)
+	}
+
+	return duration, nil
+}
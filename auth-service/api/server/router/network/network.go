@@ -5,25 +5,117 @@ import (
 	"net/http"
 
 	"auth-service/api/server/middleware"
+	"auth-service/internal/ratelimit"
 	"auth-service/internal/service"
+	"auth-service/pkg/consts"
 )
 
 // SetupRoutes set up the Routes
 // @BasePath.
-func SetupRoutes(svc *service.RewardService) http.Handler {
+func SetupRoutes(svc *service.RewardService, cfg *Config, limiter ratelimit.Store) http.Handler {
 	r := chi.NewRouter()
 
+	limit := func(name string) func(http.Handler) http.Handler {
+		route := cfg.RateLimit.Routes[name]
+		key, _ := middleware.KeyFuncByName(route.Key)
+
+		return middleware.RateLimit(limiter, name, route.Rule, key)
+	}
+
 	r.Group(func(secure chi.Router) {
-		secure.Use(middleware.Auth())
+		secure.Use(middleware.Auth(svc.APIKeys, svc.Repo, svc), middleware.APIKeyQuota(limiter, cfg.RateLimit.APIKey))
+
+		if svc.Policy != nil {
+			secure.Use(middleware.Authorize(svc.Policy, svc.PolicyResource))
+		}
+
+		secure.Get("/users/me", svc.RetrieveMe)
+		secure.With(middleware.RequireOwner("id", consts.PermissionUsersRead)).Get("/users/{id}/status", svc.RetrieveOne)
+		secure.With(middleware.RequirePermission(consts.PermissionUsersRead)).Get("/users/leaderboard", svc.GetLeaderboard)
+		secure.Get("/userinfo", svc.UserInfo)
+		secure.Post("/userinfo", svc.UserInfo)
+
+		secure.With(middleware.RequirePermission(consts.PermissionUsersRead)).Get("/admin/users", svc.ListUsers)
+		secure.With(middleware.RequirePermission(consts.PermissionUsersRead)).Get("/admin/users/{id}", svc.GetUser)
+
+		secure.Group(func(users chi.Router) {
+			users.Use(middleware.RequirePermission(consts.PermissionUsersWrite))
+
+			users.Post("/admin/users", svc.CreateUser)
+			users.Put("/admin/users/{id}", svc.UpdateUser)
+			users.Post("/admin/users/{id}/deactivate", svc.DeactivateUser)
+			users.Post("/admin/users/{id}/reactivate", svc.ReactivateUser)
+			users.Post("/admin/users/{id}/logout", svc.LogoutUser)
+			users.Post("/admin/users/{id}/mfa/reset", svc.ResetUserMFA)
+			users.Post("/admin/users/{id}/password-reset", svc.SendPasswordReset)
+		})
+
+		secure.Group(func(session chi.Router) {
+			session.Use(middleware.SessionOnly())
+
+			session.With(middleware.RequireOwner("id", consts.PermissionUsersWrite)).Get("/refresh/{id}", svc.Refresh)
+			session.With(middleware.RequireSelf("id"), limit("provide")).Get("/provide/{id}", svc.Provide)
+			session.Patch("/users/me", svc.UpdateMe)
+			session.Delete("/users/me", svc.DeleteMe)
+			session.Delete("/users/me/deletion", svc.CancelDeleteMe)
+			session.With(limit("data_export")).Post("/users/me/exports", svc.RequestDataExport)
+			session.Get("/users/me/exports/{id}", svc.GetDataExport)
+			session.With(limit("email_change")).Post("/users/me/email", svc.StartEmailChange)
+			session.Post("/mfa/totp/enroll", svc.EnrollTOTP)
+			session.Post("/mfa/totp/confirm", svc.ConfirmTOTP)
+			session.Post("/mfa/recovery-codes", svc.RegenerateRecoveryCodes)
+			session.Post("/webauthn/register/begin", svc.BeginPasskeyRegistration)
+			session.Post("/webauthn/register/finish", svc.FinishPasskeyRegistration)
+			session.Get("/webauthn/credentials", svc.ListPasskeys)
+			session.Get("/oauth/device", svc.OAuthDeviceRequest)
+			session.With(limit("oauth_device")).Post("/oauth/device", svc.DecideOAuthDevice)
+			session.With(middleware.StepUp()).Delete("/webauthn/credentials/{id}", svc.DeletePasskey)
+			session.Get("/apikeys", svc.ListAPIKeys)
+			session.Post("/apikeys", svc.CreateAPIKey)
+			session.Delete("/apikeys/{id}", svc.RevokeAPIKey)
+		})
+	})
+
+	r.Group(func(admin chi.Router) {
+		admin.Use(middleware.Admin(cfg.Admin.Token))
 
-		secure.Get("/users/{id}/status", svc.RetrieveOne)
-		secure.Get("/users/leaderboard", svc.GetLeaderboard)
-		secure.Get("/refresh/{id}", svc.Refresh)
+		admin.Post("/admin/users/import", svc.ImportUsers)
+		admin.Post("/admin/users/{id}/unlock", svc.UnlockUser)
+		admin.Post("/admin/oauth/clients", svc.RegisterOAuthClient)
+		admin.Get("/admin/apikeys", svc.ListServiceAPIKeys)
+		admin.Post("/admin/apikeys", svc.CreateServiceAPIKey)
+		admin.Delete("/admin/apikeys/{id}", svc.RevokeAnyAPIKey)
+		admin.Get("/admin/roles", svc.ListRoles)
+		admin.Put("/admin/roles/{name}", svc.SaveRole)
+		admin.Delete("/admin/roles/{name}", svc.DeleteRole)
+		admin.Get("/admin/users/{id}/roles", svc.ListUserRoles)
+		admin.Put("/admin/users/{id}/roles/{role}", svc.AssignRole)
+		admin.Delete("/admin/users/{id}/roles/{role}", svc.UnassignRole)
 	})
 
-	r.Post("/authenticate", svc.Authenticate)
-	r.Post("/registrate", svc.Registrate)
-	r.Get("/provide/{id}", svc.Provide)
+	r.With(limit("authenticate")).Post("/authenticate", svc.Authenticate)
+	r.With(limit("authenticate_mfa")).Post("/authenticate/mfa", svc.AuthenticateMFA)
+	r.With(limit("authenticate_passkey")).Post("/authenticate/passkey/begin", svc.BeginPasskeyLogin)
+	r.With(limit("authenticate_passkey")).Post("/authenticate/passkey/finish", svc.FinishPasskeyLogin)
+	r.With(limit("authenticate_email")).Post("/authenticate/email", svc.StartEmailLogin)
+	r.With(limit("authenticate_email")).Post("/authenticate/email/verify", svc.VerifyEmailCode)
+	r.With(limit("authenticate_email")).Get("/authenticate/email/link", svc.VerifyEmailLink)
+	r.Get("/federation/providers", svc.FederationProviders)
+	r.With(limit("authenticate_federated")).Get("/federation/{provider}/login", svc.BeginFederatedLogin)
+	r.With(limit("authenticate_federated")).Get("/federation/{provider}/callback", svc.CompleteFederatedLogin)
+	r.Get("/saml/metadata", svc.SAMLMetadata)
+	r.With(limit("authenticate_federated")).Get("/saml/{provider}/login", svc.BeginSAMLLogin)
+	r.With(limit("authenticate_federated")).Post("/saml/acs", svc.CompleteSAMLLogin)
+	r.With(limit("registrate")).Post("/registrate", svc.Registrate)
+	r.With(limit("password_reset")).Post("/password/reset", svc.ResetPassword)
+	r.With(limit("email_change")).Post("/email-change/confirm", svc.ConfirmEmailChange)
+	r.With(limit("email_change")).Post("/email-change/cancel", svc.CancelEmailChange)
+	r.Get("/exports/{id}", svc.DownloadDataExport)
+	r.With(middleware.OptionalAuth(svc.Repo)).Get("/oauth/authorize", svc.OAuthAuthorize)
+	r.With(limit("oauth_token")).Post("/oauth/token", svc.OAuthToken)
+	r.With(limit("oauth_token")).Post("/oauth/device_authorization", svc.OAuthDeviceAuthorization)
+	r.Get("/oauth/jwks", svc.OAuthJWKS)
+	r.Get("/.well-known/openid-configuration", svc.OpenIDConfiguration)
 
 	return r
 }
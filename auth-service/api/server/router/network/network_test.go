@@ -0,0 +1,545 @@
+package network_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/router/network"
+	"auth-service/internal/emailchange"
+	"auth-service/internal/erasure"
+	"auth-service/internal/mfa"
+	"auth-service/internal/notify"
+	"auth-service/internal/postgres/repository"
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/rbac"
+	"auth-service/internal/secretbox"
+	"auth-service/internal/service"
+	"auth-service/internal/token"
+	"auth-service/internal/webauthn"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPassword is the password of every user of stubRepository.
+const stubPassword = "correct-password"
+
+// stubRepository knows active users 4, 5 and 6; user 5 is an administrator.
+// Sessions of user 4 were revoked an hour ago, sessions of user 6 are revoked
+// whenever they are checked. Other methods are not used by the routes under
+// test.
+type stubRepository struct {
+	repository.Repository
+	repository.RoleRepository
+	repository.EmailChangeRepository
+	repository.AccountDeletionRepository
+	repository.MFARepository
+	repository.WebAuthnRepository
+}
+
+func (stubRepository) GetOne(id int) (*calltypes.User, error) {
+	return &calltypes.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Status: calltypes.UserStatusActive}, nil
+}
+
+func (stubRepository) GetByEmail(string) (*calltypes.User, error) {
+	return nil, errormsg.ErrUserNotFound
+}
+
+func (stubRepository) PasswordMatches(plainText string, _ calltypes.User) (bool, error) {
+	return plainText == stubPassword, nil
+}
+
+func (stubRepository) CreateEmailChange(calltypes.EmailChange) error {
+	return nil
+}
+
+// GetTOTP reports TOTP enabled for every user.
+func (stubRepository) GetTOTP(userID int) (*calltypes.TOTP, error) {
+	return &calltypes.TOTP{UserID: userID, Confirmed: true}, nil
+}
+
+func (stubRepository) SaveTOTP(int, string) error {
+	return errormsg.ErrMFAAlreadyEnabled
+}
+
+func (stubRepository) SavePendingTOTP(int, string) error {
+	return nil
+}
+
+func (stubRepository) ReplaceRecoveryCodes(int, []string) error {
+	return nil
+}
+
+// ConsumeRecoveryCode accepts any recovery code.
+func (stubRepository) ConsumeRecoveryCode(int, string) (bool, error) {
+	return true, nil
+}
+
+// ClaimMFAChallengeAttempt knows one pending login of user 4, who passed an
+// email code as the first factor.
+func (stubRepository) ClaimMFAChallengeAttempt(string, string, int, time.Time) (*calltypes.MFAChallenge, error) {
+	return &calltypes.MFAChallenge{UserID: 4, IP: "192.0.2.1", AMR: []string{token.AMROTP}, Attempts: 1}, nil
+}
+
+func (stubRepository) DeleteMFAChallenge(string) error {
+	return nil
+}
+
+func (stubRepository) GetAccountDeletion(int) (*calltypes.AccountDeletion, error) {
+	return nil, errormsg.ErrDeletionNotScheduled
+}
+
+func (stubRepository) CreateAccountDeletion(calltypes.AccountDeletion) error {
+	return nil
+}
+
+func (stubRepository) GetWebAuthnCredentials(int) ([]*calltypes.WebAuthnCredential, error) {
+	return nil, nil
+}
+
+func (stubRepository) CreateWebAuthnSession(calltypes.WebAuthnSession) error {
+	return nil
+}
+
+func (stubRepository) GetAll() ([]*calltypes.User, error) {
+	return []*calltypes.User{}, nil
+}
+
+func (stubRepository) GetUserRoles(userID int) ([]*calltypes.Role, error) {
+	if userID != 5 {
+		return nil, nil
+	}
+
+	return []*calltypes.Role{{Name: "admin", Permissions: []string{consts.PermissionUsersRead}}}, nil
+}
+
+func (stubRepository) UserStatus(int) (calltypes.UserStatus, error) {
+	return calltypes.UserStatusActive, nil
+}
+
+func (stubRepository) SessionState(id int) (*calltypes.SessionState, error) {
+	state := &calltypes.SessionState{Status: calltypes.UserStatusActive}
+
+	switch id {
+	case 4:
+		state.RevokedAt = time.Now().Add(-time.Hour)
+	case 6:
+		state.RevokedAt = time.Now()
+	}
+
+	return state, nil
+}
+
+func (stubRepository) StoreRefreshToken(int, string) error {
+	return nil
+}
+
+// setupRoutes returns the router of a service backed by stubRepository.
+func setupRoutes(t *testing.T) http.Handler {
+	t.Helper()
+
+	t.Setenv("DSN", "postgres://localhost/test")
+	t.Setenv("PORT", "8080")
+	t.Setenv("SECRET_KEY", "test_secret_key_1234567890")
+	t.Setenv("MAIL_BACKEND", "log")
+	t.Setenv("WEBAUTHN_RP_ID", "example.com")
+
+	cfg, err := network.Load()
+	require.NoError(t, err)
+
+	svc := service.NewRewardService(stubRepository{})
+	svc.RBAC = rbac.NewManager(stubRepository{})
+	svc.EmailChange = emailchange.NewManager(stubRepository{}, notify.NewLogMailer(), cfg.EmailChange)
+	svc.Erasure = erasure.NewManager(stubRepository{}, notify.NewLogMailer(), cfg.AccountDeletion)
+
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+
+	svc.MFA = mfa.NewManager(stubRepository{}, box, cfg.MFA.Config)
+	svc.Passkey = webauthn.NewRelyingParty(stubRepository{}, cfg.WebAuthn)
+
+	return network.SetupRoutes(svc, cfg, ratelimit.NewMemoryStore())
+}
+
+// accessToken returns a session token of the user, issued after a password
+// login unless amr says otherwise.
+func accessToken(t *testing.T, userID int, amr ...string) string {
+	t.Helper()
+
+	if len(amr) == 0 {
+		amr = []string{token.AMRPassword}
+	}
+
+	signed, err := token.NewTokenService().GenerateAccessToken(userID, "192.0.2.1", token.Access{}, amr...)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestSetupRoutes_Ownership(t *testing.T) {
+	router := setupRoutes(t)
+
+	tests := []struct {
+		name         string
+		path         string
+		accessToken  string
+		expectedCode int
+	}{
+		{name: "anonymous caller", path: "/provide/4", expectedCode: http.StatusUnauthorized},
+		{name: "forged token", path: "/provide/4", accessToken: "forged", expectedCode: http.StatusUnauthorized},
+		{name: "other user", path: "/provide/4", accessToken: accessToken(t, 5), expectedCode: http.StatusForbidden},
+		{name: "own session", path: "/provide/4", accessToken: accessToken(t, 4), expectedCode: http.StatusOK},
+		{name: "revoked session", path: "/provide/6", accessToken: accessToken(t, 6), expectedCode: http.StatusUnauthorized},
+		{name: "revoked delegated session", path: "/provide/6", accessToken: delegatedToken(t, 6, consts.PermissionUsersRead), expectedCode: http.StatusUnauthorized},
+		{name: "anonymous owner check", path: "/users/4/status", expectedCode: http.StatusUnauthorized},
+		{name: "other user owner check", path: "/users/4/status", accessToken: accessToken(t, 5), expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "192.0.2.1:12345"
+
+			if tt.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				assert.Empty(t, rr.Result().Cookies(), "no tokens may be issued")
+			}
+		})
+	}
+}
+
+// delegatedToken returns a token an OAuth client got on behalf of the user.
+func delegatedToken(t *testing.T, userID int, scope string) string {
+	t.Helper()
+
+	signed, err := token.NewTokenService().GenerateGrantToken(token.Grant{
+		UserID:   userID,
+		ClientID: "registered-app",
+		Scope:    scope,
+		ClientIP: "192.0.2.1",
+	})
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestSetupRoutes_DelegatedPermissions(t *testing.T) {
+	router := setupRoutes(t)
+
+	tests := []struct {
+		name         string
+		path         string
+		accessToken  string
+		expectedCode int
+	}{
+		{
+			name:         "non-admin through a client with users:read",
+			path:         "/admin/users",
+			accessToken:  delegatedToken(t, 4, consts.PermissionUsersRead),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "non-admin reading users through a client",
+			path:         "/users/leaderboard",
+			accessToken:  delegatedToken(t, 4, consts.PermissionUsersRead),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "admin through a client without the scope",
+			path:         "/users/leaderboard",
+			accessToken:  delegatedToken(t, 5, "profile"),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "admin through a client with the scope",
+			path:         "/users/leaderboard",
+			accessToken:  delegatedToken(t, 5, consts.PermissionUsersRead),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_EmailChangeReauthentication(t *testing.T) {
+	router := setupRoutes(t)
+
+	tests := []struct {
+		name         string
+		accessToken  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "password session without the password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"email":"new@example.com"}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "password session with a wrong password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"email":"new@example.com","password":"wrong-password"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "password session with the password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"email":"new@example.com","password":"` + stubPassword + `"}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "session with a second factor",
+			accessToken:  accessToken(t, 4, token.AMRPassword, token.AMROTP, token.AMRMFA),
+			body:         `{"email":"new@example.com"}`,
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users/me/email", strings.NewReader(tt.body))
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_AccountDeletionReauthentication(t *testing.T) {
+	router := setupRoutes(t)
+
+	tests := []struct {
+		name         string
+		accessToken  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "password session without the password",
+			accessToken:  accessToken(t, 4),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "password session with a wrong password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"password":"wrong-password"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "password session with the password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"password":"` + stubPassword + `"}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "session with a passkey without user verification",
+			accessToken:  accessToken(t, 4, token.AMRHardwareKey),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "session with a user-verified passkey",
+			accessToken:  accessToken(t, 4, token.AMRHardwareKey, token.AMRMFA),
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/users/me", strings.NewReader(tt.body))
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_PasskeyRegistrationReauthentication(t *testing.T) {
+	router := setupRoutes(t)
+
+	tests := []struct {
+		name         string
+		accessToken  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "password session without the password",
+			accessToken:  accessToken(t, 4),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "password session with a wrong password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"password":"wrong-password"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "password session with the password",
+			accessToken:  accessToken(t, 4),
+			body:         `{"password":"` + stubPassword + `"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "session with a passkey without user verification",
+			accessToken:  accessToken(t, 4, token.AMRHardwareKey),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "session with a second factor",
+			accessToken:  accessToken(t, 4, token.AMRPassword, token.AMROTP, token.AMRMFA),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webauthn/register/begin", strings.NewReader(tt.body))
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_MFARecovery(t *testing.T) {
+	router := setupRoutes(t)
+
+	recoveryLogin := accessToken(t, 4, token.AMRPassword, token.AMROTP, token.AMRMFA)
+
+	tests := []struct {
+		name         string
+		path         string
+		accessToken  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "regenerate without a code",
+			path:         "/mfa/recovery-codes",
+			accessToken:  accessToken(t, 4),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "regenerate with a recovery code",
+			path:         "/mfa/recovery-codes",
+			accessToken:  accessToken(t, 4),
+			body:         `{"recoveryCode":"ABCDE-FGHIJ"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "regenerate after a second factor login",
+			path:         "/mfa/recovery-codes",
+			accessToken:  recoveryLogin,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "replace TOTP from a password session",
+			path:         "/mfa/totp/enroll",
+			accessToken:  accessToken(t, 4),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "replace TOTP after a second factor login",
+			path:         "/mfa/totp/enroll",
+			accessToken:  recoveryLogin,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("Authorization", "Bearer "+tt.accessToken)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSetupRoutes_MFALoginKeepsFirstFactor(t *testing.T) {
+	router := setupRoutes(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/authenticate/mfa",
+		strings.NewReader(`{"challenge":"pending","recoveryCode":"ABCDE-FGHIJ"}`))
+	req.RemoteAddr = "192.0.2.1:12345"
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var issued string
+
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == "accessToken" {
+			issued = cookie.Value
+		}
+	}
+
+	claims, err := token.NewTokenService().ValidateAccessToken(issued)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []interface{}{token.AMROTP, token.AMRMFA}, claims["amr"])
+}
+
+func TestSetupRoutes_RateLimitPerIPv6Client(t *testing.T) {
+	t.Setenv("RATE_LIMIT_AUTHENTICATE", "1/1m")
+
+	router := setupRoutes(t)
+
+	login := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", strings.NewReader(`{"email":"user@example.com","password":"wrong"}`))
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	assert.NotEqual(t, http.StatusTooManyRequests, login("[2001:db8::1]:443"))
+	assert.Equal(t, http.StatusTooManyRequests, login("[2001:db8::1]:50000"), "ports of one client share the limit")
+	assert.NotEqual(t, http.StatusTooManyRequests, login("[2001:db8::2]:443"), "other IPv6 clients keep their own limit")
+}
@@ -2,23 +2,48 @@ package server
 
 import (
 	"auth-service/api/server/router/network"
+	"auth-service/internal/apikey"
+	"auth-service/internal/audit"
+	"auth-service/internal/dataexport"
+	"auth-service/internal/emailchange"
+	"auth-service/internal/emaillogin"
+	"auth-service/internal/erasure"
+	"auth-service/internal/federation"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mfa"
+	"auth-service/internal/notify"
+	"auth-service/internal/oauth"
+	"auth-service/internal/passwordreset"
+	"auth-service/internal/policy"
 	"auth-service/internal/postgres/models"
+	"auth-service/internal/profile"
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/rbac"
+	"auth-service/internal/saml"
+	"auth-service/internal/secretbox"
 	"auth-service/internal/service"
+	"auth-service/internal/useradmin"
+	"auth-service/internal/webauthn"
 	"auth-service/migrations"
 	"auth-service/pkg/consts"
 	"auth-service/pkg/db"
 	"auth-service/pkg/errormsg"
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"io"
 	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
 type Server struct {
-	cfg    *network.Config
-	router *chi.Mux
+	cfg        *network.Config
+	router     *chi.Mux
+	erasure    *erasure.Manager
+	dataExport *dataexport.Manager
 }
 
 func NewServer(cfg *network.Config) (*Server, error) {
@@ -28,22 +53,116 @@ func NewServer(cfg *network.Config) (*Server, error) {
 	}
 
 	if err := migrations.Apply(conn); err != nil {
-		return nil, errormsg.ErrApplyMigrations
+		return nil, fmt.Errorf("%w: %w", errormsg.ErrApplyMigrations, err)
 	}
 
 	repo := models.NewPostgresRepository(conn)
+	repo.LowercaseEmails = cfg.Email.LowercaseLocal
+
+	var mailer notify.Mailer
+	if cfg.Mail.Backend == network.MailLog {
+		log.Println("MAIL_BACKEND is log, emails are not sent")
+		mailer = notify.NewLogMailer()
+	} else if mailer, err = notify.NewSMTPMailer(cfg.Mail.SMTP); err != nil {
+		return nil, err
+	}
+
 	svc := service.NewRewardService(repo)
+	svc.Lockout = lockout.NewGuard(repo, cfg.Lockout, mailer)
+	svc.Audit = audit.NewLogger(repo)
+	svc.RBAC = rbac.NewManager(repo)
+	svc.APIKeys = apikey.NewManager(repo, svc.RBAC)
+	svc.Users = useradmin.NewManager(repo)
+	svc.Profile = profile.NewManager(repo)
+	svc.PasswordReset = passwordreset.NewManager(repo, mailer, cfg.PasswordReset)
+	svc.EmailChange = emailchange.NewManager(repo, mailer, cfg.EmailChange)
+	svc.Erasure = erasure.NewManager(repo, mailer, cfg.AccountDeletion)
+
+	if cfg.DataExport.Secret == "" {
+		log.Println("EXPORT_SECRET is not set, data export is disabled")
+	} else {
+		svc.DataExport = dataexport.NewManager(repo, cfg.DataExport)
+	}
+
+	if cfg.MFA.EncryptionKey == "" {
+		log.Println("MFA_ENCRYPTION_KEY is not set, MFA is disabled")
+	} else {
+		box, err := secretbox.New(cfg.MFA.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+
+		svc.MFA = mfa.NewManager(repo, box, cfg.MFA.Config)
+	}
+
+	if cfg.WebAuthn.RPID == "" {
+		log.Println("WEBAUTHN_RP_ID is not set, passkeys are disabled")
+	} else {
+		svc.Passkey = webauthn.NewRelyingParty(repo, cfg.WebAuthn)
+	}
+
+	if cfg.EmailLogin.Secret == "" {
+		log.Println("EMAIL_LOGIN_SECRET is not set, email login is disabled")
+	} else {
+		svc.EmailLogin = emaillogin.NewManager(repo, mailer, cfg.EmailLogin)
+	}
+
+	if cfg.OAuth.Issuer == "" {
+		log.Println("OAUTH_ISSUER is not set, OAuth is disabled")
+	} else {
+		svc.OAuth = oauth.NewServer(repo, repo, cfg.OAuth)
+
+		if !svc.OAuth.OIDCEnabled() {
+			log.Println("OIDC_SIGNING_KEY_FILE is not set, OpenID Connect is disabled")
+		}
+	}
+
+	if len(cfg.Federation.Providers) == 0 {
+		log.Println("FEDERATION_PROVIDERS is not set, federated login is disabled")
+	} else {
+		svc.Federation = federation.NewManager(repo, repo, cfg.Federation)
+	}
+
+	if len(cfg.SAML.Providers) == 0 {
+		log.Println("SAML_PROVIDERS is not set, SAML login is disabled")
+	} else {
+		svc.SAML = saml.NewServiceProvider(repo, federation.NewIdentities(repo, repo), cfg.SAML)
+	}
+
+	if cfg.Policy.Dir == "" {
+		log.Println("POLICY_DIR is not set, policy engine is disabled")
+	} else {
+		decisions := io.Writer(os.Stdout)
+
+		if cfg.Policy.DecisionLog != "" {
+			decisions, err = os.OpenFile(cfg.Policy.DecisionLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open policy decision log: %w", err)
+			}
+		}
+
+		if svc.Policy, err = policy.NewEngine(cfg.Policy, decisions); err != nil {
+			return nil, err
+		}
+	}
 
 	router := chi.NewRouter()
 	router.Use(network.CORS())
 	router.Get("/swagger/*", httpSwagger.WrapHandler)
 
-	handler := network.SetupRoutes(svc)
+	var limiter ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Backend == network.RateLimitPostgres {
+		limiter = ratelimit.NewPostgresStore(repo, consts.RateLimitStaleAge)
+	}
+
+	handler := network.SetupRoutes(svc, cfg, limiter)
 	router.Mount("/", handler)
 
 	return &Server{
-		cfg:    cfg,
-		router: router,
+		cfg:        cfg,
+		router:     router,
+		erasure:    svc.Erasure,
+		dataExport: svc.DataExport,
 	}, nil
 }
 
@@ -56,6 +175,12 @@ func (s *Server) Start() error {
 		IdleTimeout:  consts.IdleTimeout * time.Second,
 	}
 
+	go s.erasure.Run(context.Background())
+
+	if s.dataExport != nil {
+		go s.dataExport.Run(context.Background())
+	}
+
 	log.Printf("Server started on :%s", s.cfg.Server.Port)
 
 	if err := server.ListenAndServe(); err != nil {
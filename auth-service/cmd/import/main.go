@@ -0,0 +1,93 @@
+// Command import bulk-loads users exported from legacy systems. The input file is
+// a JSON array of calltypes.ImportUser; password hashes are stored as provided and
+// replaced with bcrypt on each user's first successful login.
+package main
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/models"
+	"auth-service/internal/service"
+	"auth-service/migrations"
+	"auth-service/pkg/db"
+	"auth-service/pkg/errormsg"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+func main() {
+	file := flag.String("file", "", "path to JSON file with users to import")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("Usage: import -file users.json")
+	}
+
+	if err := run(*file); err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
+
+func run(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	var users []calltypes.ImportUser
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("failed to parse import file: %w", err)
+	}
+
+	if len(users) == 0 {
+		return errormsg.ErrEmptyImport
+	}
+
+	dsn := os.Getenv("DSN")
+	if dsn == "" {
+		return errormsg.ErrDSNRequired
+	}
+
+	conn, err := db.Connect(dsn)
+	if err != nil {
+		return errormsg.ErrConnectDB
+	}
+	defer conn.Close()
+
+	if err := migrations.Apply(conn); err != nil {
+		return fmt.Errorf("%w: %w", errormsg.ErrApplyMigrations, err)
+	}
+
+	repo := models.NewPostgresRepository(conn)
+
+	if value := os.Getenv("EMAIL_LOWERCASE_LOCAL"); value != "" {
+		if repo.LowercaseEmails, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%w: EMAIL_LOWERCASE_LOCAL", errormsg.ErrInvalidConfig)
+		}
+	}
+
+	failed := 0
+
+	for _, result := range service.ImportLegacyUsers(repo, users) {
+		if result.Error != "" {
+			failed++
+
+			log.Printf("Failed to import %s: %s", result.Email, result.Error)
+
+			continue
+		}
+
+		log.Printf("Imported %s as id %d", result.Email, result.ID)
+	}
+
+	log.Printf("Imported %d of %d users", len(users)-failed, len(users))
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d users", errormsg.ErrImportFailed, failed)
+	}
+
+	return nil
+}
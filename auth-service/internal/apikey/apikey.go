@@ -0,0 +1,277 @@
+// Package apikey manages long-lived API keys for scripts and integrations.
+// A key is a public ID and a random secret joined with a prefix, e.g.
+// mdk_3f9a1c0d7b2e4a65_<secret>; only a hash of the secret is stored. Personal
+// keys act as their user, service keys have no user. Either way a key has just
+// the scopes it was created with, and a personal key loses those its user is
+// no longer permitted.
+package apikey
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/internal/ratelimit"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kinds of API keys.
+const (
+	KindPersonal = "personal"
+	KindService  = "service"
+)
+
+const (
+	// Prefix marks API keys, which makes leaked keys easy to find by secret
+	// scanners.
+	Prefix       = "mdk_"
+	idLength     = 8
+	secretLength = 32
+	maxNameLen   = 255
+)
+
+// Permissions returns the permissions granted to a user.
+type Permissions interface {
+	Permissions(userID int) ([]string, error)
+}
+
+// Manager creates, authenticates and revokes API keys.
+type Manager struct {
+	repo        repository.APIKeyRepository
+	permissions Permissions
+	now         func() time.Time
+}
+
+func NewManager(repo repository.APIKeyRepository, permissions Permissions) *Manager {
+	return &Manager{
+		repo:        repo,
+		permissions: permissions,
+		now:         time.Now,
+	}
+}
+
+// CreatePersonal creates a key acting as the user. Personal keys get the
+// default quota, since only administrators may set a rate limit, and a user
+// may have at most consts.APIKeyMaxPersonal active keys.
+func (m *Manager) CreatePersonal(userID int, request calltypes.APIKeyRequest) (*calltypes.APIKeyCredentials, error) {
+	if request.RateLimit != "" {
+		return nil, fmt.Errorf("%w: rateLimit can only be set by administrators", errormsg.ErrInvalidAPIKeyRequest)
+	}
+
+	keys, err := m.repo.GetAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+
+	active := 0
+
+	for _, key := range keys {
+		if key.RevokedAt == nil && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt)) {
+			active++
+		}
+	}
+
+	if active >= consts.APIKeyMaxPersonal {
+		return nil, errormsg.ErrTooManyAPIKeys
+	}
+
+	return m.create(KindPersonal, userID, request)
+}
+
+// CreateService creates a key of an integration, which has no user. Only
+// service keys may have their own rate limit.
+func (m *Manager) CreateService(request calltypes.APIKeyRequest) (*calltypes.APIKeyCredentials, error) {
+	return m.create(KindService, 0, request)
+}
+
+// List returns personal keys of the user, or service keys for zero userID.
+func (m *Manager) List(userID int) ([]*calltypes.APIKey, error) {
+	return m.repo.GetAPIKeys(userID)
+}
+
+// Revoke revokes any key.
+func (m *Manager) Revoke(id string) (*calltypes.APIKey, error) {
+	key, err := m.repo.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, m.revoke(key)
+}
+
+// RevokePersonal revokes a personal key of the user.
+func (m *Manager) RevokePersonal(userID int, id string) (*calltypes.APIKey, error) {
+	key, err := m.repo.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Kind != KindPersonal || key.UserID != userID {
+		return nil, errormsg.ErrAPIKeyNotFound
+	}
+
+	return key, m.revoke(key)
+}
+
+// Authenticate returns the key presented by a request. Expired and revoked keys
+// are rejected. Scopes of a personal key are narrowed to the permissions of its
+// user. Last use is recorded at most once per consts.APIKeyTouchInterval.
+func (m *Manager) Authenticate(presented string) (*calltypes.APIKey, error) {
+	id, secret, ok := parse(presented)
+	if !ok {
+		return nil, errormsg.ErrInvalidAPIKey
+	}
+
+	key, err := m.repo.GetAPIKey(id)
+	if err != nil {
+		if errors.Is(err, errormsg.ErrAPIKeyNotFound) {
+			return nil, errormsg.ErrInvalidAPIKey
+		}
+
+		return nil, err
+	}
+
+	now := m.now()
+
+	switch {
+	case subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1,
+		key.RevokedAt != nil,
+		key.ExpiresAt != nil && !now.Before(*key.ExpiresAt):
+		return nil, errormsg.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= consts.APIKeyTouchInterval {
+		if err := m.repo.TouchAPIKey(key.ID, now); err != nil {
+			return nil, err
+		}
+
+		key.LastUsedAt = &now
+	}
+
+	if key.Kind == KindPersonal {
+		permissions, err := m.permissions.Permissions(key.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		key.Scopes = slices.DeleteFunc(key.Scopes, func(scope string) bool {
+			return !slices.Contains(permissions, scope)
+		})
+	}
+
+	return key, nil
+}
+
+func (m *Manager) create(kind string, userID int, request calltypes.APIKeyRequest) (*calltypes.APIKeyCredentials, error) {
+	if err := m.validate(request); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, idLength)
+	secret := make([]byte, secretLength)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	key := calltypes.APIKey{
+		ID:        hex.EncodeToString(id),
+		Kind:      kind,
+		UserID:    userID,
+		Name:      strings.TrimSpace(request.Name),
+		Scopes:    request.Scopes,
+		RateLimit: request.RateLimit,
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: m.now(),
+	}
+
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	key.SecretHash = hashSecret(encodedSecret)
+
+	if err := m.repo.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	return &calltypes.APIKeyCredentials{
+		ID:  key.ID,
+		Key: Prefix + key.ID + "_" + encodedSecret,
+	}, nil
+}
+
+func (m *Manager) validate(request calltypes.APIKeyRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > maxNameLen {
+		return fmt.Errorf("%w: name is required and must not exceed %d bytes", errormsg.ErrInvalidAPIKeyRequest, maxNameLen)
+	}
+
+	if len(request.Scopes) > consts.APIKeyMaxScopes {
+		return fmt.Errorf("%w: too many scopes", errormsg.ErrInvalidAPIKeyRequest)
+	}
+
+	for _, scope := range request.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\r\n\"\\") {
+			return fmt.Errorf("%w: invalid scope %q", errormsg.ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+
+	if request.RateLimit != "" {
+		if _, err := ratelimit.ParseRule(request.RateLimit); err != nil {
+			return fmt.Errorf("%w: %w", errormsg.ErrInvalidAPIKeyRequest, err)
+		}
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(m.now()) {
+		return fmt.Errorf("%w: expiresAt must be in the future", errormsg.ErrInvalidAPIKeyRequest)
+	}
+
+	return nil
+}
+
+func (m *Manager) revoke(key *calltypes.APIKey) error {
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	now := m.now()
+	key.RevokedAt = &now
+
+	return m.repo.RevokeAPIKey(key.ID, now)
+}
+
+// parse splits a presented key into its ID and secret.
+func parse(presented string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(presented, Prefix)
+	if !ok {
+		return "", "", false
+	}
+
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != hex.EncodedLen(idLength) || secret == "" {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// hashSecret hashes a secret for storage. Secrets are random, so a fast hash
+// is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
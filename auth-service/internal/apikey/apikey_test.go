@@ -0,0 +1,281 @@
+package apikey_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/apikey"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepository struct {
+	mu      sync.Mutex
+	keys    map[string]calltypes.APIKey
+	touches int
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{keys: map[string]calltypes.APIKey{}}
+}
+
+func (m *memoryRepository) CreateAPIKey(key calltypes.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[key.ID] = key
+
+	return nil
+}
+
+func (m *memoryRepository) GetAPIKey(id string) (*calltypes.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, errormsg.ErrAPIKeyNotFound
+	}
+
+	return &key, nil
+}
+
+func (m *memoryRepository) GetAPIKeys(userID int) ([]*calltypes.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []*calltypes.APIKey
+
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, &key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *memoryRepository) RevokeAPIKey(id string, at time.Time) error {
+	return m.update(id, func(key *calltypes.APIKey) { key.RevokedAt = &at })
+}
+
+func (m *memoryRepository) TouchAPIKey(id string, at time.Time) error {
+	return m.update(id, func(key *calltypes.APIKey) {
+		key.LastUsedAt = &at
+		m.touches++
+	})
+}
+
+func (m *memoryRepository) update(id string, change func(key *calltypes.APIKey)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := m.keys[id]
+	change(&key)
+	m.keys[id] = key
+
+	return nil
+}
+
+// permissions grants every user the same permissions.
+type permissions []string
+
+func (p permissions) Permissions(int) ([]string, error) {
+	return p, nil
+}
+
+var granted = permissions{"users:read"}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	manager := apikey.NewManager(repo, granted)
+
+	credentials, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{
+		Name:   "Backup script",
+		Scopes: []string{"users:read"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(credentials.Key, apikey.Prefix+credentials.ID+"_"))
+
+	key, err := manager.Authenticate(credentials.Key)
+	require.NoError(t, err)
+
+	assert.Equal(t, apikey.KindPersonal, key.Kind)
+	assert.Equal(t, 7, key.UserID)
+	assert.Equal(t, []string{"users:read"}, key.Scopes)
+	assert.Empty(t, key.RateLimit)
+	assert.NotNil(t, key.LastUsedAt)
+
+	// Last use is recorded once per interval.
+	_, err = manager.Authenticate(credentials.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.touches)
+
+	service, err := manager.CreateService(calltypes.APIKeyRequest{Name: "Billing", RateLimit: "100/1m"})
+	require.NoError(t, err)
+
+	key, err = manager.Authenticate(service.Key)
+	require.NoError(t, err)
+	assert.Equal(t, apikey.KindService, key.Kind)
+	assert.Zero(t, key.UserID)
+	assert.Equal(t, "100/1m", key.RateLimit)
+}
+
+func TestAuthenticateNarrowsScopes(t *testing.T) {
+	t.Parallel()
+
+	manager := apikey.NewManager(newMemoryRepository(), granted)
+
+	personal, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Script", Scopes: []string{"users:read", "users:write"}})
+	require.NoError(t, err)
+
+	service, err := manager.CreateService(calltypes.APIKeyRequest{Name: "Billing", Scopes: []string{"users:read", "users:write"}})
+	require.NoError(t, err)
+
+	key, err := manager.Authenticate(personal.Key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read"}, key.Scopes, "personal keys must not exceed permissions of the user")
+
+	key, err = manager.Authenticate(service.Key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read", "users:write"}, key.Scopes)
+}
+
+func TestAuthenticateRejected(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	manager := apikey.NewManager(repo, granted)
+
+	credentials, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Backup script"})
+	require.NoError(t, err)
+
+	expired, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Old script"})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.update(expired.ID, func(key *calltypes.APIKey) {
+		past := time.Now().Add(-time.Minute)
+		key.ExpiresAt = &past
+	}))
+
+	revoked, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Leaked script"})
+	require.NoError(t, err)
+
+	_, err = manager.RevokePersonal(7, revoked.ID)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		key  string
+	}{
+		{name: "wrong secret", key: apikey.Prefix + credentials.ID + "_wrong"},
+		{name: "unknown ID", key: apikey.Prefix + "0000000000000000_secret"},
+		{name: "no prefix", key: strings.TrimPrefix(credentials.Key, apikey.Prefix)},
+		{name: "no secret", key: apikey.Prefix + credentials.ID},
+		{name: "expired", key: expired.Key},
+		{name: "revoked", key: revoked.Key},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := manager.Authenticate(tc.key)
+			require.ErrorIs(t, err, errormsg.ErrInvalidAPIKey)
+		})
+	}
+}
+
+func TestCreateRejected(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name    string
+		request calltypes.APIKeyRequest
+	}{
+		{name: "no name", request: calltypes.APIKeyRequest{Name: "  "}},
+		{name: "invalid scope", request: calltypes.APIKeyRequest{Name: "Script", Scopes: []string{"users read"}}},
+		{name: "invalid rate limit", request: calltypes.APIKeyRequest{Name: "Script", RateLimit: "fast"}},
+		{name: "personal rate limit", request: calltypes.APIKeyRequest{Name: "Script", RateLimit: "1000000/1s"}},
+		{name: "expires in the past", request: calltypes.APIKeyRequest{Name: "Script", ExpiresAt: &past}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := apikey.NewManager(newMemoryRepository(), granted).CreatePersonal(7, tc.request)
+			require.ErrorIs(t, err, errormsg.ErrInvalidAPIKeyRequest)
+		})
+	}
+}
+
+func TestCreatePersonalLimitsActiveKeys(t *testing.T) {
+	t.Parallel()
+
+	manager := apikey.NewManager(newMemoryRepository(), granted)
+
+	var first *calltypes.APIKeyCredentials
+
+	for i := range consts.APIKeyMaxPersonal {
+		credentials, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Script"})
+		require.NoError(t, err)
+
+		if i == 0 {
+			first = credentials
+		}
+	}
+
+	_, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Script"})
+	require.ErrorIs(t, err, errormsg.ErrTooManyAPIKeys)
+
+	_, err = manager.CreatePersonal(8, calltypes.APIKeyRequest{Name: "Script"})
+	require.NoError(t, err, "other users have their own limit")
+
+	_, err = manager.RevokePersonal(7, first.ID)
+	require.NoError(t, err)
+
+	_, err = manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Script"})
+	require.NoError(t, err, "revoked keys do not count")
+}
+
+func TestRevoke(t *testing.T) {
+	t.Parallel()
+
+	manager := apikey.NewManager(newMemoryRepository(), granted)
+
+	personal, err := manager.CreatePersonal(7, calltypes.APIKeyRequest{Name: "Script"})
+	require.NoError(t, err)
+
+	service, err := manager.CreateService(calltypes.APIKeyRequest{Name: "Billing"})
+	require.NoError(t, err)
+
+	// Users revoke only their own personal keys.
+	_, err = manager.RevokePersonal(8, personal.ID)
+	require.ErrorIs(t, err, errormsg.ErrAPIKeyNotFound)
+
+	_, err = manager.RevokePersonal(0, service.ID)
+	require.ErrorIs(t, err, errormsg.ErrAPIKeyNotFound)
+
+	_, err = manager.Revoke(service.ID)
+	require.NoError(t, err)
+
+	_, err = manager.Authenticate(service.Key)
+	require.ErrorIs(t, err, errormsg.ErrInvalidAPIKey)
+
+	_, err = manager.Authenticate(personal.Key)
+	require.NoError(t, err)
+
+	_, err = manager.Revoke("unknown")
+	require.ErrorIs(t, err, errormsg.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,62 @@
+// Package audit records security relevant actions to the audit trail.
+package audit
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"log"
+)
+
+// Actions recorded in the audit trail.
+const (
+	ActionRecoveryCodeUsed         = "mfa.recovery_code_used"
+	ActionRecoveryCodesRegenerated = "mfa.recovery_codes_regenerated"
+	ActionDeviceApproved           = "oauth.device_approved"
+	ActionDeviceDenied             = "oauth.device_denied"
+	ActionIdentityLinked           = "federation.identity_linked"
+	ActionUserProvisioned          = "federation.user_provisioned"
+	ActionAPIKeyCreated            = "apikey.created"
+	ActionAPIKeyRevoked            = "apikey.revoked"
+	ActionRoleUpdated              = "rbac.role_updated"
+	ActionRoleDeleted              = "rbac.role_deleted"
+	ActionRoleAssigned             = "rbac.role_assigned"
+	ActionRoleUnassigned           = "rbac.role_unassigned"
+	ActionUserCreated              = "admin.user_created"
+	ActionUserUpdated              = "admin.user_updated"
+	ActionUserDeactivated          = "admin.user_deactivated"
+	ActionUserReactivated          = "admin.user_reactivated"
+	ActionUserLoggedOut            = "admin.user_logged_out"
+	ActionMFAReset                 = "admin.mfa_reset"
+	ActionPasswordResetSent        = "admin.password_reset_sent"
+	ActionPasswordReset            = "user.password_reset"
+	ActionEmailChangeRequested     = "user.email_change_requested"
+	ActionEmailChanged             = "user.email_changed"
+	ActionEmailChangeCancelled     = "user.email_change_cancelled"
+	ActionDeletionScheduled        = "user.deletion_scheduled"
+	ActionDeletionCancelled        = "user.deletion_cancelled"
+	ActionDataExportRequested      = "user.data_export_requested"
+	ActionDataExportDownloaded     = "user.data_export_downloaded"
+)
+
+// Logger writes audit events. Failures are logged and never break the audited
+// operation.
+type Logger struct {
+	repo repository.AuditRepository
+}
+
+func NewLogger(repo repository.AuditRepository) *Logger {
+	return &Logger{
+		repo: repo,
+	}
+}
+
+// Record appends the event to the audit trail. A nil Logger records nothing.
+func (l *Logger) Record(event calltypes.AuditEvent) {
+	if l == nil {
+		return
+	}
+
+	if err := l.repo.InsertAuditEvent(event); err != nil {
+		log.Printf("failed to record audit event %s: %v", event.Action, err)
+	}
+}
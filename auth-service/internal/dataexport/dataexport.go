@@ -0,0 +1,333 @@
+// Package dataexport lets users download everything the service holds about
+// them. Exports are requested through the API and generated by a background
+// job into local files, which are downloaded through short-lived links signed
+// with HMAC and removed after a retention period.
+package dataexport
+
+import (
+	"archive/zip"
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatJSON = "json"
+	FormatZIP  = "zip"
+)
+
+// Export statuses.
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+const (
+	idLength      = 16
+	signatureInfo = "data-export"
+)
+
+// Config holds data export settings.
+type Config struct {
+	// Secret signs download links. Data export is disabled without it.
+	Secret string
+	// Dir is the directory the export files are stored in.
+	Dir string
+	// DownloadURL is the URL exports are downloaded from. The export ID is
+	// appended as a path segment.
+	DownloadURL string
+	// LinkTTL is how long a download link is valid.
+	LinkTTL time.Duration
+	// Retention is how long an export is kept after it is generated.
+	Retention time.Duration
+	// Interval is how often pending exports are looked for.
+	Interval time.Duration
+}
+
+// Manager requests, generates and serves data exports.
+type Manager struct {
+	repo repository.DataExportRepository
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewManager(repo repository.DataExportRepository, cfg Config) *Manager {
+	return &Manager{
+		repo: repo,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// Request asks for an export of the personal data of the user in the format,
+// JSON by default. The export is generated by Run.
+func (m *Manager) Request(userID int, format string) (*calltypes.DataExport, error) {
+	if format == "" {
+		format = FormatJSON
+	}
+
+	if format != FormatJSON && format != FormatZIP {
+		return nil, errormsg.ErrInvalidExportFormat
+	}
+
+	raw := make([]byte, idLength)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	now := m.now()
+
+	export := calltypes.DataExport{
+		ID:        hex.EncodeToString(raw),
+		UserID:    userID,
+		Format:    format,
+		Status:    StatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.cfg.Retention),
+	}
+
+	if err := m.repo.CreateDataExport(export); err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// Get returns the export of the user. Ready exports get a fresh download link.
+func (m *Manager) Get(userID int, id string) (*calltypes.DataExport, error) {
+	export, err := m.repo.GetDataExport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.UserID != userID {
+		return nil, errormsg.ErrDataExportNotFound
+	}
+
+	if export.Status == StatusReady {
+		export.DownloadURL = m.link(export.ID, m.now().Add(m.cfg.LinkTTL))
+	}
+
+	return export, nil
+}
+
+// Open checks the signature of a download link and opens the file of its
+// export. The caller closes the file.
+func (m *Manager) Open(id string, query url.Values) (*calltypes.DataExport, *os.File, error) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(query.Get("sig")), []byte(m.signature(id, expires))) {
+		return nil, nil, errormsg.ErrInvalidDownloadLink
+	}
+
+	if m.now().Unix() > expires {
+		return nil, nil, errormsg.ErrInvalidDownloadLink
+	}
+
+	export, err := m.repo.GetDataExport(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if export.Status != StatusReady || !m.now().Before(export.ExpiresAt) {
+		return nil, nil, errormsg.ErrDataExportNotReady
+	}
+
+	file, err := os.Open(m.path(export))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open data export: %w", err)
+	}
+
+	return export, file, nil
+}
+
+// ProcessPending generates the pending exports and returns how many were
+// generated.
+func (m *Manager) ProcessPending() (int, error) {
+	processed := 0
+
+	for {
+		export, err := m.repo.ClaimDataExport()
+		if errors.Is(err, errormsg.ErrDataExportNotFound) {
+			return processed, nil
+		}
+
+		if err != nil {
+			return processed, err
+		}
+
+		buildErr := m.build(export)
+
+		completedAt := m.now()
+		export.CompletedAt = &completedAt
+		export.ExpiresAt = completedAt.Add(m.cfg.Retention)
+		export.Status = StatusReady
+
+		if buildErr != nil {
+			export.Status = StatusFailed
+			log.Printf("failed to generate data export %s: %v", export.ID, buildErr)
+		}
+
+		if err := m.repo.FinishDataExport(*export); err != nil {
+			return processed, err
+		}
+
+		processed++
+	}
+}
+
+// RemoveExpired removes expired exports and their files.
+func (m *Manager) RemoveExpired() error {
+	exports, err := m.repo.TakeExpiredDataExports(m.now())
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(m.path(export)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove data export %s: %w", export.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Run generates pending exports and removes expired ones every Interval until
+// ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.ProcessPending(); err != nil {
+			log.Printf("data export failed: %v", err)
+		}
+
+		if err := m.RemoveExpired(); err != nil {
+			log.Printf("data export cleanup failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// build writes the export file. It is written to a temporary file first, so
+// that a partial file is never served.
+func (m *Manager) build(export *calltypes.DataExport) error {
+	data, err := m.repo.GetPersonalData(export.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create data export directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(m.cfg.Dir, export.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create data export file: %w", err)
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if export.Format == FormatZIP {
+		err = writeZIP(tmp, data)
+	} else {
+		err = writeJSON(tmp, data)
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write data export: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), m.path(export))
+}
+
+func (m *Manager) path(export *calltypes.DataExport) string {
+	return filepath.Join(m.cfg.Dir, export.ID+"."+export.Format)
+}
+
+func (m *Manager) link(id string, expiresAt time.Time) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("sig", m.signature(id, expiresAt.Unix()))
+
+	return m.cfg.DownloadURL + "/" + id + "?" + query.Encode()
+}
+
+func (m *Manager) signature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	fmt.Fprintf(mac, "%s|%s|%d", signatureInfo, id, expires)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// writeZIP writes one JSON file per part of the personal data.
+func writeZIP(w io.Writer, data *calltypes.PersonalData) error {
+	archive := zip.NewWriter(w)
+
+	parts := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", data.Profile},
+		{"roles.json", data.Roles},
+		{"sessions.json", data.Sessions},
+		{"consents.json", data.Consents},
+		{"passkeys.json", data.Passkeys},
+		{"api-keys.json", data.APIKeys},
+		{"federated-identities.json", data.FederatedIdentities},
+		{"security-events.json", data.SecurityEvents},
+	}
+
+	for _, part := range parts {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     part.name,
+			Method:   zip.Deflate,
+			Modified: data.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSON(file, part.value); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,275 @@
+package dataexport_test
+
+import (
+	"archive/zip"
+	"auth-service/api/calltypes"
+	"auth-service/internal/dataexport"
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps exports in memory.
+type memoryRepository struct {
+	mu      sync.Mutex
+	exports map[string]calltypes.DataExport
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{exports: make(map[string]calltypes.DataExport)}
+}
+
+func (m *memoryRepository) GetPersonalData(userID int) (*calltypes.PersonalData, error) {
+	return &calltypes.PersonalData{
+		GeneratedAt: time.Now(),
+		Profile:     &calltypes.User{ID: userID, Email: "user@example.com"},
+		SecurityEvents: []*calltypes.AuditEvent{
+			{UserID: userID, Action: "user.password_reset", CreatedAt: time.Now()},
+		},
+	}, nil
+}
+
+func (m *memoryRepository) CreateDataExport(export calltypes.DataExport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.exports[export.ID] = export
+
+	return nil
+}
+
+func (m *memoryRepository) GetDataExport(id string) (*calltypes.DataExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	export, ok := m.exports[id]
+	if !ok {
+		return nil, errormsg.ErrDataExportNotFound
+	}
+
+	return &export, nil
+}
+
+func (m *memoryRepository) ClaimDataExport() (*calltypes.DataExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, export := range m.exports {
+		if export.Status == dataexport.StatusPending {
+			export.Status = "running"
+			m.exports[id] = export
+
+			return &export, nil
+		}
+	}
+
+	return nil, errormsg.ErrDataExportNotFound
+}
+
+func (m *memoryRepository) FinishDataExport(export calltypes.DataExport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.exports[export.ID] = export
+
+	return nil
+}
+
+func (m *memoryRepository) TakeExpiredDataExports(at time.Time) ([]*calltypes.DataExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []*calltypes.DataExport
+
+	for id, export := range m.exports {
+		if !export.ExpiresAt.After(at) {
+			delete(m.exports, id)
+			expired = append(expired, &export)
+		}
+	}
+
+	return expired, nil
+}
+
+func testConfig(t *testing.T) dataexport.Config {
+	t.Helper()
+
+	return dataexport.Config{
+		Secret:      "test-secret",
+		Dir:         t.TempDir(),
+		DownloadURL: "https://example.com/exports",
+		LinkTTL:     5 * time.Minute,
+		Retention:   time.Hour,
+	}
+}
+
+// generate requests an export, generates it and returns its download link.
+func generate(t *testing.T, manager *dataexport.Manager, format string) *url.URL {
+	t.Helper()
+
+	export, err := manager.Request(4, format)
+	require.NoError(t, err)
+	assert.Equal(t, dataexport.StatusPending, export.Status)
+
+	processed, err := manager.ProcessPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	export, err = manager.Get(4, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, dataexport.StatusReady, export.Status)
+
+	link, err := url.Parse(export.DownloadURL)
+	require.NoError(t, err)
+
+	return link
+}
+
+// download opens the export behind the link and returns its content.
+func download(t *testing.T, manager *dataexport.Manager, link *url.URL) []byte {
+	t.Helper()
+
+	_, file, err := manager.Open(path.Base(link.Path), link.Query())
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+
+	return content
+}
+
+func TestManager_JSON(t *testing.T) {
+	t.Parallel()
+
+	manager := dataexport.NewManager(newMemoryRepository(), testConfig(t))
+
+	var data calltypes.PersonalData
+
+	require.NoError(t, json.Unmarshal(download(t, manager, generate(t, manager, "")), &data))
+	assert.Equal(t, "user@example.com", data.Profile.Email)
+	require.Len(t, data.SecurityEvents, 1)
+}
+
+func TestManager_ZIP(t *testing.T) {
+	t.Parallel()
+
+	manager := dataexport.NewManager(newMemoryRepository(), testConfig(t))
+	content := download(t, manager, generate(t, manager, dataexport.FormatZIP))
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(archive.File))
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+
+	assert.Contains(t, names, "profile.json")
+	assert.Contains(t, names, "security-events.json")
+}
+
+func TestManager_Open(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		change  func(link *url.URL)
+		wantErr error
+	}{
+		{
+			name: "tampered signature",
+			change: func(link *url.URL) {
+				query := link.Query()
+				query.Set("sig", "forged")
+				link.RawQuery = query.Encode()
+			},
+			wantErr: errormsg.ErrInvalidDownloadLink,
+		},
+		{
+			name: "extended expiry",
+			change: func(link *url.URL) {
+				query := link.Query()
+				query.Set("expires", "99999999999")
+				link.RawQuery = query.Encode()
+			},
+			wantErr: errormsg.ErrInvalidDownloadLink,
+		},
+		{
+			name: "other export",
+			change: func(link *url.URL) {
+				link.Path = "/exports/0123456789abcdef0123456789abcdef"
+			},
+			wantErr: errormsg.ErrInvalidDownloadLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			manager := dataexport.NewManager(newMemoryRepository(), testConfig(t))
+
+			link := generate(t, manager, dataexport.FormatJSON)
+			tt.change(link)
+
+			_, _, err := manager.Open(path.Base(link.Path), link.Query())
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestManager_GetOtherUser(t *testing.T) {
+	t.Parallel()
+
+	manager := dataexport.NewManager(newMemoryRepository(), testConfig(t))
+
+	export, err := manager.Request(4, dataexport.FormatJSON)
+	require.NoError(t, err)
+
+	_, err = manager.Get(5, export.ID)
+	require.ErrorIs(t, err, errormsg.ErrDataExportNotFound)
+}
+
+func TestManager_RequestInvalidFormat(t *testing.T) {
+	t.Parallel()
+
+	manager := dataexport.NewManager(newMemoryRepository(), testConfig(t))
+
+	_, err := manager.Request(4, "csv")
+	require.ErrorIs(t, err, errormsg.ErrInvalidExportFormat)
+}
+
+func TestManager_RemoveExpired(t *testing.T) {
+	t.Parallel()
+
+	cfg := testConfig(t)
+	cfg.Retention = -time.Minute
+
+	repo := newMemoryRepository()
+	manager := dataexport.NewManager(repo, cfg)
+
+	export, err := manager.Request(4, dataexport.FormatJSON)
+	require.NoError(t, err)
+
+	_, err = manager.ProcessPending()
+	require.NoError(t, err)
+
+	require.NoError(t, manager.RemoveExpired())
+	assert.Empty(t, repo.exports)
+
+	files, err := os.ReadDir(cfg.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "file of export %s must be removed", export.ID)
+}
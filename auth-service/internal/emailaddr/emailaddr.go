@@ -0,0 +1,58 @@
+// Package emailaddr normalizes email addresses, so that an address is stored
+// and looked up in one form however the user typed it.
+package emailaddr
+
+import (
+	"auth-service/pkg/errormsg"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const maxLen = 255
+
+// Normalize trims the address and converts its domain to lowercase ASCII,
+// encoding internationalized domains with IDNA. The local part is lowercased
+// too when lowercaseLocal is set; otherwise it is kept as typed, since it may
+// be case-sensitive at the mail server. It returns errormsg.ErrInvalidEmail
+// when the address is not a bare email address.
+func Normalize(address string, lowercaseLocal bool) (string, error) {
+	address = strings.TrimSpace(address)
+
+	at := strings.LastIndexByte(address, '@')
+	if at <= 0 || at == len(address)-1 {
+		return "", errormsg.ErrInvalidEmail
+	}
+
+	local, domain := address[:at], address[at+1:]
+
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", errormsg.ErrInvalidEmail
+	}
+
+	if lowercaseLocal {
+		local = strings.ToLower(local)
+	}
+
+	normalized := local + "@" + strings.ToLower(domain)
+
+	parsed, err := mail.ParseAddress(normalized)
+	if err != nil || parsed.Address != normalized || len(normalized) > maxLen {
+		return "", errormsg.ErrInvalidEmail
+	}
+
+	return normalized, nil
+}
+
+// Key returns the form addresses are compared in: normalized and lowercased.
+// Addresses that cannot be normalized are only trimmed and lowercased.
+func Key(address string) string {
+	normalized, err := Normalize(address, true)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(address))
+	}
+
+	return normalized
+}
@@ -0,0 +1,82 @@
+package emailaddr_test
+
+import (
+	"auth-service/internal/emailaddr"
+	"auth-service/pkg/errormsg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		address        string
+		lowercaseLocal bool
+		want           string
+		wantErr        error
+	}{
+		{
+			name:    "trimmed and domain lowercased",
+			address: "  User.Name@Example.COM ",
+			want:    "User.Name@example.com",
+		},
+		{
+			name:           "fully lowercased",
+			address:        "User.Name@Example.COM",
+			lowercaseLocal: true,
+			want:           "user.name@example.com",
+		},
+		{
+			name:    "internationalized domain",
+			address: "user@Bücher.Example",
+			want:    "user@xn--bcher-kva.example",
+		},
+		{
+			name:    "display name",
+			address: "Bob <bob@example.com>",
+			wantErr: errormsg.ErrInvalidEmail,
+		},
+		{
+			name:    "missing domain",
+			address: "user@",
+			wantErr: errormsg.ErrInvalidEmail,
+		},
+		{
+			name:    "missing at sign",
+			address: "user.example.com",
+			wantErr: errormsg.ErrInvalidEmail,
+		},
+		{
+			name:    "invalid domain",
+			address: "user@exa mple.com",
+			wantErr: errormsg.ErrInvalidEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := emailaddr.Normalize(tt.address, tt.lowercaseLocal)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, emailaddr.Key("user@example.com"), emailaddr.Key(" USER@Example.com"))
+	assert.Equal(t, "not an email", emailaddr.Key(" Not an Email "))
+}
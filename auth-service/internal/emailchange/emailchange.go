@@ -0,0 +1,167 @@
+// Package emailchange lets users change their email. The change is confirmed
+// through a link sent to the new address and can be cancelled through a link
+// sent to the old one; it is applied only after confirmation. Only hashes of
+// the link tokens are stored.
+package emailchange
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailaddr"
+	"auth-service/internal/notify"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const tokenLength = 32
+
+// Config holds email change settings.
+type Config struct {
+	// ConfirmURL is the page where users confirm the new email. The token is
+	// added as the token query parameter.
+	ConfirmURL string
+	// CancelURL is the page where users cancel the change from the old email.
+	// The token is added as the token query parameter.
+	CancelURL string
+	// TTL is how long a change can be confirmed.
+	TTL time.Duration
+}
+
+// Manager starts, confirms and cancels email changes.
+type Manager struct {
+	repo   repository.EmailChangeRepository
+	mailer notify.Mailer
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewManager(repo repository.EmailChangeRepository, mailer notify.Mailer, cfg Config) *Manager {
+	return &Manager{
+		repo:   repo,
+		mailer: mailer,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Start sends a confirmation link to the new email and a cancellation link to
+// the current email of the user. It replaces the pending change the user may
+// already have.
+func (m *Manager) Start(user *calltypes.User, newEmail string) (*calltypes.PendingEmailChange, error) {
+	newEmail, err := emailaddr.Normalize(newEmail, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if emailaddr.Key(newEmail) == emailaddr.Key(user.Email) {
+		return nil, errormsg.ErrSameEmail
+	}
+
+	if _, err := m.repo.GetByEmail(newEmail); err == nil {
+		return nil, errormsg.ErrEmailTaken
+	}
+
+	confirmLink, err := url.Parse(m.cfg.ConfirmURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email change confirm URL: %w", err)
+	}
+
+	cancelLink, err := url.Parse(m.cfg.CancelURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email change cancel URL: %w", err)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	cancelToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	change := calltypes.EmailChange{
+		TokenHash:  hashToken(token),
+		CancelHash: hashToken(cancelToken),
+		UserID:     user.ID,
+		NewEmail:   newEmail,
+		ExpiresAt:  m.now().Add(m.cfg.TTL),
+	}
+
+	if err := m.repo.CreateEmailChange(change); err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("Open this link to confirm your new email: %s\nIt expires in %s. "+
+		"If you did not ask for it, ignore this email.", withToken(confirmLink, token), m.cfg.TTL)
+
+	if err := m.mailer.Send(newEmail, "Confirm your new email", body); err != nil {
+		return nil, fmt.Errorf("failed to send email change confirmation: %w", err)
+	}
+
+	body = fmt.Sprintf("Someone asked to change the email of your account to %s.\n"+
+		"If it was not you, open this link to cancel the change: %s", newEmail, withToken(cancelLink, cancelToken))
+
+	if err := m.mailer.Send(user.Email, "Your email is being changed", body); err != nil {
+		return nil, fmt.Errorf("failed to send email change notice: %w", err)
+	}
+
+	return &calltypes.PendingEmailChange{Email: newEmail, ExpiresAt: change.ExpiresAt}, nil
+}
+
+// Confirm applies the email change with the token of a confirmation link and
+// returns it. Each link is used once. The sessions of the user are revoked.
+func (m *Manager) Confirm(token string) (*calltypes.EmailChange, error) {
+	change, err := m.repo.TakeEmailChange(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.now().Before(change.ExpiresAt) {
+		return nil, errormsg.ErrInvalidEmailChange
+	}
+
+	if err := m.repo.ApplyEmailChange(change.UserID, change.NewEmail); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// Cancel drops the email change with the token of a cancellation link and
+// returns it.
+func (m *Manager) Cancel(token string) (*calltypes.EmailChange, error) {
+	return m.repo.CancelEmailChange(hashToken(token))
+}
+
+func newToken() (string, error) {
+	raw := make([]byte, tokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func withToken(link *url.URL, token string) *url.URL {
+	withToken := *link
+
+	query := withToken.Query()
+	query.Set("token", token)
+	withToken.RawQuery = query.Encode()
+
+	return &withToken
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
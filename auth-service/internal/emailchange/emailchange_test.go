@@ -0,0 +1,258 @@
+package emailchange_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailchange"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps users and pending changes in memory.
+type memoryRepository struct {
+	mu      sync.Mutex
+	emails  map[int]string
+	changes map[int]calltypes.EmailChange
+	revoked map[int]int
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		emails:  map[int]string{4: "user@example.com", 5: "other@example.com"},
+		changes: make(map[int]calltypes.EmailChange),
+		revoked: make(map[int]int),
+	}
+}
+
+func (m *memoryRepository) GetByEmail(email string) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, userEmail := range m.emails {
+		if strings.EqualFold(userEmail, email) {
+			return &calltypes.User{ID: id, Email: email}, nil
+		}
+	}
+
+	return nil, errors.New("no rows")
+}
+
+func (m *memoryRepository) CreateEmailChange(change calltypes.EmailChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.changes[change.UserID] = change
+
+	return nil
+}
+
+func (m *memoryRepository) TakeEmailChange(tokenHash string) (*calltypes.EmailChange, error) {
+	return m.take(func(change calltypes.EmailChange) bool { return change.TokenHash == tokenHash })
+}
+
+func (m *memoryRepository) CancelEmailChange(cancelHash string) (*calltypes.EmailChange, error) {
+	return m.take(func(change calltypes.EmailChange) bool { return change.CancelHash == cancelHash })
+}
+
+func (m *memoryRepository) take(match func(calltypes.EmailChange) bool) (*calltypes.EmailChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, change := range m.changes {
+		if match(change) {
+			delete(m.changes, userID)
+
+			return &change, nil
+		}
+	}
+
+	return nil, errormsg.ErrInvalidEmailChange
+}
+
+func (m *memoryRepository) ApplyEmailChange(userID int, newEmail string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, email := range m.emails {
+		if email == newEmail && id != userID {
+			return errormsg.ErrEmailTaken
+		}
+	}
+
+	m.emails[userID] = newEmail
+	m.revoked[userID]++
+
+	return nil
+}
+
+// inbox remembers the last message sent to each address.
+type inbox struct {
+	mu     sync.Mutex
+	bodies map[string]string
+}
+
+func (i *inbox) Send(to, _, body string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.bodies == nil {
+		i.bodies = make(map[string]string)
+	}
+
+	i.bodies[to] = body
+
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+func testConfig() emailchange.Config {
+	return emailchange.Config{
+		ConfirmURL: "https://example.com/email/confirm",
+		CancelURL:  "https://example.com/email/cancel",
+		TTL:        time.Hour,
+	}
+}
+
+func testUser() *calltypes.User {
+	return &calltypes.User{ID: 4, Email: "user@example.com"}
+}
+
+// linkToken returns the token of the last link sent to the address.
+func linkToken(t *testing.T, mail *inbox, to string) string {
+	t.Helper()
+
+	link, err := url.Parse(linkPattern.FindString(mail.bodies[to]))
+	require.NoError(t, err)
+
+	return link.Query().Get("token")
+}
+
+func TestManager_Confirm(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	mail := &inbox{}
+	manager := emailchange.NewManager(repo, mail, testConfig())
+
+	pending, err := manager.Start(testUser(), " new@example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", pending.Email)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), pending.ExpiresAt, time.Minute)
+	assert.Equal(t, "user@example.com", repo.emails[4], "email must not change before confirmation")
+
+	token := linkToken(t, mail, "new@example.com")
+	require.NotEmpty(t, token)
+	assert.NotEmpty(t, linkToken(t, mail, "user@example.com"), "old address must get a cancel link")
+
+	change, err := manager.Confirm(token)
+	require.NoError(t, err)
+	assert.Equal(t, 4, change.UserID)
+	assert.Equal(t, "new@example.com", repo.emails[4])
+	assert.Equal(t, 1, repo.revoked[4], "confirmation must revoke sessions")
+
+	_, err = manager.Confirm(token)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailChange, "link must be single-use")
+}
+
+func TestManager_Cancel(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	mail := &inbox{}
+	manager := emailchange.NewManager(repo, mail, testConfig())
+
+	_, err := manager.Start(testUser(), "new@example.com")
+	require.NoError(t, err)
+
+	change, err := manager.Cancel(linkToken(t, mail, "user@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", change.NewEmail)
+
+	_, err = manager.Confirm(linkToken(t, mail, "new@example.com"))
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailChange, "cancelled change must not be applied")
+	assert.Equal(t, "user@example.com", repo.emails[4])
+}
+
+func TestManager_ConfirmTakenEmail(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	mail := &inbox{}
+	manager := emailchange.NewManager(repo, mail, testConfig())
+
+	_, err := manager.Start(testUser(), "new@example.com")
+	require.NoError(t, err)
+
+	repo.emails[5] = "new@example.com"
+
+	_, err = manager.Confirm(linkToken(t, mail, "new@example.com"))
+	require.ErrorIs(t, err, errormsg.ErrEmailTaken)
+	assert.Equal(t, "user@example.com", repo.emails[4])
+	assert.Zero(t, repo.revoked[4])
+}
+
+func TestManager_Start(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		email   string
+		ttl     time.Duration
+		wantErr error
+	}{
+		{
+			name:    "invalid email",
+			email:   "Bob <new@example.com>",
+			wantErr: errormsg.ErrInvalidEmail,
+		},
+		{
+			name:    "same email",
+			email:   "User@example.com",
+			wantErr: errormsg.ErrSameEmail,
+		},
+		{
+			name:    "taken email",
+			email:   "other@example.com",
+			wantErr: errormsg.ErrEmailTaken,
+		},
+		{
+			name:    "taken email in other case",
+			email:   "Other@EXAMPLE.com",
+			wantErr: errormsg.ErrEmailTaken,
+		},
+		{
+			name:    "expired link",
+			email:   "new@example.com",
+			ttl:     -time.Minute,
+			wantErr: errormsg.ErrInvalidEmailChange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testConfig()
+			cfg.TTL = tt.ttl
+
+			mail := &inbox{}
+			manager := emailchange.NewManager(newMemoryRepository(), mail, cfg)
+
+			_, err := manager.Start(testUser(), tt.email)
+			if err == nil {
+				_, err = manager.Confirm(linkToken(t, mail, tt.email))
+			}
+
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
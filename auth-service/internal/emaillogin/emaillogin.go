@@ -0,0 +1,274 @@
+// Package emaillogin implements passwordless login with a one-time code or a
+// signed magic link sent by email. Every pending login is bound to the browser
+// that requested it through a cookie, so a leaked code or link is useless
+// anywhere else.
+package emaillogin
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/notify"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Login methods.
+const (
+	MethodCode = "code"
+	MethodLink = "link"
+)
+
+const (
+	codeDigits    = 6
+	codeModulo    = 1_000_000
+	idLength      = 16
+	nonceLength   = 32
+	bindingSep    = "."
+	signatureInfo = "email-login"
+)
+
+// Config holds email login settings.
+type Config struct {
+	// Secret signs magic links and keys code hashes.
+	Secret string
+	// LinkURL is the public URL of the magic link endpoint.
+	LinkURL string
+	// CodeTTL is how long a code is valid.
+	CodeTTL time.Duration
+	// LinkTTL is how long a magic link is valid.
+	LinkTTL time.Duration
+	// MaxAttempts is how many wrong codes a pending login tolerates.
+	MaxAttempts int
+}
+
+// Pending is a started login. Binding must be stored in the requesting browser
+// and presented when the login is completed.
+type Pending struct {
+	Binding   string
+	ExpiresAt time.Time
+}
+
+// Manager starts and verifies email logins.
+type Manager struct {
+	repo   repository.EmailLoginRepository
+	mailer notify.Mailer
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewManager(repo repository.EmailLoginRepository, mailer notify.Mailer, cfg Config) *Manager {
+	return &Manager{
+		repo:   repo,
+		mailer: mailer,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Start begins a login of the user with the method and sends the code or the
+// link. A nil user gets a binding too, without anything being stored or sent,
+// so callers do not reveal whether the account exists.
+func (m *Manager) Start(user *calltypes.User, method string) (*Pending, error) {
+	ttl := m.cfg.CodeTTL
+	if method == MethodLink {
+		ttl = m.cfg.LinkTTL
+	} else if method != MethodCode {
+		return nil, errormsg.ErrInvalidLoginMethod
+	}
+
+	id, err := randomString(idLength)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomString(nonceLength)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &Pending{
+		Binding:   id + bindingSep + nonce,
+		ExpiresAt: m.now().Add(ttl),
+	}
+
+	if user == nil {
+		return pending, nil
+	}
+
+	login := calltypes.EmailLogin{
+		ID:          id,
+		UserID:      user.ID,
+		Method:      method,
+		BrowserHash: hashValue(nonce),
+		ExpiresAt:   pending.ExpiresAt,
+	}
+
+	var subject, body string
+
+	if method == MethodCode {
+		code, err := newCode()
+		if err != nil {
+			return nil, err
+		}
+
+		login.CodeHash = m.codeHash(id, code)
+		subject = "Your login code"
+		body = fmt.Sprintf("Your login code is %s. It expires in %s.", code, ttl)
+	} else {
+		subject = "Your login link"
+		body = fmt.Sprintf("Open this link in the browser you requested it from: %s\nIt expires in %s.", m.link(id, pending.ExpiresAt), ttl)
+	}
+
+	if err := m.repo.CreateEmailLogin(login); err != nil {
+		return nil, err
+	}
+
+	if err := m.mailer.Send(user.Email, subject, body); err != nil {
+		return nil, fmt.Errorf("failed to send login email: %w", err)
+	}
+
+	return pending, nil
+}
+
+// VerifyCode completes a code login started in the browser holding the binding
+// and returns ID of the user. Every attempt is counted before the code is
+// compared, so concurrent guesses cannot exceed MaxAttempts, and the login is
+// dropped after that many of them.
+func (m *Manager) VerifyCode(binding, code string) (int, error) {
+	login, err := m.bound(binding, MethodCode)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.repo.ClaimEmailLoginAttempt(login.ID, m.cfg.MaxAttempts, m.now()); err != nil {
+		return 0, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(login.CodeHash), []byte(m.codeHash(login.ID, strings.TrimSpace(code)))) != 1 {
+		return 0, errormsg.ErrInvalidLoginCode
+	}
+
+	return m.consume(login)
+}
+
+// VerifyLink completes a magic link login. The link must be opened in the
+// browser holding the binding of the same login.
+func (m *Manager) VerifyLink(binding string, query url.Values) (int, error) {
+	id := query.Get("login")
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(query.Get("sig")), []byte(m.signature(id, expires))) {
+		return 0, errormsg.ErrInvalidLoginLink
+	}
+
+	if m.now().Unix() > expires {
+		return 0, errormsg.ErrInvalidLoginLink
+	}
+
+	login, err := m.bound(binding, MethodLink)
+	if err != nil {
+		return 0, err
+	}
+
+	if login.ID != id {
+		return 0, errormsg.ErrInvalidLoginLink
+	}
+
+	return m.consume(login)
+}
+
+// consume makes the login single-use and returns ID of its user.
+func (m *Manager) consume(login *calltypes.EmailLogin) (int, error) {
+	consumed, err := m.repo.ConsumeEmailLogin(login.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !consumed {
+		return 0, errormsg.ErrInvalidEmailLogin
+	}
+
+	return login.UserID, nil
+}
+
+// bound returns the pending login referenced by the browser binding.
+func (m *Manager) bound(binding, method string) (*calltypes.EmailLogin, error) {
+	id, nonce, ok := strings.Cut(binding, bindingSep)
+	if !ok {
+		return nil, errormsg.ErrInvalidEmailLogin
+	}
+
+	login, err := m.repo.GetEmailLogin(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if login.Method != method ||
+		subtle.ConstantTimeCompare([]byte(login.BrowserHash), []byte(hashValue(nonce))) != 1 ||
+		login.Attempts >= m.cfg.MaxAttempts ||
+		m.now().After(login.ExpiresAt) {
+		return nil, errormsg.ErrInvalidEmailLogin
+	}
+
+	return login, nil
+}
+
+func (m *Manager) link(id string, expiresAt time.Time) string {
+	query := url.Values{}
+	query.Set("login", id)
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("sig", m.signature(id, expiresAt.Unix()))
+
+	return m.cfg.LinkURL + "?" + query.Encode()
+}
+
+func (m *Manager) signature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	fmt.Fprintf(mac, "%s|%s|%d", signatureInfo, id, expires)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// codeHash keys the hash with the secret, so the small code space cannot be
+// searched offline from a database dump.
+func (m *Manager) codeHash(id, code string) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	fmt.Fprintf(mac, "%s|%s", id, code)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newCode() (string, error) {
+	number, err := rand.Int(rand.Reader, big.NewInt(codeModulo))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate login code: %w", err)
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, number.Int64()), nil
+}
+
+func randomString(length int) (string, error) {
+	raw := make([]byte, length)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate email login token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
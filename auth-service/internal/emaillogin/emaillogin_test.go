@@ -0,0 +1,303 @@
+package emaillogin_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emaillogin"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps pending logins in memory.
+type memoryRepository struct {
+	mu     sync.Mutex
+	logins map[string]calltypes.EmailLogin
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{logins: make(map[string]calltypes.EmailLogin)}
+}
+
+func (m *memoryRepository) CreateEmailLogin(login calltypes.EmailLogin) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, existing := range m.logins {
+		if existing.UserID == login.UserID {
+			delete(m.logins, id)
+		}
+	}
+
+	m.logins[login.ID] = login
+
+	return nil
+}
+
+func (m *memoryRepository) GetEmailLogin(id string) (*calltypes.EmailLogin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	login, ok := m.logins[id]
+	if !ok {
+		return nil, errormsg.ErrInvalidEmailLogin
+	}
+
+	return &login, nil
+}
+
+func (m *memoryRepository) ClaimEmailLoginAttempt(id string, maxAttempts int, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	login, ok := m.logins[id]
+	if !ok || login.Attempts >= maxAttempts || !now.Before(login.ExpiresAt) {
+		return errormsg.ErrInvalidEmailLogin
+	}
+
+	login.Attempts++
+	m.logins[id] = login
+
+	return nil
+}
+
+func (m *memoryRepository) ConsumeEmailLogin(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.logins[id]
+	delete(m.logins, id)
+
+	return ok, nil
+}
+
+// inbox remembers the last message sent.
+type inbox struct {
+	to, body string
+}
+
+func (i *inbox) Send(to, _, body string) error {
+	i.to, i.body = to, body
+
+	return nil
+}
+
+var (
+	codePattern = regexp.MustCompile(`\b\d{6}\b`)
+	linkPattern = regexp.MustCompile(`https://\S+`)
+)
+
+func testConfig() emaillogin.Config {
+	return emaillogin.Config{
+		Secret:      "test-secret",
+		LinkURL:     "https://example.com/authenticate/email/link",
+		CodeTTL:     10 * time.Minute,
+		LinkTTL:     15 * time.Minute,
+		MaxAttempts: 3,
+	}
+}
+
+func testUser() *calltypes.User {
+	return &calltypes.User{ID: 4, Email: "user@example.com"}
+}
+
+func TestManager_Code(t *testing.T) {
+	t.Parallel()
+
+	mail := &inbox{}
+	manager := emaillogin.NewManager(newMemoryRepository(), mail, testConfig())
+
+	pending, err := manager.Start(testUser(), emaillogin.MethodCode)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", mail.to)
+
+	code := codePattern.FindString(mail.body)
+	require.NotEmpty(t, code)
+
+	other, err := manager.Start(nil, emaillogin.MethodCode)
+	require.NoError(t, err)
+
+	_, err = manager.VerifyCode(other.Binding, code)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailLogin, "code must not work in another browser")
+
+	userID, err := manager.VerifyCode(pending.Binding, code)
+	require.NoError(t, err)
+	assert.Equal(t, 4, userID)
+
+	_, err = manager.VerifyCode(pending.Binding, code)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailLogin, "code must be single-use")
+}
+
+func TestManager_CodeAttemptsAreLimited(t *testing.T) {
+	t.Parallel()
+
+	mail := &inbox{}
+	manager := emaillogin.NewManager(newMemoryRepository(), mail, testConfig())
+
+	pending, err := manager.Start(testUser(), emaillogin.MethodCode)
+	require.NoError(t, err)
+
+	code := codePattern.FindString(mail.body)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for range testConfig().MaxAttempts {
+		_, err = manager.VerifyCode(pending.Binding, wrong)
+		require.ErrorIs(t, err, errormsg.ErrInvalidLoginCode)
+	}
+
+	_, err = manager.VerifyCode(pending.Binding, code)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailLogin)
+}
+
+func TestManager_ConcurrentCodeAttemptsAreLimited(t *testing.T) {
+	t.Parallel()
+
+	mail := &inbox{}
+	manager := emaillogin.NewManager(newMemoryRepository(), mail, testConfig())
+
+	pending, err := manager.Start(testUser(), emaillogin.MethodCode)
+	require.NoError(t, err)
+
+	code := codePattern.FindString(mail.body)
+
+	const guesses = 20
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		checked int
+	)
+
+	for guess := range guesses {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			wrong := fmt.Sprintf("%06d", guess)
+			if wrong == code {
+				wrong = "999999"
+			}
+
+			_, err := manager.VerifyCode(pending.Binding, wrong)
+			if errors.Is(err, errormsg.ErrInvalidLoginCode) {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, testConfig().MaxAttempts, checked, "only MaxAttempts guesses may reach the code")
+
+	_, err = manager.VerifyCode(pending.Binding, code)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailLogin)
+}
+
+func TestManager_NewLoginReplacesPrevious(t *testing.T) {
+	t.Parallel()
+
+	mail := &inbox{}
+	manager := emaillogin.NewManager(newMemoryRepository(), mail, testConfig())
+
+	first, err := manager.Start(testUser(), emaillogin.MethodCode)
+	require.NoError(t, err)
+
+	firstCode := codePattern.FindString(mail.body)
+
+	_, err = manager.Start(testUser(), emaillogin.MethodCode)
+	require.NoError(t, err)
+
+	_, err = manager.VerifyCode(first.Binding, firstCode)
+	require.ErrorIs(t, err, errormsg.ErrInvalidEmailLogin)
+}
+
+func TestManager_Link(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tamper     func(query url.Values)
+		otherAgent bool
+		wantErr    error
+	}{
+		{
+			name: "valid link",
+		},
+		{
+			name:       "opened in another browser",
+			otherAgent: true,
+			wantErr:    errormsg.ErrInvalidEmailLogin,
+		},
+		{
+			name:    "forged expiry",
+			tamper:  func(query url.Values) { query.Set("expires", "9999999999") },
+			wantErr: errormsg.ErrInvalidLoginLink,
+		},
+		{
+			name:    "forged signature",
+			tamper:  func(query url.Values) { query.Set("sig", "AAAA") },
+			wantErr: errormsg.ErrInvalidLoginLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mail := &inbox{}
+			manager := emaillogin.NewManager(newMemoryRepository(), mail, testConfig())
+
+			pending, err := manager.Start(testUser(), emaillogin.MethodLink)
+			require.NoError(t, err)
+
+			link, err := url.Parse(linkPattern.FindString(mail.body))
+			require.NoError(t, err)
+
+			query := link.Query()
+			if tt.tamper != nil {
+				tt.tamper(query)
+			}
+
+			binding := pending.Binding
+			if tt.otherAgent {
+				other, err := manager.Start(nil, emaillogin.MethodLink)
+				require.NoError(t, err)
+
+				binding = other.Binding
+			}
+
+			userID, err := manager.VerifyLink(binding, query)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 4, userID)
+		})
+	}
+}
+
+func TestManager_UnknownMethod(t *testing.T) {
+	t.Parallel()
+
+	manager := emaillogin.NewManager(newMemoryRepository(), &inbox{}, testConfig())
+
+	_, err := manager.Start(testUser(), "sms")
+	require.ErrorIs(t, err, errormsg.ErrInvalidLoginMethod)
+}
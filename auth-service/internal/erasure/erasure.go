@@ -0,0 +1,122 @@
+// Package erasure deletes user accounts on request of their owners. Deletion is
+// scheduled after a grace period during which the user can cancel it; when the
+// period ends, a background job erases the personal data of the user and
+// leaves a tombstone recording the erasure.
+package erasure
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/notify"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Config holds account deletion settings.
+type Config struct {
+	// GracePeriod is how long after the request the account is erased.
+	GracePeriod time.Duration
+	// Interval is how often due deletions are looked for.
+	Interval time.Duration
+}
+
+// Manager schedules account deletions and erases accounts when they are due.
+type Manager struct {
+	repo   repository.AccountDeletionRepository
+	mailer notify.Mailer
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewManager(repo repository.AccountDeletionRepository, mailer notify.Mailer, cfg Config) *Manager {
+	return &Manager{
+		repo:   repo,
+		mailer: mailer,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Schedule schedules the deletion of the account of the user and tells the user
+// by email. If the deletion is already scheduled, it is returned unchanged.
+func (m *Manager) Schedule(user *calltypes.User) (*calltypes.AccountDeletion, error) {
+	deletion, err := m.repo.GetAccountDeletion(user.ID)
+	if err == nil {
+		return deletion, nil
+	}
+
+	if !errors.Is(err, errormsg.ErrDeletionNotScheduled) {
+		return nil, err
+	}
+
+	now := m.now()
+
+	deletion = &calltypes.AccountDeletion{
+		UserID:      user.ID,
+		RequestedAt: now,
+		EraseAt:     now.Add(m.cfg.GracePeriod),
+	}
+
+	if err := m.repo.CreateAccountDeletion(*deletion); err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("Your account will be deleted on %s together with all its data.\n"+
+		"If you did not ask for it, log in and cancel the deletion before then.", deletion.EraseAt.UTC().Format(time.RFC1123))
+
+	if err := m.mailer.Send(user.Email, "Your account will be deleted", body); err != nil {
+		return nil, fmt.Errorf("failed to send account deletion notice: %w", err)
+	}
+
+	return deletion, nil
+}
+
+// Cancel cancels the scheduled deletion of the account of the user.
+func (m *Manager) Cancel(userID int) error {
+	return m.repo.DeleteAccountDeletion(userID)
+}
+
+// EraseDue erases the accounts whose deletion is due and returns how many were
+// erased.
+func (m *Manager) EraseDue() (int, error) {
+	deletions, err := m.repo.GetDueAccountDeletions(m.now(), consts.AccountErasureBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, deletion := range deletions {
+		if err := m.repo.EraseUser(*deletion, m.now()); err != nil {
+			return i, fmt.Errorf("failed to erase user %d: %w", deletion.UserID, err)
+		}
+	}
+
+	return len(deletions), nil
+}
+
+// Run erases due accounts every Interval until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		erased, err := m.EraseDue()
+		if err != nil {
+			log.Printf("account erasure failed: %v", err)
+		}
+
+		if erased > 0 {
+			log.Printf("erased %d accounts", erased)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package erasure_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/erasure"
+	"auth-service/pkg/errormsg"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps scheduled deletions and tombstones in memory.
+type memoryRepository struct {
+	mu         sync.Mutex
+	deletions  map[int]calltypes.AccountDeletion
+	tombstones map[int]time.Time
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		deletions:  make(map[int]calltypes.AccountDeletion),
+		tombstones: make(map[int]time.Time),
+	}
+}
+
+func (m *memoryRepository) CreateAccountDeletion(deletion calltypes.AccountDeletion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deletions[deletion.UserID] = deletion
+
+	return nil
+}
+
+func (m *memoryRepository) GetAccountDeletion(userID int) (*calltypes.AccountDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deletion, ok := m.deletions[userID]
+	if !ok {
+		return nil, errormsg.ErrDeletionNotScheduled
+	}
+
+	return &deletion, nil
+}
+
+func (m *memoryRepository) DeleteAccountDeletion(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.deletions[userID]; !ok {
+		return errormsg.ErrDeletionNotScheduled
+	}
+
+	delete(m.deletions, userID)
+
+	return nil
+}
+
+func (m *memoryRepository) GetDueAccountDeletions(at time.Time, limit int) ([]*calltypes.AccountDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*calltypes.AccountDeletion
+
+	for _, deletion := range m.deletions {
+		if !deletion.EraseAt.After(at) && len(due) < limit {
+			due = append(due, &deletion)
+		}
+	}
+
+	return due, nil
+}
+
+func (m *memoryRepository) EraseUser(deletion calltypes.AccountDeletion, erasedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.deletions, deletion.UserID)
+	m.tombstones[deletion.UserID] = erasedAt
+
+	return nil
+}
+
+// inbox remembers the last message sent.
+type inbox struct {
+	to, body string
+}
+
+func (i *inbox) Send(to, _, body string) error {
+	i.to, i.body = to, body
+
+	return nil
+}
+
+func testUser() *calltypes.User {
+	return &calltypes.User{ID: 4, Email: "user@example.com"}
+}
+
+func TestManager_Schedule(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	mail := &inbox{}
+	manager := erasure.NewManager(repo, mail, erasure.Config{GracePeriod: 30 * 24 * time.Hour})
+
+	deletion, err := manager.Schedule(testUser())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), deletion.EraseAt, time.Minute)
+	assert.Equal(t, "user@example.com", mail.to, "user must be told about the deletion")
+
+	again, err := manager.Schedule(testUser())
+	require.NoError(t, err)
+	assert.Equal(t, deletion.EraseAt, again.EraseAt, "repeated request must not postpone the deletion")
+
+	erased, err := manager.EraseDue()
+	require.NoError(t, err)
+	assert.Zero(t, erased, "account must not be erased during the grace period")
+	assert.Empty(t, repo.tombstones)
+}
+
+func TestManager_Cancel(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	manager := erasure.NewManager(repo, &inbox{}, erasure.Config{})
+
+	_, err := manager.Schedule(testUser())
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Cancel(4))
+
+	erased, err := manager.EraseDue()
+	require.NoError(t, err)
+	assert.Zero(t, erased, "cancelled deletion must not erase the account")
+
+	require.ErrorIs(t, manager.Cancel(4), errormsg.ErrDeletionNotScheduled)
+}
+
+func TestManager_EraseDue(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	manager := erasure.NewManager(repo, &inbox{}, erasure.Config{})
+
+	_, err := manager.Schedule(testUser())
+	require.NoError(t, err)
+
+	erased, err := manager.EraseDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, erased)
+	assert.Contains(t, repo.tombstones, 4, "erasure must leave a tombstone")
+
+	_, err = repo.GetAccountDeletion(4)
+	require.ErrorIs(t, err, errormsg.ErrDeletionNotScheduled)
+}
@@ -0,0 +1,275 @@
+// Package federation lets users log in through external OpenID Connect
+// providers, such as the corporate identity providers of partner clinics. It
+// runs the authorization code flow with PKCE against endpoints found by
+// discovery, verifies the ID token with the keys the provider publishes and
+// maps the provider subject to a user: known identities log in directly, new
+// ones are linked to the user with the same verified email or provisioned just
+// in time. Identities implements that mapping for other protocols as well.
+package federation
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultScope is requested from providers without configured scopes.
+	DefaultScope = "openid email profile"
+	scopeOpenID  = "openid"
+	randomLength = 32
+)
+
+// ProviderConfig describes an upstream OpenID provider.
+type ProviderConfig struct {
+	// Name identifies the provider in URLs, e.g. "clinic".
+	Name string
+	// Issuer is the issuer identifier of the provider. Its metadata is read
+	// from <Issuer>/.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scope is a space separated list of requested scopes, DefaultScope when
+	// empty. openid is always requested.
+	Scope string
+	// TrustEmail treats emails from the provider as verified even without the
+	// email_verified claim, for providers that never send it.
+	TrustEmail bool
+}
+
+// Config holds federated login settings.
+type Config struct {
+	Providers []ProviderConfig
+	// BaseURL is the public URL of the federation endpoints. The redirect URI
+	// registered at a provider is <BaseURL>/<name>/callback.
+	BaseURL string
+	// LoginTTL is how long a started login waits for the provider.
+	LoginTTL time.Duration
+}
+
+// Pending is a started login. State must be stored in the requesting browser
+// and presented when the login is completed.
+type Pending struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// Manager runs logins through the configured providers.
+type Manager struct {
+	repo       repository.FederationRepository
+	identities *Identities
+	cfg        Config
+	client     *http.Client
+	providers  map[string]*provider
+	now        func() time.Time
+}
+
+func NewManager(repo repository.FederationRepository, users repository.UserProvisioner, cfg Config) *Manager {
+	providers := make(map[string]*provider, len(cfg.Providers))
+	for _, providerCfg := range cfg.Providers {
+		providers[providerCfg.Name] = &provider{cfg: providerCfg}
+	}
+
+	return &Manager{
+		repo:       repo,
+		identities: NewIdentities(repo, users),
+		cfg:        cfg,
+		client:     &http.Client{Timeout: consts.FederationTimeout},
+		providers:  providers,
+		now:        time.Now,
+	}
+}
+
+// Providers returns names of the configured providers.
+func (m *Manager) Providers() []string {
+	names := make([]string, 0, len(m.cfg.Providers))
+	for _, providerCfg := range m.cfg.Providers {
+		names = append(names, providerCfg.Name)
+	}
+
+	return names
+}
+
+// Begin starts a login through the provider and returns the URL of its
+// authorization endpoint to send the browser to.
+func (m *Manager) Begin(name string) (*Pending, error) {
+	p, ok := m.providers[name]
+	if !ok {
+		return nil, errormsg.ErrUnknownProvider
+	}
+
+	metadata, err := p.discover(m.client)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomString(randomLength)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomString(randomLength)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomString(randomLength)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := m.now().Add(m.cfg.LoginTTL)
+
+	err = m.repo.CreateFederatedLogin(calltypes.FederatedLogin{
+		StateHash:    hashValue(state),
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authorization endpoint", errormsg.ErrProviderUnavailable)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := authorizationURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", m.redirectURI(name))
+	query.Set("scope", requestedScope(p.cfg.Scope))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authorizationURL.RawQuery = query.Encode()
+
+	return &Pending{
+		URL:       authorizationURL.String(),
+		State:     state,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Complete handles the authorization response of the provider. state is the
+// value kept in the browser by Begin and must match the one in the response.
+func (m *Manager) Complete(name, state string, response url.Values) (*Result, error) {
+	p, ok := m.providers[name]
+	if !ok {
+		return nil, errormsg.ErrUnknownProvider
+	}
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(response.Get("state"))) != 1 {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	login, err := m.repo.TakeFederatedLogin(hashValue(state))
+	if err != nil {
+		return nil, err
+	}
+
+	if login.Provider != name || m.now().After(login.ExpiresAt) {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	if code := response.Get("error"); code != "" {
+		return nil, fmt.Errorf("%w: %s %s", errormsg.ErrFederatedLoginDenied, code, response.Get("error_description"))
+	}
+
+	// The iss parameter (RFC 9207) guards against mix-ups between providers.
+	if response.Has("iss") && response.Get("iss") != p.cfg.Issuer {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	if response.Get("code") == "" {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	idToken, err := p.exchange(m.client, response.Get("code"), m.redirectURI(name), login.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.verifyIDToken(m.client, idToken, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	firstName, _ := claims["given_name"].(string)
+	lastName, _ := claims["family_name"].(string)
+
+	return m.identities.Resolve(Identity{
+		Provider:      name,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: p.cfg.TrustEmail || emailVerified(claims["email_verified"]),
+		FirstName:     firstName,
+		LastName:      lastName,
+	})
+}
+
+func (m *Manager) redirectURI(name string) string {
+	return strings.TrimSuffix(m.cfg.BaseURL, "/") + "/" + url.PathEscape(name) + "/callback"
+}
+
+// requestedScope returns the configured scope, adding openid when it is missing.
+func requestedScope(scope string) string {
+	if strings.TrimSpace(scope) == "" {
+		return DefaultScope
+	}
+
+	scopes := strings.Fields(scope)
+	if !slices.Contains(scopes, scopeOpenID) {
+		scopes = append([]string{scopeOpenID}, scopes...)
+	}
+
+	return strings.Join(scopes, " ")
+}
+
+// emailVerified reads the email_verified claim. Some providers send it as a
+// string.
+func emailVerified(claim interface{}) bool {
+	switch value := claim.(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,607 @@
+package federation_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/federation"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "medods"
+	testClientSecret = "s3cret"
+	testBaseURL      = "https://auth.example.com/federation"
+	testProvider     = "clinic"
+)
+
+// idpKey is generated once, RSA key generation is slow.
+var idpKey = sync.OnceValue(func() *rsa.PrivateKey { //nolint: gochecknoglobals
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+})
+
+// mockIdP is a local OpenID provider. Its authorization endpoint logs in the
+// user described by claims right away and redirects back with a code.
+type mockIdP struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	claims jwt.MapClaims
+	// tamper changes ID token claims before signing.
+	tamper func(claims jwt.MapClaims)
+	// key signs ID tokens, idpKey by default.
+	key   *rsa.PrivateKey
+	codes map[string]url.Values
+}
+
+func newMockIdP(t *testing.T, claims jwt.MapClaims) *mockIdP {
+	t.Helper()
+
+	idp := &mockIdP{claims: claims, key: idpKey(), codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /authorize", idp.authorize)
+	mux.HandleFunc("POST /token", idp.token)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, calltypes.OpenIDConfiguration{
+		Issuer:                idp.URL,
+		AuthorizationEndpoint: idp.URL + "/authorize",
+		TokenEndpoint:         idp.URL + "/token",
+		JWKSURI:               idp.URL + "/jwks",
+	})
+}
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code := base64.RawURLEncoding.EncodeToString([]byte(query.Get("state")))
+
+	idp.mu.Lock()
+	idp.codes[code] = query
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}, "iss": {idp.URL}}.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	request, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mu.Unlock()
+
+	clientID, secret, _ := r.BasicAuth()
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	if !ok || clientID != testClientID || secret != testClientSecret ||
+		r.PostFormValue("redirect_uri") != request.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != request.Get("code_challenge") {
+		writeJSON(w, http.StatusBadRequest, calltypes.OAuthErrorResponse{Error: "invalid_grant"})
+
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": request.Get("nonce"),
+	}
+	for name, value := range idp.claims {
+		claims[name] = value
+	}
+
+	if idp.tamper != nil {
+		idp.tamper(claims)
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "idp-1"
+
+	signed, err := idToken.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, calltypes.OAuthErrorResponse{Error: "server_error"})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, calltypes.OAuthTokenResponse{AccessToken: "at", TokenType: "Bearer", IDToken: signed})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	public := idpKey().PublicKey
+
+	writeJSON(w, http.StatusOK, calltypes.JSONWebKeySet{Keys: []calltypes.JSONWebKey{{
+		Kty: "RSA",
+		Kid: "idp-1",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// memoryRepository keeps users, pending logins and identities in memory.
+type memoryRepository struct {
+	mu         sync.Mutex
+	users      map[int]calltypes.User
+	logins     map[string]calltypes.FederatedLogin
+	identities map[string]calltypes.FederatedIdentity
+}
+
+func newMemoryRepository(users ...calltypes.User) *memoryRepository {
+	repo := &memoryRepository{
+		users:      map[int]calltypes.User{},
+		logins:     map[string]calltypes.FederatedLogin{},
+		identities: map[string]calltypes.FederatedIdentity{},
+	}
+
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+
+	return repo
+}
+
+func (m *memoryRepository) GetOne(id int) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, errormsg.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (m *memoryRepository) GetByEmail(email string) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, errormsg.ErrUserNotFound
+}
+
+func (m *memoryRepository) InsertExternal(user calltypes.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user.ID = len(m.users) + 100
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (m *memoryRepository) CreateFederatedLogin(login calltypes.FederatedLogin) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logins[login.StateHash] = login
+
+	return nil
+}
+
+func (m *memoryRepository) TakeFederatedLogin(stateHash string) (*calltypes.FederatedLogin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	login, ok := m.logins[stateHash]
+	if !ok {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	delete(m.logins, stateHash)
+
+	return &login, nil
+}
+
+func (m *memoryRepository) GetFederatedIdentity(provider, subject string) (*calltypes.FederatedIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity, ok := m.identities[provider+"|"+subject]
+	if !ok {
+		return nil, errormsg.ErrIdentityNotFound
+	}
+
+	return &identity, nil
+}
+
+func (m *memoryRepository) LinkFederatedIdentity(identity calltypes.FederatedIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.identities[identity.Provider+"|"+identity.Subject] = identity
+
+	return nil
+}
+
+func (m *memoryRepository) TouchFederatedIdentity(provider, subject, email string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity := m.identities[provider+"|"+subject]
+	identity.Email = email
+	identity.LastLoginAt = at
+	m.identities[provider+"|"+subject] = identity
+
+	return nil
+}
+
+func newManager(idp *mockIdP, repo *memoryRepository, trustEmail bool) *federation.Manager {
+	return federation.NewManager(repo, repo, federation.Config{
+		Providers: []federation.ProviderConfig{{
+			Name:         testProvider,
+			Issuer:       idp.URL,
+			ClientID:     testClientID,
+			ClientSecret: testClientSecret,
+			TrustEmail:   trustEmail,
+		}},
+		BaseURL:  testBaseURL,
+		LoginTTL: time.Minute,
+	})
+}
+
+// authorize starts a login and returns the state kept in the browser together
+// with the authorization response of the provider.
+func authorize(t *testing.T, manager *federation.Manager) (string, url.Values) {
+	t.Helper()
+
+	pending, err := manager.Begin(testProvider)
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	response, err := client.Get(pending.URL) //nolint: noctx
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), testBaseURL+"/"+testProvider+"/callback?"))
+
+	return pending.State, location.Query()
+}
+
+func login(t *testing.T, manager *federation.Manager) (*federation.Result, error) {
+	t.Helper()
+
+	state, response := authorize(t, manager)
+
+	return manager.Complete(testProvider, state, response)
+}
+
+func employee() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":            "emp-1",
+		"email":          "doctor@clinic.example",
+		"email_verified": true,
+		"given_name":     "Anna",
+		"family_name":    "Petrova",
+	}
+}
+
+func TestManager_Begin(t *testing.T) {
+	t.Parallel()
+
+	idp := newMockIdP(t, employee())
+	manager := newManager(idp, newMemoryRepository(), false)
+
+	pending, err := manager.Begin(testProvider)
+	require.NoError(t, err)
+
+	authorizationURL, err := url.Parse(pending.URL)
+	require.NoError(t, err)
+
+	query := authorizationURL.Query()
+	assert.Equal(t, idp.URL+"/authorize", authorizationURL.Scheme+"://"+authorizationURL.Host+authorizationURL.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Equal(t, testBaseURL+"/clinic/callback", query.Get("redirect_uri"))
+	assert.Equal(t, federation.DefaultScope, query.Get("scope"))
+	assert.Equal(t, pending.State, query.Get("state"))
+	assert.NotEmpty(t, query.Get("nonce"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	_, err = manager.Begin("unknown")
+	require.ErrorIs(t, err, errormsg.ErrUnknownProvider)
+}
+
+func TestManager_ProvisionsUser(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	manager := newManager(newMockIdP(t, employee()), repo, false)
+
+	result, err := login(t, manager)
+	require.NoError(t, err)
+
+	assert.True(t, result.Provisioned)
+	assert.False(t, result.Linked)
+	assert.Equal(t, "emp-1", result.Subject)
+	assert.Equal(t, "doctor@clinic.example", result.User.Email)
+	assert.True(t, result.User.EmailVerified)
+	assert.Equal(t, "Anna", result.User.FirstName)
+	assert.Equal(t, "Petrova", result.User.LastName)
+
+	again, err := login(t, manager)
+	require.NoError(t, err)
+
+	assert.False(t, again.Provisioned)
+	assert.False(t, again.Linked)
+	assert.Equal(t, result.User.ID, again.User.ID)
+	assert.Len(t, repo.users, 1)
+}
+
+func TestManager_LinksByVerifiedEmail(t *testing.T) {
+	t.Parallel()
+
+	existing := calltypes.User{ID: 7, Email: "doctor@clinic.example", EmailVerified: true, FirstName: "Anna"}
+	repo := newMemoryRepository(existing)
+	manager := newManager(newMockIdP(t, employee()), repo, false)
+
+	result, err := login(t, manager)
+	require.NoError(t, err)
+
+	assert.True(t, result.Linked)
+	assert.False(t, result.Provisioned)
+	assert.Equal(t, existing.ID, result.User.ID)
+	assert.Contains(t, repo.identities, testProvider+"|emp-1")
+}
+
+func TestManager_RefusesUnverifiedLocalAccount(t *testing.T) {
+	t.Parallel()
+
+	// Whoever registered the email without verifying it may not own it, so
+	// the owner signing in through the provider must not inherit the account.
+	repo := newMemoryRepository(calltypes.User{ID: 7, Email: "doctor@clinic.example"})
+	manager := newManager(newMockIdP(t, employee()), repo, false)
+
+	_, err := login(t, manager)
+	require.ErrorIs(t, err, errormsg.ErrUnverifiedLocalEmail)
+
+	assert.Empty(t, repo.identities)
+	assert.Len(t, repo.users, 1)
+}
+
+func TestManager_UnverifiedEmail(t *testing.T) {
+	t.Parallel()
+
+	claims := employee()
+	delete(claims, "email_verified")
+
+	tests := []struct {
+		name       string
+		trustEmail bool
+		wantErr    error
+	}{
+		{
+			name:    "not linked or provisioned",
+			wantErr: errormsg.ErrUnverifiedEmail,
+		},
+		{
+			name:       "trusted provider",
+			trustEmail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemoryRepository(calltypes.User{ID: 7, Email: "doctor@clinic.example", EmailVerified: true})
+			manager := newManager(newMockIdP(t, claims), repo, tt.trustEmail)
+
+			result, err := login(t, manager)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, repo.identities)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, result.Linked)
+		})
+	}
+}
+
+func TestManager_RejectsIDToken(t *testing.T) {
+	t.Parallel()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+		key    *rsa.PrivateKey
+	}{
+		{
+			name:   "nonce mismatch",
+			tamper: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" },
+		},
+		{
+			name:   "another audience",
+			tamper: func(claims jwt.MapClaims) { claims["aud"] = "grafana" },
+		},
+		{
+			name:   "another party",
+			tamper: func(claims jwt.MapClaims) { claims["aud"] = []string{testClientID, "grafana"} },
+		},
+		{
+			name:   "another issuer",
+			tamper: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example" },
+		},
+		{
+			name:   "expired",
+			tamper: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+		},
+		{
+			name:   "no expiry",
+			tamper: func(claims jwt.MapClaims) { delete(claims, "exp") },
+		},
+		{
+			name:   "no subject",
+			tamper: func(claims jwt.MapClaims) { delete(claims, "sub") },
+		},
+		{
+			name: "foreign key",
+			key:  otherKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			idp := newMockIdP(t, employee())
+			idp.tamper = tt.tamper
+
+			if tt.key != nil {
+				idp.key = tt.key
+			}
+
+			repo := newMemoryRepository()
+
+			_, err := login(t, newManager(idp, repo, false))
+			require.ErrorIs(t, err, errormsg.ErrInvalidIDToken)
+			assert.Empty(t, repo.users)
+		})
+	}
+}
+
+func TestManager_RejectsResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		modify  func(state string, response url.Values) (string, url.Values)
+		wantErr error
+	}{
+		{
+			name: "state of another browser",
+			modify: func(_ string, response url.Values) (string, url.Values) {
+				return "stolen", response
+			},
+			wantErr: errormsg.ErrInvalidFederatedLogin,
+		},
+		{
+			name: "no state",
+			modify: func(_ string, response url.Values) (string, url.Values) {
+				response.Del("state")
+
+				return "", response
+			},
+			wantErr: errormsg.ErrInvalidFederatedLogin,
+		},
+		{
+			name: "mixed up issuer",
+			modify: func(state string, response url.Values) (string, url.Values) {
+				response.Set("iss", "https://other.example")
+
+				return state, response
+			},
+			wantErr: errormsg.ErrInvalidFederatedLogin,
+		},
+		{
+			name: "wrong code",
+			modify: func(state string, response url.Values) (string, url.Values) {
+				response.Set("code", "guessed")
+
+				return state, response
+			},
+			wantErr: errormsg.ErrInvalidFederatedLogin,
+		},
+		{
+			name: "denied by the user",
+			modify: func(state string, _ url.Values) (string, url.Values) {
+				return state, url.Values{"state": {state}, "error": {"access_denied"}}
+			},
+			wantErr: errormsg.ErrFederatedLoginDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			manager := newManager(newMockIdP(t, employee()), newMemoryRepository(), false)
+
+			state, response := tt.modify(authorize(t, manager))
+
+			_, err := manager.Complete(testProvider, state, response)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestManager_ResponseAcceptedOnce(t *testing.T) {
+	t.Parallel()
+
+	manager := newManager(newMockIdP(t, employee()), newMemoryRepository(), false)
+
+	state, response := authorize(t, manager)
+
+	_, err := manager.Complete(testProvider, state, response)
+	require.NoError(t, err)
+
+	_, err = manager.Complete(testProvider, state, response)
+	require.ErrorIs(t, err, errormsg.ErrInvalidFederatedLogin)
+}
+
+func TestManager_ProviderUnavailable(t *testing.T) {
+	t.Parallel()
+
+	idp := newMockIdP(t, employee())
+	manager := newManager(idp, newMemoryRepository(), false)
+	idp.Close()
+
+	_, err := manager.Begin(testProvider)
+	require.ErrorIs(t, err, errormsg.ErrProviderUnavailable)
+}
@@ -0,0 +1,119 @@
+package federation
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"time"
+)
+
+// Identity is a user as described by an external identity provider.
+type Identity struct {
+	// Provider names the provider the subject belongs to.
+	Provider string
+	Subject  string
+	Email    string
+	// EmailVerified tells whether the provider vouches for the email.
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// Result is a completed login. Linked is set when the identity has just been
+// linked to an existing user, Provisioned when the user has been created for it.
+type Result struct {
+	User        *calltypes.User
+	Provider    string
+	Subject     string
+	Linked      bool
+	Provisioned bool
+}
+
+// Identities maps subjects of external identity providers to users.
+type Identities struct {
+	repo  repository.FederationRepository
+	users repository.UserProvisioner
+	now   func() time.Time
+}
+
+func NewIdentities(repo repository.FederationRepository, users repository.UserProvisioner) *Identities {
+	return &Identities{
+		repo:  repo,
+		users: users,
+		now:   time.Now,
+	}
+}
+
+// Resolve finds the user of the identity. An unknown identity is linked to the
+// user with the same email, or a user is created for it; either way the
+// provider must vouch for the email. A user who has not verified the email is
+// not linked: anyone could have registered it, and their password would keep
+// working after the owner signed in through the provider.
+func (i *Identities) Resolve(identity Identity) (*Result, error) {
+	result := &Result{Provider: identity.Provider, Subject: identity.Subject}
+
+	linked, err := i.repo.GetFederatedIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		if err := i.repo.TouchFederatedIdentity(identity.Provider, identity.Subject, identity.Email, i.now()); err != nil {
+			return nil, err
+		}
+
+		result.User, err = i.users.GetOne(linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	if !errors.Is(err, errormsg.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errormsg.ErrUnverifiedEmail
+	}
+
+	// A failed lookup is taken as a missing user; should it be a database
+	// error, the insert below fails too.
+	user, err := i.users.GetByEmail(identity.Email)
+	if err == nil {
+		if !user.EmailVerified {
+			return nil, errormsg.ErrUnverifiedLocalEmail
+		}
+
+		result.Linked = true
+	} else {
+		id, err := i.users.InsertExternal(calltypes.User{
+			Email:         identity.Email,
+			EmailVerified: true,
+			FirstName:     identity.FirstName,
+			LastName:      identity.LastName,
+			Status:        calltypes.UserStatusActive,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if user, err = i.users.GetOne(id); err != nil {
+			return nil, err
+		}
+
+		result.Provisioned = true
+	}
+
+	err = i.repo.LinkFederatedIdentity(calltypes.FederatedIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserID:   user.ID,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.User = user
+
+	return result, nil
+}
@@ -0,0 +1,291 @@
+package federation
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Signing algorithms accepted for ID tokens.
+const (
+	algRS256 = "RS256"
+	algES256 = "ES256"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// keyRefreshInterval limits how often the key set is refetched when an ID
+	// token is signed with an unknown key, e.g. after key rotation.
+	keyRefreshInterval = time.Minute
+	minRSAKeyBits      = 2048
+)
+
+// provider caches the metadata and the signing keys of an upstream provider.
+type provider struct {
+	cfg ProviderConfig
+
+	mu            sync.Mutex
+	metadata      *calltypes.OpenIDConfiguration
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// discover returns the provider metadata, fetching it on first use.
+func (p *provider) discover(client *http.Client) (*calltypes.OpenIDConfiguration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata calltypes.OpenIDConfiguration
+
+	if err := getJSON(client, strings.TrimSuffix(p.cfg.Issuer, "/")+discoveryPath, &metadata); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case metadata.Issuer != p.cfg.Issuer:
+		return nil, fmt.Errorf("%w: metadata is issued for %q", errormsg.ErrProviderUnavailable, metadata.Issuer)
+	case metadata.AuthorizationEndpoint == "", metadata.TokenEndpoint == "", metadata.JWKSURI == "":
+		return nil, fmt.Errorf("%w: metadata lacks endpoints", errormsg.ErrProviderUnavailable)
+	}
+
+	p.metadata = &metadata
+
+	return p.metadata, nil
+}
+
+// exchange redeems the authorization code at the token endpoint and returns
+// the ID token. The client authenticates with client_secret_basic.
+func (p *provider) exchange(client *http.Client, code, redirectURI, verifier string) (string, error) {
+	metadata, err := p.discover(client)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errormsg.ErrProviderUnavailable, err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errormsg.ErrProviderUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	body := io.LimitReader(response.Body, consts.Megabyte)
+
+	if response.StatusCode != http.StatusOK {
+		var oauthErr calltypes.OAuthErrorResponse
+		if err := json.NewDecoder(body).Decode(&oauthErr); err != nil || oauthErr.Error == "" {
+			return "", fmt.Errorf("%w: token endpoint responded %d", errormsg.ErrProviderUnavailable, response.StatusCode)
+		}
+
+		return "", fmt.Errorf("%w: %s %s", errormsg.ErrInvalidFederatedLogin, oauthErr.Error, oauthErr.ErrorDescription)
+	}
+
+	var tokens calltypes.OAuthTokenResponse
+	if err := json.NewDecoder(body).Decode(&tokens); err != nil {
+		return "", fmt.Errorf("%w: malformed token response", errormsg.ErrProviderUnavailable)
+	}
+
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("%w: no ID token in the token response", errormsg.ErrInvalidIDToken)
+	}
+
+	return tokens.IDToken, nil
+}
+
+// verifyIDToken checks the signature and the claims of the ID token (OpenID
+// Connect Core section 3.1.3.7).
+func (p *provider) verifyIDToken(client *http.Client, idToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{ValidMethods: []string{algRS256, algES256}}
+
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		return p.key(client, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errormsg.ErrInvalidIDToken, err)
+	}
+
+	audiences := stringList(claims["aud"])
+	subject, _ := claims["sub"].(string)
+	_, hasExpiry := claims["exp"]
+
+	switch {
+	case claims["iss"] != p.cfg.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer", errormsg.ErrInvalidIDToken)
+	case !slices.Contains(audiences, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: issued for another audience", errormsg.ErrInvalidIDToken)
+	case len(audiences) > 1 && claims["azp"] != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: issued for another party", errormsg.ErrInvalidIDToken)
+	case !hasExpiry:
+		return nil, fmt.Errorf("%w: no expiry", errormsg.ErrInvalidIDToken)
+	case claims["nonce"] != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", errormsg.ErrInvalidIDToken)
+	case subject == "":
+		return nil, fmt.Errorf("%w: no subject", errormsg.ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// key returns the signing key with the key ID. An unknown key makes the key set
+// refetched, at most once per keyRefreshInterval. Without a key ID the only key
+// of the set is used.
+func (p *provider) key(client *http.Client, kid string) (crypto.PublicKey, error) {
+	metadata, err := p.discover(client)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown signing key %q", errormsg.ErrInvalidIDToken, kid)
+	}
+
+	var set calltypes.JSONWebKeySet
+	if err := getJSON(client, metadata.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	p.keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	p.keysFetchedAt = time.Now()
+
+	for _, jwk := range set.Keys {
+		if key := publicKey(jwk); key != nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+
+	if key := p.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown signing key %q", errormsg.ErrInvalidIDToken, kid)
+}
+
+func (p *provider) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+
+	return p.keys[kid]
+}
+
+// publicKey decodes an RSA or P-256 signing key. Keys of other types, keys
+// for encryption and weak RSA keys are skipped.
+func publicKey(jwk calltypes.JSONWebKey) crypto.PublicKey {
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 || len(n)*8 < minRSAKeyBits {
+			return nil
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+
+		if jwk.Crv != "P-256" || errX != nil || errY != nil {
+			return nil
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) { //nolint: staticcheck
+			return nil
+		}
+
+		return key
+	default:
+		return nil
+	}
+}
+
+// getJSON fetches a JSON document of the provider.
+func getJSON(client *http.Client, location string, target interface{}) error {
+	response, err := client.Get(location) //nolint: noctx
+	if err != nil {
+		return fmt.Errorf("%w: %w", errormsg.ErrProviderUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s responded %d", errormsg.ErrProviderUnavailable, location, response.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(response.Body, consts.Megabyte)).Decode(target); err != nil {
+		return fmt.Errorf("%w: malformed %s: %w", errormsg.ErrProviderUnavailable, location, err)
+	}
+
+	return nil
+}
+
+// stringList reads a claim that is either a string or an array of strings.
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+// Package lockout protects password authentication from brute force: failed
+// attempts are counted per account and per source IP, further attempts are
+// delayed exponentially and accounts are locked after too many failures.
+package lockout
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailaddr"
+	"auth-service/internal/notify"
+	"auth-service/internal/postgres/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// Policy holds lockout thresholds.
+type Policy struct {
+	// MaxAccountFailures locks the account after that many consecutive failures.
+	MaxAccountFailures int
+	// MaxIPFailures locks the source IP after that many failures.
+	MaxIPFailures int
+	// LockDuration is how long a lock lasts.
+	LockDuration time.Duration
+	// BaseDelay is the delay after the first failure, doubled with every next one.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential delay.
+	MaxDelay time.Duration
+	// FailureWindow is the time after which failures are forgotten.
+	FailureWindow time.Duration
+}
+
+// Delay returns how long to wait after the given number of consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if failures <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxDelay)
+}
+
+// RetryAfter returns how long the holder of the given counters has to wait before
+// the next attempt, zero if it may try right away.
+func (p Policy) RetryAfter(state *calltypes.LoginFailures, now time.Time) time.Duration {
+	if state.LockedUntil.After(now) {
+		return state.LockedUntil.Sub(now)
+	}
+
+	if state.Failures == 0 || now.Sub(state.LastFailureAt) > p.FailureWindow {
+		return 0
+	}
+
+	return max(state.LastFailureAt.Add(p.Delay(state.Failures)).Sub(now), 0)
+}
+
+// Guard applies Policy to authentication attempts.
+type Guard struct {
+	repo   repository.LoginAttemptRepository
+	policy Policy
+	mailer notify.Mailer
+	now    func() time.Time
+}
+
+func NewGuard(repo repository.LoginAttemptRepository, policy Policy, mailer notify.Mailer) *Guard {
+	return &Guard{
+		repo:   repo,
+		policy: policy,
+		mailer: mailer,
+		now:    time.Now,
+	}
+}
+
+// Check returns how long the caller has to wait before trying to log in to the
+// account from the IP, zero if the attempt may proceed. Accounts are keyed by
+// emailaddr.Key, so that changing the case of the email does not evade a lock.
+func (g *Guard) Check(email, ip string) (time.Duration, error) {
+	now := g.now()
+
+	var wait time.Duration
+
+	for scope, key := range map[string]string{ScopeAccount: emailaddr.Key(email), ScopeIP: ip} {
+		state, err := g.repo.GetLoginFailures(scope, key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check %s lockout: %w", scope, err)
+		}
+
+		wait = max(wait, g.policy.RetryAfter(state, now))
+	}
+
+	return wait, nil
+}
+
+// RegisterFailure counts a failed attempt and locks the account or the IP once
+// their threshold is reached. The owner of an existing account is notified about
+// the lock; unknown emails are counted too, but nobody is notified.
+func (g *Guard) RegisterFailure(email, ip string, accountExists bool) error {
+	account, err := g.repo.RecordLoginFailure(ScopeAccount, emailaddr.Key(email), g.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if g.policy.MaxAccountFailures > 0 && account.Failures >= g.policy.MaxAccountFailures {
+		until := g.now().Add(g.policy.LockDuration)
+
+		if err := g.repo.LockLogin(ScopeAccount, emailaddr.Key(email), until); err != nil {
+			return err
+		}
+
+		if accountExists {
+			g.notifyLocked(email, ip, until)
+		}
+	}
+
+	source, err := g.repo.RecordLoginFailure(ScopeIP, ip, g.policy.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if g.policy.MaxIPFailures > 0 && source.Failures >= g.policy.MaxIPFailures {
+		return g.repo.LockLogin(ScopeIP, ip, g.now().Add(g.policy.LockDuration))
+	}
+
+	return nil
+}
+
+// RegisterSuccess clears failed attempts of the account. IP counters are kept so
+// that logging in to one's own account does not reset an attack from the same IP.
+func (g *Guard) RegisterSuccess(email string) error {
+	return g.repo.ResetLoginFailures(ScopeAccount, emailaddr.Key(email))
+}
+
+// Unlock clears failed attempts and the lock of the account.
+func (g *Guard) Unlock(email string) error {
+	return g.repo.ResetLoginFailures(ScopeAccount, emailaddr.Key(email))
+}
+
+func (g *Guard) notifyLocked(email, ip string, until time.Time) {
+	body := fmt.Sprintf(
+		"Security warning: your account has been locked after %d failed login attempts\n"+
+			"Last attempt IP: %s\n"+
+			"Locked until: %s",
+		g.policy.MaxAccountFailures, ip, until.Format(time.RFC3339),
+	)
+
+	if err := g.mailer.Send(email, "Security Warning - Account Locked", body); err != nil {
+		log.Println("failed to send lockout notification: ", err)
+	}
+}
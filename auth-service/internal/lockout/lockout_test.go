@@ -0,0 +1,184 @@
+package lockout_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/lockout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAttempts struct {
+	mock.Mock
+}
+
+func (m *MockAttempts) GetLoginFailures(scope, key string) (*calltypes.LoginFailures, error) {
+	args := m.Called(scope, key)
+
+	return args.Get(0).(*calltypes.LoginFailures), args.Error(1) //nolint: forcetypeassert
+}
+
+func (m *MockAttempts) RecordLoginFailure(scope, key string, window time.Duration) (*calltypes.LoginFailures, error) {
+	args := m.Called(scope, key, window)
+
+	return args.Get(0).(*calltypes.LoginFailures), args.Error(1) //nolint: forcetypeassert
+}
+
+func (m *MockAttempts) LockLogin(scope, key string, until time.Time) error {
+	args := m.Called(scope, key, until)
+
+	return args.Error(0) //nolint: wrapcheck
+}
+
+func (m *MockAttempts) ResetLoginFailures(scope, key string) error {
+	args := m.Called(scope, key)
+
+	return args.Error(0) //nolint: wrapcheck
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(to, subject, body string) error {
+	args := m.Called(to, subject, body)
+
+	return args.Error(0) //nolint: wrapcheck
+}
+
+func testPolicy() lockout.Policy {
+	return lockout.Policy{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      10,
+		LockDuration:       15 * time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Minute,
+		FailureWindow:      time.Hour,
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	t.Parallel()
+
+	policy := testPolicy()
+
+	assert.Equal(t, time.Duration(0), policy.Delay(0))
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 2*time.Second, policy.Delay(2))
+	assert.Equal(t, 16*time.Second, policy.Delay(5))
+	assert.Equal(t, time.Minute, policy.Delay(40))
+}
+
+func TestPolicy_RetryAfter(t *testing.T) {
+	t.Parallel()
+
+	policy := testPolicy()
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		state    calltypes.LoginFailures
+		expected time.Duration
+	}{
+		{
+			name:     "no failures",
+			state:    calltypes.LoginFailures{},
+			expected: 0,
+		},
+		{
+			name:     "backoff pending",
+			state:    calltypes.LoginFailures{Failures: 3, LastFailureAt: now.Add(-time.Second)},
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "backoff elapsed",
+			state:    calltypes.LoginFailures{Failures: 2, LastFailureAt: now.Add(-5 * time.Second)},
+			expected: 0,
+		},
+		{
+			name:     "locked",
+			state:    calltypes.LoginFailures{Failures: 3, LastFailureAt: now, LockedUntil: now.Add(10 * time.Minute)},
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "failures outside window",
+			state:    calltypes.LoginFailures{Failures: 30, LastFailureAt: now.Add(-2 * time.Hour)},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, policy.RetryAfter(&tt.state, now))
+		})
+	}
+}
+
+func TestGuard_RegisterFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		failures      int
+		accountExists bool
+		expectLock    bool
+		expectNotify  bool
+	}{
+		{name: "below threshold", failures: 2, accountExists: true},
+		{name: "threshold reached", failures: 3, accountExists: true, expectLock: true, expectNotify: true},
+		{name: "unknown account", failures: 3, accountExists: false, expectLock: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := new(MockAttempts)
+			mailer := new(MockMailer)
+			policy := testPolicy()
+
+			repo.On("RecordLoginFailure", lockout.ScopeAccount, "user@example.com", policy.FailureWindow).
+				Return(&calltypes.LoginFailures{Failures: tt.failures, LastFailureAt: time.Now()}, nil)
+			repo.On("RecordLoginFailure", lockout.ScopeIP, "10.0.0.1", policy.FailureWindow).
+				Return(&calltypes.LoginFailures{Failures: 1, LastFailureAt: time.Now()}, nil)
+
+			if tt.expectLock {
+				repo.On("LockLogin", lockout.ScopeAccount, "user@example.com", mock.MatchedBy(func(until time.Time) bool {
+					return time.Until(until) > 14*time.Minute
+				})).Return(nil)
+			}
+
+			if tt.expectNotify {
+				mailer.On("Send", "user@example.com", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			guard := lockout.NewGuard(repo, policy, mailer)
+
+			require.NoError(t, guard.RegisterFailure("user@example.com", "10.0.0.1", tt.accountExists))
+
+			repo.AssertExpectations(t)
+			mailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGuard_Check(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockAttempts)
+	repo.On("GetLoginFailures", lockout.ScopeAccount, "user@example.com").
+		Return(&calltypes.LoginFailures{}, nil)
+	repo.On("GetLoginFailures", lockout.ScopeIP, "10.0.0.1").
+		Return(&calltypes.LoginFailures{Failures: 20, LastFailureAt: time.Now(), LockedUntil: time.Now().Add(time.Minute)}, nil)
+
+	guard := lockout.NewGuard(repo, testPolicy(), new(MockMailer))
+
+	wait, err := guard.Check(" User@Example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), wait.Seconds(), 1)
+}
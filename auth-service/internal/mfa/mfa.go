@@ -0,0 +1,258 @@
+// Package mfa implements TOTP based multi-factor authentication: enrollment,
+// code verification with replay protection, single-use recovery codes and the
+// pending login challenge issued between the password and the second factor.
+package mfa
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/internal/secretbox"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	MethodTOTP           = "totp"
+	challengeTokenLength = 32
+)
+
+// Config holds MFA settings.
+type Config struct {
+	// Issuer is the name shown by authenticator apps.
+	Issuer string
+	// Skew is the number of time steps of clock drift tolerated in each direction.
+	Skew int
+	// ChallengeTTL is how long a pending login waits for the second factor.
+	ChallengeTTL time.Duration
+	// MaxChallengeAttempts is how many wrong codes a pending login tolerates.
+	MaxChallengeAttempts int
+}
+
+// Manager enrolls and verifies second factors.
+type Manager struct {
+	repo repository.MFARepository
+	box  *secretbox.Box
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewManager(repo repository.MFARepository, box *secretbox.Box, cfg Config) *Manager {
+	return &Manager{
+		repo: repo,
+		box:  box,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// Enroll generates a new TOTP secret for the user. The secret stays inactive
+// until confirmed with a first code.
+func (m *Manager) Enroll(userID int, account string) (*calltypes.TOTPEnrollment, error) {
+	enrollment, sealed, err := m.newSecret(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.repo.SaveTOTP(userID, sealed); err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// Reenroll generates a new TOTP secret for a user who may already have TOTP
+// enabled, e.g. after losing the authenticator and logging in with a recovery
+// code. The current secret keeps working until the new one is confirmed.
+func (m *Manager) Reenroll(userID int, account string) (*calltypes.TOTPEnrollment, error) {
+	enabled, err := m.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		return m.Enroll(userID, account)
+	}
+
+	enrollment, sealed, err := m.newSecret(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.repo.SavePendingTOTP(userID, sealed); err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// newSecret generates a TOTP secret and returns it both as shown to the user
+// and encrypted for storage.
+func (m *Manager) newSecret(account string) (*calltypes.TOTPEnrollment, string, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sealed, err := m.box.Seal(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &calltypes.TOTPEnrollment{
+		Secret: EncodeSecret(secret),
+		URI:    URI(m.cfg.Issuer, account, secret),
+	}, sealed, nil
+}
+
+// Confirm activates TOTP of the user, or the secret replacing it, after
+// checking the first code and returns a new set of recovery codes.
+func (m *Manager) Confirm(userID int, code string) ([]string, error) {
+	totp, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp.Confirmed {
+		if totp.PendingSecret == "" {
+			return nil, errormsg.ErrMFAAlreadyEnabled
+		}
+
+		step, err := m.validate(&calltypes.TOTP{Secret: totp.PendingSecret}, code)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.repo.ReplaceTOTP(userID, step); err != nil {
+			return nil, err
+		}
+
+		return m.GenerateRecoveryCodes(userID)
+	}
+
+	step, err := m.validate(totp, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.repo.ConfirmTOTP(userID, step); err != nil {
+		return nil, err
+	}
+
+	return m.GenerateRecoveryCodes(userID)
+}
+
+// Enabled reports whether the user has confirmed TOTP.
+func (m *Manager) Enabled(userID int) (bool, error) {
+	totp, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, errormsg.ErrMFANotEnrolled) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return totp.Confirmed, nil
+}
+
+// Reset removes TOTP, recovery codes and pending logins of the user, e.g. when
+// the user lost the authenticator. The user logs in with a password alone and
+// may enroll again.
+func (m *Manager) Reset(userID int) error {
+	return m.repo.DeleteMFA(userID)
+}
+
+// VerifyTOTP checks a login code. Each code is accepted once.
+func (m *Manager) VerifyTOTP(userID int, code string) error {
+	totp, err := m.repo.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+
+	if !totp.Confirmed {
+		return errormsg.ErrMFANotEnrolled
+	}
+
+	step, err := m.validate(totp, code)
+	if err != nil {
+		return err
+	}
+
+	if step <= totp.LastUsedStep {
+		return errormsg.ErrMFACodeReused
+	}
+
+	fresh, err := m.repo.UseTOTPStep(userID, step)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return errormsg.ErrMFACodeReused
+	}
+
+	return nil
+}
+
+// NewChallenge starts a pending login for the user who passed the first factor
+// with the authentication methods amr and returns its token.
+func (m *Manager) NewChallenge(userID int, ip string, amr []string) (string, time.Time, error) {
+	raw := make([]byte, challengeTokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate MFA challenge: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := m.now().Add(m.cfg.ChallengeTTL)
+
+	err := m.repo.CreateMFAChallenge(calltypes.MFAChallenge{
+		ID:        hashToken(token),
+		UserID:    userID,
+		IP:        ip,
+		AMR:       amr,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// Challenge counts an attempt to complete the pending login of the token and
+// returns the login. It must be called before the code is checked, and the
+// challenge must be completed from the IP that passed the first factor.
+func (m *Manager) Challenge(token, ip string) (*calltypes.MFAChallenge, error) {
+	return m.repo.ClaimMFAChallengeAttempt(hashToken(token), ip, m.cfg.MaxChallengeAttempts, m.now())
+}
+
+// CompleteChallenge removes the challenge once the login has succeeded.
+func (m *Manager) CompleteChallenge(challenge *calltypes.MFAChallenge) error {
+	return m.repo.DeleteMFAChallenge(challenge.ID)
+}
+
+func (m *Manager) validate(totp *calltypes.TOTP, code string) (int64, error) {
+	secret, err := m.box.Open(totp.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := Validate(secret, code, m.now(), m.cfg.Skew)
+	if !ok {
+		return 0, errormsg.ErrInvalidMFACode
+	}
+
+	return step, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
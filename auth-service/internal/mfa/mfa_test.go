@@ -0,0 +1,307 @@
+package mfa_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/mfa"
+	"auth-service/internal/secretbox"
+	"auth-service/pkg/errormsg"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) GetTOTP(userID int) (*calltypes.TOTP, error) {
+	args := m.Called(userID)
+
+	totp, _ := args.Get(0).(*calltypes.TOTP)
+
+	return totp, args.Error(1) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) SaveTOTP(userID int, encryptedSecret string) error {
+	return m.Called(userID, encryptedSecret).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) ConfirmTOTP(userID int, step int64) error {
+	return m.Called(userID, step).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) SavePendingTOTP(userID int, encryptedSecret string) error {
+	return m.Called(userID, encryptedSecret).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) ReplaceTOTP(userID int, step int64) error {
+	return m.Called(userID, step).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	args := m.Called(userID, step)
+
+	return args.Bool(0), args.Error(1) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) CreateMFAChallenge(challenge calltypes.MFAChallenge) error {
+	return m.Called(challenge).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) ClaimMFAChallengeAttempt(id, ip string, maxAttempts int, now time.Time) (*calltypes.MFAChallenge, error) {
+	args := m.Called(id, ip, maxAttempts, now)
+
+	challenge, _ := args.Get(0).(*calltypes.MFAChallenge)
+
+	return challenge, args.Error(1) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) DeleteMFAChallenge(id string) error {
+	return m.Called(id).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	return m.Called(userID, codeHashes).Error(0) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) ConsumeRecoveryCode(userID int, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+
+	return args.Bool(0), args.Error(1) //nolint: wrapcheck
+}
+
+func (m *MockMFARepository) DeleteMFA(userID int) error {
+	return m.Called(userID).Error(0) //nolint: wrapcheck
+}
+
+func testConfig() mfa.Config {
+	return mfa.Config{
+		Issuer:               "medods",
+		Skew:                 1,
+		ChallengeTTL:         5 * time.Minute,
+		MaxChallengeAttempts: 3,
+	}
+}
+
+func testBox(t *testing.T) *secretbox.Box {
+	t.Helper()
+
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+
+	return box
+}
+
+func TestManager_EnrollAndConfirm(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	box := testBox(t)
+	manager := mfa.NewManager(repo, box, testConfig())
+
+	var sealed string
+
+	repo.On("SaveTOTP", 7, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		sealed = args.String(1)
+	}).Return(nil)
+
+	enrollment, err := manager.Enroll(7, "user@example.com")
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.NotContains(t, sealed, enrollment.Secret)
+
+	secret, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, mfa.EncodeSecret(secret))
+
+	repo.On("GetTOTP", 7).Return(&calltypes.TOTP{UserID: 7, Secret: sealed}, nil)
+
+	_, err = manager.Confirm(7, "000000")
+	require.ErrorIs(t, err, errormsg.ErrInvalidMFACode)
+
+	var storedHashes []string
+
+	step := mfa.Step(time.Now())
+	repo.On("ConfirmTOTP", 7, step).Return(nil)
+	repo.On("ReplaceRecoveryCodes", 7, mock.AnythingOfType("[]string")).Run(func(args mock.Arguments) {
+		storedHashes, _ = args.Get(1).([]string)
+	}).Return(nil)
+
+	codes, err := manager.Confirm(7, mfa.Code(secret, step))
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Len(t, storedHashes, 10)
+	assert.NotContains(t, storedHashes, codes[0])
+
+	repo.AssertExpectations(t)
+}
+
+func TestManager_Reenroll(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	box := testBox(t)
+	manager := mfa.NewManager(repo, box, testConfig())
+
+	current, err := mfa.GenerateSecret()
+	require.NoError(t, err)
+
+	sealedCurrent, err := box.Seal(current)
+	require.NoError(t, err)
+
+	var sealedPending string
+
+	repo.On("GetTOTP", 8).Return(&calltypes.TOTP{UserID: 8, Secret: sealedCurrent, Confirmed: true}, nil).Once()
+	repo.On("SavePendingTOTP", 8, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		sealedPending = args.String(1)
+	}).Return(nil)
+
+	enrollment, err := manager.Reenroll(8, "user@example.com")
+	require.NoError(t, err)
+
+	pending, err := box.Open(sealedPending)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, mfa.EncodeSecret(pending))
+
+	totp := &calltypes.TOTP{UserID: 8, Secret: sealedCurrent, PendingSecret: sealedPending, Confirmed: true}
+	repo.On("GetTOTP", 8).Return(totp, nil)
+
+	step := mfa.Step(time.Now())
+
+	_, err = manager.Confirm(8, mfa.Code(current, step))
+	require.ErrorIs(t, err, errormsg.ErrInvalidMFACode, "the code of the current secret does not confirm the new one")
+
+	repo.On("ReplaceTOTP", 8, step).Return(nil).Once()
+	repo.On("ReplaceRecoveryCodes", 8, mock.AnythingOfType("[]string")).Return(nil).Once()
+
+	codes, err := manager.Confirm(8, mfa.Code(pending, step))
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	repo.AssertExpectations(t)
+}
+
+func TestManager_ConfirmEnabledWithoutPendingSecret(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	manager := mfa.NewManager(repo, testBox(t), testConfig())
+
+	repo.On("GetTOTP", 9).Return(&calltypes.TOTP{UserID: 9, Secret: "sealed", Confirmed: true}, nil)
+
+	_, err := manager.Confirm(9, "123456")
+	require.ErrorIs(t, err, errormsg.ErrMFAAlreadyEnabled)
+}
+
+func TestManager_VerifyTOTPRejectsReplay(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	box := testBox(t)
+	manager := mfa.NewManager(repo, box, testConfig())
+
+	secret, err := mfa.GenerateSecret()
+	require.NoError(t, err)
+
+	sealed, err := box.Seal(secret)
+	require.NoError(t, err)
+
+	step := mfa.Step(time.Now())
+	code := mfa.Code(secret, step)
+
+	repo.On("GetTOTP", 1).Return(&calltypes.TOTP{UserID: 1, Secret: sealed, Confirmed: true}, nil).Once()
+	repo.On("UseTOTPStep", 1, step).Return(true, nil).Once()
+	require.NoError(t, manager.VerifyTOTP(1, code))
+
+	repo.On("GetTOTP", 1).Return(&calltypes.TOTP{UserID: 1, Secret: sealed, Confirmed: true, LastUsedStep: step}, nil).Once()
+	require.ErrorIs(t, manager.VerifyTOTP(1, code), errormsg.ErrMFACodeReused)
+
+	repo.AssertExpectations(t)
+}
+
+func TestManager_Challenge(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	manager := mfa.NewManager(repo, testBox(t), testConfig())
+
+	var stored calltypes.MFAChallenge
+
+	repo.On("CreateMFAChallenge", mock.AnythingOfType("calltypes.MFAChallenge")).Run(func(args mock.Arguments) {
+		stored, _ = args.Get(0).(calltypes.MFAChallenge)
+	}).Return(nil)
+
+	token, _, err := manager.NewChallenge(5, "10.0.0.1", []string{"otp"})
+	require.NoError(t, err)
+	assert.NotEqual(t, token, stored.ID)
+	assert.Equal(t, []string{"otp"}, stored.AMR)
+
+	claimed := stored
+	claimed.Attempts = 1
+
+	repo.On("ClaimMFAChallengeAttempt", stored.ID, "10.0.0.1", testConfig().MaxChallengeAttempts, mock.AnythingOfType("time.Time")).
+		Return(&claimed, nil)
+	repo.On("ClaimMFAChallengeAttempt", stored.ID, "10.0.0.2", testConfig().MaxChallengeAttempts, mock.AnythingOfType("time.Time")).
+		Return(nil, errormsg.ErrInvalidMFAChallenge)
+
+	challenge, err := manager.Challenge(token, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 5, challenge.UserID)
+	assert.Equal(t, []string{"otp"}, challenge.AMR)
+
+	_, err = manager.Challenge(token, "10.0.0.2")
+	require.ErrorIs(t, err, errormsg.ErrInvalidMFAChallenge)
+
+	repo.AssertExpectations(t)
+}
+
+func TestManager_RecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	manager := mfa.NewManager(repo, testBox(t), testConfig())
+
+	var storedHashes []string
+
+	repo.On("ReplaceRecoveryCodes", 3, mock.AnythingOfType("[]string")).Run(func(args mock.Arguments) {
+		storedHashes, _ = args.Get(1).([]string)
+	}).Return(nil)
+
+	codes, err := manager.GenerateRecoveryCodes(3)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	assert.Regexp(t, `^[A-Z2-9]{5}-[A-Z2-9]{5}$`, codes[0])
+
+	repo.On("ConsumeRecoveryCode", 3, storedHashes[0]).Return(true, nil).Once()
+	require.NoError(t, manager.UseRecoveryCode(3, strings.ToLower(strings.ReplaceAll(codes[0], "-", " "))))
+
+	repo.On("ConsumeRecoveryCode", 3, storedHashes[0]).Return(false, nil).Once()
+	require.ErrorIs(t, manager.UseRecoveryCode(3, codes[0]), errormsg.ErrInvalidRecoveryCode)
+
+	require.ErrorIs(t, manager.UseRecoveryCode(3, "short"), errormsg.ErrInvalidRecoveryCode)
+
+	repo.AssertExpectations(t)
+}
+
+func TestManager_Reset(t *testing.T) {
+	t.Parallel()
+
+	repo := new(MockMFARepository)
+	manager := mfa.NewManager(repo, testBox(t), testConfig())
+
+	repo.On("DeleteMFA", 4).Return(nil).Once()
+	require.NoError(t, manager.Reset(4))
+
+	repo.On("GetTOTP", 4).Return(nil, errormsg.ErrMFANotEnrolled).Once()
+
+	enabled, err := manager.Enabled(4)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	repo.AssertExpectations(t)
+}
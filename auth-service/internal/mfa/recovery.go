@@ -0,0 +1,90 @@
+package mfa
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const (
+	MethodRecoveryCode = "recovery_code"
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// recoveryAlphabet avoids characters that are easily confused when read aloud.
+	recoveryAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// GenerateRecoveryCodes creates a new set of single-use recovery codes for the
+// user, invalidating the previous set. Only hashes are stored; the returned codes
+// must be shown to the user once.
+func (m *Manager) GenerateRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := m.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes one of the recovery codes of the user.
+func (m *Manager) UseRecoveryCode(userID int, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return errormsg.ErrInvalidRecoveryCode
+	}
+
+	consumed, err := m.repo.ConsumeRecoveryCode(userID, hashToken(normalized))
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return errormsg.ErrInvalidRecoveryCode
+	}
+
+	return nil
+}
+
+// newRecoveryCode returns a code formatted as XXXXX-XXXXX.
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+
+	code := make([]byte, 0, recoveryCodeLength+1)
+
+	for i, b := range raw {
+		if i == recoveryCodeLength/2 {
+			code = append(code, '-')
+		}
+
+		code = append(code, recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+	}
+
+	return string(code), nil
+}
+
+// normalizeRecoveryCode makes codes typed with other case or separators match.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -0,0 +1,71 @@
+package mfa_test
+
+import (
+	"auth-service/internal/mfa"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 seed from RFC 6238 Appendix B.
+var rfcSecret = []byte("12345678901234567890") //nolint: gochecknoglobals
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, mfa.Code(rfcSecret, mfa.Step(time.Unix(tt.unix, 0))))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1111111109, 0)
+	step := mfa.Step(now)
+
+	matched, ok := mfa.Validate(rfcSecret, "081804", now, 1)
+	require.True(t, ok)
+	assert.Equal(t, step, matched)
+
+	previous := mfa.Code(rfcSecret, step-1)
+	matched, ok = mfa.Validate(rfcSecret, previous, now, 1)
+	require.True(t, ok)
+	assert.Equal(t, step-1, matched)
+
+	_, ok = mfa.Validate(rfcSecret, mfa.Code(rfcSecret, step-2), now, 1)
+	assert.False(t, ok)
+
+	_, ok = mfa.Validate(rfcSecret, previous, now, 0)
+	assert.False(t, ok)
+
+	_, ok = mfa.Validate(rfcSecret, "81804", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	t.Parallel()
+
+	uri, err := url.Parse(mfa.URI("medods", "user@example.com", rfcSecret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/medods:user@example.com", uri.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+	assert.Equal(t, "medods", uri.Query().Get("issuer"))
+}
@@ -0,0 +1,30 @@
+// Package notify delivers notifications to users.
+package notify
+
+import "log"
+
+// Mailer sends email messages.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer prints messages to the log instead of sending them. It is meant
+// for development: bodies carry links and codes that grant access to
+// accounts, so they are not logged.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send prints the recipient and subject of the message.
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("=== EMAIL ===\n"+
+		"To: %s\n"+
+		"Subject: %s\n"+
+		"Body: [redacted, %d bytes]\n"+
+		"=============\n",
+		to, subject, len(body))
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures delivery through an SMTP relay.
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN, which net/smtp only
+	// allows over TLS or to localhost. Authentication is skipped without
+	// Username.
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name.
+	From string
+}
+
+// SMTPMailer sends messages through an SMTP relay, upgrading the connection
+// with STARTTLS when the relay supports it.
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errormsg.ErrInvalidEmail, err)
+	}
+
+	return &SMTPMailer{cfg: cfg, from: from}, nil
+}
+
+// Send delivers a plain text message to one recipient.
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return errormsg.ErrInvalidMailHeader
+	}
+
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errormsg.ErrInvalidEmail, err)
+	}
+
+	msg, err := m.message(recipient, subject, body)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	return smtp.SendMail(addr, auth, m.from.Address, []string{recipient.Address}, msg)
+}
+
+func (m *SMTPMailer) message(to *mail.Address, subject, body string) ([]byte, error) {
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&msg)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,171 @@
+package notify_test
+
+import (
+	"auth-service/internal/notify"
+	"auth-service/pkg/errormsg"
+	"bufio"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envelope is a message received by fakeRelay.
+type envelope struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeRelay accepts one SMTP session without extensions and returns the
+// message it received.
+func fakeRelay(t *testing.T) (int, <-chan envelope) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan envelope, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg envelope
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost ESMTP")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+
+				var data strings.Builder
+
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					if line == ".\r\n" {
+						break
+					}
+
+					data.WriteString(line)
+				}
+
+				msg.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				received <- msg
+
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	t.Parallel()
+
+	port, received := fakeRelay(t)
+
+	mailer, err := notify.NewSMTPMailer(notify.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "Medods <noreply@medods.example>",
+	})
+	require.NoError(t, err)
+
+	body := "Подтвердите вход: https://medods.example/login?token=abc=def\n"
+	require.NoError(t, mailer.Send("user@example.com", "Вход в аккаунт", body))
+
+	msg := <-received
+	assert.Equal(t, "noreply@medods.example", msg.from)
+	assert.Equal(t, []string{"user@example.com"}, msg.to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(msg.data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Вход в аккаунт", subject)
+	assert.Equal(t, `"Medods" <noreply@medods.example>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<user@example.com>", parsed.Header.Get("To"))
+	assert.Equal(t, "quoted-printable", parsed.Header.Get("Content-Transfer-Encoding"))
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	require.NoError(t, err)
+	assert.Equal(t, strings.ReplaceAll(body, "\n", "\r\n"), string(decoded))
+}
+
+func TestSMTPMailerSendRejectsHeaderInjection(t *testing.T) {
+	t.Parallel()
+
+	mailer, err := notify.NewSMTPMailer(notify.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: 1,
+		From: "noreply@medods.example",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		to      string
+		subject string
+	}{
+		{name: "recipient", to: "user@example.com\r\nBcc: victim@example.com", subject: "Hello"},
+		{name: "subject", to: "user@example.com", subject: "Hello\nBcc: victim@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := mailer.Send(tt.to, tt.subject, "body")
+			assert.ErrorIs(t, err, errormsg.ErrInvalidMailHeader)
+		})
+	}
+}
+
+func TestNewSMTPMailerInvalidFrom(t *testing.T) {
+	t.Parallel()
+
+	_, err := notify.NewSMTPMailer(notify.SMTPConfig{Host: "127.0.0.1", Port: 25, From: "not an address"})
+	assert.ErrorIs(t, err, errormsg.ErrInvalidEmail)
+}
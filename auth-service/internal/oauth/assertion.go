@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// TokenEndpoint returns the URL of the token endpoint, which is the expected
+// audience of client assertions.
+func (s *Server) TokenEndpoint() string {
+	return s.endpoint("/oauth/token")
+}
+
+// verifyAssertion checks a private_key_jwt client assertion (RFC 7523 section
+// 3): the signature by one of the client keys, iss and sub naming the client,
+// the audience and a short lifetime. Each assertion is accepted once.
+func (s *Server) verifyAssertion(client *calltypes.OAuthClient, assertion string) error {
+	parser := jwt.Parser{ValidMethods: []string{AlgES256, AlgRS256}}
+
+	parsed, err := parser.Parse(assertion, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		for _, jwk := range client.JWKS {
+			if kid != "" && jwk.Kid != kid {
+				continue
+			}
+
+			if key, alg, err := publicKey(jwk); err == nil && alg == t.Method.Alg() {
+				return key, nil
+			}
+		}
+
+		return nil, fmt.Errorf("%w: no matching key", errormsg.ErrInvalidClientAssertion)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", errormsg.ErrInvalidClientAssertion, err.Error())
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return errormsg.ErrInvalidClientAssertion
+	}
+
+	iss, _ := claims["iss"].(string)
+	sub, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	expiresAt := time.Unix(int64(exp), 0)
+
+	switch {
+	case iss != client.ID || sub != client.ID:
+		return fmt.Errorf("%w: iss and sub must be the client ID", errormsg.ErrInvalidClientAssertion)
+	case !claims.VerifyAudience(s.TokenEndpoint(), true) && !claims.VerifyAudience(s.cfg.Issuer, true):
+		return fmt.Errorf("%w: unexpected audience", errormsg.ErrInvalidClientAssertion)
+	case jti == "":
+		return fmt.Errorf("%w: jti is required", errormsg.ErrInvalidClientAssertion)
+	case exp == 0 || expiresAt.After(s.now().Add(consts.OAuthAssertionMaxAge)):
+		return fmt.Errorf("%w: exp must be within %s", errormsg.ErrInvalidClientAssertion, consts.OAuthAssertionMaxAge)
+	}
+
+	fresh, err := s.repo.UseClientAssertion(client.ID, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return fmt.Errorf("%w: assertion has already been used", errormsg.ErrInvalidClientAssertion)
+	}
+
+	return nil
+}
+
+// assertionSubject returns the client ID claimed by an unverified assertion,
+// for requests that do not send client_id.
+func assertionSubject(assertion string) string {
+	claims := jwt.MapClaims{}
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(assertion, claims); err != nil {
+		return ""
+	}
+
+	sub, _ := claims["sub"].(string)
+
+	return sub
+}
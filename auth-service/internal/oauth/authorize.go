@@ -0,0 +1,185 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	responseTypeCode    = "code"
+	challengeMethodS256 = "S256"
+	challengeLength     = 43
+	minVerifierLength   = 43
+	maxVerifierLength   = 128
+	promptNone          = "none"
+)
+
+// AuthorizeRequest is a validated authorization request. Nonce and Prompt are
+// parameters of OpenID Connect requests.
+type AuthorizeRequest struct {
+	Client        *calltypes.OAuthClient
+	RedirectURI   string
+	State         string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	Prompt        string
+}
+
+// Interactive reports whether the user may be asked to log in. With
+// prompt=none a user without a session gets the login_required error instead.
+func (r *AuthorizeRequest) Interactive() bool {
+	return r.Prompt != promptNone
+}
+
+// ParseAuthorizeRequest validates query parameters of the authorization
+// endpoint. When the client or the redirect URI is invalid the returned request
+// is nil and the error must be shown to the user; otherwise the error is to be
+// sent to the client with ErrorRedirect.
+func (s *Server) ParseAuthorizeRequest(query url.Values) (*AuthorizeRequest, error) {
+	client, err := s.repo.GetOAuthClient(query.Get("client_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	redirectURI := query.Get("redirect_uri")
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, errormsg.ErrInvalidRedirectURI
+	}
+
+	request := &AuthorizeRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		State:         query.Get("state"),
+		CodeChallenge: query.Get("code_challenge"),
+		Nonce:         query.Get("nonce"),
+		Prompt:        query.Get("prompt"),
+	}
+
+	if query.Get("response_type") != responseTypeCode {
+		return request, errormsg.ErrUnsupportedResponseType
+	}
+
+	if !slices.Contains(client.GrantTypes, GrantAuthorizationCode) {
+		return request, errormsg.ErrUnauthorizedClient
+	}
+
+	// Users are not asked for consent, so third-party clients would get access
+	// to accounts of users merely visiting their pages while logged in.
+	if !client.FirstParty {
+		return request, fmt.Errorf("%w: only first-party clients may use the authorization endpoint", errormsg.ErrUnauthorizedClient)
+	}
+
+	if request.CodeChallenge == "" {
+		return request, fmt.Errorf("%w: code_challenge is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	if query.Get("code_challenge_method") != challengeMethodS256 {
+		return request, fmt.Errorf("%w: code_challenge_method must be S256", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	if decoded, err := base64.RawURLEncoding.DecodeString(request.CodeChallenge); err != nil ||
+		len(request.CodeChallenge) != challengeLength || len(decoded) != sha256.Size {
+		return request, fmt.Errorf("%w: malformed code_challenge", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	if request.Scope, err = s.requestedScope(query.Get("scope"), client); err != nil {
+		return request, err
+	}
+
+	if prompts := strings.Fields(request.Prompt); len(prompts) > 1 && slices.Contains(prompts, promptNone) {
+		return request, fmt.Errorf("%w: prompt=none must not be combined with other values", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	return request, nil
+}
+
+// Authorize issues an authorization code to the user for the request and
+// returns the URI to redirect the user to.
+func (s *Server) Authorize(request *AuthorizeRequest, userID int) (string, error) {
+	code, err := randomString(tokenLength)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.CreateAuthorizationCode(calltypes.OAuthAuthorizationCode{
+		CodeHash:      hashValue(code),
+		ClientID:      request.Client.ID,
+		UserID:        userID,
+		RedirectURI:   request.RedirectURI,
+		Scope:         request.Scope,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		ExpiresAt:     s.now().Add(s.cfg.CodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.redirect(request, url.Values{"code": {code}}), nil
+}
+
+// ErrorRedirect returns the URI that reports the error to the client.
+func (s *Server) ErrorRedirect(request *AuthorizeRequest, err error) string {
+	code := ErrorCode(err)
+
+	params := url.Values{"error": {code}}
+	if code != ErrorServerError {
+		params.Set("error_description", err.Error())
+	}
+
+	return s.redirect(request, params)
+}
+
+// redirect adds the parameters, state and issuer to the redirect URI.
+func (s *Server) redirect(request *AuthorizeRequest, params url.Values) string {
+	location, err := url.Parse(request.RedirectURI)
+	if err != nil {
+		return request.RedirectURI
+	}
+
+	query := location.Query()
+
+	for key, values := range params {
+		query[key] = values
+	}
+
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+
+	query.Set("iss", s.cfg.Issuer)
+	location.RawQuery = query.Encode()
+
+	return location.String()
+}
+
+// verifyCodeVerifier checks the PKCE code verifier against the S256 challenge.
+func verifyCodeVerifier(verifier, challenge string) bool {
+	if len(verifier) < minVerifierLength || len(verifier) > maxVerifierLength {
+		return false
+	}
+
+	for _, c := range verifier {
+		if !isUnreserved(c) {
+			return false
+		}
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// isUnreserved reports whether the character is allowed in a code verifier.
+func isUnreserved(c rune) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
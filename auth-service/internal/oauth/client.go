@@ -0,0 +1,259 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ClientAssertionTypeJWT is the client assertion type of private_key_jwt (RFC 7523).
+const ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// supportedGrantTypes lists grant types clients may be registered for.
+var supportedGrantTypes = []string{ //nolint: gochecknoglobals
+	GrantAuthorizationCode,
+	GrantRefreshToken,
+	GrantClientCredentials,
+	GrantDeviceCode,
+	GrantTokenExchange,
+}
+
+// ClientCredentials are credentials presented at the token endpoint. Basic
+// reports whether they came in the Authorization header. Clients using
+// private_key_jwt present a signed assertion instead of a secret.
+type ClientCredentials struct {
+	ID            string
+	Secret        string
+	Basic         bool
+	AssertionType string
+	Assertion     string
+}
+
+// RegisterClient validates the client metadata and registers the client.
+// Confidential clients get a secret, which is stored only as a hash.
+func (s *Server) RegisterClient(request calltypes.OAuthClientRequest) (*calltypes.OAuthClientCredentials, error) {
+	client, err := clientFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.ID, err = randomString(idLength); err != nil {
+		return nil, err
+	}
+
+	credentials := &calltypes.OAuthClientCredentials{ClientID: client.ID}
+
+	if client.TokenEndpointAuthMethod == AuthMethodSecretBasic || client.TokenEndpointAuthMethod == AuthMethodSecretPost {
+		if credentials.ClientSecret, err = randomString(tokenLength); err != nil {
+			return nil, err
+		}
+
+		client.SecretHash = hashValue(credentials.ClientSecret)
+	}
+
+	client.CreatedAt = s.now()
+
+	if err := s.repo.CreateOAuthClient(*client); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// AuthenticateClient returns the client if the credentials match the
+// authentication method it was registered with.
+func (s *Server) AuthenticateClient(credentials ClientCredentials) (*calltypes.OAuthClient, error) {
+	if credentials.ID == "" && credentials.Assertion != "" {
+		credentials.ID = assertionSubject(credentials.Assertion)
+	}
+
+	if credentials.ID == "" {
+		return nil, errormsg.ErrInvalidClient
+	}
+
+	client, err := s.repo.GetOAuthClient(credentials.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if (credentials.Assertion != "") != (client.TokenEndpointAuthMethod == AuthMethodPrivateKeyJWT) {
+		return nil, errormsg.ErrInvalidClient
+	}
+
+	switch client.TokenEndpointAuthMethod {
+	case AuthMethodNone:
+		if credentials.Secret != "" {
+			return nil, errormsg.ErrInvalidClient
+		}
+	case AuthMethodSecretBasic, AuthMethodSecretPost:
+		if credentials.Basic != (client.TokenEndpointAuthMethod == AuthMethodSecretBasic) ||
+			subtle.ConstantTimeCompare([]byte(hashValue(credentials.Secret)), []byte(client.SecretHash)) != 1 {
+			return nil, errormsg.ErrInvalidClient
+		}
+	case AuthMethodPrivateKeyJWT:
+		if credentials.Basic || credentials.Secret != "" || credentials.AssertionType != ClientAssertionTypeJWT {
+			return nil, errormsg.ErrInvalidClient
+		}
+
+		if err := s.verifyAssertion(client, credentials.Assertion); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errormsg.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func clientFromRequest(request calltypes.OAuthClientRequest) (*calltypes.OAuthClient, error) {
+	client := &calltypes.OAuthClient{
+		Name:                    strings.TrimSpace(request.Name),
+		RedirectURIs:            request.RedirectURIs,
+		GrantTypes:              request.GrantTypes,
+		Scopes:                  request.Scopes,
+		TokenEndpointAuthMethod: request.TokenEndpointAuthMethod,
+		ExchangeAudiences:       request.ExchangeAudiences,
+		FirstParty:              request.FirstParty,
+	}
+
+	if request.JWKS != nil {
+		client.JWKS = request.JWKS.Keys
+	}
+
+	if client.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", errormsg.ErrInvalidClientMetadata)
+	}
+
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken}
+	}
+
+	for _, grantType := range client.GrantTypes {
+		if !slices.Contains(supportedGrantTypes, grantType) {
+			return nil, fmt.Errorf("%w: unsupported grant type %q", errormsg.ErrInvalidClientMetadata, grantType)
+		}
+	}
+
+	if client.TokenEndpointAuthMethod == "" {
+		client.TokenEndpointAuthMethod = AuthMethodSecretBasic
+	}
+
+	switch client.TokenEndpointAuthMethod {
+	case AuthMethodSecretBasic, AuthMethodSecretPost, AuthMethodNone, AuthMethodPrivateKeyJWT:
+	default:
+		return nil, fmt.Errorf("%w: unsupported token endpoint auth method %q",
+			errormsg.ErrInvalidClientMetadata, client.TokenEndpointAuthMethod)
+	}
+
+	if err := validateKeys(client); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(client.GrantTypes, GrantClientCredentials) && client.TokenEndpointAuthMethod == AuthMethodNone {
+		return nil, fmt.Errorf("%w: public clients cannot use client_credentials", errormsg.ErrInvalidClientMetadata)
+	}
+
+	if err := validateExchangePolicy(client); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(client.GrantTypes, GrantAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return nil, fmt.Errorf("%w: redirect URIs are required", errormsg.ErrInvalidClientMetadata)
+	}
+
+	for _, redirectURI := range client.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, scope := range client.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\r\n\"\\") {
+			return nil, fmt.Errorf("%w: invalid scope %q", errormsg.ErrInvalidClientMetadata, scope)
+		}
+	}
+
+	return client, nil
+}
+
+// validateExchangePolicy requires confidential token exchange clients to list
+// the audiences they may exchange tokens for, and other clients not to.
+func validateExchangePolicy(client *calltypes.OAuthClient) error {
+	if !slices.Contains(client.GrantTypes, GrantTokenExchange) {
+		if len(client.ExchangeAudiences) > 0 {
+			return fmt.Errorf("%w: exchangeAudiences is used only with token exchange", errormsg.ErrInvalidClientMetadata)
+		}
+
+		return nil
+	}
+
+	if client.TokenEndpointAuthMethod == AuthMethodNone {
+		return fmt.Errorf("%w: public clients cannot exchange tokens", errormsg.ErrInvalidClientMetadata)
+	}
+
+	if len(client.ExchangeAudiences) == 0 {
+		return fmt.Errorf("%w: exchangeAudiences is required for token exchange", errormsg.ErrInvalidClientMetadata)
+	}
+
+	for _, audience := range client.ExchangeAudiences {
+		if audience == "" || strings.ContainsAny(audience, " \t\r\n") {
+			return fmt.Errorf("%w: invalid audience %q", errormsg.ErrInvalidClientMetadata, audience)
+		}
+	}
+
+	return nil
+}
+
+// validateKeys requires public keys for private_key_jwt and only for it.
+func validateKeys(client *calltypes.OAuthClient) error {
+	if client.TokenEndpointAuthMethod != AuthMethodPrivateKeyJWT {
+		if len(client.JWKS) > 0 {
+			return fmt.Errorf("%w: jwks is used only with private_key_jwt", errormsg.ErrInvalidClientMetadata)
+		}
+
+		return nil
+	}
+
+	if len(client.JWKS) == 0 {
+		return fmt.Errorf("%w: jwks is required for private_key_jwt", errormsg.ErrInvalidClientMetadata)
+	}
+
+	for _, jwk := range client.JWKS {
+		if _, _, err := publicKey(jwk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRedirectURI accepts absolute URIs without fragments. Plain http is
+// allowed for loopback addresses only, custom schemes are allowed for native
+// apps.
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.ContainsAny(redirectURI, " \t\r\n#") {
+		return fmt.Errorf("%w: invalid redirect URI %q", errormsg.ErrInvalidClientMetadata, redirectURI)
+	}
+
+	if parsed.Scheme == "http" && !isLoopback(parsed.Hostname()) {
+		return fmt.Errorf("%w: redirect URI %q must use https", errormsg.ErrInvalidClientMetadata, redirectURI)
+	}
+
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
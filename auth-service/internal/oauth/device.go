@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// userCodeAlphabet has no vowels, so codes do not spell words, and no
+	// characters that are easily confused (RFC 8628 section 6.1).
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+	// slowDownStep is added to the interval of a device polling too often.
+	slowDownStep = 5 * time.Second
+)
+
+// AuthorizeDevice handles a request to the device authorization endpoint: it
+// authenticates the client and issues a device code and a user code.
+func (s *Server) AuthorizeDevice(form url.Values, credentials ClientCredentials) (*calltypes.OAuthDeviceAuthorizationResponse, error) {
+	client, err := s.AuthenticateClient(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(client.GrantTypes, GrantDeviceCode) {
+		return nil, errormsg.ErrUnauthorizedClient
+	}
+
+	scope, err := s.requestedScope(form.Get("scope"), client)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := randomString(tokenLength)
+	if err != nil {
+		return nil, err
+	}
+
+	userCode, err := randomUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateDeviceCode(calltypes.OAuthDeviceCode{
+		DeviceCodeHash: hashValue(deviceCode),
+		UserCode:       userCode,
+		ClientID:       client.ID,
+		Scope:          scope,
+		Interval:       consts.OAuthDeviceInterval,
+		ExpiresAt:      s.now().Add(s.cfg.DeviceCodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := s.cfg.DeviceVerificationURL
+	if verificationURI == "" {
+		verificationURI = s.endpoint("/oauth/device")
+	}
+
+	complete, err := url.Parse(verificationURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device verification URL: %w", err)
+	}
+
+	query := complete.Query()
+	query.Set("user_code", formatUserCode(userCode))
+	complete.RawQuery = query.Encode()
+
+	return &calltypes.OAuthDeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: complete.String(),
+		ExpiresIn:               int(s.cfg.DeviceCodeTTL.Seconds()),
+		Interval:                int(consts.OAuthDeviceInterval.Seconds()),
+	}, nil
+}
+
+// DeviceRequest returns the pending device authorization of the user code, to
+// be shown to the user before the decision.
+func (s *Server) DeviceRequest(userCode string) (*calltypes.OAuthDeviceRequest, error) {
+	code, err := s.repo.GetDeviceCode(normalizeUserCode(userCode))
+	if err != nil {
+		return nil, err
+	}
+
+	if code.UserID != 0 || code.Denied || s.now().After(code.ExpiresAt) {
+		return nil, errormsg.ErrInvalidUserCode
+	}
+
+	client, err := s.repo.GetOAuthClient(code.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &calltypes.OAuthDeviceRequest{
+		UserCode:   formatUserCode(code.UserCode),
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scope:      code.Scope,
+	}, nil
+}
+
+// DecideDevice approves or denies the device authorization of the user code
+// on behalf of the user.
+func (s *Server) DecideDevice(userCode string, userID int, approved bool) error {
+	decided, err := s.repo.DecideDeviceCode(normalizeUserCode(userCode), userID, approved)
+	if err != nil {
+		return err
+	}
+
+	if !decided {
+		return errormsg.ErrInvalidUserCode
+	}
+
+	return nil
+}
+
+// exchangeDeviceCode runs the device code grant. Until the user decides the
+// device gets authorization_pending, and slow_down with a longer interval when
+// it polls too often. A finished authorization is removed, so tokens are
+// issued once.
+func (s *Server) exchangeDeviceCode(client *calltypes.OAuthClient, form url.Values, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	deviceCode := form.Get("device_code")
+	if deviceCode == "" {
+		return nil, fmt.Errorf("%w: device_code is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	now := s.now()
+
+	code, err := s.repo.PollDeviceCode(hashValue(deviceCode), now)
+	if err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, errormsg.ErrInvalidGrant
+	}
+
+	if now.After(code.ExpiresAt) {
+		if _, err := s.repo.DeleteDeviceCode(code.DeviceCodeHash); err != nil {
+			return nil, err
+		}
+
+		return nil, errormsg.ErrExpiredToken
+	}
+
+	if code.UserID == 0 {
+		if !code.LastPolledAt.IsZero() && now.Sub(code.LastPolledAt) < code.Interval {
+			if err := s.repo.SlowDownDeviceCode(code.DeviceCodeHash, code.Interval+slowDownStep); err != nil {
+				return nil, err
+			}
+
+			return nil, errormsg.ErrSlowDown
+		}
+
+		return nil, errormsg.ErrAuthorizationPending
+	}
+
+	deleted, err := s.repo.DeleteDeviceCode(code.DeviceCodeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !deleted:
+		return nil, errormsg.ErrInvalidGrant
+	case code.Denied:
+		return nil, errormsg.ErrAccessDenied
+	}
+
+	return s.issue(client, code.UserID, code.Scope, "", clientIP)
+}
+
+// randomUserCode generates a user code of userCodeLength characters.
+func randomUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	limit := big.NewInt(int64(len(userCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// normalizeUserCode drops separators and case the user may have typed.
+func normalizeUserCode(userCode string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == ' ' {
+			return -1
+		}
+
+		return c
+	}, strings.ToUpper(userCode))
+}
+
+// formatUserCode splits the user code in halves for readability, e.g. WDJB-MJHT.
+func formatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
@@ -0,0 +1,177 @@
+package oauth_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/oauth"
+	"auth-service/pkg/errormsg"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerDevice registers a public client of a CLI for the device grant.
+func registerDevice(t *testing.T, server *oauth.Server) oauth.ClientCredentials {
+	t.Helper()
+
+	client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:                    "deploy CLI",
+		GrantTypes:              []string{oauth.GrantDeviceCode, oauth.GrantRefreshToken},
+		Scopes:                  []string{"users:read"},
+		TokenEndpointAuthMethod: oauth.AuthMethodNone,
+	})
+	require.NoError(t, err)
+
+	return oauth.ClientCredentials{ID: client.ClientID}
+}
+
+func pollForm(deviceCode string) url.Values {
+	return url.Values{
+		"grant_type":  {oauth.GrantDeviceCode},
+		"device_code": {deviceCode},
+	}
+}
+
+func TestServer_DeviceFlow(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	credentials := registerDevice(t, server)
+
+	authorization, err := server.AuthorizeDevice(url.Values{"scope": {"users:read"}}, credentials)
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[B-DF-HJ-NP-TV-XZ]{4}-[B-DF-HJ-NP-TV-XZ]{4}$`), authorization.UserCode)
+	assert.Equal(t, testIssuer+"/oauth/device", authorization.VerificationURI)
+	assert.Equal(t, testIssuer+"/oauth/device?user_code="+authorization.UserCode, authorization.VerificationURIComplete)
+	assert.Equal(t, 60, authorization.ExpiresIn)
+	assert.Equal(t, 5, authorization.Interval)
+
+	_, err = server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrAuthorizationPending)
+	assert.Equal(t, oauth.ErrorAuthorizationPending, oauth.ErrorCode(err))
+
+	_, err = server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrSlowDown, "polling faster than the interval")
+	assert.Equal(t, oauth.ErrorSlowDown, oauth.ErrorCode(err))
+
+	// Users may type the code in lower case and without the dash.
+	typed := strings.ToLower(strings.ReplaceAll(authorization.UserCode, "-", ""))
+
+	request, err := server.DeviceRequest(typed)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy CLI", request.ClientName)
+	assert.Equal(t, "users:read", request.Scope)
+
+	require.NoError(t, server.DecideDevice(typed, testUser.ID, true))
+	require.ErrorIs(t, server.DecideDevice(typed, testUser.ID, false), errormsg.ErrInvalidUserCode, "decision is final")
+
+	response, err := server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "users:read", response.Scope)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	_, err = server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant, "tokens are issued once")
+}
+
+func TestServer_DeviceFlowUserSuspendedAfterApproval(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	server := oauth.NewServer(repo, repo, testConfig())
+	credentials := registerDevice(t, server)
+
+	authorization, err := server.AuthorizeDevice(url.Values{"scope": {"users:read"}}, credentials)
+	require.NoError(t, err)
+
+	require.NoError(t, server.DecideDevice(authorization.UserCode, testUser.ID, true))
+
+	repo.mu.Lock()
+	user := repo.users[testUser.ID]
+	user.Status = calltypes.UserStatusSuspended
+	repo.users[testUser.ID] = user
+	repo.mu.Unlock()
+
+	_, err = server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant)
+	assert.Empty(t, repo.refreshTokens, "no refresh token may survive the suspension")
+}
+
+func TestServer_DeviceFlowRejected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     func(cfg *oauth.Config)
+		decide  func(t *testing.T, server *oauth.Server, userCode string)
+		other   bool
+		wantErr error
+	}{
+		{
+			name: "denied by the user",
+			decide: func(t *testing.T, server *oauth.Server, userCode string) {
+				t.Helper()
+				require.NoError(t, server.DecideDevice(userCode, testUser.ID, false))
+			},
+			wantErr: errormsg.ErrAccessDenied,
+		},
+		{
+			name:    "expired",
+			cfg:     func(cfg *oauth.Config) { cfg.DeviceCodeTTL = -time.Second },
+			wantErr: errormsg.ErrExpiredToken,
+		},
+		{
+			name:    "polled by another client",
+			other:   true,
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+
+			server := newServer(cfg)
+			credentials := registerDevice(t, server)
+
+			authorization, err := server.AuthorizeDevice(url.Values{}, credentials)
+			require.NoError(t, err)
+
+			if tt.decide != nil {
+				tt.decide(t, server, authorization.UserCode)
+			}
+
+			if tt.other {
+				credentials = registerDevice(t, server)
+			}
+
+			_, err = server.Exchange(pollForm(authorization.DeviceCode), credentials, "10.0.0.1")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_AuthorizeDeviceRejected(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+
+	_, err := server.AuthorizeDevice(url.Values{}, oauth.ClientCredentials{ID: register(t, server, oauth.AuthMethodNone).ClientID})
+	require.ErrorIs(t, err, errormsg.ErrUnauthorizedClient)
+
+	_, err = server.AuthorizeDevice(url.Values{"scope": {"users:write"}}, registerDevice(t, server))
+	require.ErrorIs(t, err, errormsg.ErrInvalidScope)
+
+	_, err = server.DeviceRequest("BCDF-GHJK")
+	require.ErrorIs(t, err, errormsg.ErrInvalidUserCode)
+}
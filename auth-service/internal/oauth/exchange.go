@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/token"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// TokenTypeAccessToken is the only token type accepted and issued by token
+// exchange (RFC 8693 section 3).
+const TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+// exchangeToken runs the token exchange grant: the client swaps an access
+// token of a user for a token restricted to one of the audiences allowed for
+// the client, with the scope narrowed down. Only tokens of active users are
+// exchanged. The new token names the client in the act claim and gets no
+// refresh token.
+func (s *Server) exchangeToken(client *calltypes.OAuthClient, form url.Values, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	switch {
+	case form.Get("subject_token") == "":
+		return nil, fmt.Errorf("%w: subject_token is required", errormsg.ErrInvalidOAuthRequest)
+	case form.Get("subject_token_type") != TokenTypeAccessToken:
+		return nil, fmt.Errorf("%w: subject_token_type must be %s", errormsg.ErrInvalidOAuthRequest, TokenTypeAccessToken)
+	case form.Has("requested_token_type") && form.Get("requested_token_type") != TokenTypeAccessToken:
+		return nil, fmt.Errorf("%w: only access tokens can be requested", errormsg.ErrInvalidOAuthRequest)
+	case form.Has("actor_token"):
+		return nil, fmt.Errorf("%w: actor_token is not supported, the client is the actor", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	audiences := form["audience"]
+	if len(audiences) != 1 || audiences[0] == "" {
+		return nil, fmt.Errorf("%w: exactly one audience is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	audience := audiences[0]
+	if !slices.Contains(client.ExchangeAudiences, audience) {
+		return nil, fmt.Errorf("%w: %s", errormsg.ErrInvalidTarget, audience)
+	}
+
+	subject, err := s.tokens.ValidateAccessToken(form.Get("subject_token"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid subject_token", errormsg.ErrInvalidGrant)
+	}
+
+	// Tokens already restricted to an audience are not exchanged again, so act
+	// never needs to nest prior actors.
+	if _, ok := subject["aud"]; ok {
+		return nil, fmt.Errorf("%w: subject_token is restricted to an audience", errormsg.ErrInvalidGrant)
+	}
+
+	userID, ok := subject["sub"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: subject_token must belong to a user", errormsg.ErrInvalidGrant)
+	}
+
+	state, err := s.users.SessionState(int(userID))
+	if err != nil || state.Status != calltypes.UserStatusActive {
+		return nil, fmt.Errorf("%w: the user of subject_token is not active", errormsg.ErrInvalidGrant)
+	}
+
+	// The exchanged token gets a new iat, so a token issued before sessions
+	// of the user were revoked must not be exchanged for one.
+	if issuedAt, _ := subject["iat"].(float64); state.Revoked(int64(issuedAt)) {
+		return nil, fmt.Errorf("%w: subject_token has been revoked", errormsg.ErrInvalidGrant)
+	}
+
+	scope, err := exchangedScope(form.Get("scope"), client.Scopes, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokens.GenerateGrantToken(token.Grant{
+		UserID:   int(userID),
+		ClientID: client.ID,
+		Scope:    scope,
+		ClientIP: clientIP,
+		AMR:      stringClaims(subject["amr"]),
+		Audience: audience,
+		Actor:    map[string]interface{}{"sub": client.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &calltypes.OAuthTokenResponse{
+		AccessToken:     accessToken,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       tokenTypeBearer,
+		ExpiresIn:       int(consts.AccessTokenExpireTime.Seconds()),
+		Scope:           scope,
+	}, nil
+}
+
+// exchangedScope narrows the requested scope down to scopes of both the client
+// and the subject token: the scopes granted to the client the token was issued
+// to or, for user sessions, the permissions of the user. The exchanged token
+// never grants more than the subject token.
+func exchangedScope(requested string, clientScopes []string, subject jwt.MapClaims) (string, error) {
+	subjectScope, _ := subject["scope"].(string)
+	allowed := slices.DeleteFunc(slices.Clone(clientScopes), func(scope string) bool {
+		return !slices.Contains(strings.Fields(subjectScope), scope)
+	})
+
+	return grantedScope(requested, allowed)
+}
+
+// stringClaims converts a decoded JSON array claim to strings.
+func stringClaims(claim interface{}) []string {
+	values, _ := claim.([]interface{})
+	result := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,210 @@
+package oauth_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/oauth"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerGateway registers a client allowed to exchange tokens for the
+// orders service.
+func registerGateway(t *testing.T, server *oauth.Server) oauth.ClientCredentials {
+	t.Helper()
+
+	client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:              "gateway",
+		GrantTypes:        []string{oauth.GrantTokenExchange},
+		Scopes:            []string{"orders:read", "orders:write"},
+		ExchangeAudiences: []string{"orders"},
+	})
+	require.NoError(t, err)
+
+	return oauth.ClientCredentials{ID: client.ClientID, Secret: client.ClientSecret, Basic: true}
+}
+
+func exchangeForm(subjectToken string) url.Values {
+	return url.Values{
+		"grant_type":         {oauth.GrantTokenExchange},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.TokenTypeAccessToken},
+		"audience":           {"orders"},
+		"scope":              {"orders:read"},
+	}
+}
+
+func TestServer_TokenExchange(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	credentials := registerGateway(t, server)
+
+	subjectToken, err := token.NewTokenService().GenerateAccessToken(testUser.ID, "10.0.0.2", token.Access{
+		Permissions: []string{"orders:read"},
+	}, token.AMRPassword)
+	require.NoError(t, err)
+
+	response, err := server.Exchange(exchangeForm(subjectToken), credentials, "10.0.0.1")
+	require.NoError(t, err)
+
+	assert.Equal(t, oauth.TokenTypeAccessToken, response.IssuedTokenType)
+	assert.Equal(t, "orders:read", response.Scope)
+	assert.Empty(t, response.RefreshToken, "exchanged tokens must not be refreshable")
+
+	claims, err := token.NewTokenService().ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.InDelta(t, testUser.ID, claims["sub"], 0)
+	assert.Equal(t, "orders", claims["aud"])
+	assert.Equal(t, map[string]interface{}{"sub": credentials.ID}, claims["act"])
+	assert.Equal(t, []interface{}{token.AMRPassword}, claims["amr"])
+
+	_, err = server.Exchange(exchangeForm(response.AccessToken), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant, "tokens with an audience must not be exchanged again")
+}
+
+func TestServer_TokenExchangeRejected(t *testing.T) {
+	t.Parallel()
+
+	userToken, err := token.NewTokenService().GenerateAccessToken(testUser.ID, "10.0.0.2", token.Access{
+		Permissions: []string{"orders:read"},
+	})
+	require.NoError(t, err)
+
+	inactiveToken, err := token.NewTokenService().GenerateAccessToken(suspendedUser.ID, "10.0.0.2", token.Access{
+		Permissions: []string{"orders:read"},
+	})
+	require.NoError(t, err)
+
+	revokedToken, err := token.NewTokenService().GenerateAccessToken(revokedUser.ID, "10.0.0.2", token.Access{
+		Permissions: []string{"orders:read"},
+	})
+	require.NoError(t, err)
+
+	clientToken, err := token.NewTokenService().GenerateGrantToken(token.Grant{ClientID: "billing", Scope: "orders:read"})
+	require.NoError(t, err)
+
+	delegatedToken, err := token.NewTokenService().GenerateGrantToken(token.Grant{
+		UserID:   testUser.ID,
+		ClientID: "spa",
+		Scope:    "orders:read",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		modify  func(form url.Values)
+		wantErr error
+	}{
+		{
+			name:    "audience not allowed",
+			modify:  func(form url.Values) { form.Set("audience", "billing") },
+			wantErr: errormsg.ErrInvalidTarget,
+		},
+		{
+			name:    "no audience",
+			modify:  func(form url.Values) { form.Del("audience") },
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name:    "several audiences",
+			modify:  func(form url.Values) { form.Add("audience", "orders") },
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name:    "scope not allowed for the client",
+			modify:  func(form url.Values) { form.Set("scope", "users:delete") },
+			wantErr: errormsg.ErrInvalidScope,
+		},
+		{
+			name: "scope beyond the subject token",
+			modify: func(form url.Values) {
+				form.Set("subject_token", delegatedToken)
+				form.Set("scope", "orders:write")
+			},
+			wantErr: errormsg.ErrInvalidScope,
+		},
+		{
+			name:    "scope beyond the permissions of the user",
+			modify:  func(form url.Values) { form.Set("scope", "orders:write") },
+			wantErr: errormsg.ErrInvalidScope,
+		},
+		{
+			name:    "subject user not active",
+			modify:  func(form url.Values) { form.Set("subject_token", inactiveToken) },
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name:    "subject token issued before sessions were revoked",
+			modify:  func(form url.Values) { form.Set("subject_token", revokedToken) },
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name:    "wrong subject token type",
+			modify:  func(form url.Values) { form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:id_token") },
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name: "refresh token requested",
+			modify: func(form url.Values) {
+				form.Set("requested_token_type", "urn:ietf:params:oauth:token-type:refresh_token")
+			},
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name:    "actor token",
+			modify:  func(form url.Values) { form.Set("actor_token", userToken) },
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name:    "invalid subject token",
+			modify:  func(form url.Values) { form.Set("subject_token", "not-a-token") },
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name:    "subject token of a client",
+			modify:  func(form url.Values) { form.Set("subject_token", clientToken) },
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newServer(testConfig())
+			credentials := registerGateway(t, server)
+
+			form := exchangeForm(userToken)
+			tt.modify(form)
+
+			_, err := server.Exchange(form, credentials, "10.0.0.1")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_TokenExchangeNarrowsDelegatedScope(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	credentials := registerGateway(t, server)
+
+	subjectToken, err := token.NewTokenService().GenerateGrantToken(token.Grant{
+		UserID:   testUser.ID,
+		ClientID: "spa",
+		Scope:    "orders:read profile",
+	})
+	require.NoError(t, err)
+
+	form := exchangeForm(subjectToken)
+	form.Del("scope")
+
+	response, err := server.Exchange(form, credentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read", response.Scope)
+}
@@ -0,0 +1,172 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/token"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const tokenTypeBearer = "Bearer"
+
+// Exchange handles a request to the token endpoint: it authenticates the
+// client and runs the grant named in the form.
+func (s *Server) Exchange(form url.Values, credentials ClientCredentials, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	client, err := s.AuthenticateClient(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	grantType := form.Get("grant_type")
+
+	if grantType == "" {
+		return nil, fmt.Errorf("%w: grant_type is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	if !slices.Contains(supportedGrantTypes, grantType) {
+		return nil, errormsg.ErrUnsupportedGrantType
+	}
+
+	if !slices.Contains(client.GrantTypes, grantType) {
+		return nil, errormsg.ErrUnauthorizedClient
+	}
+
+	switch grantType {
+	case GrantAuthorizationCode:
+		return s.exchangeCode(client, form, clientIP)
+	case GrantRefreshToken:
+		return s.refresh(client, form, clientIP)
+	case GrantDeviceCode:
+		return s.exchangeDeviceCode(client, form, clientIP)
+	case GrantTokenExchange:
+		return s.exchangeToken(client, form, clientIP)
+	default:
+		return s.clientCredentials(client, form, clientIP)
+	}
+}
+
+// exchangeCode runs the authorization code grant. The code is consumed even
+// when the exchange fails, so a leaked code cannot be retried.
+func (s *Server) exchangeCode(client *calltypes.OAuthClient, form url.Values, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	code := form.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("%w: code is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	grant, err := s.repo.TakeAuthorizationCode(hashValue(code))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case grant.ClientID != client.ID, s.now().After(grant.ExpiresAt):
+		return nil, errormsg.ErrInvalidGrant
+	case form.Get("redirect_uri") != grant.RedirectURI:
+		return nil, fmt.Errorf("%w: redirect_uri does not match", errormsg.ErrInvalidGrant)
+	case !verifyCodeVerifier(form.Get("code_verifier"), grant.CodeChallenge):
+		return nil, fmt.Errorf("%w: code_verifier does not match", errormsg.ErrInvalidGrant)
+	}
+
+	return s.issue(client, grant.UserID, grant.Scope, grant.Nonce, clientIP)
+}
+
+// refresh runs the refresh token grant. The refresh token is rotated and the
+// scope may be narrowed down.
+func (s *Server) refresh(client *calltypes.OAuthClient, form url.Values, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	refreshToken := form.Get("refresh_token")
+	if refreshToken == "" {
+		return nil, fmt.Errorf("%w: refresh_token is required", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	grant, err := s.repo.TakeOAuthRefreshToken(hashValue(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.ClientID != client.ID || s.now().After(grant.ExpiresAt) {
+		return nil, errormsg.ErrInvalidGrant
+	}
+
+	scope, err := grantedScope(form.Get("scope"), strings.Fields(grant.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(client, grant.UserID, scope, "", clientIP)
+}
+
+// clientCredentials runs the client credentials grant: the client gets a token
+// of its own, without a user and without a refresh token.
+func (s *Server) clientCredentials(client *calltypes.OAuthClient, form url.Values, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	scope, err := grantedScope(form.Get("scope"), client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(client, 0, scope, "", clientIP)
+}
+
+// issue generates an access token, an ID token for the openid scope and, for
+// users of clients allowed to refresh, a refresh token. Zero userID issues a
+// token of the client itself. Codes and refresh tokens may outlive the user
+// being active, so nothing is issued for users who are not active anymore.
+func (s *Server) issue(client *calltypes.OAuthClient, userID int, scope, nonce, clientIP string) (*calltypes.OAuthTokenResponse, error) {
+	var user *calltypes.User
+
+	if userID != 0 {
+		var err error
+
+		user, err = s.users.GetOne(userID)
+		if err != nil || user.Status != calltypes.UserStatusActive {
+			return nil, fmt.Errorf("%w: the user is not active", errormsg.ErrInvalidGrant)
+		}
+	}
+
+	accessToken, err := s.tokens.GenerateGrantToken(token.Grant{
+		UserID:   userID,
+		ClientID: client.ID,
+		Scope:    scope,
+		ClientIP: clientIP,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &calltypes.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int(consts.AccessTokenExpireTime.Seconds()),
+		Scope:       scope,
+	}
+
+	if userID != 0 && s.OIDCEnabled() && slices.Contains(strings.Fields(scope), ScopeOpenID) {
+		if response.IDToken, err = s.idToken(client, user, scope, nonce, accessToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if userID == 0 || !slices.Contains(client.GrantTypes, GrantRefreshToken) {
+		return response, nil
+	}
+
+	if response.RefreshToken, err = randomString(tokenLength); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.CreateOAuthRefreshToken(calltypes.OAuthRefreshToken{
+		TokenHash: hashValue(response.RefreshToken),
+		ClientID:  client.ID,
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: s.now().Add(s.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// Signing algorithms accepted for client assertions.
+const (
+	AlgES256 = "ES256"
+	AlgRS256 = "RS256"
+)
+
+const (
+	ktyEC           = "EC"
+	ktyRSA          = "RSA"
+	crvP256         = "P-256"
+	minRSAKeyBits   = 2048
+	maxRSAExponent  = 4
+	useSignature    = "sig"
+	p256CoordLength = 32
+)
+
+// publicKey decodes the JWK and returns the key together with the algorithm it
+// verifies.
+func publicKey(jwk calltypes.JSONWebKey) (crypto.PublicKey, string, error) {
+	if jwk.Use != "" && jwk.Use != useSignature {
+		return nil, "", fmt.Errorf("%w: key %q is not for signatures", errormsg.ErrInvalidClientMetadata, jwk.Kid)
+	}
+
+	switch {
+	case jwk.Kty == ktyEC && (jwk.Alg == "" || jwk.Alg == AlgES256):
+		return parseECKey(jwk)
+	case jwk.Kty == ktyRSA && (jwk.Alg == "" || jwk.Alg == AlgRS256):
+		return parseRSAKey(jwk)
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported key %q (kty %s, alg %s)",
+			errormsg.ErrInvalidClientMetadata, jwk.Kid, jwk.Kty, jwk.Alg)
+	}
+}
+
+func parseECKey(jwk calltypes.JSONWebKey) (crypto.PublicKey, string, error) {
+	x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+
+	if jwk.Crv != crvP256 || errX != nil || errY != nil || len(x) != p256CoordLength || len(y) != p256CoordLength {
+		return nil, "", fmt.Errorf("%w: malformed EC key %q", errormsg.ErrInvalidClientMetadata, jwk.Kid)
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	if !key.Curve.IsOnCurve(key.X, key.Y) { //nolint: staticcheck
+		return nil, "", fmt.Errorf("%w: point of key %q is not on curve", errormsg.ErrInvalidClientMetadata, jwk.Kid)
+	}
+
+	return key, AlgES256, nil
+}
+
+func parseRSAKey(jwk calltypes.JSONWebKey) (crypto.PublicKey, string, error) {
+	n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+	e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+
+	if errN != nil || errE != nil || len(e) == 0 || len(e) > maxRSAExponent {
+		return nil, "", fmt.Errorf("%w: malformed RSA key %q", errormsg.ErrInvalidClientMetadata, jwk.Kid)
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+
+	if key.N.BitLen() < minRSAKeyBits {
+		return nil, "", fmt.Errorf("%w: RSA key %q is shorter than %d bits", errormsg.ErrInvalidClientMetadata, jwk.Kid, minRSAKeyBits)
+	}
+
+	return key, AlgRS256, nil
+}
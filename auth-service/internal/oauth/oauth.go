@@ -0,0 +1,204 @@
+// Package oauth implements an OAuth 2.0 authorization server: the
+// authorization code grant with PKCE (RFC 7636), refresh token rotation, the
+// client credentials grant, the device authorization grant (RFC 8628), token
+// exchange (RFC 8693) and a registry of clients authenticating with
+// secrets or private key JWTs (RFC 7523). Access tokens are issued by the token
+// package. Given a signing key the server is also an OpenID Connect provider
+// issuing RS256 ID tokens.
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grant types.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+	GrantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// Client authentication methods at the token endpoint.
+const (
+	AuthMethodSecretBasic   = "client_secret_basic"
+	AuthMethodSecretPost    = "client_secret_post"
+	AuthMethodPrivateKeyJWT = "private_key_jwt"
+	AuthMethodNone          = "none"
+)
+
+// Error codes of RFC 6749 sections 4.1.2.1 and 5.2, RFC 8628 section 3.5,
+// RFC 8693 section 2.2.2 and OpenID Connect Core section 3.1.2.6.
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorServerError             = "server_error"
+	ErrorLoginRequired           = "login_required"
+	ErrorAuthorizationPending    = "authorization_pending"
+	ErrorSlowDown                = "slow_down"
+	ErrorAccessDenied            = "access_denied"
+	ErrorExpiredToken            = "expired_token"
+	ErrorInvalidTarget           = "invalid_target"
+)
+
+const (
+	tokenLength = 32
+	idLength    = 16
+)
+
+// errorCodes maps errors to OAuth error codes.
+var errorCodes = []struct { //nolint: gochecknoglobals
+	err  error
+	code string
+}{
+	{errormsg.ErrInvalidOAuthRequest, ErrorInvalidRequest},
+	{errormsg.ErrInvalidRedirectURI, ErrorInvalidRequest},
+	{errormsg.ErrInvalidClient, ErrorInvalidClient},
+	{errormsg.ErrInvalidClientAssertion, ErrorInvalidClient},
+	{errormsg.ErrInvalidGrant, ErrorInvalidGrant},
+	{errormsg.ErrUnauthorizedClient, ErrorUnauthorizedClient},
+	{errormsg.ErrUnsupportedGrantType, ErrorUnsupportedGrantType},
+	{errormsg.ErrUnsupportedResponseType, ErrorUnsupportedResponseType},
+	{errormsg.ErrInvalidScope, ErrorInvalidScope},
+	{errormsg.ErrLoginRequired, ErrorLoginRequired},
+	{errormsg.ErrAuthorizationPending, ErrorAuthorizationPending},
+	{errormsg.ErrSlowDown, ErrorSlowDown},
+	{errormsg.ErrAccessDenied, ErrorAccessDenied},
+	{errormsg.ErrExpiredToken, ErrorExpiredToken},
+	{errormsg.ErrInvalidTarget, ErrorInvalidTarget},
+}
+
+// ErrorCode returns the OAuth error code for the error, "server_error" for
+// errors that are not part of the protocol.
+func ErrorCode(err error) string {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+
+	return ErrorServerError
+}
+
+// Config holds authorization server settings.
+type Config struct {
+	// Issuer identifies the server, e.g. "https://auth.example.com". It is sent
+	// with authorization responses (RFC 9207).
+	Issuer string
+	// LoginURL is where users without a session are sent from the authorization
+	// endpoint. The authorization request is passed in the return_to parameter.
+	LoginURL string
+	// CodeTTL is how long an authorization code is valid.
+	CodeTTL time.Duration
+	// RefreshTokenTTL is how long a refresh token is valid.
+	RefreshTokenTTL time.Duration
+	// SigningKey signs ID tokens. OpenID Connect is disabled without it.
+	SigningKey *rsa.PrivateKey
+	// DeviceCodeTTL is how long a device authorization waits for the user.
+	DeviceCodeTTL time.Duration
+	// DeviceVerificationURL is the page where users enter user codes. The
+	// /oauth/device endpoint of the issuer is used when it is empty.
+	DeviceVerificationURL string
+}
+
+// Server issues authorization codes and tokens.
+type Server struct {
+	repo   repository.OAuthRepository
+	users  repository.UserReader
+	tokens *token.ServiceToken
+	cfg    Config
+	keyID  string
+	now    func() time.Time
+}
+
+func NewServer(repo repository.OAuthRepository, users repository.UserReader, cfg Config) *Server {
+	return &Server{
+		repo:   repo,
+		users:  users,
+		tokens: token.NewTokenService(),
+		cfg:    cfg,
+		keyID:  keyID(cfg.SigningKey),
+		now:    time.Now,
+	}
+}
+
+// Config returns settings of the server.
+func (s *Server) Config() Config {
+	return s.cfg
+}
+
+// requestedScope returns the scope granted to the client for the request. The
+// openid scope requires OpenID Connect to be enabled.
+func (s *Server) requestedScope(requested string, client *calltypes.OAuthClient) (string, error) {
+	scope, err := grantedScope(requested, client.Scopes)
+	if err != nil {
+		return "", err
+	}
+
+	if slices.Contains(strings.Fields(scope), ScopeOpenID) && !s.OIDCEnabled() {
+		return "", fmt.Errorf("%w: %s", errormsg.ErrInvalidScope, errormsg.ErrOIDCDisabled.Error())
+	}
+
+	return scope, nil
+}
+
+// grantedScope checks the space separated requested scope against scopes of
+// the client. An empty request is granted all scopes of the client.
+func grantedScope(requested string, allowed []string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), nil
+	}
+
+	var granted []string
+
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(allowed, scope) {
+			return "", fmt.Errorf("%w: %s", errormsg.ErrInvalidScope, scope)
+		}
+
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	return strings.Join(granted, " "), nil
+}
+
+func randomString(length int) (string, error) {
+	raw := make([]byte, length)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// endpoint returns the URL of the path on the issuer.
+func (s *Server) endpoint(path string) string {
+	return strings.TrimSuffix(s.cfg.Issuer, "/") + path
+}
+
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
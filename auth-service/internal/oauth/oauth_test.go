@@ -0,0 +1,886 @@
+package oauth_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/oauth"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRedirectURI = "https://app.example.com/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testIssuer      = "https://auth.example.com"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("SECRET_KEY", "test_secret_key_1234567890")
+
+	os.Exit(m.Run())
+}
+
+// testUser is the user authorizing clients in the tests.
+var testUser = calltypes.User{ //nolint: gochecknoglobals
+	ID:            5,
+	Email:         "user@example.com",
+	EmailVerified: true,
+	FirstName:     "John",
+	LastName:      "Doe",
+	Status:        calltypes.UserStatusActive,
+}
+
+// suspendedUser is a user who may not authorize clients.
+var suspendedUser = calltypes.User{ID: 6, Email: "suspended@example.com", Status: calltypes.UserStatusSuspended} //nolint: gochecknoglobals
+
+// revokedUser is an active user whose sessions are revoked whenever they are
+// checked.
+var revokedUser = calltypes.User{ID: 8, Email: "revoked@example.com", Status: calltypes.UserStatusActive} //nolint: gochecknoglobals
+
+// memoryRepository keeps users, clients, codes and refresh tokens in memory.
+type memoryRepository struct {
+	mu            sync.Mutex
+	users         map[int]calltypes.User
+	clients       map[string]calltypes.OAuthClient
+	codes         map[string]calltypes.OAuthAuthorizationCode
+	refreshTokens map[string]calltypes.OAuthRefreshToken
+	assertions    map[string]bool
+	deviceCodes   map[string]calltypes.OAuthDeviceCode
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		users:         map[int]calltypes.User{testUser.ID: testUser, suspendedUser.ID: suspendedUser, revokedUser.ID: revokedUser},
+		clients:       make(map[string]calltypes.OAuthClient),
+		codes:         make(map[string]calltypes.OAuthAuthorizationCode),
+		refreshTokens: make(map[string]calltypes.OAuthRefreshToken),
+		assertions:    make(map[string]bool),
+		deviceCodes:   make(map[string]calltypes.OAuthDeviceCode),
+	}
+}
+
+func (m *memoryRepository) GetOne(id int) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, errormsg.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (m *memoryRepository) SessionState(id int) (*calltypes.SessionState, error) {
+	user, err := m.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &calltypes.SessionState{Status: user.Status}
+	if id == revokedUser.ID {
+		state.RevokedAt = time.Now()
+	}
+
+	return state, nil
+}
+
+func (m *memoryRepository) CreateOAuthClient(client calltypes.OAuthClient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clients[client.ID] = client
+
+	return nil
+}
+
+func (m *memoryRepository) GetOAuthClient(id string) (*calltypes.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return nil, errormsg.ErrInvalidClient
+	}
+
+	return &client, nil
+}
+
+func (m *memoryRepository) CreateAuthorizationCode(code calltypes.OAuthAuthorizationCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.codes[code.CodeHash] = code
+
+	return nil
+}
+
+func (m *memoryRepository) TakeAuthorizationCode(codeHash string) (*calltypes.OAuthAuthorizationCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, ok := m.codes[codeHash]
+	if !ok {
+		return nil, errormsg.ErrInvalidGrant
+	}
+
+	delete(m.codes, codeHash)
+
+	return &code, nil
+}
+
+func (m *memoryRepository) CreateOAuthRefreshToken(token calltypes.OAuthRefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshTokens[token.TokenHash] = token
+
+	return nil
+}
+
+func (m *memoryRepository) TakeOAuthRefreshToken(tokenHash string) (*calltypes.OAuthRefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refreshToken, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return nil, errormsg.ErrInvalidGrant
+	}
+
+	delete(m.refreshTokens, tokenHash)
+
+	return &refreshToken, nil
+}
+
+func (m *memoryRepository) UseClientAssertion(clientID, jti string, _ time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := clientID + "|" + jti
+	if m.assertions[key] {
+		return false, nil
+	}
+
+	m.assertions[key] = true
+
+	return true, nil
+}
+
+func (m *memoryRepository) CreateDeviceCode(code calltypes.OAuthDeviceCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deviceCodes[code.DeviceCodeHash] = code
+
+	return nil
+}
+
+func (m *memoryRepository) GetDeviceCode(userCode string) (*calltypes.OAuthDeviceCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, code := range m.deviceCodes {
+		if code.UserCode == userCode {
+			return &code, nil
+		}
+	}
+
+	return nil, errormsg.ErrInvalidUserCode
+}
+
+func (m *memoryRepository) DecideDeviceCode(userCode string, userID int, approved bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, code := range m.deviceCodes {
+		if code.UserCode != userCode || code.UserID != 0 || code.Denied || time.Now().After(code.ExpiresAt) {
+			continue
+		}
+
+		code.UserID = userID
+		code.Denied = !approved
+		m.deviceCodes[hash] = code
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (m *memoryRepository) PollDeviceCode(deviceCodeHash string, polledAt time.Time) (*calltypes.OAuthDeviceCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, ok := m.deviceCodes[deviceCodeHash]
+	if !ok {
+		return nil, errormsg.ErrInvalidGrant
+	}
+
+	polled := code
+	polled.LastPolledAt = polledAt
+	m.deviceCodes[deviceCodeHash] = polled
+
+	return &code, nil
+}
+
+func (m *memoryRepository) SlowDownDeviceCode(deviceCodeHash string, interval time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := m.deviceCodes[deviceCodeHash]
+	code.Interval = interval
+	m.deviceCodes[deviceCodeHash] = code
+
+	return nil
+}
+
+func (m *memoryRepository) DeleteDeviceCode(deviceCodeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.deviceCodes[deviceCodeHash]
+	delete(m.deviceCodes, deviceCodeHash)
+
+	return ok, nil
+}
+
+func newServer(cfg oauth.Config) *oauth.Server {
+	repo := newMemoryRepository()
+
+	return oauth.NewServer(repo, repo, cfg)
+}
+
+func testConfig() oauth.Config {
+	return oauth.Config{
+		Issuer:          testIssuer,
+		CodeTTL:         time.Minute,
+		RefreshTokenTTL: time.Hour,
+		DeviceCodeTTL:   time.Minute,
+	}
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// register registers a client with the auth method and returns its credentials.
+func register(t *testing.T, server *oauth.Server, authMethod string) *calltypes.OAuthClientCredentials {
+	t.Helper()
+
+	credentials, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:                    "app",
+		RedirectURIs:            []string{testRedirectURI, "http://127.0.0.1:8080/callback"},
+		Scopes:                  []string{"profile", "email"},
+		TokenEndpointAuthMethod: authMethod,
+		FirstParty:              true,
+	})
+	require.NoError(t, err)
+
+	return credentials
+}
+
+func authorizeQuery(clientID string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {testRedirectURI},
+		"code_challenge":        {challenge(testVerifier)},
+		"code_challenge_method": {"S256"},
+		"scope":                 {"profile"},
+		"state":                 {"xyz"},
+	}
+}
+
+// authorize runs the authorization request and returns the issued code.
+func authorize(t *testing.T, server *oauth.Server, clientID string) string {
+	t.Helper()
+
+	request, err := server.ParseAuthorizeRequest(authorizeQuery(clientID))
+	require.NoError(t, err)
+
+	location, err := server.Authorize(request, testUser.ID)
+	require.NoError(t, err)
+
+	redirect, err := url.Parse(location)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(location, testRedirectURI+"?"))
+	assert.Equal(t, "xyz", redirect.Query().Get("state"))
+	assert.Equal(t, testIssuer, redirect.Query().Get("iss"))
+
+	return redirect.Query().Get("code")
+}
+
+func codeForm(code string) url.Values {
+	return url.Values{
+		"grant_type":    {oauth.GrantAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testVerifier},
+	}
+}
+
+func TestServer_CodeFlow(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	client := register(t, server, oauth.AuthMethodSecretBasic)
+	credentials := oauth.ClientCredentials{ID: client.ClientID, Secret: client.ClientSecret, Basic: true}
+
+	code := authorize(t, server, client.ClientID)
+
+	response, err := server.Exchange(codeForm(code), credentials, "10.0.0.1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, "profile", response.Scope)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	claims, err := token.NewTokenService().ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.InDelta(t, float64(5), claims["sub"], 0.0001)
+	assert.Equal(t, client.ClientID, claims["client_id"])
+	assert.Equal(t, "profile", claims["scope"])
+
+	_, err = server.Exchange(codeForm(code), credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant, "code must be single-use")
+
+	refreshed, err := server.Exchange(url.Values{
+		"grant_type":    {oauth.GrantRefreshToken},
+		"refresh_token": {response.RefreshToken},
+	}, credentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEqual(t, response.RefreshToken, refreshed.RefreshToken)
+
+	_, err = server.Exchange(url.Values{
+		"grant_type":    {oauth.GrantRefreshToken},
+		"refresh_token": {response.RefreshToken},
+	}, credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant, "refresh tokens must be rotated")
+}
+
+func TestServer_PublicClient(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	client := register(t, server, oauth.AuthMethodNone)
+	require.Empty(t, client.ClientSecret)
+
+	code := authorize(t, server, client.ClientID)
+
+	_, err := server.Exchange(codeForm(code), oauth.ClientCredentials{ID: client.ClientID}, "10.0.0.1")
+	require.NoError(t, err)
+}
+
+func TestServer_ParseAuthorizeRequest(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	client := register(t, server, oauth.AuthMethodSecretBasic)
+
+	tests := []struct {
+		name       string
+		tamper     func(query url.Values)
+		wantErr    error
+		redirected bool
+	}{
+		{
+			name:    "unknown client",
+			tamper:  func(query url.Values) { query.Set("client_id", "unknown") },
+			wantErr: errormsg.ErrInvalidClient,
+		},
+		{
+			name:    "redirect URI with trailing slash",
+			tamper:  func(query url.Values) { query.Set("redirect_uri", testRedirectURI+"/") },
+			wantErr: errormsg.ErrInvalidRedirectURI,
+		},
+		{
+			name:    "redirect URI with extra query",
+			tamper:  func(query url.Values) { query.Set("redirect_uri", testRedirectURI+"?next=evil") },
+			wantErr: errormsg.ErrInvalidRedirectURI,
+		},
+		{
+			name:    "missing redirect URI",
+			tamper:  func(query url.Values) { query.Del("redirect_uri") },
+			wantErr: errormsg.ErrInvalidRedirectURI,
+		},
+		{
+			name:       "implicit grant",
+			tamper:     func(query url.Values) { query.Set("response_type", "token") },
+			wantErr:    errormsg.ErrUnsupportedResponseType,
+			redirected: true,
+		},
+		{
+			name:       "missing code challenge",
+			tamper:     func(query url.Values) { query.Del("code_challenge") },
+			wantErr:    errormsg.ErrInvalidOAuthRequest,
+			redirected: true,
+		},
+		{
+			name:       "plain code challenge",
+			tamper:     func(query url.Values) { query.Set("code_challenge_method", "plain") },
+			wantErr:    errormsg.ErrInvalidOAuthRequest,
+			redirected: true,
+		},
+		{
+			name:       "unregistered scope",
+			tamper:     func(query url.Values) { query.Set("scope", "profile admin") },
+			wantErr:    errormsg.ErrInvalidScope,
+			redirected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query := authorizeQuery(client.ClientID)
+			tt.tamper(query)
+
+			request, err := server.ParseAuthorizeRequest(query)
+			require.ErrorIs(t, err, tt.wantErr)
+
+			if !tt.redirected {
+				assert.Nil(t, request, "error must not be redirected to an untrusted URI")
+
+				return
+			}
+
+			require.NotNil(t, request)
+
+			redirect, err := url.Parse(server.ErrorRedirect(request, err))
+			require.NoError(t, err)
+			assert.Equal(t, oauth.ErrorCode(tt.wantErr), redirect.Query().Get("error"))
+			assert.Equal(t, "xyz", redirect.Query().Get("state"))
+		})
+	}
+}
+
+func TestServer_ParseAuthorizeRequestThirdParty(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+
+	client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:         "partner",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{"profile"},
+	})
+	require.NoError(t, err)
+
+	request, err := server.ParseAuthorizeRequest(authorizeQuery(client.ClientID))
+	require.ErrorIs(t, err, errormsg.ErrUnauthorizedClient)
+	require.NotNil(t, request)
+
+	redirect, err := url.Parse(server.ErrorRedirect(request, err))
+	require.NoError(t, err)
+	assert.Equal(t, "unauthorized_client", redirect.Query().Get("error"))
+	assert.Empty(t, redirect.Query().Get("code"))
+}
+
+func TestServer_ExchangeRejected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     func(cfg *oauth.Config)
+		tamper  func(form url.Values, credentials *oauth.ClientCredentials)
+		wantErr error
+	}{
+		{
+			name: "wrong code verifier",
+			tamper: func(form url.Values, _ *oauth.ClientCredentials) {
+				form.Set("code_verifier", strings.Repeat("a", 43))
+			},
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name: "missing code verifier",
+			tamper: func(form url.Values, _ *oauth.ClientCredentials) {
+				form.Del("code_verifier")
+			},
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name: "other redirect URI",
+			tamper: func(form url.Values, _ *oauth.ClientCredentials) {
+				form.Set("redirect_uri", "http://127.0.0.1:8080/callback")
+			},
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name:    "expired code",
+			cfg:     func(cfg *oauth.Config) { cfg.CodeTTL = -time.Second },
+			wantErr: errormsg.ErrInvalidGrant,
+		},
+		{
+			name: "wrong secret",
+			tamper: func(_ url.Values, credentials *oauth.ClientCredentials) {
+				credentials.Secret = "wrong"
+			},
+			wantErr: errormsg.ErrInvalidClient,
+		},
+		{
+			name: "secret in the form of a basic client",
+			tamper: func(_ url.Values, credentials *oauth.ClientCredentials) {
+				credentials.Basic = false
+			},
+			wantErr: errormsg.ErrInvalidClient,
+		},
+		{
+			name: "unsupported grant type",
+			tamper: func(form url.Values, _ *oauth.ClientCredentials) {
+				form.Set("grant_type", "password")
+			},
+			wantErr: errormsg.ErrUnsupportedGrantType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+
+			server := newServer(cfg)
+			client := register(t, server, oauth.AuthMethodSecretBasic)
+			credentials := oauth.ClientCredentials{ID: client.ClientID, Secret: client.ClientSecret, Basic: true}
+			form := codeForm(authorize(t, server, client.ClientID))
+
+			if tt.tamper != nil {
+				tt.tamper(form, &credentials)
+			}
+
+			_, err := server.Exchange(form, credentials, "10.0.0.1")
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_CodeOfAnotherClient(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+	victim := register(t, server, oauth.AuthMethodSecretBasic)
+	attacker := register(t, server, oauth.AuthMethodSecretBasic)
+
+	code := authorize(t, server, victim.ClientID)
+
+	_, err := server.Exchange(codeForm(code), oauth.ClientCredentials{
+		ID:     attacker.ClientID,
+		Secret: attacker.ClientSecret,
+		Basic:  true,
+	}, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidGrant)
+}
+
+func TestServer_RegisterClientRejected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request calltypes.OAuthClientRequest
+	}{
+		{
+			name:    "no name",
+			request: calltypes.OAuthClientRequest{RedirectURIs: []string{testRedirectURI}},
+		},
+		{
+			name:    "no redirect URIs",
+			request: calltypes.OAuthClientRequest{Name: "app"},
+		},
+		{
+			name:    "relative redirect URI",
+			request: calltypes.OAuthClientRequest{Name: "app", RedirectURIs: []string{"/callback"}},
+		},
+		{
+			name:    "redirect URI with fragment",
+			request: calltypes.OAuthClientRequest{Name: "app", RedirectURIs: []string{testRedirectURI + "#top"}},
+		},
+		{
+			name:    "plain http redirect URI",
+			request: calltypes.OAuthClientRequest{Name: "app", RedirectURIs: []string{"http://app.example.com/callback"}},
+		},
+		{
+			name: "implicit grant",
+			request: calltypes.OAuthClientRequest{
+				Name:         "app",
+				RedirectURIs: []string{testRedirectURI},
+				GrantTypes:   []string{"implicit"},
+			},
+		},
+		{
+			name: "public machine client",
+			request: calltypes.OAuthClientRequest{
+				Name:                    "app",
+				GrantTypes:              []string{oauth.GrantClientCredentials},
+				TokenEndpointAuthMethod: oauth.AuthMethodNone,
+			},
+		},
+		{
+			name: "private key JWT without keys",
+			request: calltypes.OAuthClientRequest{
+				Name:                    "app",
+				GrantTypes:              []string{oauth.GrantClientCredentials},
+				TokenEndpointAuthMethod: oauth.AuthMethodPrivateKeyJWT,
+			},
+		},
+		{
+			name: "token exchange without audiences",
+			request: calltypes.OAuthClientRequest{
+				Name:       "gateway",
+				GrantTypes: []string{oauth.GrantTokenExchange},
+			},
+		},
+		{
+			name: "audiences without token exchange",
+			request: calltypes.OAuthClientRequest{
+				Name:              "app",
+				RedirectURIs:      []string{testRedirectURI},
+				ExchangeAudiences: []string{"orders"},
+			},
+		},
+		{
+			name: "public token exchange client",
+			request: calltypes.OAuthClientRequest{
+				Name:                    "gateway",
+				GrantTypes:              []string{oauth.GrantTokenExchange},
+				TokenEndpointAuthMethod: oauth.AuthMethodNone,
+				ExchangeAudiences:       []string{"orders"},
+			},
+		},
+		{
+			name: "short RSA key",
+			request: calltypes.OAuthClientRequest{
+				Name:                    "app",
+				GrantTypes:              []string{oauth.GrantClientCredentials},
+				TokenEndpointAuthMethod: oauth.AuthMethodPrivateKeyJWT,
+				JWKS: &calltypes.JSONWebKeySet{Keys: []calltypes.JSONWebKey{
+					{Kty: "RSA", N: base64.RawURLEncoding.EncodeToString(make([]byte, 128)), E: "AQAB"},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newServer(testConfig())
+
+			_, err := server.RegisterClient(tt.request)
+			require.ErrorIs(t, err, errormsg.ErrInvalidClientMetadata)
+		})
+	}
+}
+
+func TestServer_ClientCredentials(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(testConfig())
+
+	client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:       "billing",
+		GrantTypes: []string{oauth.GrantClientCredentials},
+		Scopes:     []string{"users:read", "users:write"},
+	})
+	require.NoError(t, err)
+
+	credentials := oauth.ClientCredentials{ID: client.ClientID, Secret: client.ClientSecret, Basic: true}
+
+	response, err := server.Exchange(url.Values{
+		"grant_type": {oauth.GrantClientCredentials},
+		"scope":      {"users:read"},
+	}, credentials, "10.0.0.1")
+	require.NoError(t, err)
+
+	assert.Empty(t, response.RefreshToken, "client tokens must not be refreshable")
+	assert.Equal(t, "users:read", response.Scope)
+
+	claims, err := token.NewTokenService().ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, client.ClientID, claims["sub"])
+	assert.Equal(t, client.ClientID, claims["client_id"])
+
+	_, err = server.Exchange(url.Values{
+		"grant_type": {oauth.GrantClientCredentials},
+		"scope":      {"users:delete"},
+	}, credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrInvalidScope)
+
+	_, err = server.Exchange(url.Values{"grant_type": {oauth.GrantAuthorizationCode}}, credentials, "10.0.0.1")
+	require.ErrorIs(t, err, errormsg.ErrUnauthorizedClient)
+}
+
+// signingKey is a client key pair registered for private_key_jwt.
+type signingKey struct {
+	private *ecdsa.PrivateKey
+	jwk     calltypes.JSONWebKey
+}
+
+func newSigningKey(t *testing.T, kid string) *signingKey {
+	t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	coordinate := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, 32)))
+	}
+
+	return &signingKey{
+		private: private,
+		jwk: calltypes.JSONWebKey{
+			Kty: "EC",
+			Kid: kid,
+			Crv: "P-256",
+			X:   coordinate(private.X),
+			Y:   coordinate(private.Y),
+		},
+	}
+}
+
+func (k *signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	assertion := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	assertion.Header["kid"] = k.jwk.Kid
+
+	signed, err := assertion.SignedString(k.private)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestServer_PrivateKeyJWT(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tamper  func(claims jwt.MapClaims)
+		foreign bool
+		replay  bool
+		wantErr error
+	}{
+		{
+			name: "valid assertion",
+		},
+		{
+			name:    "issuer audience",
+			tamper:  func(claims jwt.MapClaims) { claims["aud"] = testIssuer },
+			wantErr: nil,
+		},
+		{
+			name:    "replayed assertion",
+			replay:  true,
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "signed by another key",
+			foreign: true,
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "audience of another server",
+			tamper:  func(claims jwt.MapClaims) { claims["aud"] = "https://other.example.com/oauth/token" },
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "expired",
+			tamper:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "long lived",
+			tamper:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(time.Hour).Unix() },
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "without jti",
+			tamper:  func(claims jwt.MapClaims) { delete(claims, "jti") },
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+		{
+			name:    "subject of another client",
+			tamper:  func(claims jwt.MapClaims) { claims["iss"] = "other" },
+			wantErr: errormsg.ErrInvalidClientAssertion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newServer(testConfig())
+			key := newSigningKey(t, "key-1")
+
+			client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+				Name:                    "reports",
+				GrantTypes:              []string{oauth.GrantClientCredentials},
+				TokenEndpointAuthMethod: oauth.AuthMethodPrivateKeyJWT,
+				JWKS:                    &calltypes.JSONWebKeySet{Keys: []calltypes.JSONWebKey{key.jwk}},
+			})
+			require.NoError(t, err)
+			require.Empty(t, client.ClientSecret)
+
+			claims := jwt.MapClaims{
+				"iss": client.ClientID,
+				"sub": client.ClientID,
+				"aud": server.TokenEndpoint(),
+				"jti": "assertion-1",
+				"exp": time.Now().Add(time.Minute).Unix(),
+				"iat": time.Now().Unix(),
+			}
+
+			if tt.tamper != nil {
+				tt.tamper(claims)
+			}
+
+			signer := key
+			if tt.foreign {
+				signer = newSigningKey(t, "key-1")
+			}
+
+			credentials := oauth.ClientCredentials{
+				AssertionType: oauth.ClientAssertionTypeJWT,
+				Assertion:     signer.sign(t, claims),
+			}
+			form := url.Values{"grant_type": {oauth.GrantClientCredentials}}
+
+			if tt.replay {
+				_, err = server.Exchange(form, credentials, "10.0.0.1")
+				require.NoError(t, err)
+			}
+
+			_, err = server.Exchange(form, credentials, "10.0.0.1")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, oauth.ErrorInvalidClient, oauth.ErrorCode(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
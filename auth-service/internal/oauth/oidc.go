@@ -0,0 +1,209 @@
+package oauth
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// OpenID Connect scopes.
+const (
+	ScopeOpenID  = "openid"
+	ScopeEmail   = "email"
+	ScopeProfile = "profile"
+)
+
+const (
+	minSigningKeyBits = 2048
+	subjectPublic     = "public"
+	responseModeQuery = "query"
+)
+
+// ParseSigningKey parses a PEM encoded RSA private key (PKCS #1 or PKCS #8)
+// that signs ID tokens.
+func ParseSigningKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", errormsg.ErrInvalidSigningKey)
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if pkcs8Err != nil {
+			return nil, fmt.Errorf("%w: %s", errormsg.ErrInvalidSigningKey, pkcs8Err.Error())
+		}
+
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("%w: not an RSA key", errormsg.ErrInvalidSigningKey)
+		}
+	}
+
+	if key.N.BitLen() < minSigningKeyBits {
+		return nil, fmt.Errorf("%w: RSA key must be at least %d bits", errormsg.ErrInvalidSigningKey, minSigningKeyBits)
+	}
+
+	return key, nil
+}
+
+// OIDCEnabled reports whether the server acts as an OpenID provider, which
+// requires a key to sign ID tokens.
+func (s *Server) OIDCEnabled() bool {
+	return s.cfg.SigningKey != nil
+}
+
+// Discovery returns the OpenID provider metadata.
+func (s *Server) Discovery() calltypes.OpenIDConfiguration {
+	return calltypes.OpenIDConfiguration{
+		Issuer:                                s.cfg.Issuer,
+		AuthorizationEndpoint:                 s.endpoint("/oauth/authorize"),
+		TokenEndpoint:                         s.TokenEndpoint(),
+		UserInfoEndpoint:                      s.endpoint("/userinfo"),
+		JWKSURI:                               s.endpoint("/oauth/jwks"),
+		DeviceAuthorizationEndpoint:           s.endpoint("/oauth/device_authorization"),
+		ScopesSupported:                       []string{ScopeOpenID, ScopeEmail, ScopeProfile},
+		ResponseTypesSupported:                []string{responseTypeCode},
+		ResponseModesSupported:                []string{responseModeQuery},
+		GrantTypesSupported:                   supportedGrantTypes,
+		SubjectTypesSupported:                 []string{subjectPublic},
+		IDTokenSigningAlgValuesSupported:      []string{AlgRS256},
+		TokenEndpointAuthMethodsSupported:     []string{AuthMethodSecretBasic, AuthMethodSecretPost, AuthMethodPrivateKeyJWT, AuthMethodNone},
+		TokenEndpointAuthSigningAlgsSupported: []string{AlgES256, AlgRS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce", "azp", "at_hash",
+			"email", "email_verified", "name", "given_name", "family_name",
+		},
+		CodeChallengeMethodsSupported:     []string{challengeMethodS256},
+		AuthorizationResponseISSSupported: true,
+	}
+}
+
+// JWKS returns the key set ID tokens are verified with.
+func (s *Server) JWKS() calltypes.JSONWebKeySet {
+	public := s.cfg.SigningKey.PublicKey
+
+	return calltypes.JSONWebKeySet{Keys: []calltypes.JSONWebKey{{
+		Kty: "RSA",
+		Kid: s.keyID,
+		Use: "sig",
+		Alg: AlgRS256,
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}}
+}
+
+// UserInfo returns claims about the user released for the scopes of the
+// access token, which must include openid.
+func (s *Server) UserInfo(userID int, scopes []string) (*calltypes.UserInfo, error) {
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%w: %s", errormsg.ErrInsufficientScope, ScopeOpenID)
+	}
+
+	user, err := s.users.GetOne(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := userInfo(user, scopes)
+
+	return &info, nil
+}
+
+// idToken issues an ID token (OpenID Connect Core section 2) to the client.
+// The nonce of the authorization request is echoed and at_hash binds the token
+// to the access token issued with it.
+func (s *Server) idToken(client *calltypes.OAuthClient, user *calltypes.User, scope, nonce, accessToken string) (string, error) {
+	now := s.now()
+	info := userInfo(user, strings.Fields(scope))
+
+	claims := jwt.MapClaims{
+		"iss":     s.cfg.Issuer,
+		"sub":     info.Sub,
+		"aud":     client.ID,
+		"azp":     client.ID,
+		"exp":     now.Add(consts.IDTokenExpireTime).Unix(),
+		"iat":     now.Unix(),
+		"at_hash": tokenHash(accessToken),
+	}
+
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	if info.EmailVerified != nil {
+		claims["email"] = info.Email
+		claims["email_verified"] = *info.EmailVerified
+	}
+
+	for name, value := range map[string]string{"name": info.Name, "given_name": info.GivenName, "family_name": info.FamilyName} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = s.keyID
+
+	signed, err := idToken.SignedString(s.cfg.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the ID token: %w", err)
+	}
+
+	return signed, nil
+}
+
+// userInfo selects user claims by scope: email and email_verified for
+// "email", names for "profile".
+func userInfo(user *calltypes.User, scopes []string) calltypes.UserInfo {
+	info := calltypes.UserInfo{Sub: strconv.Itoa(user.ID)}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		verified := user.EmailVerified
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		info.GivenName = user.FirstName
+		info.FamilyName = user.LastName
+		info.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	return info
+}
+
+// tokenHash returns the at_hash of the access token: the left half of its
+// SHA-256 hash.
+func tokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// keyID returns the JWK thumbprint (RFC 7638) of the public key.
+func keyID(key *rsa.PrivateKey) string {
+	if key == nil {
+		return ""
+	}
+
+	thumbprint := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+	)
+	sum := sha256.Sum256([]byte(thumbprint))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,367 @@
+package oauth_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/oauth"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerKey is generated once, RSA key generation is slow.
+var providerKey = sync.OnceValue(func() *rsa.PrivateKey { //nolint: gochecknoglobals
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+})
+
+func oidcConfig() oauth.Config {
+	cfg := testConfig()
+	cfg.SigningKey = providerKey()
+
+	return cfg
+}
+
+// authorizeOIDC registers a client for the OpenID scopes, runs an
+// authentication request and exchanges the code.
+func authorizeOIDC(t *testing.T, server *oauth.Server, scope, nonce string) (*calltypes.OAuthTokenResponse, oauth.ClientCredentials) {
+	t.Helper()
+
+	client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+		Name:         "grafana",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   []string{oauth.GrantAuthorizationCode, oauth.GrantRefreshToken},
+		Scopes:       []string{oauth.ScopeOpenID, oauth.ScopeEmail, oauth.ScopeProfile},
+		FirstParty:   true,
+	})
+	require.NoError(t, err)
+
+	query := authorizeQuery(client.ClientID)
+	query.Set("scope", scope)
+
+	if nonce != "" {
+		query.Set("nonce", nonce)
+	}
+
+	request, err := server.ParseAuthorizeRequest(query)
+	require.NoError(t, err)
+
+	location, err := server.Authorize(request, testUser.ID)
+	require.NoError(t, err)
+
+	redirect, err := url.Parse(location)
+	require.NoError(t, err)
+
+	credentials := oauth.ClientCredentials{ID: client.ClientID, Secret: client.ClientSecret, Basic: true}
+
+	response, err := server.Exchange(codeForm(redirect.Query().Get("code")), credentials, "10.0.0.1")
+	require.NoError(t, err)
+
+	return response, credentials
+}
+
+// verifyIDToken checks the ID token the way a relying party does: with a key
+// of the published JWK set.
+func verifyIDToken(t *testing.T, server *oauth.Server, idToken string) jwt.MapClaims {
+	t.Helper()
+
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{ValidMethods: []string{oauth.AlgRS256}}
+
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		for _, jwk := range server.JWKS().Keys {
+			if jwk.Kid != token.Header["kid"] {
+				continue
+			}
+
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			require.NoError(t, err)
+
+			e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+			require.NoError(t, err)
+
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+		}
+
+		return nil, errormsg.ErrInvalidSigningKey
+	})
+	require.NoError(t, err)
+
+	return claims
+}
+
+func TestServer_Discovery(t *testing.T) {
+	t.Parallel()
+
+	metadata := newServer(oidcConfig()).Discovery()
+
+	assert.Equal(t, testIssuer, metadata.Issuer)
+	assert.Equal(t, testIssuer+"/oauth/authorize", metadata.AuthorizationEndpoint)
+	assert.Equal(t, testIssuer+"/oauth/token", metadata.TokenEndpoint)
+	assert.Equal(t, testIssuer+"/userinfo", metadata.UserInfoEndpoint)
+	assert.Equal(t, testIssuer+"/oauth/jwks", metadata.JWKSURI)
+	assert.Contains(t, metadata.ScopesSupported, oauth.ScopeOpenID)
+	assert.Equal(t, []string{"code"}, metadata.ResponseTypesSupported)
+	assert.Contains(t, metadata.SubjectTypesSupported, "public")
+	assert.Equal(t, []string{oauth.AlgRS256}, metadata.IDTokenSigningAlgValuesSupported)
+	assert.Subset(t, metadata.ClaimsSupported, []string{"sub", "email", "email_verified", "name", "nonce"})
+}
+
+func TestServer_IDToken(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(oidcConfig())
+	response, credentials := authorizeOIDC(t, server, "openid email profile", "n-0S6_WzA2Mj")
+
+	require.NotEmpty(t, response.IDToken)
+
+	claims := verifyIDToken(t, server, response.IDToken)
+
+	assert.Equal(t, testIssuer, claims["iss"])
+	assert.Equal(t, "5", claims["sub"], "sub must be a string")
+	assert.True(t, claims.VerifyAudience(credentials.ID, true))
+	assert.Equal(t, credentials.ID, claims["azp"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.Equal(t, "user@example.com", claims["email"])
+	assert.Equal(t, true, claims["email_verified"])
+	assert.Equal(t, "John Doe", claims["name"])
+	assert.Equal(t, "John", claims["given_name"])
+	assert.Equal(t, "Doe", claims["family_name"])
+
+	iat, _ := claims["iat"].(float64)
+	exp, _ := claims["exp"].(float64)
+	assert.WithinDuration(t, time.Now(), time.Unix(int64(iat), 0), time.Minute)
+	assert.Greater(t, exp, iat)
+
+	sum := sha256.Sum256([]byte(response.AccessToken))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:16]), claims["at_hash"])
+
+	info, err := server.UserInfo(testUser.ID, strings.Fields(response.Scope))
+	require.NoError(t, err)
+	assert.Equal(t, claims["sub"], info.Sub, "userinfo sub must match the ID token")
+	assert.Equal(t, "user@example.com", info.Email)
+
+	refreshed, err := server.Exchange(url.Values{
+		"grant_type":    {oauth.GrantRefreshToken},
+		"refresh_token": {response.RefreshToken},
+	}, credentials, "10.0.0.1")
+	require.NoError(t, err)
+
+	refreshedClaims := verifyIDToken(t, server, refreshed.IDToken)
+	assert.Equal(t, claims["sub"], refreshedClaims["sub"])
+	assert.NotContains(t, refreshedClaims, "nonce")
+}
+
+func TestServer_IDTokenClaimsByScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		scope     string
+		nonce     string
+		present   []string
+		absent    []string
+		noIDToken bool
+	}{
+		{
+			name:    "openid only",
+			scope:   "openid",
+			present: []string{"sub"},
+			absent:  []string{"email", "email_verified", "name", "nonce"},
+		},
+		{
+			name:    "email",
+			scope:   "openid email",
+			nonce:   "abc",
+			present: []string{"email", "email_verified", "nonce"},
+			absent:  []string{"name", "given_name"},
+		},
+		{
+			name:    "profile",
+			scope:   "openid profile",
+			present: []string{"name", "given_name", "family_name"},
+			absent:  []string{"email"},
+		},
+		{
+			name:      "without openid",
+			scope:     "email profile",
+			noIDToken: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newServer(oidcConfig())
+			response, _ := authorizeOIDC(t, server, tt.scope, tt.nonce)
+
+			if tt.noIDToken {
+				assert.Empty(t, response.IDToken)
+
+				return
+			}
+
+			claims := verifyIDToken(t, server, response.IDToken)
+
+			for _, name := range tt.present {
+				assert.Contains(t, claims, name)
+			}
+
+			for _, name := range tt.absent {
+				assert.NotContains(t, claims, name)
+			}
+		})
+	}
+}
+
+func TestServer_UserInfoRequiresOpenID(t *testing.T) {
+	t.Parallel()
+
+	server := newServer(oidcConfig())
+
+	_, err := server.UserInfo(testUser.ID, []string{oauth.ScopeEmail})
+	require.ErrorIs(t, err, errormsg.ErrInsufficientScope)
+
+	_, err = server.UserInfo(testUser.ID, nil)
+	require.ErrorIs(t, err, errormsg.ErrInsufficientScope)
+}
+
+func TestServer_OpenIDRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		cfg         oauth.Config
+		prompt      string
+		wantErr     error
+		interactive bool
+	}{
+		{
+			name:        "login allowed",
+			cfg:         oidcConfig(),
+			interactive: true,
+		},
+		{
+			name:   "prompt none",
+			cfg:    oidcConfig(),
+			prompt: "none",
+		},
+		{
+			name:    "prompt none with login",
+			cfg:     oidcConfig(),
+			prompt:  "none login",
+			wantErr: errormsg.ErrInvalidOAuthRequest,
+		},
+		{
+			name:    "OpenID Connect disabled",
+			cfg:     testConfig(),
+			wantErr: errormsg.ErrInvalidScope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newServer(tt.cfg)
+
+			client, err := server.RegisterClient(calltypes.OAuthClientRequest{
+				Name:         "grafana",
+				RedirectURIs: []string{testRedirectURI},
+				Scopes:       []string{oauth.ScopeOpenID},
+				FirstParty:   true,
+			})
+			require.NoError(t, err)
+
+			query := authorizeQuery(client.ClientID)
+			query.Set("scope", oauth.ScopeOpenID)
+			query.Set("prompt", tt.prompt)
+
+			request, err := server.ParseAuthorizeRequest(query)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.NotNil(t, request, "the error must be redirected to the client")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.interactive, request.Interactive())
+
+			if !tt.interactive {
+				redirect, err := url.Parse(server.ErrorRedirect(request, errormsg.ErrLoginRequired))
+				require.NoError(t, err)
+				assert.Equal(t, oauth.ErrorLoginRequired, redirect.Query().Get("error"))
+			}
+		})
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	t.Parallel()
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(providerKey())
+	require.NoError(t, err)
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name: "PKCS #1",
+			data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(providerKey())}),
+		},
+		{
+			name: "PKCS #8",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:    "short key",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)}),
+			wantErr: true,
+		},
+		{
+			name:    "not PEM",
+			data:    []byte("secret"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := oauth.ParseSigningKey(tt.data)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errormsg.ErrInvalidSigningKey)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, key.Equal(providerKey()))
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package password
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto/sha512"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	cryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	roundsPrefix       = "rounds="
+	defaultCryptRounds = 5000
+	minCryptRounds     = 1000
+	// maxCryptRounds is far below the 999999999 of the specification: more
+	// rounds would make every login attempt for the user burn CPU.
+	maxCryptRounds      = 1_000_000
+	maxCryptSaltLength  = 16
+	sha512CryptChecksum = 86
+)
+
+// sha512CryptOrder is the byte permutation used when encoding the final digest.
+var sha512CryptOrder = [...][3]int{ //nolint: gochecknoglobals
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+type sha512CryptParams struct {
+	rounds       int
+	customRounds bool
+	salt         []byte
+}
+
+// parseSHA512Crypt accepts the $6$[rounds=N$]salt$hash format produced by glibc
+// and PHP crypt().
+func parseSHA512Crypt(encoded string) (*sha512CryptParams, error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, sha512Prefix), "$")
+	params := &sha512CryptParams{rounds: defaultCryptRounds}
+
+	if len(parts) > 0 && strings.HasPrefix(parts[0], roundsPrefix) {
+		rounds, err := strconv.Atoi(strings.TrimPrefix(parts[0], roundsPrefix))
+		if err != nil {
+			return nil, errormsg.ErrMalformedHash
+		}
+
+		if rounds > maxCryptRounds {
+			return nil, fmt.Errorf("%w: more than %d rounds", errormsg.ErrHashTooCostly, maxCryptRounds)
+		}
+
+		params.rounds = max(rounds, minCryptRounds)
+		params.customRounds = true
+		parts = parts[1:]
+	}
+
+	if len(parts) != 2 || len(parts[0]) > maxCryptSaltLength || len(parts[1]) != sha512CryptChecksum {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	params.salt = []byte(parts[0])
+
+	return params, nil
+}
+
+// sha512Crypt implements the SHA-512 based crypt scheme by Ulrich Drepper and
+// returns the full encoded hash.
+func sha512Crypt(key []byte, params *sha512CryptParams) string {
+	salt := params.salt
+
+	alternate := sha512.New()
+	alternate.Write(key)
+	alternate.Write(salt)
+	alternate.Write(key)
+	alternateSum := alternate.Sum(nil)
+
+	digest := sha512.New()
+	digest.Write(key)
+	digest.Write(salt)
+
+	for i := len(key); i > 0; i -= sha512.Size {
+		digest.Write(alternateSum[:min(i, sha512.Size)])
+	}
+
+	for i := len(key); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			digest.Write(alternateSum)
+		} else {
+			digest.Write(key)
+		}
+	}
+
+	sum := digest.Sum(nil)
+
+	keyDigest := sha512.New()
+	for range key {
+		keyDigest.Write(key)
+	}
+
+	keySequence := repeatBytes(keyDigest.Sum(nil), len(key))
+
+	saltDigest := sha512.New()
+	for range 16 + int(sum[0]) {
+		saltDigest.Write(salt)
+	}
+
+	saltSequence := repeatBytes(saltDigest.Sum(nil), len(salt))
+
+	for round := range params.rounds {
+		step := sha512.New()
+
+		if round&1 != 0 {
+			step.Write(keySequence)
+		} else {
+			step.Write(sum)
+		}
+
+		if round%3 != 0 {
+			step.Write(saltSequence)
+		}
+
+		if round%7 != 0 {
+			step.Write(keySequence)
+		}
+
+		if round&1 != 0 {
+			step.Write(sum)
+		} else {
+			step.Write(keySequence)
+		}
+
+		sum = step.Sum(nil)
+	}
+
+	var out strings.Builder
+
+	out.WriteString(sha512Prefix)
+
+	if params.customRounds {
+		out.WriteString(roundsPrefix + strconv.Itoa(params.rounds) + "$")
+	}
+
+	out.Write(salt)
+	out.WriteByte('$')
+
+	for _, group := range sha512CryptOrder {
+		encodeCrypt64(&out, uint(sum[group[0]])<<16|uint(sum[group[1]])<<8|uint(sum[group[2]]), 4) //nolint: mnd
+	}
+
+	encodeCrypt64(&out, uint(sum[63]), 2) //nolint: mnd
+
+	return out.String()
+}
+
+func repeatBytes(source []byte, length int) []byte {
+	out := make([]byte, 0, length)
+	for len(out) < length {
+		out = append(out, source[:min(len(source), length-len(out))]...)
+	}
+
+	return out
+}
+
+func encodeCrypt64(out *strings.Builder, value uint, chars int) {
+	for range chars {
+		out.WriteByte(cryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+}
@@ -0,0 +1,259 @@
+// Package password hashes and verifies user passwords. Besides the bcrypt hashes
+// produced by this service it understands the formats used by the legacy systems
+// we import users from, so those users can log in and get rehashed transparently.
+package password
+
+import (
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Algorithm identifies a supported password hash format.
+type Algorithm string
+
+const (
+	Bcrypt       Algorithm = "bcrypt"
+	PBKDF2SHA256 Algorithm = "pbkdf2-sha256"
+	Scrypt       Algorithm = "scrypt"
+	SHA512Crypt  Algorithm = "sha512-crypt"
+)
+
+const (
+	pbkdf2Prefix  = "$pbkdf2-sha256$"
+	scryptPrefix  = "$scrypt$"
+	sha512Prefix  = "$6$"
+	phcIterations = "i="
+)
+
+// Imported hashes are verified on every login attempt for their user, so a
+// crafted hash could burn CPU or memory on each one. Costs above these limits,
+// well beyond the defaults of the legacy systems, are rejected.
+const (
+	maxPBKDF2Iterations = 2_000_000
+	// maxScryptMemory caps 128 * r * N bytes; passlib defaults use 64 MiB.
+	maxScryptMemory = 64 << 20
+	maxScryptP      = 4
+	scryptBlockSize = 128
+)
+
+// Hash hashes plain text with the current algorithm.
+func Hash(plainText string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plainText), consts.BcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hashed), nil
+}
+
+// Identify reports the algorithm of an encoded hash and validates its format.
+func Identify(encoded string) (Algorithm, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if _, err := bcrypt.Cost([]byte(encoded)); err != nil {
+			return "", errormsg.ErrMalformedHash
+		}
+
+		return Bcrypt, nil
+	case strings.HasPrefix(encoded, pbkdf2Prefix):
+		if _, err := parsePBKDF2(encoded); err != nil {
+			return "", err
+		}
+
+		return PBKDF2SHA256, nil
+	case strings.HasPrefix(encoded, scryptPrefix):
+		if _, err := parseScrypt(encoded); err != nil {
+			return "", err
+		}
+
+		return Scrypt, nil
+	case strings.HasPrefix(encoded, sha512Prefix):
+		if _, err := parseSHA512Crypt(encoded); err != nil {
+			return "", err
+		}
+
+		return SHA512Crypt, nil
+	default:
+		return "", errormsg.ErrUnsupportedHash
+	}
+}
+
+// Verify compares plain text with an encoded hash of any supported format.
+func Verify(plainText, encoded string) (bool, error) {
+	algorithm, err := Identify(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	switch algorithm {
+	case Bcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plainText))
+		if err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("failed to compare passwords: %w", err)
+		}
+
+		return true, nil
+	case PBKDF2SHA256:
+		params, _ := parsePBKDF2(encoded)
+		derived := pbkdf2.Key([]byte(plainText), params.salt, params.iterations, len(params.hash), sha256.New)
+
+		return subtle.ConstantTimeCompare(derived, params.hash) == 1, nil
+	case Scrypt:
+		params, _ := parseScrypt(encoded)
+
+		derived, err := scrypt.Key([]byte(plainText), params.salt, 1<<params.logN, params.r, params.p, len(params.hash))
+		if err != nil {
+			return false, fmt.Errorf("failed to derive scrypt key: %w", err)
+		}
+
+		return subtle.ConstantTimeCompare(derived, params.hash) == 1, nil
+	case SHA512Crypt:
+		params, _ := parseSHA512Crypt(encoded)
+		computed := sha512Crypt([]byte(plainText), params)
+
+		return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+	}
+
+	return false, errormsg.ErrUnsupportedHash
+}
+
+// NeedsRehash reports whether an encoded hash should be replaced with one
+// produced by Hash, either because it uses a foreign algorithm or a lower cost.
+func NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+
+	return cost < consts.BcryptCost
+}
+
+type pbkdf2Params struct {
+	iterations int
+	salt       []byte
+	hash       []byte
+}
+
+// parsePBKDF2 accepts both the PHC form ($pbkdf2-sha256$i=N$salt$hash) and the
+// passlib form ($pbkdf2-sha256$N$salt$hash, adapted base64).
+func parsePBKDF2(encoded string) (*pbkdf2Params, error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, pbkdf2Prefix), "$")
+	if len(parts) != 3 { //nolint: mnd
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[0], phcIterations))
+	if err != nil || iterations <= 0 {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	if iterations > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("%w: more than %d iterations", errormsg.ErrHashTooCostly, maxPBKDF2Iterations)
+	}
+
+	salt, err := decodeBase64(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := decodeBase64(parts[2])
+	if err != nil || len(hash) == 0 {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	return &pbkdf2Params{iterations: iterations, salt: salt, hash: hash}, nil
+}
+
+type scryptParams struct {
+	logN int
+	r    int
+	p    int
+	salt []byte
+	hash []byte
+}
+
+// parseScrypt accepts $scrypt$ln=N,r=R,p=P$salt$hash.
+func parseScrypt(encoded string) (*scryptParams, error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, scryptPrefix), "$")
+	if len(parts) != 3 { //nolint: mnd
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	params := &scryptParams{}
+
+	for _, field := range strings.Split(parts[0], ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, errormsg.ErrMalformedHash
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil || number <= 0 {
+			return nil, errormsg.ErrMalformedHash
+		}
+
+		switch key {
+		case "ln":
+			params.logN = number
+		case "r":
+			params.r = number
+		case "p":
+			params.p = number
+		default:
+			return nil, errormsg.ErrMalformedHash
+		}
+	}
+
+	if params.logN == 0 || params.r == 0 || params.p == 0 {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	if params.logN > 30 || int64(scryptBlockSize)*int64(params.r)<<params.logN > maxScryptMemory {
+		return nil, fmt.Errorf("%w: more than %d bytes of memory", errormsg.ErrHashTooCostly, maxScryptMemory)
+	}
+
+	if params.p > maxScryptP {
+		return nil, fmt.Errorf("%w: parallelism above %d", errormsg.ErrHashTooCostly, maxScryptP)
+	}
+
+	salt, err := decodeBase64(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := decodeBase64(parts[2])
+	if err != nil || len(hash) == 0 {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	params.salt = salt
+	params.hash = hash
+
+	return params, nil
+}
+
+// decodeBase64 decodes unpadded standard base64, also accepting the "." used
+// instead of "+" by passlib.
+func decodeBase64(value string) ([]byte, error) {
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.ReplaceAll(strings.TrimRight(value, "="), ".", "+"))
+	if err != nil {
+		return nil, errormsg.ErrMalformedHash
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,139 @@
+package password_test
+
+import (
+	"auth-service/internal/password"
+	"auth-service/pkg/errormsg"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const plainText = "correct horse"
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		encoded   string
+		algorithm password.Algorithm
+	}{
+		{
+			name:      "pbkdf2 phc",
+			encoded:   "$pbkdf2-sha256$i=10000$bWVkb2RzLXNhbHQtMDAwMQ$Db3bfMxhf9gxQ8xKJ3nGVI3s8wKBTyXg4cBISUxyMU4",
+			algorithm: password.PBKDF2SHA256,
+		},
+		{
+			name:      "pbkdf2 passlib",
+			encoded:   "$pbkdf2-sha256$10000$bWVkb2RzLXNhbHQtMDAwMQ$Db3bfMxhf9gxQ8xKJ3nGVI3s8wKBTyXg4cBISUxyMU4",
+			algorithm: password.PBKDF2SHA256,
+		},
+		{
+			name:      "scrypt",
+			encoded:   "$scrypt$ln=10,r=8,p=1$bWVkb2RzLXNhbHQtMDAwMQ$BdJNiJV9fNfCtIORjO87g0pkxpTLfb8YC1Tebuk1e08",
+			algorithm: password.Scrypt,
+		},
+		{
+			name: "sha512 crypt",
+			encoded: "$6$rounds=5000$phpsalt$tYLoClk5g0xPQRkHT8zMRCuNi/tXbkJiqFRcKvjWqvyiI." +
+				"Lj3VBnwYgjaNStqrxBXq10qpMNPiKZRezcTFK400",
+			algorithm: password.SHA512Crypt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			algorithm, err := password.Identify(tt.encoded)
+			require.NoError(t, err)
+			assert.Equal(t, tt.algorithm, algorithm)
+
+			ok, err := password.Verify(plainText, tt.encoded)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = password.Verify("wrong horse", tt.encoded)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.True(t, password.NeedsRehash(tt.encoded))
+		})
+	}
+}
+
+func TestVerifySHA512CryptDefaultRounds(t *testing.T) {
+	t.Parallel()
+
+	// Reference vector from the SHA-crypt specification.
+	encoded := "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"
+
+	ok, err := password.Verify("Hello world!", encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	encoded, err := password.Hash(plainText)
+	require.NoError(t, err)
+
+	ok, err := password.Verify(plainText, encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, password.NeedsRehash(encoded))
+
+	weak, err := bcrypt.GenerateFromPassword([]byte(plainText), bcrypt.MinCost)
+	require.NoError(t, err)
+	assert.True(t, password.NeedsRehash(string(weak)))
+}
+
+func TestIdentifyRejectsUnknownFormats(t *testing.T) {
+	t.Parallel()
+
+	_, err := password.Identify("5f4dcc3b5aa765d61d8327deb882cf99")
+	require.ErrorIs(t, err, errormsg.ErrUnsupportedHash)
+
+	_, err = password.Identify("$scrypt$ln=10,r=8$c2FsdA$aGFzaA")
+	require.ErrorIs(t, err, errormsg.ErrMalformedHash)
+
+	_, err = password.Identify("$pbkdf2-sha256$i=abc$c2FsdA$aGFzaA")
+	require.ErrorIs(t, err, errormsg.ErrMalformedHash)
+}
+
+func TestIdentifyRejectsCostlyParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "pbkdf2 iterations", encoded: "$pbkdf2-sha256$i=2000001$c2FsdA$aGFzaA"},
+		{name: "pbkdf2 passlib iterations", encoded: "$pbkdf2-sha256$1000000000$c2FsdA$aGFzaA"},
+		{name: "scrypt cost", encoded: "$scrypt$ln=30,r=8,p=1$c2FsdA$aGFzaA"},
+		{name: "scrypt block size", encoded: "$scrypt$ln=10,r=1000000,p=1$c2FsdA$aGFzaA"},
+		{name: "scrypt memory", encoded: "$scrypt$ln=17,r=8,p=1$c2FsdA$aGFzaA"},
+		{name: "scrypt parallelism", encoded: "$scrypt$ln=10,r=8,p=1000$c2FsdA$aGFzaA"},
+		{name: "sha512 crypt rounds", encoded: "$6$rounds=999999999$phpsalt$" + strings.Repeat("a", 86)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := password.Identify(tt.encoded)
+			require.ErrorIs(t, err, errormsg.ErrHashTooCostly)
+
+			_, err = password.Verify(plainText, tt.encoded)
+			require.ErrorIs(t, err, errormsg.ErrHashTooCostly)
+		})
+	}
+
+	// The passlib default of scrypt stays within the limits.
+	_, err := password.Identify("$scrypt$ln=16,r=8,p=1$c2FsdA$aGFzaA")
+	require.NoError(t, err)
+}
@@ -0,0 +1,122 @@
+// Package passwordreset lets users set a new password through a single-use
+// link sent to their email. Administrators trigger the reset, e.g. for a user
+// who is locked out; only a hash of the link token is stored.
+package passwordreset
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/notify"
+	"auth-service/internal/password"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const tokenLength = 32
+
+// Config holds password reset settings.
+type Config struct {
+	// URL is the page where users enter the new password. The token is added
+	// as the token query parameter.
+	URL string
+	// TTL is how long a reset link is valid.
+	TTL time.Duration
+}
+
+// Manager sends password reset links and completes resets.
+type Manager struct {
+	repo   repository.PasswordResetRepository
+	mailer notify.Mailer
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewManager(repo repository.PasswordResetRepository, mailer notify.Mailer, cfg Config) *Manager {
+	return &Manager{
+		repo:   repo,
+		mailer: mailer,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Start sends a password reset link to the user and returns when it expires.
+func (m *Manager) Start(user *calltypes.User) (time.Time, error) {
+	link, err := url.Parse(m.cfg.URL)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid password reset URL: %w", err)
+	}
+
+	raw := make([]byte, tokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return time.Time{}, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := m.now().Add(m.cfg.TTL)
+
+	reset := calltypes.PasswordReset{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := m.repo.CreatePasswordReset(reset); err != nil {
+		return time.Time{}, err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf("Open this link to set a new password: %s\nIt expires in %s. "+
+		"If you did not ask for it, contact support.", link, m.cfg.TTL)
+
+	if err := m.mailer.Send(user.Email, "Reset your password", body); err != nil {
+		return time.Time{}, fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	return expiresAt, nil
+}
+
+// Complete sets the new password with the token of a reset link and returns ID
+// of the user. Each link is used once, and other pending links of the user are
+// dropped.
+func (m *Manager) Complete(token, newPassword string) (int, error) {
+	if len(newPassword) < consts.PassMinLength {
+		return 0, errormsg.ErrPasswordLength
+	}
+
+	reset, err := m.repo.TakePasswordReset(hashToken(token))
+	if err != nil {
+		return 0, err
+	}
+
+	if !m.now().Before(reset.ExpiresAt) {
+		return 0, errormsg.ErrInvalidPasswordReset
+	}
+
+	hashedPassword, err := password.Hash(newPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.repo.SetPassword(reset.UserID, hashedPassword); err != nil {
+		return 0, err
+	}
+
+	return reset.UserID, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
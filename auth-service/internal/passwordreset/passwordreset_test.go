@@ -0,0 +1,184 @@
+package passwordreset_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/password"
+	"auth-service/internal/passwordreset"
+	"auth-service/pkg/errormsg"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps resets and password hashes in memory.
+type memoryRepository struct {
+	mu        sync.Mutex
+	resets    map[string]calltypes.PasswordReset
+	passwords map[int]string
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		resets:    make(map[string]calltypes.PasswordReset),
+		passwords: make(map[int]string),
+	}
+}
+
+func (m *memoryRepository) CreatePasswordReset(reset calltypes.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resets[reset.TokenHash] = reset
+
+	return nil
+}
+
+func (m *memoryRepository) TakePasswordReset(tokenHash string) (*calltypes.PasswordReset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reset, ok := m.resets[tokenHash]
+	if !ok {
+		return nil, errormsg.ErrInvalidPasswordReset
+	}
+
+	delete(m.resets, tokenHash)
+
+	return &reset, nil
+}
+
+func (m *memoryRepository) SetPassword(userID int, hashedPassword string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.passwords[userID] = hashedPassword
+
+	for hash, reset := range m.resets {
+		if reset.UserID == userID {
+			delete(m.resets, hash)
+		}
+	}
+
+	return nil
+}
+
+// inbox remembers the last message sent.
+type inbox struct {
+	to, body string
+}
+
+func (i *inbox) Send(to, _, body string) error {
+	i.to, i.body = to, body
+
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+func testConfig() passwordreset.Config {
+	return passwordreset.Config{
+		URL: "https://example.com/password/reset",
+		TTL: time.Hour,
+	}
+}
+
+func testUser() *calltypes.User {
+	return &calltypes.User{ID: 4, Email: "user@example.com"}
+}
+
+// linkToken returns the token of the last link sent to mail.
+func linkToken(t *testing.T, mail *inbox) string {
+	t.Helper()
+
+	link, err := url.Parse(linkPattern.FindString(mail.body))
+	require.NoError(t, err)
+
+	return link.Query().Get("token")
+}
+
+func TestManager_Reset(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	mail := &inbox{}
+	manager := passwordreset.NewManager(repo, mail, testConfig())
+
+	expiresAt, err := manager.Start(testUser())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+	assert.Equal(t, "user@example.com", mail.to)
+
+	token := linkToken(t, mail)
+	require.NotEmpty(t, token)
+
+	userID, err := manager.Complete(token, "new-password")
+	require.NoError(t, err)
+	assert.Equal(t, 4, userID)
+
+	matches, err := password.Verify("new-password", repo.passwords[4])
+	require.NoError(t, err)
+	assert.True(t, matches)
+
+	_, err = manager.Complete(token, "other-password")
+	require.ErrorIs(t, err, errormsg.ErrInvalidPasswordReset, "link must be single-use")
+}
+
+func TestManager_Complete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		token    string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "expired link",
+			ttl:      -time.Minute,
+			password: "new-password",
+			wantErr:  errormsg.ErrInvalidPasswordReset,
+		},
+		{
+			name:     "unknown token",
+			ttl:      time.Hour,
+			token:    "unknown",
+			password: "new-password",
+			wantErr:  errormsg.ErrInvalidPasswordReset,
+		},
+		{
+			name:     "short password",
+			ttl:      time.Hour,
+			password: "short",
+			wantErr:  errormsg.ErrPasswordLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testConfig()
+			cfg.TTL = tt.ttl
+
+			mail := &inbox{}
+			manager := passwordreset.NewManager(newMemoryRepository(), mail, cfg)
+
+			_, err := manager.Start(testUser())
+			require.NoError(t, err)
+
+			token := tt.token
+			if token == "" {
+				token = linkToken(t, mail)
+			}
+
+			_, err = manager.Complete(token, tt.password)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
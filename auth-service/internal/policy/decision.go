@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of an evaluation, as written to the decision log.
+type Decision struct {
+	Time   time.Time `json:"time"`
+	Effect string    `json:"decision"`
+	// Rules lists the rules that made the decision.
+	Rules []string `json:"rules,omitempty"`
+	// Error tells why the input could not be built; the request is denied.
+	Error string `json:"error,omitempty"`
+	// Errors lists rules that failed to evaluate.
+	Errors   []string `json:"errors,omitempty"`
+	Revision string   `json:"revision"`
+	Input    Input    `json:"input"`
+}
+
+// Allowed reports whether the policy allows the request.
+func (d Decision) Allowed() bool {
+	return d.Effect == EffectAllow
+}
+
+// Denied reports whether the policy denies the request.
+func (d Decision) Denied() bool {
+	return d.Effect == EffectDeny
+}
+
+// decisionLog writes decisions as JSON lines.
+type decisionLog struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newDecisionLog(w io.Writer) *decisionLog {
+	return &decisionLog{encoder: json.NewEncoder(w)}
+}
+
+func (l *decisionLog) write(decision Decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.encoder.Encode(decision); err != nil {
+		log.Println("failed to write policy decision: ", err)
+	}
+}
@@ -0,0 +1,389 @@
+// Package policy is an embedded authorization engine for rules route-level
+// permissions cannot express, such as "clinic admins manage users of their own
+// clinic". Rules are CEL expressions read from YAML files in a directory and
+// reloaded when the files change. Each expression gets the input document
+// with the token claims, the route and the target resource, e.g.
+//
+//	rules:
+//	  - name: clinic-admins-read-clinic-users
+//	    effect: allow
+//	    methods: [GET]
+//	    routes: ["/users/{id}/status"]
+//	    condition: >
+//	      "clinic_admin" in input.claims.roles &&
+//	      input.resource.clinic_id == input.claims.clinic_id
+//
+// A matching deny rule denies the request, otherwise a matching allow rule
+// allows it. If rules cover the route but none matches, the request is denied;
+// routes without rules are left to the route-level checks. Every decision on a
+// covered route is written to the decision log.
+package policy
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+)
+
+// Effects of rules and decisions.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+	// EffectAbstain means that no rule covers the route.
+	EffectAbstain = "abstain"
+)
+
+// Config holds policy engine settings.
+type Config struct {
+	// Dir holds *.yaml policy files. The engine is disabled without it.
+	Dir string
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration
+	// DecisionLog is the file decisions are appended to, stdout when empty.
+	DecisionLog string
+}
+
+// Route describes the requested route.
+type Route struct {
+	Method  string            `json:"method"`
+	Pattern string            `json:"pattern"`
+	Path    string            `json:"path"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+// Input is the document rules are evaluated against.
+type Input struct {
+	Claims   map[string]interface{} `json:"claims"`
+	Route    Route                  `json:"route"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
+
+// ResourceFunc loads the target resource of the request. It is called only for
+// routes covered by rules.
+type ResourceFunc func() (map[string]interface{}, error)
+
+// file is the format of a policy file.
+type file struct {
+	Rules []struct {
+		Name      string   `yaml:"name"`
+		Effect    string   `yaml:"effect"`
+		Methods   []string `yaml:"methods"`
+		Routes    []string `yaml:"routes"`
+		Condition string   `yaml:"condition"`
+	} `yaml:"rules"`
+}
+
+type rule struct {
+	name    string
+	effect  string
+	methods []string
+	routes  []string
+	program cel.Program
+}
+
+// Engine evaluates requests against the policy files.
+type Engine struct {
+	cfg       Config
+	env       *cel.Env
+	decisions *decisionLog
+	now       func() time.Time
+
+	mu        sync.RWMutex
+	rules     []rule
+	revision  string
+	lastCheck time.Time
+}
+
+// NewEngine loads the policy files. Decisions are written to decisions.
+func NewEngine(cfg Config, decisions io.Writer) (*Engine, error) {
+	env, err := cel.NewEnv(cel.Variable("input", cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	e := &Engine{
+		cfg:       cfg,
+		env:       env,
+		decisions: newDecisionLog(decisions),
+		now:       time.Now,
+	}
+
+	if err := e.load(); err != nil {
+		return nil, err
+	}
+
+	e.lastCheck = e.now()
+
+	return e, nil
+}
+
+// Revision identifies the loaded policy files.
+func (e *Engine) Revision() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.revision
+}
+
+// Evaluate decides on the request and logs the decision. Rules that fail to
+// evaluate, e.g. on a missing claim, do not match.
+func (e *Engine) Evaluate(input Input, resource ResourceFunc) Decision {
+	e.reload()
+
+	e.mu.RLock()
+	rules, revision := e.rules, e.revision
+	e.mu.RUnlock()
+
+	decision := Decision{Effect: EffectAbstain, Revision: revision}
+
+	var covering []rule
+
+	for _, r := range rules {
+		if r.covers(input.Route) {
+			covering = append(covering, r)
+		}
+	}
+
+	if len(covering) == 0 {
+		return decision
+	}
+
+	decision.Time = e.now()
+	decision.Effect = EffectDeny
+
+	var err error
+	if input.Resource, err = resource(); err != nil {
+		decision.Error = err.Error()
+		decision.Input = input
+		e.decisions.write(decision)
+
+		return decision
+	}
+
+	decision.Input = input
+	document := map[string]interface{}{"input": input.document()}
+
+	var allowedBy []string
+
+	for _, r := range covering {
+		matched, err := r.matches(document)
+		if err != nil {
+			decision.Errors = append(decision.Errors, r.name+": "+err.Error())
+
+			continue
+		}
+
+		if !matched {
+			continue
+		}
+
+		if r.effect == EffectDeny {
+			decision.Rules = []string{r.name}
+			e.decisions.write(decision)
+
+			return decision
+		}
+
+		allowedBy = append(allowedBy, r.name)
+	}
+
+	if len(allowedBy) > 0 {
+		decision.Effect = EffectAllow
+		decision.Rules = allowedBy
+	}
+
+	e.decisions.write(decision)
+
+	return decision
+}
+
+// reload loads the policy files again if they changed. Invalid files are
+// reported and the previous rules stay in effect.
+func (e *Engine) reload() {
+	e.mu.Lock()
+
+	if e.now().Sub(e.lastCheck) < e.cfg.ReloadInterval {
+		e.mu.Unlock()
+
+		return
+	}
+
+	e.lastCheck = e.now()
+	e.mu.Unlock()
+
+	if err := e.load(); err != nil {
+		log.Println("failed to reload policies, keeping the previous ones: ", err)
+	}
+}
+
+// load compiles the policy files unless they are unchanged.
+func (e *Engine) load() error {
+	paths, err := filepath.Glob(filepath.Join(e.cfg.Dir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errormsg.ErrInvalidPolicy, err)
+	}
+
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	contents := make([][]byte, len(paths))
+
+	for i, path := range paths {
+		if contents[i], err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read policy file: %w", err)
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.Base(path), len(contents[i]))
+		hash.Write(contents[i])
+	}
+
+	revision := hex.EncodeToString(hash.Sum(nil))[:16]
+
+	e.mu.RLock()
+	unchanged := revision == e.revision
+	e.mu.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	var rules []rule
+
+	for i, path := range paths {
+		compiled, err := e.compile(contents[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+
+		rules = append(rules, compiled...)
+	}
+
+	e.mu.Lock()
+	e.rules, e.revision = rules, revision
+	e.mu.Unlock()
+
+	log.Printf("loaded %d policy rules from %d files, revision %s", len(rules), len(paths), revision)
+
+	return nil
+}
+
+func (e *Engine) compile(data []byte) ([]rule, error) {
+	var f file
+
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %w", errormsg.ErrInvalidPolicy, err)
+	}
+
+	rules := make([]rule, 0, len(f.Rules))
+
+	for _, r := range f.Rules {
+		switch {
+		case r.Name == "":
+			return nil, fmt.Errorf("%w: rule without a name", errormsg.ErrInvalidPolicy)
+		case r.Effect != EffectAllow && r.Effect != EffectDeny:
+			return nil, fmt.Errorf("%w: rule %s: effect must be allow or deny", errormsg.ErrInvalidPolicy, r.Name)
+		case len(r.Routes) == 0:
+			return nil, fmt.Errorf("%w: rule %s: no routes", errormsg.ErrInvalidPolicy, r.Name)
+		}
+
+		ast, issues := e.env.Compile(r.Condition)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("%w: rule %s: %w", errormsg.ErrInvalidPolicy, r.Name, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("%w: rule %s: condition must be a boolean", errormsg.ErrInvalidPolicy, r.Name)
+		}
+
+		program, err := e.env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %s: %w", errormsg.ErrInvalidPolicy, r.Name, err)
+		}
+
+		methods := make([]string, 0, len(r.Methods))
+		for _, method := range r.Methods {
+			methods = append(methods, strings.ToUpper(method))
+		}
+
+		rules = append(rules, rule{
+			name:    r.Name,
+			effect:  r.Effect,
+			methods: methods,
+			routes:  r.Routes,
+			program: program,
+		})
+	}
+
+	return rules, nil
+}
+
+// covers reports whether the rule applies to the route. A route ending with
+// "*" covers every route with that prefix.
+func (r rule) covers(route Route) bool {
+	if len(r.methods) > 0 && !slices.Contains(r.methods, route.Method) {
+		return false
+	}
+
+	return slices.ContainsFunc(r.routes, func(pattern string) bool {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			return strings.HasPrefix(route.Pattern, prefix)
+		}
+
+		return pattern == route.Pattern
+	})
+}
+
+func (r rule) matches(document map[string]interface{}) (bool, error) {
+	out, _, err := r.program.Eval(document)
+	if err != nil {
+		return false, err //nolint: wrapcheck
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition returned %v instead of a boolean", out.Type())
+	}
+
+	return matched, nil
+}
+
+// document returns the input as CEL sees it.
+func (i Input) document() map[string]interface{} {
+	claims, resource, params := i.Claims, i.Resource, i.Route.Params
+
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+
+	if resource == nil {
+		resource = map[string]interface{}{}
+	}
+
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"claims": claims,
+		"route": map[string]interface{}{
+			"method":  i.Route.Method,
+			"pattern": i.Route.Pattern,
+			"path":    i.Route.Path,
+			"params":  params,
+		},
+		"resource": resource,
+	}
+}
@@ -0,0 +1,193 @@
+package policy_test
+
+import (
+	"auth-service/internal/policy"
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const clinicPolicy = `
+rules:
+  - name: clinic-admins-read-clinic-users
+    effect: allow
+    methods: [GET]
+    routes: ["/users/{id}/status"]
+    condition: >
+      "clinic_admin" in input.claims.roles &&
+      input.resource.clinic_id == input.claims.clinic_id
+  - name: no-reading-blocked-users
+    effect: deny
+    routes: [/users/*]
+    condition: input.resource.status == "suspended"
+`
+
+func writePolicy(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func newEngine(t *testing.T, content string) (*policy.Engine, *bytes.Buffer, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writePolicy(t, dir, "clinic.yaml", content)
+
+	var decisions bytes.Buffer
+
+	engine, err := policy.NewEngine(policy.Config{Dir: dir}, &decisions)
+	require.NoError(t, err)
+
+	return engine, &decisions, dir
+}
+
+func clinicAdmin(pattern string) policy.Input {
+	return policy.Input{
+		Claims: map[string]interface{}{"sub": float64(1), "roles": []interface{}{"clinic_admin"}, "clinic_id": "north"},
+		Route:  policy.Route{Method: "GET", Pattern: pattern, Path: "/users/7/status", Params: map[string]string{"id": "7"}},
+	}
+}
+
+func resource(fields map[string]interface{}) policy.ResourceFunc {
+	return func() (map[string]interface{}, error) {
+		return fields, nil
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	engine, _, _ := newEngine(t, clinicPolicy)
+
+	testCases := []struct {
+		name     string
+		input    policy.Input
+		resource map[string]interface{}
+		effect   string
+		rules    []string
+	}{
+		{
+			name:     "user of the clinic",
+			input:    clinicAdmin("/users/{id}/status"),
+			resource: map[string]interface{}{"id": 7, "clinic_id": "north", "status": "active"},
+			effect:   policy.EffectAllow,
+			rules:    []string{"clinic-admins-read-clinic-users"},
+		},
+		{
+			name:     "user of another clinic",
+			input:    clinicAdmin("/users/{id}/status"),
+			resource: map[string]interface{}{"id": 7, "clinic_id": "south", "status": "active"},
+			effect:   policy.EffectDeny,
+		},
+		{
+			name:     "deny wins",
+			input:    clinicAdmin("/users/{id}/status"),
+			resource: map[string]interface{}{"id": 7, "clinic_id": "north", "status": "suspended"},
+			effect:   policy.EffectDeny,
+			rules:    []string{"no-reading-blocked-users"},
+		},
+		{
+			name:     "resource without the field",
+			input:    clinicAdmin("/users/{id}/status"),
+			resource: map[string]interface{}{"id": 7},
+			effect:   policy.EffectDeny,
+		},
+		{
+			name:   "route without rules",
+			input:  clinicAdmin("/apikeys"),
+			effect: policy.EffectAbstain,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			decision := engine.Evaluate(tc.input, resource(tc.resource))
+			assert.Equal(t, tc.effect, decision.Effect)
+			assert.Equal(t, tc.rules, decision.Rules)
+			assert.Equal(t, engine.Revision(), decision.Revision)
+		})
+	}
+}
+
+func TestDecisionLog(t *testing.T) {
+	t.Parallel()
+
+	engine, decisions, _ := newEngine(t, clinicPolicy)
+
+	engine.Evaluate(clinicAdmin("/apikeys"), resource(nil))
+	assert.Empty(t, decisions.String(), "decisions on routes without rules are not logged")
+
+	engine.Evaluate(clinicAdmin("/users/{id}/status"), resource(map[string]interface{}{"clinic_id": "north", "status": "active"}))
+
+	var logged policy.Decision
+
+	require.NoError(t, json.Unmarshal(decisions.Bytes(), &logged))
+	assert.Equal(t, policy.EffectAllow, logged.Effect)
+	assert.Equal(t, "/users/7/status", logged.Input.Route.Path)
+	assert.Equal(t, "north", logged.Input.Resource["clinic_id"])
+	assert.NotZero(t, logged.Time)
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	engine, _, dir := newEngine(t, clinicPolicy)
+	revision := engine.Revision()
+
+	input := clinicAdmin("/users/{id}/status")
+	user := resource(map[string]interface{}{"clinic_id": "south", "status": "active"})
+
+	require.True(t, engine.Evaluate(input, user).Denied())
+
+	writePolicy(t, dir, "clinic.yaml", `
+rules:
+  - name: clinic-admins-read-all-users
+    effect: allow
+    routes: ["/users/{id}/status"]
+    condition: '"clinic_admin" in input.claims.roles'
+`)
+
+	require.True(t, engine.Evaluate(input, user).Allowed())
+	assert.NotEqual(t, revision, engine.Revision())
+
+	// Broken files leave the previous rules in effect.
+	writePolicy(t, dir, "broken.yaml", "rules:\n  - name: broken\n    effect: allow\n    routes: [/users/me]\n    condition: input.claims.\n")
+
+	assert.True(t, engine.Evaluate(input, user).Allowed())
+}
+
+func TestNewEngineRejected(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		policy string
+	}{
+		{name: "invalid YAML", policy: "rules: ["},
+		{name: "unknown effect", policy: "rules:\n  - name: r\n    effect: maybe\n    routes: [/users/me]\n    condition: 'true'\n"},
+		{name: "no routes", policy: "rules:\n  - name: r\n    effect: allow\n    condition: 'true'\n"},
+		{name: "syntax error", policy: "rules:\n  - name: r\n    effect: allow\n    routes: [/users/me]\n    condition: 'input.'\n"},
+		{name: "not a boolean", policy: "rules:\n  - name: r\n    effect: allow\n    routes: [/users/me]\n    condition: '1 + 1'\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writePolicy(t, dir, "policy.yaml", tc.policy)
+
+			_, err := policy.NewEngine(policy.Config{Dir: dir}, &bytes.Buffer{})
+			require.ErrorIs(t, err, errormsg.ErrInvalidPolicy)
+		})
+	}
+}
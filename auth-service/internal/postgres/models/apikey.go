@@ -0,0 +1,141 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = `id, kind, COALESCE(user_id, 0), name, secret_hash, scopes, rate_limit, expires_at, created_at,
+             last_used_at, revoked_at`
+
+// CreateAPIKey stores an API key. Service keys are stored without a user.
+func (u *PostgresRepository) CreateAPIKey(key calltypes.APIKey) error {
+	stmt := `INSERT INTO api_keys (id, kind, user_id, name, secret_hash, scopes, rate_limit, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	var userID sql.NullInt64
+	if key.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(key.UserID), Valid: true}
+	}
+
+	_, err := u.execQuery(context.Background(), stmt,
+		key.ID,
+		key.Kind,
+		userID,
+		key.Name,
+		key.SecretHash,
+		strings.Join(key.Scopes, " "),
+		key.RateLimit,
+		key.ExpiresAt,
+		key.CreatedAt,
+	)
+
+	return err
+}
+
+// GetAPIKey returns the API key with the ID.
+func (u *PostgresRepository) GetAPIKey(id string) (*calltypes.APIKey, error) {
+	key, err := scanAPIKey(u.queryRow(context.Background(), `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrAPIKeyNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeys returns personal keys of the user, or service keys for zero
+// userID, oldest first.
+func (u *PostgresRepository) GetAPIKeys(userID int) ([]*calltypes.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	stmt := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE COALESCE(user_id, 0) = $1 ORDER BY created_at`
+
+	rows, err := u.Conn.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*calltypes.APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks the API key revoked. Revoked keys are kept for the record.
+func (u *PostgresRepository) RevokeAPIKey(id string, at time.Time) error {
+	_, err := u.execQuery(context.Background(), `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`, at, id)
+
+	return err
+}
+
+// TouchAPIKey records the last use of the API key.
+func (u *PostgresRepository) TouchAPIKey(id string, at time.Time) error {
+	_, err := u.execQuery(context.Background(), `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, at, id)
+
+	return err
+}
+
+func scanAPIKey(row rowScanner) (*calltypes.APIKey, error) {
+	var (
+		key                              calltypes.APIKey
+		scopes                           string
+		expiresAt, lastUsedAt, revokedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&key.ID,
+		&key.Kind,
+		&key.UserID,
+		&key.Name,
+		&key.SecretHash,
+		&scopes,
+		&key.RateLimit,
+		&expiresAt,
+		&key.CreatedAt,
+		&lastUsedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	key.RevokedAt = nullTime(revokedAt)
+
+	return &key, nil
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
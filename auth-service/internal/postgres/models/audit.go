@@ -0,0 +1,52 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// InsertAuditEvent appends an entry to the audit trail.
+func (u *PostgresRepository) InsertAuditEvent(event calltypes.AuditEvent) error {
+	var details []byte
+
+	if len(event.Details) > 0 {
+		var err error
+
+		details, err = json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+	}
+
+	stmt := `INSERT INTO audit_log (user_id, actor_id, action, ip, details, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		nullInt(event.UserID),
+		nullInt(event.ActorID),
+		event.Action,
+		sql.NullString{String: event.IP, Valid: event.IP != ""},
+		nullJSON(details),
+		time.Now(),
+	)
+
+	return err
+}
+
+// nullInt stores zero IDs as NULL.
+func nullInt(value int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(value), Valid: value != 0}
+}
+
+// nullJSON stores empty JSON documents as NULL.
+func nullJSON(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return string(value)
+}
@@ -0,0 +1,319 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const dataExportColumns = `id, user_id, format, status, created_at, completed_at, expires_at`
+
+// GetPersonalData collects everything stored about the user.
+func (u *PostgresRepository) GetPersonalData(userID int) (*calltypes.PersonalData, error) {
+	data := calltypes.PersonalData{GeneratedAt: time.Now()}
+
+	var err error
+
+	if data.Profile, err = u.GetOne(userID); err != nil {
+		return nil, err
+	}
+
+	if data.Roles, err = u.GetUserRoles(userID); err != nil {
+		return nil, err
+	}
+
+	if data.Sessions, err = u.getSessions(userID); err != nil {
+		return nil, err
+	}
+
+	if data.Consents, err = u.getOAuthConsents(userID); err != nil {
+		return nil, err
+	}
+
+	if data.Passkeys, err = u.GetWebAuthnCredentials(userID); err != nil {
+		return nil, err
+	}
+
+	if data.APIKeys, err = u.GetAPIKeys(userID); err != nil {
+		return nil, err
+	}
+
+	if data.FederatedIdentities, err = u.getFederatedIdentities(userID); err != nil {
+		return nil, err
+	}
+
+	if data.SecurityEvents, err = u.getAuditEvents(userID); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// getSessions returns the web session of the user, if any, and the refresh
+// tokens held by OAuth clients.
+func (u *PostgresRepository) getSessions(userID int) ([]*calltypes.Session, error) {
+	stmt := `SELECT '', '', NULL, refresh_token_expires FROM medods
+             WHERE id = $1 AND refresh_token IS NOT NULL AND refresh_token_expires > $2
+             UNION ALL
+             SELECT client_id, scope, created_at, expires_at FROM oauth_refresh_tokens
+             WHERE user_id = $1 AND expires_at > $2
+             ORDER BY 4`
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*calltypes.Session
+
+	for rows.Next() {
+		var (
+			session   calltypes.Session
+			createdAt sql.NullTime
+		)
+
+		if err := rows.Scan(&session.ClientID, &session.Scope, &createdAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		session.CreatedAt = nullTime(createdAt)
+		sessions = append(sessions, &session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// getOAuthConsents returns the OAuth clients holding refresh tokens of the user.
+func (u *PostgresRepository) getOAuthConsents(userID int) ([]*calltypes.OAuthConsent, error) {
+	stmt := `SELECT c.id, c.name, t.scope, MIN(t.created_at) FROM oauth_refresh_tokens t
+             JOIN oauth_clients c ON c.id = t.client_id
+             WHERE t.user_id = $1
+             GROUP BY c.id, c.name, t.scope
+             ORDER BY 4`
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OAuth consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []*calltypes.OAuthConsent
+
+	for rows.Next() {
+		var consent calltypes.OAuthConsent
+
+		if err := rows.Scan(&consent.ClientID, &consent.ClientName, &consent.Scope, &consent.GrantedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan OAuth consent: %w", err)
+		}
+
+		consents = append(consents, &consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch OAuth consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// getFederatedIdentities returns the identities of external providers linked
+// to the user.
+func (u *PostgresRepository) getFederatedIdentities(userID int) ([]*calltypes.FederatedIdentity, error) {
+	stmt := `SELECT provider, subject, email, created_at, last_login_at FROM federated_identities
+             WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch federated identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*calltypes.FederatedIdentity
+
+	for rows.Next() {
+		identity := calltypes.FederatedIdentity{UserID: userID}
+
+		err := rows.Scan(
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.LastLoginAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan federated identity: %w", err)
+		}
+
+		identities = append(identities, &identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch federated identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// getAuditEvents returns the audit trail of the user, oldest first.
+func (u *PostgresRepository) getAuditEvents(userID int) ([]*calltypes.AuditEvent, error) {
+	stmt := `SELECT id, COALESCE(actor_id, 0), action, COALESCE(ip, ''), details, created_at FROM audit_log
+             WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*calltypes.AuditEvent
+
+	for rows.Next() {
+		var details []byte
+
+		event := calltypes.AuditEvent{UserID: userID}
+
+		if err := rows.Scan(&event.ID, &event.ActorID, &event.Action, &event.IP, &details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &event.Details); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit details: %w", err)
+			}
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// CreateDataExport stores a pending data export.
+func (u *PostgresRepository) CreateDataExport(export calltypes.DataExport) error {
+	stmt := `INSERT INTO data_exports (id, user_id, format, status, created_at, expires_at)
+             VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		export.ID,
+		export.UserID,
+		export.Format,
+		export.Status,
+		export.CreatedAt,
+		export.ExpiresAt,
+	)
+
+	return err
+}
+
+// GetDataExport returns the data export.
+func (u *PostgresRepository) GetDataExport(id string) (*calltypes.DataExport, error) {
+	stmt := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1`
+
+	export, err := scanDataExport(u.queryRow(context.Background(), stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrDataExportNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// ClaimDataExport marks the oldest pending data export running and returns it,
+// so that replicas do not generate the same export twice. It returns
+// errormsg.ErrDataExportNotFound when nothing is pending.
+func (u *PostgresRepository) ClaimDataExport() (*calltypes.DataExport, error) {
+	stmt := `UPDATE data_exports SET status = 'running'
+             WHERE id = (SELECT id FROM data_exports WHERE status = 'pending'
+                         ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+             RETURNING ` + dataExportColumns
+
+	export, err := scanDataExport(u.queryRow(context.Background(), stmt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrDataExportNotFound
+		}
+
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// FinishDataExport stores the outcome of a data export.
+func (u *PostgresRepository) FinishDataExport(export calltypes.DataExport) error {
+	stmt := `UPDATE data_exports SET status = $1, completed_at = $2, expires_at = $3 WHERE id = $4`
+
+	_, err := u.execQuery(context.Background(), stmt, export.Status, export.CompletedAt, export.ExpiresAt, export.ID)
+
+	return err
+}
+
+// TakeExpiredDataExports removes the data exports expired at the given time
+// and returns them, so that their files can be removed.
+func (u *PostgresRepository) TakeExpiredDataExports(at time.Time) ([]*calltypes.DataExport, error) {
+	stmt := `DELETE FROM data_exports WHERE expires_at <= $1 RETURNING ` + dataExportColumns
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []*calltypes.DataExport
+
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+
+		exports = append(exports, export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+func scanDataExport(row rowScanner) (*calltypes.DataExport, error) {
+	var (
+		export      calltypes.DataExport
+		completedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Format,
+		&export.Status,
+		&export.CreatedAt,
+		&completedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	export.CompletedAt = nullTime(completedAt)
+
+	return &export, nil
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether the statement failed on a unique index.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// CreateEmailChange stores a pending email change, replacing the one the user
+// may already have.
+func (u *PostgresRepository) CreateEmailChange(change calltypes.EmailChange) error {
+	stmt := `INSERT INTO email_changes (token_hash, cancel_hash, user_id, new_email, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6)
+             ON CONFLICT (user_id) DO UPDATE SET
+             token_hash = EXCLUDED.token_hash,
+             cancel_hash = EXCLUDED.cancel_hash,
+             new_email = EXCLUDED.new_email,
+             expires_at = EXCLUDED.expires_at,
+             created_at = EXCLUDED.created_at`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		change.TokenHash,
+		change.CancelHash,
+		change.UserID,
+		change.NewEmail,
+		change.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeEmailChange removes the email change with the confirmation token and
+// returns it, so that each token is used once.
+func (u *PostgresRepository) TakeEmailChange(tokenHash string) (*calltypes.EmailChange, error) {
+	return u.takeEmailChange("token_hash", tokenHash)
+}
+
+// CancelEmailChange removes the email change with the cancellation token and
+// returns it.
+func (u *PostgresRepository) CancelEmailChange(cancelHash string) (*calltypes.EmailChange, error) {
+	return u.takeEmailChange("cancel_hash", cancelHash)
+}
+
+// takeEmailChange removes the email change whose column matches the hash.
+// Expired changes are removed along the way.
+func (u *PostgresRepository) takeEmailChange(column, hash string) (*calltypes.EmailChange, error) {
+	var change calltypes.EmailChange
+
+	stmt := `DELETE FROM email_changes WHERE ` + column + ` = $1
+             RETURNING token_hash, cancel_hash, user_id, new_email, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, hash).Scan(
+		&change.TokenHash,
+		&change.CancelHash,
+		&change.UserID,
+		&change.NewEmail,
+		&change.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidEmailChange
+		}
+
+		return nil, fmt.Errorf("failed to fetch email change: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM email_changes WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// ApplyEmailChange sets the new email of the user, marks it verified and
+// revokes the sessions of the user. It fails with errormsg.ErrEmailTaken if
+// another user has taken the email since the change was requested.
+func (u *PostgresRepository) ApplyEmailChange(userID int, newEmail string) error {
+	newEmail, err := u.normalizeEmail(newEmail)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var taken bool
+
+	stmt := `SELECT EXISTS (SELECT 1 FROM medods WHERE lower(email) = lower($1) AND id <> $2)`
+
+	if err := tx.QueryRowContext(ctx, stmt, newEmail, userID).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+
+	if taken {
+		return errormsg.ErrEmailTaken
+	}
+
+	stmt = `UPDATE medods SET email = $1, email_verified = TRUE, version = version + 1, updated_at = $2 WHERE id = $3`
+
+	result, err := tx.ExecContext(ctx, stmt, newEmail, time.Now(), userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errormsg.ErrEmailTaken
+		}
+
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	if rows == 0 {
+		return errormsg.ErrUserNotFound
+	}
+
+	if err := revokeSessions(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit email change: %w", err)
+	}
+
+	return nil
+}
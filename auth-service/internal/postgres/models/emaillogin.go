@@ -0,0 +1,101 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateEmailLogin stores a pending email login, dropping previous pending
+// logins of the user and all expired ones, so only the latest code or link works.
+func (u *PostgresRepository) CreateEmailLogin(login calltypes.EmailLogin) error {
+	stmt := `DELETE FROM email_logins WHERE user_id = $1 OR expires_at < $2`
+
+	if _, err := u.execQuery(context.Background(), stmt, login.UserID, time.Now()); err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO email_logins (id, user_id, method, code_hash, browser_hash, expires_at, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		login.ID,
+		login.UserID,
+		login.Method,
+		login.CodeHash,
+		login.BrowserHash,
+		login.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// GetEmailLogin returns a pending email login.
+func (u *PostgresRepository) GetEmailLogin(id string) (*calltypes.EmailLogin, error) {
+	var login calltypes.EmailLogin
+
+	stmt := `SELECT id, user_id, method, code_hash, browser_hash, attempts, expires_at FROM email_logins WHERE id = $1`
+
+	err := u.queryRow(context.Background(), stmt, id).Scan(
+		&login.ID,
+		&login.UserID,
+		&login.Method,
+		&login.CodeHash,
+		&login.BrowserHash,
+		&login.Attempts,
+		&login.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidEmailLogin
+		}
+
+		return nil, fmt.Errorf("failed to fetch email login: %w", err)
+	}
+
+	return &login, nil
+}
+
+// ClaimEmailLoginAttempt counts an attempt to enter the code of the login.
+// Attempts are counted in one statement before the code is checked, so
+// concurrent requests cannot exceed maxAttempts.
+func (u *PostgresRepository) ClaimEmailLoginAttempt(id string, maxAttempts int, now time.Time) error {
+	stmt := `UPDATE email_logins SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2 AND expires_at > $3`
+
+	result, err := u.execQuery(context.Background(), stmt, id, maxAttempts, now)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to claim email login attempt: %w", err)
+	}
+
+	if affected == 0 {
+		return errormsg.ErrInvalidEmailLogin
+	}
+
+	return nil
+}
+
+// ConsumeEmailLogin removes the login once it has been used. It reports false
+// if the login has already been consumed by a concurrent request.
+func (u *PostgresRepository) ConsumeEmailLogin(id string) (bool, error) {
+	result, err := u.execQuery(context.Background(), `DELETE FROM email_logins WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume email login: %w", err)
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailaddr"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// userDataTables hold data of a user that is removed when the user is erased.
+var userDataTables = []string{ //nolint: gochecknoglobals
+	"user_totp",
+	"mfa_challenges",
+	"mfa_recovery_codes",
+	"webauthn_credentials",
+	"webauthn_sessions",
+	"email_logins",
+	"oauth_authorization_codes",
+	"oauth_refresh_tokens",
+	"oauth_device_codes",
+	"federated_identities",
+	"api_keys",
+	"user_roles",
+	"password_resets",
+	"email_changes",
+	"account_deletions",
+}
+
+// CreateAccountDeletion schedules the deletion of the account of a user.
+func (u *PostgresRepository) CreateAccountDeletion(deletion calltypes.AccountDeletion) error {
+	stmt := `INSERT INTO account_deletions (user_id, requested_at, erase_at) VALUES ($1, $2, $3)`
+
+	_, err := u.execQuery(context.Background(), stmt, deletion.UserID, deletion.RequestedAt, deletion.EraseAt)
+
+	return err
+}
+
+// GetAccountDeletion returns the scheduled deletion of the account of a user.
+func (u *PostgresRepository) GetAccountDeletion(userID int) (*calltypes.AccountDeletion, error) {
+	deletion := calltypes.AccountDeletion{UserID: userID}
+
+	stmt := `SELECT requested_at, erase_at FROM account_deletions WHERE user_id = $1`
+
+	err := u.queryRow(context.Background(), stmt, userID).Scan(&deletion.RequestedAt, &deletion.EraseAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrDeletionNotScheduled
+		}
+
+		return nil, fmt.Errorf("failed to fetch account deletion: %w", err)
+	}
+
+	return &deletion, nil
+}
+
+// DeleteAccountDeletion cancels the scheduled deletion of the account of a user.
+func (u *PostgresRepository) DeleteAccountDeletion(userID int) error {
+	result, err := u.execQuery(context.Background(), `DELETE FROM account_deletions WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	if rows == 0 {
+		return errormsg.ErrDeletionNotScheduled
+	}
+
+	return nil
+}
+
+// GetDueAccountDeletions returns at most limit deletions due at the given time,
+// the longest overdue first.
+func (u *PostgresRepository) GetDueAccountDeletions(at time.Time, limit int) ([]*calltypes.AccountDeletion, error) {
+	stmt := `SELECT user_id, requested_at, erase_at FROM account_deletions
+             WHERE erase_at <= $1 ORDER BY erase_at LIMIT $2`
+
+	rows, err := u.Conn.QueryContext(context.Background(), stmt, at, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var deletions []*calltypes.AccountDeletion
+
+	for rows.Next() {
+		var deletion calltypes.AccountDeletion
+
+		if err := rows.Scan(&deletion.UserID, &deletion.RequestedAt, &deletion.EraseAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account deletion: %w", err)
+		}
+
+		deletions = append(deletions, &deletion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch account deletions: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// EraseUser anonymizes the user and marks it deleted, removes its credentials,
+// sessions and other data, strips personal data from its audit trail, expires
+// its data exports so that their files are removed, and writes a tombstone.
+// The row is kept so that its ID is not reused and the audit trail stays
+// consistent.
+func (u *PostgresRepository) EraseUser(deletion calltypes.AccountDeletion, erasedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var email string
+
+	err = tx.QueryRowContext(ctx, `SELECT email FROM medods WHERE id = $1 FOR UPDATE`, deletion.UserID).Scan(&email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errormsg.ErrUserNotFound
+		}
+
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	stmt := `UPDATE medods SET
+             email = 'deleted-' || id || '@deleted.invalid',
+             first_name = '',
+             last_name = '',
+             password = '',
+             refresh_token = NULL,
+             refresh_token_expires = NULL,
+             email_verified = FALSE,
+             status = 'deleted',
+             version = version + 1,
+             updated_at = $1
+             WHERE id = $2`
+
+	if _, err := tx.ExecContext(ctx, stmt, erasedAt, deletion.UserID); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	for _, table := range userDataTables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, deletion.UserID); err != nil {
+			return fmt.Errorf("failed to erase %s: %w", table, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM login_failures WHERE scope = 'account' AND key = $1`, emailaddr.Key(email)); err != nil {
+		return fmt.Errorf("failed to erase login failures: %w", err)
+	}
+
+	stmt = `UPDATE audit_log SET ip = NULL,
+            details = CASE WHEN user_id = $1 THEN NULL ELSE details END
+            WHERE user_id = $1 OR actor_id = $1`
+
+	if _, err := tx.ExecContext(ctx, stmt, deletion.UserID); err != nil {
+		return fmt.Errorf("failed to anonymize audit trail: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE data_exports SET expires_at = $1 WHERE user_id = $2`, erasedAt, deletion.UserID); err != nil {
+		return fmt.Errorf("failed to expire data exports: %w", err)
+	}
+
+	stmt = `INSERT INTO user_tombstones (user_id, requested_at, erased_at) VALUES ($1, $2, $3)`
+
+	if _, err := tx.ExecContext(ctx, stmt, deletion.UserID, deletion.RequestedAt, erasedAt); err != nil {
+		return fmt.Errorf("failed to write tombstone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user erasure: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateFederatedLogin stores a pending login through an identity provider.
+func (u *PostgresRepository) CreateFederatedLogin(login calltypes.FederatedLogin) error {
+	stmt := `INSERT INTO federated_logins (state_hash, provider, nonce, code_verifier, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		login.StateHash,
+		login.Provider,
+		login.Nonce,
+		login.CodeVerifier,
+		login.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeFederatedLogin removes the pending login and returns it, so that each
+// authorization response is accepted once. Expired logins are removed along
+// the way.
+func (u *PostgresRepository) TakeFederatedLogin(stateHash string) (*calltypes.FederatedLogin, error) {
+	var login calltypes.FederatedLogin
+
+	stmt := `DELETE FROM federated_logins WHERE state_hash = $1
+             RETURNING state_hash, provider, nonce, code_verifier, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, stateHash).Scan(
+		&login.StateHash,
+		&login.Provider,
+		&login.Nonce,
+		&login.CodeVerifier,
+		&login.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidFederatedLogin
+		}
+
+		return nil, fmt.Errorf("failed to fetch federated login: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM federated_logins WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &login, nil
+}
+
+// GetFederatedIdentity returns the identity of the provider subject.
+func (u *PostgresRepository) GetFederatedIdentity(provider, subject string) (*calltypes.FederatedIdentity, error) {
+	identity := calltypes.FederatedIdentity{Provider: provider, Subject: subject}
+
+	stmt := `SELECT user_id, email, created_at, last_login_at FROM federated_identities
+             WHERE provider = $1 AND subject = $2`
+
+	err := u.queryRow(context.Background(), stmt, provider, subject).Scan(
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrIdentityNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch federated identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// LinkFederatedIdentity links the provider subject to the user.
+func (u *PostgresRepository) LinkFederatedIdentity(identity calltypes.FederatedIdentity) error {
+	stmt := `INSERT INTO federated_identities (provider, subject, user_id, email, created_at, last_login_at)
+             VALUES ($1, $2, $3, $4, $5, $5)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TouchFederatedIdentity records a login through the identity together with
+// the email the provider currently reports.
+func (u *PostgresRepository) TouchFederatedIdentity(provider, subject, email string, at time.Time) error {
+	stmt := `UPDATE federated_identities SET email = $3, last_login_at = $4 WHERE provider = $1 AND subject = $2`
+
+	_, err := u.execQuery(context.Background(), stmt, provider, subject, email, at)
+
+	return err
+}
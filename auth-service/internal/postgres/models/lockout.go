@@ -0,0 +1,79 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetLoginFailures returns failed login counters, zero values if there are none.
+func (u *PostgresRepository) GetLoginFailures(scope, key string) (*calltypes.LoginFailures, error) {
+	var (
+		state       calltypes.LoginFailures
+		lockedUntil sql.NullTime
+	)
+
+	stmt := `SELECT failures, last_failure_at, locked_until FROM login_failures WHERE scope = $1 AND key = $2`
+
+	err := u.queryRow(context.Background(), stmt, scope, key).Scan(&state.Failures, &state.LastFailureAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &calltypes.LoginFailures{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to fetch login failures: %w", err)
+	}
+
+	state.LockedUntil = lockedUntil.Time
+
+	return &state, nil
+}
+
+// RecordLoginFailure increments failed login counter. Counters whose last failure
+// is older than window start over.
+func (u *PostgresRepository) RecordLoginFailure(scope, key string, window time.Duration) (*calltypes.LoginFailures, error) {
+	var (
+		state       calltypes.LoginFailures
+		lockedUntil sql.NullTime
+	)
+
+	now := time.Now()
+
+	stmt := `INSERT INTO login_failures (scope, key, failures, last_failure_at)
+             VALUES ($1, $2, 1, $3)
+             ON CONFLICT (scope, key) DO UPDATE SET
+             failures = CASE WHEN login_failures.last_failure_at < $4 THEN 1 ELSE login_failures.failures + 1 END,
+             last_failure_at = EXCLUDED.last_failure_at
+             RETURNING failures, last_failure_at, locked_until`
+
+	err := u.queryRow(context.Background(), stmt, scope, key, now, now.Add(-window)).
+		Scan(&state.Failures, &state.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	state.LockedUntil = lockedUntil.Time
+
+	return &state, nil
+}
+
+// LockLogin forbids logins for the scope and key until the given time.
+func (u *PostgresRepository) LockLogin(scope, key string, until time.Time) error {
+	stmt := `UPDATE login_failures SET locked_until = $1 WHERE scope = $2 AND key = $3`
+
+	_, err := u.execQuery(context.Background(), stmt, until, scope, key)
+
+	return err
+}
+
+// ResetLoginFailures removes failed login counters and any lock.
+func (u *PostgresRepository) ResetLoginFailures(scope, key string) error {
+	stmt := `DELETE FROM login_failures WHERE scope = $1 AND key = $2`
+
+	_, err := u.execQuery(context.Background(), stmt, scope, key)
+
+	return err
+}
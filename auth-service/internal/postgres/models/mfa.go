@@ -0,0 +1,260 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetTOTP returns TOTP enrollment of the user.
+func (u *PostgresRepository) GetTOTP(userID int) (*calltypes.TOTP, error) {
+	var (
+		totp        calltypes.TOTP
+		confirmedAt sql.NullTime
+	)
+
+	stmt := `SELECT user_id, secret_encrypted, COALESCE(pending_secret_encrypted, ''), confirmed_at, last_used_step
+             FROM user_totp WHERE user_id = $1`
+
+	err := u.queryRow(context.Background(), stmt, userID).Scan(&totp.UserID, &totp.Secret, &totp.PendingSecret, &confirmedAt, &totp.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrMFANotEnrolled
+		}
+
+		return nil, fmt.Errorf("failed to fetch TOTP enrollment: %w", err)
+	}
+
+	totp.Confirmed = confirmedAt.Valid
+
+	return &totp, nil
+}
+
+// SaveTOTP stores a new unconfirmed TOTP secret, replacing a previous unconfirmed one.
+func (u *PostgresRepository) SaveTOTP(userID int, encryptedSecret string) error {
+	stmt := `INSERT INTO user_totp (user_id, secret_encrypted, created_at) VALUES ($1, $2, $3)
+             ON CONFLICT (user_id) DO UPDATE SET
+             secret_encrypted = EXCLUDED.secret_encrypted,
+             confirmed_at = NULL,
+             last_used_step = 0,
+             created_at = EXCLUDED.created_at
+             WHERE user_totp.confirmed_at IS NULL`
+
+	result, err := u.execQuery(context.Background(), stmt, userID, encryptedSecret, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP enables TOTP of the user, recording the step of the first code.
+func (u *PostgresRepository) ConfirmTOTP(userID int, step int64) error {
+	stmt := `UPDATE user_totp SET confirmed_at = $1, last_used_step = $2 WHERE user_id = $3 AND confirmed_at IS NULL`
+
+	_, err := u.execQuery(context.Background(), stmt, time.Now(), step, userID)
+
+	return err
+}
+
+// SavePendingTOTP stores a new secret for a confirmed TOTP, replacing a previous
+// pending one. The current secret keeps working until ReplaceTOTP.
+func (u *PostgresRepository) SavePendingTOTP(userID int, encryptedSecret string) error {
+	stmt := `UPDATE user_totp SET pending_secret_encrypted = $1 WHERE user_id = $2 AND confirmed_at IS NOT NULL`
+
+	result, err := u.execQuery(context.Background(), stmt, encryptedSecret, userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrMFANotEnrolled
+	}
+
+	return nil
+}
+
+// ReplaceTOTP makes the pending secret the secret of the user, recording the
+// step of its first code.
+func (u *PostgresRepository) ReplaceTOTP(userID int, step int64) error {
+	stmt := `UPDATE user_totp SET secret_encrypted = pending_secret_encrypted, pending_secret_encrypted = NULL,
+             confirmed_at = $1, last_used_step = $2
+             WHERE user_id = $3 AND pending_secret_encrypted IS NOT NULL`
+
+	result, err := u.execQuery(context.Background(), stmt, time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrMFANotEnrolled
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the step of an accepted code. It reports false if that or a
+// later step has already been used, which means the code is being replayed.
+func (u *PostgresRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	stmt := `UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+
+	result, err := u.execQuery(context.Background(), stmt, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record TOTP step: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// CreateMFAChallenge stores a pending MFA login.
+func (u *PostgresRepository) CreateMFAChallenge(challenge calltypes.MFAChallenge) error {
+	stmt := `INSERT INTO mfa_challenges (id, user_id, ip, amr, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		challenge.ID,
+		challenge.UserID,
+		challenge.IP,
+		strings.Join(challenge.AMR, " "),
+		challenge.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// ClaimMFAChallengeAttempt counts an attempt to complete a pending MFA login
+// from the IP and returns the login. Attempts are counted in one statement
+// before the code is checked, so concurrent requests cannot exceed
+// maxAttempts.
+func (u *PostgresRepository) ClaimMFAChallengeAttempt(id, ip string, maxAttempts int, now time.Time) (*calltypes.MFAChallenge, error) {
+	var (
+		challenge calltypes.MFAChallenge
+		amr       string
+	)
+
+	stmt := `UPDATE mfa_challenges SET attempts = attempts + 1
+             WHERE id = $1 AND ip = $2 AND attempts < $3 AND expires_at > $4
+             RETURNING id, user_id, ip, amr, attempts, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, id, ip, maxAttempts, now).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.IP,
+		&amr,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidMFAChallenge
+		}
+
+		return nil, fmt.Errorf("failed to claim MFA challenge attempt: %w", err)
+	}
+
+	challenge.AMR = strings.Fields(amr)
+
+	return &challenge, nil
+}
+
+// DeleteMFAChallenge removes the challenge together with all expired ones.
+func (u *PostgresRepository) DeleteMFAChallenge(id string) error {
+	stmt := `DELETE FROM mfa_challenges WHERE id = $1 OR expires_at < $2`
+
+	_, err := u.execQuery(context.Background(), stmt, id, time.Now())
+
+	return err
+}
+
+// ReplaceRecoveryCodes removes all recovery codes of the user and stores new ones.
+func (u *PostgresRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		stmt := `INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)`
+
+		if _, err := tx.ExecContext(ctx, stmt, userID, codeHash, time.Now()); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks the code as used. It reports false if the user has no
+// such unused code.
+func (u *PostgresRepository) ConsumeRecoveryCode(userID int, codeHash string) (bool, error) {
+	stmt := `UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+
+	result, err := u.execQuery(context.Background(), stmt, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// DeleteMFA removes TOTP, recovery codes and pending MFA challenges of the user.
+func (u *PostgresRepository) DeleteMFA(userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, table := range []string{"user_totp", "mfa_recovery_codes", "mfa_challenges"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit MFA reset: %w", err)
+	}
+
+	return nil
+}
@@ -11,12 +11,17 @@ import (
 	"strings"
 	"time"
 
+	"auth-service/internal/emailaddr"
+	"auth-service/internal/password"
 	"auth-service/pkg/errormsg"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type PostgresRepository struct {
 	Conn *sql.DB
+	// LowercaseEmails stores emails fully lowercased. Otherwise only their
+	// domain is lowercased; lookups ignore case either way.
+	LowercaseEmails bool
 }
 
 func NewPostgresRepository(pool *sql.DB) *PostgresRepository {
@@ -39,10 +44,11 @@ func (u *PostgresRepository) UserExists(id int) (bool, error) {
 	return exists, nil
 }
 
-// GetAll returns a slice of all users, sorted by last name.
+// GetAll returns a slice of all users, sorted by last name. Erased users are
+// left out.
 func (u *PostgresRepository) GetAll() ([]*calltypes.User, error) {
-	query := `select id, email, first_name, last_name, active, created_at, updated_at
-              from medods`
+	query := `select id, email, email_verified, first_name, last_name, status, created_at, updated_at
+              from medods where status <> 'deleted'`
 
 	rows, err := u.Conn.QueryContext(context.Background(), query)
 	if err != nil {
@@ -57,9 +63,10 @@ func (u *PostgresRepository) GetAll() ([]*calltypes.User, error) {
 		err := rows.Scan(
 			&user.ID,
 			&user.Email,
+			&user.EmailVerified,
 			&user.FirstName,
 			&user.LastName,
-			&user.Active,
+			&user.Status,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -87,7 +94,7 @@ func (u *PostgresRepository) EmailCheck(email string) (*calltypes.User, error) {
 	var emailExists bool
 
 	err := u.queryRow(context.Background(),
-		"SELECT EXISTS(SELECT 1 from medods WHERE email = $1)", email).Scan(&emailExists)
+		"SELECT EXISTS(SELECT 1 from medods WHERE lower(email) = lower($1))", emailaddr.Key(email)).Scan(&emailExists)
 	if err != nil {
 		log.Println("failed to check email: ")
 
@@ -100,10 +107,10 @@ func (u *PostgresRepository) EmailCheck(email string) (*calltypes.User, error) {
 		return nil, fmt.Errorf("user with that email does not exists: %w", err)
 	}
 
-	query := `select first_name, password from medods where email = $1`
+	query := `select first_name, password from medods where lower(email) = lower($1)`
 
 	var user calltypes.User
-	err = u.queryRow(context.Background(), query, email).Scan(
+	err = u.queryRow(context.Background(), query, emailaddr.Key(email)).Scan(
 		&user.FirstName,
 		&user.Password,
 	)
@@ -117,19 +124,20 @@ func (u *PostgresRepository) EmailCheck(email string) (*calltypes.User, error) {
 	return &user, nil
 }
 
-// GetByEmail returns info of one user by email.
+// GetByEmail returns info of one user by email, ignoring its case.
 func (u *PostgresRepository) GetByEmail(email string) (*calltypes.User, error) {
-	query := `select id, email, first_name, last_name, password, active, created_at, updated_at 
-              from medods where email = $1`
+	query := `select id, email, email_verified, first_name, last_name, password, status, created_at, updated_at 
+              from medods where lower(email) = lower($1)`
 
 	var user calltypes.User
-	err := u.queryRow(context.Background(), query, email).Scan(
+	err := u.queryRow(context.Background(), query, emailaddr.Key(email)).Scan(
 		&user.ID,
 		&user.Email,
+		&user.EmailVerified,
 		&user.FirstName,
 		&user.LastName,
 		&user.Password,
-		&user.Active,
+		&user.Status,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -154,16 +162,18 @@ func (u *PostgresRepository) GetOne(id int) (*calltypes.User, error) {
 		return nil, errormsg.ErrUserNotFound
 	}
 
-	query := `select id, email, first_name, last_name, active, created_at, updated_at
+	query := `select id, email, email_verified, first_name, last_name, status, version, created_at, updated_at
               from medods where id = $1`
 
 	var user calltypes.User
 	err = u.queryRow(context.Background(), query, id).Scan(
 		&user.ID,
 		&user.Email,
+		&user.EmailVerified,
 		&user.FirstName,
 		&user.LastName,
-		&user.Active,
+		&user.Status,
+		&user.Version,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -188,11 +198,16 @@ func (u *PostgresRepository) Update(user calltypes.User) error {
 		return errormsg.ErrUserNotFound
 	}
 
+	if user.Email, err = u.normalizeEmail(user.Email); err != nil {
+		return err
+	}
+
 	stmt := `update medods set
              email = $1,
              first_name = $2,
              last_name = $3,
-             active = $4,
+             status = $4,
+             version = version + 1,
              updated_at = $5
              where id = $6`
 
@@ -200,11 +215,15 @@ func (u *PostgresRepository) Update(user calltypes.User) error {
 		user.Email,
 		user.FirstName,
 		user.LastName,
-		user.Active,
+		user.Status,
 		time.Now(),
 		user.ID,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return errormsg.ErrEmailTaken
+		}
+
 		log.Println("failed to update user: ", err)
 
 		return fmt.Errorf("failed to update user: %w", err)
@@ -219,26 +238,52 @@ func (u *PostgresRepository) Insert(user calltypes.User) (int, error) {
 		return 0, errormsg.ErrPasswordLength
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), consts.BcryptCost)
+	hashedPassword, err := password.Hash(user.Password)
 	if err != nil {
-		return 0, fmt.Errorf("failed to hash password: %w", err)
+		return 0, err
 	}
 
-	var newID int
+	return u.insertUser(user, hashedPassword)
+}
 
-	stmt := `insert into medods (email, first_name, last_name, password, active, created_at, updated_at)
-         values ($1, $2, $3, $4, $5, $6, $7) returning id`
+// Import adds a user migrated from another system. The password field must
+// already hold a hash in one of the formats understood by the password package.
+func (u *PostgresRepository) Import(user calltypes.User) (int, error) {
+	if _, err := password.Identify(user.Password); err != nil {
+		return 0, err
+	}
+
+	return u.insertUser(user, user.Password)
+}
+
+func (u *PostgresRepository) insertUser(user calltypes.User, hashedPassword string) (int, error) {
+	var (
+		newID int
+		err   error
+	)
+
+	if user.Email, err = u.normalizeEmail(user.Email); err != nil {
+		return 0, err
+	}
+
+	stmt := `insert into medods (email, email_verified, first_name, last_name, password, status, created_at, updated_at)
+         values ($1, $2, $3, $4, $5, $6, $7, $8) returning id`
 
 	err = u.queryRow(context.Background(), stmt,
 		user.Email,
+		user.EmailVerified,
 		user.FirstName,
 		user.LastName,
 		hashedPassword,
-		user.Active,
+		user.Status,
 		time.Now(),
 		time.Now(),
 	).Scan(&newID)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, errormsg.ErrEmailTaken
+		}
+
 		log.Println("failed to insert new user: ", err)
 
 		return 0, fmt.Errorf("failed to insert new user: %w", err)
@@ -247,21 +292,57 @@ func (u *PostgresRepository) Insert(user calltypes.User) (int, error) {
 	return newID, nil
 }
 
-// PasswordMatches uses Go's bcrypt package to compare a user supplied password
-// with the hash we have stored for a given user in the database. If the password
-// and hash match, we return true; otherwise, we return false.
+// normalizeEmail brings the email to the form it is stored in.
+func (u *PostgresRepository) normalizeEmail(email string) (string, error) {
+	return emailaddr.Normalize(email, u.LowercaseEmails)
+}
+
+// InsertExternal adds a user signing in through an external identity provider.
+// Such users have no password and cannot log in with one.
+func (u *PostgresRepository) InsertExternal(user calltypes.User) (int, error) {
+	return u.insertUser(user, "")
+}
+
+// PasswordMatches compares a user supplied password with the hash we have stored
+// for a given user in the database. Besides bcrypt it accepts the foreign formats
+// of imported users; such hashes are replaced with a bcrypt one on the first
+// successful match.
 func (u *PostgresRepository) PasswordMatches(plainText string, user calltypes.User) (bool, error) {
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(plainText))
+	matches, err := password.Verify(plainText, user.Password)
 	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, nil
-		default:
-			return false, fmt.Errorf("failed to compare passwords: %w", err)
+		return false, fmt.Errorf("failed to compare passwords: %w", err)
+	}
+
+	if matches && password.NeedsRehash(user.Password) {
+		if err := u.rehashPassword(user.ID, plainText); err != nil {
+			log.Println("failed to rehash password: ", err)
 		}
 	}
 
-	return true, nil
+	return matches, nil
+}
+
+// rehashPassword stores the password hashed with the current algorithm.
+func (u *PostgresRepository) rehashPassword(id int, plainText string) error {
+	hashedPassword, err := password.Hash(plainText)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE medods SET password = $1, updated_at = $2 WHERE id = $3`
+
+	_, err = u.execQuery(context.Background(), stmt, hashedPassword, time.Now(), id)
+
+	return err
+}
+
+// MarkEmailVerified records that the user proved ownership of the email address.
+func (u *PostgresRepository) MarkEmailVerified(id int) error {
+	stmt := `UPDATE medods SET email_verified = TRUE, version = version + 1, updated_at = $1 WHERE id = $2`
+
+	_, err := u.execQuery(context.Background(), stmt, time.Now(), id)
+
+	return err
 }
 
 // StoreRefreshToken stores provided refresh token.
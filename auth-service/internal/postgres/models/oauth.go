@@ -0,0 +1,359 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreateOAuthClient registers an OAuth client. Lists are stored space
+// separated and public keys as a JWK set.
+func (u *PostgresRepository) CreateOAuthClient(client calltypes.OAuthClient) error {
+	var jwks []byte
+
+	if len(client.JWKS) > 0 {
+		encoded, err := json.Marshal(calltypes.JSONWebKeySet{Keys: client.JWKS})
+		if err != nil {
+			return fmt.Errorf("failed to encode client keys: %w", err)
+		}
+
+		jwks = encoded
+	}
+
+	stmt := `INSERT INTO oauth_clients
+             (id, name, secret_hash, redirect_uris, grant_types, scopes, token_endpoint_auth_method, jwks,
+             exchange_audiences, first_party, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		client.ID,
+		client.Name,
+		client.SecretHash,
+		strings.Join(client.RedirectURIs, " "),
+		strings.Join(client.GrantTypes, " "),
+		strings.Join(client.Scopes, " "),
+		client.TokenEndpointAuthMethod,
+		string(jwks),
+		strings.Join(client.ExchangeAudiences, " "),
+		client.FirstParty,
+		client.CreatedAt,
+	)
+
+	return err
+}
+
+// GetOAuthClient returns a registered OAuth client.
+func (u *PostgresRepository) GetOAuthClient(id string) (*calltypes.OAuthClient, error) {
+	var (
+		client                                           calltypes.OAuthClient
+		redirectURIs, grantTypes, scope, jwks, audiences string
+	)
+
+	stmt := `SELECT id, name, secret_hash, redirect_uris, grant_types, scopes, token_endpoint_auth_method, jwks,
+             exchange_audiences, first_party, created_at
+             FROM oauth_clients WHERE id = $1`
+
+	err := u.queryRow(context.Background(), stmt, id).Scan(
+		&client.ID,
+		&client.Name,
+		&client.SecretHash,
+		&redirectURIs,
+		&grantTypes,
+		&scope,
+		&client.TokenEndpointAuthMethod,
+		&jwks,
+		&audiences,
+		&client.FirstParty,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidClient
+		}
+
+		return nil, fmt.Errorf("failed to fetch OAuth client: %w", err)
+	}
+
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	client.GrantTypes = strings.Fields(grantTypes)
+	client.Scopes = strings.Fields(scope)
+	client.ExchangeAudiences = strings.Fields(audiences)
+
+	if jwks != "" {
+		var set calltypes.JSONWebKeySet
+		if err := json.Unmarshal([]byte(jwks), &set); err != nil {
+			return nil, fmt.Errorf("failed to decode client keys: %w", err)
+		}
+
+		client.JWKS = set.Keys
+	}
+
+	return &client, nil
+}
+
+// CreateAuthorizationCode stores an issued authorization code.
+func (u *PostgresRepository) CreateAuthorizationCode(code calltypes.OAuthAuthorizationCode) error {
+	stmt := `INSERT INTO oauth_authorization_codes
+             (code_hash, client_id, user_id, redirect_uri, scope, code_challenge, nonce, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scope,
+		code.CodeChallenge,
+		code.Nonce,
+		code.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeAuthorizationCode removes the code and returns it, so that each code is
+// exchanged once. Expired codes are removed along the way.
+func (u *PostgresRepository) TakeAuthorizationCode(codeHash string) (*calltypes.OAuthAuthorizationCode, error) {
+	var code calltypes.OAuthAuthorizationCode
+
+	stmt := `DELETE FROM oauth_authorization_codes WHERE code_hash = $1
+             RETURNING code_hash, client_id, user_id, redirect_uri, scope, code_challenge, nonce, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, codeHash).Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scope,
+		&code.CodeChallenge,
+		&code.Nonce,
+		&code.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidGrant
+		}
+
+		return nil, fmt.Errorf("failed to fetch authorization code: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM oauth_authorization_codes WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// CreateOAuthRefreshToken stores a refresh token issued to an OAuth client.
+func (u *PostgresRepository) CreateOAuthRefreshToken(token calltypes.OAuthRefreshToken) error {
+	stmt := `INSERT INTO oauth_refresh_tokens (token_hash, client_id, user_id, scope, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		token.TokenHash,
+		token.ClientID,
+		token.UserID,
+		token.Scope,
+		token.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeOAuthRefreshToken removes the refresh token and returns it. Refresh
+// tokens are rotated, so each of them is used once. Expired tokens are removed
+// along the way.
+func (u *PostgresRepository) TakeOAuthRefreshToken(tokenHash string) (*calltypes.OAuthRefreshToken, error) {
+	var token calltypes.OAuthRefreshToken
+
+	stmt := `DELETE FROM oauth_refresh_tokens WHERE token_hash = $1
+             RETURNING token_hash, client_id, user_id, scope, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, tokenHash).Scan(
+		&token.TokenHash,
+		&token.ClientID,
+		&token.UserID,
+		&token.Scope,
+		&token.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidGrant
+		}
+
+		return nil, fmt.Errorf("failed to fetch OAuth refresh token: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM oauth_refresh_tokens WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UseClientAssertion records the ID of a client assertion until it expires. It
+// reports false if the assertion has already been used. Expired assertions are
+// removed along the way.
+func (u *PostgresRepository) UseClientAssertion(clientID, jti string, expiresAt time.Time) (bool, error) {
+	if _, err := u.execQuery(context.Background(), `DELETE FROM oauth_client_assertions WHERE expires_at < $1`, time.Now()); err != nil {
+		return false, err
+	}
+
+	stmt := `INSERT INTO oauth_client_assertions (client_id, jti, expires_at) VALUES ($1, $2, $3)
+             ON CONFLICT (client_id, jti) DO NOTHING`
+
+	result, err := u.execQuery(context.Background(), stmt, clientID, jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record client assertion: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// CreateDeviceCode stores a pending device authorization. Expired ones are
+// removed along the way.
+func (u *PostgresRepository) CreateDeviceCode(code calltypes.OAuthDeviceCode) error {
+	if _, err := u.execQuery(context.Background(), `DELETE FROM oauth_device_codes WHERE expires_at < $1`, time.Now()); err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO oauth_device_codes
+             (device_code_hash, user_code, client_id, scope, interval_seconds, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		code.DeviceCodeHash,
+		code.UserCode,
+		code.ClientID,
+		code.Scope,
+		int(code.Interval.Seconds()),
+		code.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// GetDeviceCode returns the device authorization the user code was issued for.
+func (u *PostgresRepository) GetDeviceCode(userCode string) (*calltypes.OAuthDeviceCode, error) {
+	stmt := `SELECT device_code_hash, user_code, client_id, scope, COALESCE(user_id, 0), denied,
+             interval_seconds, last_polled_at, expires_at
+             FROM oauth_device_codes WHERE user_code = $1`
+
+	code, err := scanDeviceCode(u.queryRow(context.Background(), stmt, userCode))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidUserCode
+		}
+
+		return nil, fmt.Errorf("failed to fetch device code: %w", err)
+	}
+
+	return code, nil
+}
+
+// DecideDeviceCode records the decision of the user on a pending, unexpired
+// device authorization. It reports false if there is no such authorization.
+func (u *PostgresRepository) DecideDeviceCode(userCode string, userID int, approved bool) (bool, error) {
+	stmt := `UPDATE oauth_device_codes SET user_id = $1, denied = $2
+             WHERE user_code = $3 AND user_id IS NULL AND NOT denied AND expires_at > $4`
+
+	result, err := u.execQuery(context.Background(), stmt, userID, !approved, userCode, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record device decision: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// PollDeviceCode records a poll of the device and returns the authorization
+// with the time of the previous poll.
+func (u *PostgresRepository) PollDeviceCode(deviceCodeHash string, polledAt time.Time) (*calltypes.OAuthDeviceCode, error) {
+	stmt := `UPDATE oauth_device_codes AS d SET last_polled_at = $2
+             FROM oauth_device_codes AS previous
+             WHERE d.device_code_hash = $1 AND previous.device_code_hash = d.device_code_hash
+             RETURNING d.device_code_hash, d.user_code, d.client_id, d.scope, COALESCE(d.user_id, 0), d.denied,
+             d.interval_seconds, previous.last_polled_at, d.expires_at`
+
+	code, err := scanDeviceCode(u.queryRow(context.Background(), stmt, deviceCodeHash, polledAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidGrant
+		}
+
+		return nil, fmt.Errorf("failed to poll device code: %w", err)
+	}
+
+	return code, nil
+}
+
+// SlowDownDeviceCode sets the polling interval of the device.
+func (u *PostgresRepository) SlowDownDeviceCode(deviceCodeHash string, interval time.Duration) error {
+	stmt := `UPDATE oauth_device_codes SET interval_seconds = $1 WHERE device_code_hash = $2`
+
+	_, err := u.execQuery(context.Background(), stmt, int(interval.Seconds()), deviceCodeHash)
+
+	return err
+}
+
+// DeleteDeviceCode removes a device authorization once it is finished. It
+// reports false if it has already been removed, so tokens are issued once.
+func (u *PostgresRepository) DeleteDeviceCode(deviceCodeHash string) (bool, error) {
+	result, err := u.execQuery(context.Background(), `DELETE FROM oauth_device_codes WHERE device_code_hash = $1`, deviceCodeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete device code: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func scanDeviceCode(row *sql.Row) (*calltypes.OAuthDeviceCode, error) {
+	var (
+		code         calltypes.OAuthDeviceCode
+		interval     int
+		lastPolledAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&code.DeviceCodeHash,
+		&code.UserCode,
+		&code.ClientID,
+		&code.Scope,
+		&code.UserID,
+		&code.Denied,
+		&interval,
+		&lastPolledAt,
+		&code.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	code.Interval = time.Duration(interval) * time.Second
+	code.LastPolledAt = lastPolledAt.Time
+
+	return &code, nil
+}
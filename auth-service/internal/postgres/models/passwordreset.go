@@ -0,0 +1,80 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreatePasswordReset stores a pending password reset.
+func (u *PostgresRepository) CreatePasswordReset(reset calltypes.PasswordReset) error {
+	stmt := `INSERT INTO password_resets (token_hash, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4)`
+
+	_, err := u.execQuery(context.Background(), stmt, reset.TokenHash, reset.UserID, reset.ExpiresAt, time.Now())
+
+	return err
+}
+
+// TakePasswordReset removes the password reset and returns it, so that each
+// token is used once. Expired resets are removed along the way.
+func (u *PostgresRepository) TakePasswordReset(tokenHash string) (*calltypes.PasswordReset, error) {
+	var reset calltypes.PasswordReset
+
+	stmt := `DELETE FROM password_resets WHERE token_hash = $1 RETURNING token_hash, user_id, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, tokenHash).Scan(
+		&reset.TokenHash,
+		&reset.UserID,
+		&reset.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidPasswordReset
+		}
+
+		return nil, fmt.Errorf("failed to fetch password reset: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM password_resets WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &reset, nil
+}
+
+// SetPassword replaces the password hash of the user and drops the other
+// pending resets of the user.
+func (u *PostgresRepository) SetPassword(userID int, hashedPassword string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt := `UPDATE medods SET password = $1, updated_at = $2 WHERE id = $3`
+
+	if _, err := tx.ExecContext(ctx, stmt, hashedPassword, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM password_resets WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete password resets: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit password: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"auth-service/pkg/errormsg"
+	"context"
+	"time"
+)
+
+// UpdateProfile replaces names of the user if the user still has the given
+// version, increasing the version.
+func (u *PostgresRepository) UpdateProfile(id, version int, firstName, lastName string) error {
+	stmt := `UPDATE medods SET first_name = $1, last_name = $2, version = version + 1, updated_at = $3
+             WHERE id = $4 AND version = $5`
+
+	result, err := u.execQuery(context.Background(), stmt, firstName, lastName, time.Now(), id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err //nolint: wrapcheck
+	}
+
+	exists, err := u.UserExists(id)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errormsg.ErrUserNotFound
+	}
+
+	return errormsg.ErrVersionConflict
+}
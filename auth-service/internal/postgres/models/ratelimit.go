@@ -0,0 +1,43 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeRateLimitToken refills the bucket for the elapsed time and takes one token
+// from it in a single statement, so concurrent replicas never overdraw a bucket.
+// It returns the tokens left and whether a token was taken.
+func (u *PostgresRepository) TakeRateLimitToken(key string, capacity, refillPerSecond float64) (float64, bool, error) {
+	var (
+		tokens  float64
+		allowed bool
+	)
+
+	stmt := `INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+             VALUES ($1, $2 - 1, TRUE, $3)
+             ON CONFLICT (key) DO UPDATE SET
+             tokens = LEAST($2, b.tokens + EXTRACT(EPOCH FROM ($3 - b.updated_at)) * $4)
+                      - CASE WHEN LEAST($2, b.tokens + EXTRACT(EPOCH FROM ($3 - b.updated_at)) * $4) >= 1
+                        THEN 1 ELSE 0 END,
+             allowed = LEAST($2, b.tokens + EXTRACT(EPOCH FROM ($3 - b.updated_at)) * $4) >= 1,
+             updated_at = $3
+             RETURNING tokens, allowed`
+
+	err := u.queryRow(context.Background(), stmt, key, capacity, time.Now(), refillPerSecond).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return tokens, allowed, nil
+}
+
+// DeleteStaleRateLimitBuckets removes buckets not touched for the given time.
+func (u *PostgresRepository) DeleteStaleRateLimitBuckets(olderThan time.Duration) error {
+	stmt := `DELETE FROM rate_limit_buckets WHERE updated_at < $1`
+
+	_, err := u.execQuery(context.Background(), stmt, time.Now().Add(-olderThan))
+
+	return err
+}
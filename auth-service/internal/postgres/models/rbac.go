@@ -0,0 +1,162 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const roleQuery = `SELECT r.name, r.description, COALESCE(string_agg(p.permission, ' ' ORDER BY p.permission), '')
+             FROM roles r LEFT JOIN role_permissions p ON p.role = r.name`
+
+// UpsertRole creates the role or replaces its description and permissions.
+func (u *PostgresRepository) UpsertRole(role calltypes.Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt := `INSERT INTO roles (name, description, created_at) VALUES ($1, $2, $3)
+             ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description`
+
+	if _, err := tx.ExecContext(ctx, stmt, role.Name, role.Description, time.Now()); err != nil {
+		return fmt.Errorf("failed to upsert role: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role = $1`, role.Name); err != nil {
+		return fmt.Errorf("failed to delete role permissions: %w", err)
+	}
+
+	for _, permission := range role.Permissions {
+		stmt := `INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+		if _, err := tx.ExecContext(ctx, stmt, role.Name, permission); err != nil {
+			return fmt.Errorf("failed to insert role permission: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+
+	return nil
+}
+
+// GetRole returns the role with its permissions.
+func (u *PostgresRepository) GetRole(name string) (*calltypes.Role, error) {
+	role, err := scanRole(u.queryRow(context.Background(), roleQuery+` WHERE r.name = $1 GROUP BY r.name`, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrRoleNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch role: %w", err)
+	}
+
+	return role, nil
+}
+
+// GetRoles returns all roles ordered by name.
+func (u *PostgresRepository) GetRoles() ([]*calltypes.Role, error) {
+	return u.queryRoles(roleQuery + ` GROUP BY r.name ORDER BY r.name`)
+}
+
+// DeleteRole removes the role together with its assignments.
+func (u *PostgresRepository) DeleteRole(name string) error {
+	result, err := u.execQuery(context.Background(), `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// AssignRole assigns the role to the user. Assigning it again has no effect.
+func (u *PostgresRepository) AssignRole(userID int, role string) error {
+	stmt := `INSERT INTO user_roles (user_id, role, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	_, err := u.execQuery(context.Background(), stmt, userID, role, time.Now())
+
+	return err
+}
+
+// UnassignRole takes the role away from the user.
+func (u *PostgresRepository) UnassignRole(userID int, role string) error {
+	result, err := u.execQuery(context.Background(), `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, userID, role)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// GetUserRoles returns roles assigned to the user ordered by name.
+func (u *PostgresRepository) GetUserRoles(userID int) ([]*calltypes.Role, error) {
+	stmt := roleQuery + ` WHERE r.name IN (SELECT role FROM user_roles WHERE user_id = $1) GROUP BY r.name ORDER BY r.name`
+
+	return u.queryRoles(stmt, userID)
+}
+
+func (u *PostgresRepository) queryRoles(stmt string, args ...interface{}) ([]*calltypes.Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	rows, err := u.Conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*calltypes.Role
+
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func scanRole(row rowScanner) (*calltypes.Role, error) {
+	var (
+		role        calltypes.Role
+		permissions string
+	)
+
+	if err := row.Scan(&role.Name, &role.Description, &permissions); err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	role.Permissions = strings.Fields(permissions)
+
+	return &role, nil
+}
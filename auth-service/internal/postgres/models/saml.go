@@ -0,0 +1,53 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateSAMLRequest stores an AuthnRequest awaiting its response.
+func (u *PostgresRepository) CreateSAMLRequest(request calltypes.SAMLRequest) error {
+	stmt := `INSERT INTO saml_requests (id, provider, expires_at, created_at)
+             VALUES ($1, $2, $3, $4)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		request.ID,
+		request.Provider,
+		request.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeSAMLRequest removes the AuthnRequest and returns it, so that each
+// response is accepted once. Expired requests are removed along the way.
+func (u *PostgresRepository) TakeSAMLRequest(id string) (*calltypes.SAMLRequest, error) {
+	var request calltypes.SAMLRequest
+
+	stmt := `DELETE FROM saml_requests WHERE id = $1 RETURNING id, provider, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, id).Scan(
+		&request.ID,
+		&request.Provider,
+		&request.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidFederatedLogin
+		}
+
+		return nil, fmt.Errorf("failed to fetch SAML request: %w", err)
+	}
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM saml_requests WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// userFilterCondition matches the query against the email and names and
+// optionally filters by the status.
+const userFilterCondition = `($1 = '' OR email ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1)
+             AND ($2 = '' OR status = $2)`
+
+// SearchUsers returns a page of users matching the filter ordered by ID and
+// the number of all matching users.
+func (u *PostgresRepository) SearchUsers(filter calltypes.UserFilter) ([]*calltypes.User, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	var pattern string
+	if filter.Query != "" {
+		pattern = "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Query) + "%"
+	}
+
+	var total int
+
+	err := u.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM medods WHERE `+userFilterCondition, pattern, filter.Status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	stmt := `SELECT id, email, email_verified, first_name, last_name, status, created_at, updated_at
+             FROM medods WHERE ` + userFilterCondition + ` ORDER BY id LIMIT $3 OFFSET $4`
+
+	rows, err := u.Conn.QueryContext(ctx, stmt, pattern, filter.Status, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*calltypes.User{}
+
+	for rows.Next() {
+		var user calltypes.User
+
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.EmailVerified,
+			&user.FirstName,
+			&user.LastName,
+			&user.Status,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, errormsg.ErrScanUser
+		}
+
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// SetUserStatus changes the status of the user. Leaving the active status also
+// revokes sessions of the user, as RevokeSessions does.
+func (u *PostgresRepository) SetUserStatus(id int, status calltypes.UserStatus) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.ExecContext(ctx, `UPDATE medods SET status = $1, version = version + 1, updated_at = $2 WHERE id = $3`, status, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrUserNotFound
+	}
+
+	if status != calltypes.UserStatusActive {
+		if err := revokeSessions(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user status: %w", err)
+	}
+
+	return nil
+}
+
+// UserStatus returns the status of the user.
+func (u *PostgresRepository) UserStatus(id int) (calltypes.UserStatus, error) {
+	var status calltypes.UserStatus
+
+	err := u.queryRow(context.Background(), `SELECT status FROM medods WHERE id = $1`, id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errormsg.ErrUserNotFound
+		}
+
+		return "", fmt.Errorf("failed to fetch user status: %w", err)
+	}
+
+	return status, nil
+}
+
+// SessionState returns the status of the user and when sessions of the user
+// were last revoked.
+func (u *PostgresRepository) SessionState(id int) (*calltypes.SessionState, error) {
+	var (
+		state     calltypes.SessionState
+		revokedAt sql.NullTime
+	)
+
+	err := u.queryRow(context.Background(), `SELECT status, sessions_revoked_at FROM medods WHERE id = $1`, id).
+		Scan(&state.Status, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch session state: %w", err)
+	}
+
+	state.RevokedAt = revokedAt.Time
+
+	return &state, nil
+}
+
+// RevokeSessions drops the refresh token of the user and the refresh tokens of
+// OAuth clients acting for the user and records the time, so that access tokens
+// issued until then are refused as well.
+func (u *PostgresRepository) RevokeSessions(userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	tx, err := u.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := revokeSessions(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session revocation: %w", err)
+	}
+
+	return nil
+}
+
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID int) error {
+	stmt := `UPDATE medods SET refresh_token = NULL, refresh_token_expires = NULL, sessions_revoked_at = $1 WHERE id = $2`
+
+	if _, err := tx.ExecContext(ctx, stmt, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM oauth_refresh_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to revoke OAuth refresh tokens: %w", err)
+	}
+
+	return nil
+}
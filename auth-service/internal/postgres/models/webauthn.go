@@ -0,0 +1,210 @@
+package models
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreateWebAuthnSession stores a pending WebAuthn ceremony.
+func (u *PostgresRepository) CreateWebAuthnSession(session calltypes.WebAuthnSession) error {
+	stmt := `INSERT INTO webauthn_sessions (id, user_id, ceremony, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := u.execQuery(context.Background(), stmt,
+		session.ID,
+		nullInt(session.UserID),
+		session.Ceremony,
+		session.ExpiresAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+// TakeWebAuthnSession removes the ceremony and returns it, so that each
+// challenge is answered once. Expired ceremonies are removed along the way.
+func (u *PostgresRepository) TakeWebAuthnSession(id string) (*calltypes.WebAuthnSession, error) {
+	var (
+		session calltypes.WebAuthnSession
+		userID  sql.NullInt64
+	)
+
+	stmt := `DELETE FROM webauthn_sessions WHERE id = $1 RETURNING id, user_id, ceremony, expires_at`
+
+	err := u.queryRow(context.Background(), stmt, id).Scan(&session.ID, &userID, &session.Ceremony, &session.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrInvalidCeremony
+		}
+
+		return nil, fmt.Errorf("failed to fetch WebAuthn session: %w", err)
+	}
+
+	session.UserID = int(userID.Int64)
+
+	if _, err := u.execQuery(context.Background(), `DELETE FROM webauthn_sessions WHERE expires_at < $1`, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// CreateWebAuthnCredential stores a new passkey.
+func (u *PostgresRepository) CreateWebAuthnCredential(credential calltypes.WebAuthnCredential) error {
+	stmt := `INSERT INTO webauthn_credentials
+             (id, user_id, name, public_key, sign_count, aaguid, transports, backup_eligible, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+             ON CONFLICT (id) DO NOTHING`
+
+	result, err := u.execQuery(context.Background(), stmt,
+		credential.ID,
+		credential.UserID,
+		credential.Name,
+		credential.PublicKey,
+		int64(credential.SignCount),
+		credential.AAGUID,
+		strings.Join(credential.Transports, ","),
+		credential.BackupEligible,
+		credential.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrCredentialExists
+	}
+
+	return nil
+}
+
+// GetWebAuthnCredential returns a passkey by its credential ID.
+func (u *PostgresRepository) GetWebAuthnCredential(id string) (*calltypes.WebAuthnCredential, error) {
+	stmt := `SELECT id, user_id, name, public_key, sign_count, aaguid, transports, backup_eligible, created_at, last_used_at
+             FROM webauthn_credentials WHERE id = $1`
+
+	credential, err := scanWebAuthnCredential(u.queryRow(context.Background(), stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errormsg.ErrCredentialNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch WebAuthn credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// GetWebAuthnCredentials returns passkeys of the user, oldest first.
+func (u *PostgresRepository) GetWebAuthnCredentials(userID int) ([]*calltypes.WebAuthnCredential, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DbTimeout)
+	defer cancel()
+
+	stmt := `SELECT id, user_id, name, public_key, sign_count, aaguid, transports, backup_eligible, created_at, last_used_at
+             FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := u.Conn.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch WebAuthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []*calltypes.WebAuthnCredential
+
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan WebAuthn credential: %w", err)
+		}
+
+		credentials = append(credentials, credential)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch WebAuthn credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// UseWebAuthnCredential records the sign counter of an accepted assertion. It
+// reports false if the stored counter is not lower, which means the assertion
+// is replayed or the credential is cloned. Counters staying at zero are allowed.
+func (u *PostgresRepository) UseWebAuthnCredential(id string, signCount uint32, usedAt time.Time) (bool, error) {
+	stmt := `UPDATE webauthn_credentials SET sign_count = $1, last_used_at = $2
+             WHERE id = $3 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))`
+
+	result, err := u.execQuery(context.Background(), stmt, int64(signCount), usedAt, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record WebAuthn sign counter: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// DeleteWebAuthnCredential removes a passkey of the user.
+func (u *PostgresRepository) DeleteWebAuthnCredential(userID int, id string) error {
+	result, err := u.execQuery(context.Background(), `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errormsg.ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebAuthnCredential(row rowScanner) (*calltypes.WebAuthnCredential, error) {
+	var (
+		credential calltypes.WebAuthnCredential
+		signCount  int64
+		transports string
+		lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&credential.ID,
+		&credential.UserID,
+		&credential.Name,
+		&credential.PublicKey,
+		&signCount,
+		&credential.AAGUID,
+		&transports,
+		&credential.BackupEligible,
+		&credential.CreatedAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+
+	credential.SignCount = uint32(signCount) //nolint: gosec
+
+	if transports != "" {
+		credential.Transports = strings.Split(transports, ",")
+	}
+
+	if lastUsedAt.Valid {
+		credential.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &credential, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"auth-service/api/calltypes"
+	"time"
 )
 
 type Repository interface {
@@ -10,9 +11,193 @@ type Repository interface {
 	GetOne(id int) (*calltypes.User, error)
 	Update(user calltypes.User) error
 	Insert(user calltypes.User) (int, error)
+	Import(user calltypes.User) (int, error)
 	PasswordMatches(plainText string, user calltypes.User) (bool, error)
 	EmailCheck(email string) (*calltypes.User, error)
 	StoreRefreshToken(id int, hashedToken string) error
 	ValidateRefreshToken(rawToken, clientIP string, id int) (bool, error)
 	UpdateRefreshToken(id int, rawToken string) error
+	MarkEmailVerified(id int) error
+	UserStatus(id int) (calltypes.UserStatus, error)
+	SessionState(id int) (*calltypes.SessionState, error)
+}
+
+// UserReader is the part of Repository used by features that only read users.
+type UserReader interface {
+	GetOne(id int) (*calltypes.User, error)
+	SessionState(id int) (*calltypes.SessionState, error)
+}
+
+// UserProvisioner is the part of Repository used to find, link and create users
+// signing in through external identity providers.
+type UserProvisioner interface {
+	GetOne(id int) (*calltypes.User, error)
+	GetByEmail(email string) (*calltypes.User, error)
+	InsertExternal(user calltypes.User) (int, error)
+}
+
+// LoginAttemptRepository stores failed login counters. Scope is either "account"
+// or "ip" and key is the email or the client IP accordingly.
+type LoginAttemptRepository interface {
+	GetLoginFailures(scope, key string) (*calltypes.LoginFailures, error)
+	RecordLoginFailure(scope, key string, window time.Duration) (*calltypes.LoginFailures, error)
+	LockLogin(scope, key string, until time.Time) error
+	ResetLoginFailures(scope, key string) error
+}
+
+// RateLimitRepository stores token buckets shared between service replicas.
+type RateLimitRepository interface {
+	TakeRateLimitToken(key string, capacity, refillPerSecond float64) (float64, bool, error)
+	DeleteStaleRateLimitBuckets(olderThan time.Duration) error
+}
+
+// MFARepository stores TOTP enrollments and pending MFA login challenges.
+type MFARepository interface {
+	GetTOTP(userID int) (*calltypes.TOTP, error)
+	SaveTOTP(userID int, encryptedSecret string) error
+	ConfirmTOTP(userID int, step int64) error
+	SavePendingTOTP(userID int, encryptedSecret string) error
+	ReplaceTOTP(userID int, step int64) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+	CreateMFAChallenge(challenge calltypes.MFAChallenge) error
+	ClaimMFAChallengeAttempt(id, ip string, maxAttempts int, now time.Time) (*calltypes.MFAChallenge, error)
+	DeleteMFAChallenge(id string) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	ConsumeRecoveryCode(userID int, codeHash string) (bool, error)
+	DeleteMFA(userID int) error
+}
+
+// AuditRepository stores the audit trail.
+type AuditRepository interface {
+	InsertAuditEvent(event calltypes.AuditEvent) error
+}
+
+// WebAuthnRepository stores passkeys and pending WebAuthn ceremonies.
+type WebAuthnRepository interface {
+	CreateWebAuthnSession(session calltypes.WebAuthnSession) error
+	TakeWebAuthnSession(id string) (*calltypes.WebAuthnSession, error)
+	CreateWebAuthnCredential(credential calltypes.WebAuthnCredential) error
+	GetWebAuthnCredential(id string) (*calltypes.WebAuthnCredential, error)
+	GetWebAuthnCredentials(userID int) ([]*calltypes.WebAuthnCredential, error)
+	UseWebAuthnCredential(id string, signCount uint32, usedAt time.Time) (bool, error)
+	DeleteWebAuthnCredential(userID int, id string) error
+}
+
+// EmailLoginRepository stores pending passwordless logins.
+type EmailLoginRepository interface {
+	CreateEmailLogin(login calltypes.EmailLogin) error
+	GetEmailLogin(id string) (*calltypes.EmailLogin, error)
+	ClaimEmailLoginAttempt(id string, maxAttempts int, now time.Time) error
+	ConsumeEmailLogin(id string) (bool, error)
+}
+
+// OAuthRepository stores OAuth clients, authorization codes, refresh tokens and
+// device authorizations.
+type OAuthRepository interface {
+	CreateOAuthClient(client calltypes.OAuthClient) error
+	GetOAuthClient(id string) (*calltypes.OAuthClient, error)
+	CreateAuthorizationCode(code calltypes.OAuthAuthorizationCode) error
+	TakeAuthorizationCode(codeHash string) (*calltypes.OAuthAuthorizationCode, error)
+	CreateOAuthRefreshToken(token calltypes.OAuthRefreshToken) error
+	TakeOAuthRefreshToken(tokenHash string) (*calltypes.OAuthRefreshToken, error)
+	UseClientAssertion(clientID, jti string, expiresAt time.Time) (bool, error)
+	CreateDeviceCode(code calltypes.OAuthDeviceCode) error
+	GetDeviceCode(userCode string) (*calltypes.OAuthDeviceCode, error)
+	DecideDeviceCode(userCode string, userID int, approved bool) (bool, error)
+	PollDeviceCode(deviceCodeHash string, polledAt time.Time) (*calltypes.OAuthDeviceCode, error)
+	SlowDownDeviceCode(deviceCodeHash string, interval time.Duration) error
+	DeleteDeviceCode(deviceCodeHash string) (bool, error)
+}
+
+// FederationRepository stores pending logins through external identity
+// providers and the identities linked to users.
+type FederationRepository interface {
+	CreateFederatedLogin(login calltypes.FederatedLogin) error
+	TakeFederatedLogin(stateHash string) (*calltypes.FederatedLogin, error)
+	GetFederatedIdentity(provider, subject string) (*calltypes.FederatedIdentity, error)
+	LinkFederatedIdentity(identity calltypes.FederatedIdentity) error
+	TouchFederatedIdentity(provider, subject, email string, at time.Time) error
+}
+
+// SAMLRepository stores AuthnRequests awaiting the response of a SAML identity
+// provider.
+type SAMLRepository interface {
+	CreateSAMLRequest(request calltypes.SAMLRequest) error
+	TakeSAMLRequest(id string) (*calltypes.SAMLRequest, error)
+}
+
+// APIKeyRepository stores API keys.
+type APIKeyRepository interface {
+	CreateAPIKey(key calltypes.APIKey) error
+	GetAPIKey(id string) (*calltypes.APIKey, error)
+	GetAPIKeys(userID int) ([]*calltypes.APIKey, error)
+	RevokeAPIKey(id string, at time.Time) error
+	TouchAPIKey(id string, at time.Time) error
+}
+
+// RoleRepository stores roles, their permissions and role assignments.
+type RoleRepository interface {
+	UpsertRole(role calltypes.Role) error
+	GetRole(name string) (*calltypes.Role, error)
+	GetRoles() ([]*calltypes.Role, error)
+	DeleteRole(name string) error
+	AssignRole(userID int, role string) error
+	UnassignRole(userID int, role string) error
+	GetUserRoles(userID int) ([]*calltypes.Role, error)
+}
+
+// UserAdminRepository is the part of Repository used to manage users on behalf
+// of administrators.
+type UserAdminRepository interface {
+	GetOne(id int) (*calltypes.User, error)
+	GetByEmail(email string) (*calltypes.User, error)
+	Insert(user calltypes.User) (int, error)
+	Update(user calltypes.User) error
+	SearchUsers(filter calltypes.UserFilter) ([]*calltypes.User, int, error)
+	SetUserStatus(id int, status calltypes.UserStatus) error
+	RevokeSessions(userID int) error
+}
+
+// ProfileRepository is the part of Repository used by users to change their own
+// profile.
+type ProfileRepository interface {
+	GetOne(id int) (*calltypes.User, error)
+	UpdateProfile(id, version int, firstName, lastName string) error
+}
+
+// PasswordResetRepository stores pending password resets.
+type PasswordResetRepository interface {
+	CreatePasswordReset(reset calltypes.PasswordReset) error
+	TakePasswordReset(tokenHash string) (*calltypes.PasswordReset, error)
+	SetPassword(userID int, hashedPassword string) error
+}
+
+// EmailChangeRepository stores pending email changes and applies them.
+type EmailChangeRepository interface {
+	GetByEmail(email string) (*calltypes.User, error)
+	CreateEmailChange(change calltypes.EmailChange) error
+	TakeEmailChange(tokenHash string) (*calltypes.EmailChange, error)
+	CancelEmailChange(cancelHash string) (*calltypes.EmailChange, error)
+	ApplyEmailChange(userID int, newEmail string) error
+}
+
+// AccountDeletionRepository stores scheduled account deletions and erases the
+// accounts when they are due.
+type AccountDeletionRepository interface {
+	CreateAccountDeletion(deletion calltypes.AccountDeletion) error
+	GetAccountDeletion(userID int) (*calltypes.AccountDeletion, error)
+	DeleteAccountDeletion(userID int) error
+	GetDueAccountDeletions(at time.Time, limit int) ([]*calltypes.AccountDeletion, error)
+	EraseUser(deletion calltypes.AccountDeletion, erasedAt time.Time) error
+}
+
+// DataExportRepository reads the personal data of users and stores their data
+// exports.
+type DataExportRepository interface {
+	GetPersonalData(userID int) (*calltypes.PersonalData, error)
+	CreateDataExport(export calltypes.DataExport) error
+	GetDataExport(id string) (*calltypes.DataExport, error)
+	ClaimDataExport() (*calltypes.DataExport, error)
+	FinishDataExport(export calltypes.DataExport) error
+	TakeExpiredDataExports(at time.Time) ([]*calltypes.DataExport, error)
 }
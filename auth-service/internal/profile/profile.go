@@ -0,0 +1,100 @@
+// Package profile lets users change their own profile. Changes are JSON merge
+// patches (RFC 7396) applied with optimistic concurrency: every change names
+// the version of the user it was based on and fails if the user has changed
+// since, so that two clients do not overwrite each other.
+package profile
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const maxNameLen = 100
+
+// Manager applies profile changes.
+type Manager struct {
+	repo repository.ProfileRepository
+}
+
+func NewManager(repo repository.ProfileRepository) *Manager {
+	return &Manager{repo: repo}
+}
+
+// Patch applies the merge patch to the profile of the user and returns the
+// changed user. version is the version of the user the patch was based on,
+// zero matching any version. Members the patch leaves out are kept and null
+// members are removed; only firstName and lastName can be changed.
+func (m *Manager) Patch(id, version int, patch map[string]json.RawMessage) (*calltypes.User, error) {
+	user, err := m.repo.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		version = user.Version
+	}
+
+	if user.Version != version {
+		return nil, errormsg.ErrVersionConflict
+	}
+
+	for member, value := range patch {
+		var target *string
+
+		switch member {
+		case "firstName":
+			target = &user.FirstName
+		case "lastName":
+			target = &user.LastName
+		default:
+			return nil, fmt.Errorf("%w: %s cannot be changed", errormsg.ErrInvalidProfile, member)
+		}
+
+		name, err := patchName(member, value)
+		if err != nil {
+			return nil, err
+		}
+
+		*target = name
+	}
+
+	if len(patch) == 0 {
+		return user, nil
+	}
+
+	if err := m.repo.UpdateProfile(id, version, user.FirstName, user.LastName); err != nil {
+		return nil, err
+	}
+
+	return m.repo.GetOne(id)
+}
+
+// patchName reads a name from the patch member, null being the empty name.
+func patchName(member string, value json.RawMessage) (string, error) {
+	var name *string
+
+	if err := json.Unmarshal(value, &name); err != nil {
+		return "", fmt.Errorf("%w: %s must be a string or null", errormsg.ErrInvalidProfile, member)
+	}
+
+	if name == nil {
+		return "", nil
+	}
+
+	trimmed := strings.TrimSpace(*name)
+
+	if len(trimmed) > maxNameLen {
+		return "", fmt.Errorf("%w: %s must not exceed %d bytes", errormsg.ErrInvalidProfile, member, maxNameLen)
+	}
+
+	if strings.IndexFunc(trimmed, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: %s must not contain control characters", errormsg.ErrInvalidProfile, member)
+	}
+
+	return trimmed, nil
+}
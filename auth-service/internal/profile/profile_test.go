@@ -0,0 +1,161 @@
+package profile_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/profile"
+	"auth-service/pkg/errormsg"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps one user in memory.
+type memoryRepository struct {
+	mu   sync.Mutex
+	user calltypes.User
+}
+
+func (m *memoryRepository) GetOne(id int) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id != m.user.ID {
+		return nil, errormsg.ErrUserNotFound
+	}
+
+	user := m.user
+
+	return &user, nil
+}
+
+func (m *memoryRepository) UpdateProfile(id, version int, firstName, lastName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id != m.user.ID {
+		return errormsg.ErrUserNotFound
+	}
+
+	if version != m.user.Version {
+		return errormsg.ErrVersionConflict
+	}
+
+	m.user.FirstName, m.user.LastName = firstName, lastName
+	m.user.Version++
+
+	return nil
+}
+
+func newRepository() *memoryRepository {
+	return &memoryRepository{user: calltypes.User{ID: 4, FirstName: "Ann", LastName: "Lee", Version: 3}}
+}
+
+func mergePatch(t *testing.T, raw string) map[string]json.RawMessage {
+	t.Helper()
+
+	var patch map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(raw), &patch))
+
+	return patch
+}
+
+func TestManager_Patch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		version       int
+		patch         string
+		wantFirstName string
+		wantLastName  string
+		wantErr       error
+	}{
+		{
+			name:          "change one member",
+			version:       3,
+			patch:         `{"firstName": " Anna "}`,
+			wantFirstName: "Anna",
+			wantLastName:  "Lee",
+		},
+		{
+			name:          "null removes member",
+			version:       3,
+			patch:         `{"lastName": null}`,
+			wantFirstName: "Ann",
+		},
+		{
+			name:          "any version",
+			patch:         `{"firstName": "Anna", "lastName": "Park"}`,
+			wantFirstName: "Anna",
+			wantLastName:  "Park",
+		},
+		{
+			name:    "stale version",
+			version: 2,
+			patch:   `{"firstName": "Anna"}`,
+			wantErr: errormsg.ErrVersionConflict,
+		},
+		{
+			name:    "read-only member",
+			version: 3,
+			patch:   `{"email": "anna@example.com"}`,
+			wantErr: errormsg.ErrInvalidProfile,
+		},
+		{
+			name:    "not a string",
+			version: 3,
+			patch:   `{"firstName": 7}`,
+			wantErr: errormsg.ErrInvalidProfile,
+		},
+		{
+			name:    "too long",
+			version: 3,
+			patch:   `{"lastName": "` + strings.Repeat("a", 101) + `"}`,
+			wantErr: errormsg.ErrInvalidProfile,
+		},
+		{
+			name:    "control characters",
+			version: 3,
+			patch:   `{"lastName": "Lee\u0000"}`,
+			wantErr: errormsg.ErrInvalidProfile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepository()
+			manager := profile.NewManager(repo)
+
+			user, err := manager.Patch(4, tt.version, mergePatch(t, tt.patch))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, 3, repo.user.Version, "failed patch must not change the user")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFirstName, user.FirstName)
+			assert.Equal(t, tt.wantLastName, user.LastName)
+			assert.Equal(t, 4, user.Version)
+		})
+	}
+}
+
+func TestManager_PatchOverwriteIsRejected(t *testing.T) {
+	t.Parallel()
+
+	manager := profile.NewManager(newRepository())
+
+	_, err := manager.Patch(4, 3, mergePatch(t, `{"firstName": "Anna"}`))
+	require.NoError(t, err)
+
+	_, err = manager.Patch(4, 3, mergePatch(t, `{"firstName": "Annie"}`))
+	require.ErrorIs(t, err, errormsg.ErrVersionConflict, "second client based on the same version must not overwrite")
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rule      Rule
+}
+
+// MemoryStore keeps buckets in process memory. It suits a single replica;
+// use PostgresStore to share counters between replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take removes one token from the bucket, if there is one.
+func (m *MemoryStore) Take(key string, rule Rule) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	current, ok := m.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(rule.Burst), updatedAt: now}
+		m.buckets[key] = current
+	}
+
+	current.rule = rule
+	current.tokens = refill(rule, current.tokens, now.Sub(current.updatedAt))
+	current.updatedAt = now
+
+	allowed := current.tokens >= 1
+	if allowed {
+		current.tokens--
+	}
+
+	return newResult(rule, current.tokens, allowed), nil
+}
+
+// sweep drops buckets that have refilled completely, since a fresh bucket is
+// equivalent to them.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+
+	m.lastSweep = now
+
+	for key, stored := range m.buckets {
+		if refill(stored.rule, stored.tokens, now.Sub(stored.updatedAt)) >= float64(stored.rule.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
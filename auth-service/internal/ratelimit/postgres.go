@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"auth-service/internal/postgres/repository"
+	"log"
+	"sync"
+	"time"
+)
+
+// PostgresStore keeps buckets in Postgres so that several replicas share counters.
+type PostgresStore struct {
+	repo      repository.RateLimitRepository
+	mu        sync.Mutex
+	lastSweep time.Time
+	staleAge  time.Duration
+}
+
+func NewPostgresStore(repo repository.RateLimitRepository, staleAge time.Duration) *PostgresStore {
+	return &PostgresStore{
+		repo:     repo,
+		staleAge: staleAge,
+	}
+}
+
+// Take removes one token from the bucket, if there is one.
+func (p *PostgresStore) Take(key string, rule Rule) (Result, error) {
+	p.sweep()
+
+	tokens, allowed, err := p.repo.TakeRateLimitToken(key, float64(rule.Burst), rule.RefillRate())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(rule, tokens, allowed), nil
+}
+
+// sweep periodically removes buckets that have not been used for a while.
+func (p *PostgresStore) sweep() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.lastSweep) < p.staleAge {
+		return
+	}
+
+	p.lastSweep = time.Now()
+
+	if err := p.repo.DeleteStaleRateLimitBuckets(p.staleAge); err != nil {
+		log.Println("failed to delete stale rate limit buckets: ", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package ratelimit implements token bucket rate limiting with in-memory and
+// Postgres-backed stores.
+package ratelimit
+
+import (
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule describes a token bucket holding up to Burst tokens and refilled with
+// Limit tokens every Period.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// ParseRule parses rules written as "limit/period[:burst]", e.g. "10/1m" or
+// "10/1m:20". Burst defaults to limit.
+func ParseRule(value string) (Rule, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+
+	limit, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("%w: %q", errormsg.ErrInvalidRateLimit, value)
+	}
+
+	var (
+		rule Rule
+		err  error
+	)
+
+	if rule.Limit, err = strconv.Atoi(limit); err != nil || rule.Limit <= 0 {
+		return Rule{}, fmt.Errorf("%w: %q", errormsg.ErrInvalidRateLimit, value)
+	}
+
+	if rule.Period, err = time.ParseDuration(period); err != nil || rule.Period <= 0 {
+		return Rule{}, fmt.Errorf("%w: %q", errormsg.ErrInvalidRateLimit, value)
+	}
+
+	rule.Burst = rule.Limit
+
+	if hasBurst {
+		if rule.Burst, err = strconv.Atoi(burst); err != nil || rule.Burst <= 0 {
+			return Rule{}, fmt.Errorf("%w: %q", errormsg.ErrInvalidRateLimit, value)
+		}
+	}
+
+	return rule, nil
+}
+
+// RefillRate returns the number of tokens added per second.
+func (r Rule) RefillRate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store takes tokens from buckets identified by key.
+type Store interface {
+	Take(key string, rule Rule) (Result, error)
+}
+
+// newResult builds Result from the tokens left in the bucket after the attempt.
+func newResult(rule Rule, tokens float64, allowed bool) Result {
+	rate := rule.RefillRate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     rule.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(rule.Burst) - tokens) / rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+// refill returns tokens in the bucket after elapsed time, capped by burst.
+func refill(rule Rule, tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(rule.Burst), tokens+elapsed.Seconds()*rule.RefillRate())
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(seconds, 0) * float64(time.Second))
+}
@@ -0,0 +1,91 @@
+package ratelimit_test
+
+import (
+	"auth-service/internal/ratelimit"
+	"auth-service/pkg/errormsg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    string
+		expected ratelimit.Rule
+		wantErr  bool
+	}{
+		{value: "10/1m", expected: ratelimit.Rule{Limit: 10, Period: time.Minute, Burst: 10}},
+		{value: "5/1s:20", expected: ratelimit.Rule{Limit: 5, Period: time.Second, Burst: 20}},
+		{value: "10", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "10/soon", wantErr: true},
+		{value: "10/1m:-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := ratelimit.ParseRule(tt.value)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errormsg.ErrInvalidRateLimit)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rule)
+		})
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	t.Parallel()
+
+	store := ratelimit.NewMemoryStore()
+	rule := ratelimit.Rule{Limit: 1, Period: time.Minute, Burst: 3}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take("client", rule)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := store.Take("client", rule)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.InDelta(t, time.Minute.Seconds(), result.RetryAfter.Seconds(), 1)
+	assert.InDelta(t, (3 * time.Minute).Seconds(), result.Reset.Seconds(), 1)
+
+	other, err := store.Take("other client", rule)
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+}
+
+func TestMemoryStore_Refill(t *testing.T) {
+	t.Parallel()
+
+	store := ratelimit.NewMemoryStore()
+	rule := ratelimit.Rule{Limit: 1, Period: 50 * time.Millisecond, Burst: 1}
+
+	result, err := store.Take("client", rule)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take("client", rule)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, err = store.Take("client", rule)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,129 @@
+// Package rbac manages roles and the permissions they grant. A permission names
+// a resource and an action, e.g. users:read; routes demand permissions and
+// access tokens carry the roles and permissions of their user.
+package rbac
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	maxDescriptionLen = 255
+	maxPermissions    = 50
+)
+
+var (
+	roleName   = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+	permission = regexp.MustCompile(`^[a-z0-9_-]{1,60}:[a-z0-9_*-]{1,60}$`)
+)
+
+// Manager manages roles and their assignments to users.
+type Manager struct {
+	repo repository.RoleRepository
+}
+
+func NewManager(repo repository.RoleRepository) *Manager {
+	return &Manager{repo: repo}
+}
+
+// Roles returns all roles.
+func (m *Manager) Roles() ([]*calltypes.Role, error) {
+	return m.repo.GetRoles()
+}
+
+// SaveRole creates the role or replaces its description and permissions.
+func (m *Manager) SaveRole(name string, request calltypes.RoleRequest) (*calltypes.Role, error) {
+	if !roleName.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must consist of 1 to 64 lowercase letters, digits, '_' and '-'", errormsg.ErrInvalidRole)
+	}
+
+	description := strings.TrimSpace(request.Description)
+	if len(description) > maxDescriptionLen {
+		return nil, fmt.Errorf("%w: description must not exceed %d bytes", errormsg.ErrInvalidRole, maxDescriptionLen)
+	}
+
+	if len(request.Permissions) > maxPermissions {
+		return nil, fmt.Errorf("%w: too many permissions", errormsg.ErrInvalidRole)
+	}
+
+	for _, p := range request.Permissions {
+		if !permission.MatchString(p) {
+			return nil, fmt.Errorf("%w: permission %q must look like resource:action", errormsg.ErrInvalidRole, p)
+		}
+	}
+
+	permissions := slices.Clone(request.Permissions)
+	slices.Sort(permissions)
+
+	role := calltypes.Role{
+		Name:        name,
+		Description: description,
+		Permissions: slices.Compact(permissions),
+	}
+
+	if err := m.repo.UpsertRole(role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// DeleteRole removes the role, taking it away from its users.
+func (m *Manager) DeleteRole(name string) error {
+	return m.repo.DeleteRole(name)
+}
+
+// UserRoles returns roles assigned to the user.
+func (m *Manager) UserRoles(userID int) ([]*calltypes.Role, error) {
+	return m.repo.GetUserRoles(userID)
+}
+
+// Assign assigns an existing role to the user.
+func (m *Manager) Assign(userID int, name string) error {
+	if _, err := m.repo.GetRole(name); err != nil {
+		return err
+	}
+
+	return m.repo.AssignRole(userID, name)
+}
+
+// Unassign takes the role away from the user.
+func (m *Manager) Unassign(userID int, name string) error {
+	return m.repo.UnassignRole(userID, name)
+}
+
+// Access returns the roles of the user and the permissions they grant, sorted
+// and without duplicates.
+func (m *Manager) Access(userID int) (token.Access, error) {
+	roles, err := m.repo.GetUserRoles(userID)
+	if err != nil {
+		return token.Access{}, err
+	}
+
+	var access token.Access
+
+	for _, role := range roles {
+		access.Roles = append(access.Roles, role.Name)
+		access.Permissions = append(access.Permissions, role.Permissions...)
+	}
+
+	slices.Sort(access.Roles)
+	slices.Sort(access.Permissions)
+	access.Permissions = slices.Compact(access.Permissions)
+
+	return access, nil
+}
+
+// Permissions returns the permissions granted to the user.
+func (m *Manager) Permissions(userID int) ([]string, error) {
+	access, err := m.Access(userID)
+
+	return access.Permissions, err
+}
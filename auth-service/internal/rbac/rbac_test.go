@@ -0,0 +1,173 @@
+package rbac_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/rbac"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepository struct {
+	mu          sync.Mutex
+	roles       map[string]calltypes.Role
+	assignments map[int][]string
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{roles: map[string]calltypes.Role{}, assignments: map[int][]string{}}
+}
+
+func (m *memoryRepository) UpsertRole(role calltypes.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.roles[role.Name] = role
+
+	return nil
+}
+
+func (m *memoryRepository) GetRole(name string) (*calltypes.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	role, ok := m.roles[name]
+	if !ok {
+		return nil, errormsg.ErrRoleNotFound
+	}
+
+	return &role, nil
+}
+
+func (m *memoryRepository) GetRoles() ([]*calltypes.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var roles []*calltypes.Role
+
+	for _, role := range m.roles {
+		roles = append(roles, &role)
+	}
+
+	return roles, nil
+}
+
+func (m *memoryRepository) DeleteRole(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[name]; !ok {
+		return errormsg.ErrRoleNotFound
+	}
+
+	delete(m.roles, name)
+
+	for userID, roles := range m.assignments {
+		m.assignments[userID] = slices.DeleteFunc(roles, func(role string) bool { return role == name })
+	}
+
+	return nil
+}
+
+func (m *memoryRepository) AssignRole(userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(m.assignments[userID], role) {
+		m.assignments[userID] = append(m.assignments[userID], role)
+	}
+
+	return nil
+}
+
+func (m *memoryRepository) UnassignRole(userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.Index(m.assignments[userID], role)
+	if index < 0 {
+		return errormsg.ErrRoleNotFound
+	}
+
+	m.assignments[userID] = slices.Delete(m.assignments[userID], index, index+1)
+
+	return nil
+}
+
+func (m *memoryRepository) GetUserRoles(userID int) ([]*calltypes.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var roles []*calltypes.Role
+
+	for _, name := range m.assignments[userID] {
+		role := m.roles[name]
+		roles = append(roles, &role)
+	}
+
+	return roles, nil
+}
+
+func TestAccess(t *testing.T) {
+	t.Parallel()
+
+	manager := rbac.NewManager(newMemoryRepository())
+
+	_, err := manager.SaveRole("support", calltypes.RoleRequest{Permissions: []string{"users:read", "tickets:write"}})
+	require.NoError(t, err)
+
+	_, err = manager.SaveRole("admin", calltypes.RoleRequest{Permissions: []string{"users:read", "users:write", "users:read"}})
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Assign(7, "support"))
+	require.NoError(t, manager.Assign(7, "admin"))
+	require.NoError(t, manager.Assign(7, "admin"))
+
+	access, err := manager.Access(7)
+	require.NoError(t, err)
+	assert.Equal(t, token.Access{
+		Roles:       []string{"admin", "support"},
+		Permissions: []string{"tickets:write", "users:read", "users:write"},
+	}, access)
+
+	require.NoError(t, manager.Unassign(7, "admin"))
+	require.ErrorIs(t, manager.Unassign(7, "admin"), errormsg.ErrRoleNotFound)
+
+	require.NoError(t, manager.DeleteRole("support"))
+
+	access, err = manager.Access(7)
+	require.NoError(t, err)
+	assert.Empty(t, access.Roles)
+	assert.Empty(t, access.Permissions)
+
+	require.ErrorIs(t, manager.Assign(7, "support"), errormsg.ErrRoleNotFound)
+}
+
+func TestSaveRoleRejected(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		role        string
+		permissions []string
+	}{
+		{name: "empty name", role: "", permissions: []string{"users:read"}},
+		{name: "uppercase name", role: "Admin", permissions: []string{"users:read"}},
+		{name: "no action", role: "admin", permissions: []string{"users"}},
+		{name: "space in permission", role: "admin", permissions: []string{"users: read"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := rbac.NewManager(newMemoryRepository()).SaveRole(tc.role, calltypes.RoleRequest{Permissions: tc.permissions})
+			require.ErrorIs(t, err, errormsg.ErrInvalidRole)
+		})
+	}
+}
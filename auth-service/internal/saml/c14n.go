@@ -0,0 +1,134 @@
+package saml
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// canonicalize serializes the element with Exclusive XML Canonicalization 1.0
+// without comments (https://www.w3.org/TR/xml-exc-c14n/). inclusive lists the
+// prefixes of the InclusiveNamespaces PrefixList, defaultNSToken standing for
+// the default namespace. skip is left out of the output, which implements the
+// enveloped signature transform.
+func canonicalize(e *element, inclusive []string, skip *element) string {
+	var b strings.Builder
+
+	writeCanonical(&b, e, map[string]string{"": ""}, inclusive, skip)
+
+	return b.String()
+}
+
+func writeCanonical(b *strings.Builder, e *element, rendered map[string]string, inclusive []string, skip *element) {
+	// Namespaces visibly utilized by the element and its attributes, plus the
+	// inclusive ones in scope.
+	utilized := map[string]bool{e.prefix: true}
+
+	for _, attr := range e.attrs {
+		if attr.prefix != "" && attr.prefix != "xml" {
+			utilized[attr.prefix] = true
+		}
+	}
+
+	for _, prefix := range inclusive {
+		if prefix == defaultNSToken {
+			prefix = ""
+		}
+
+		if _, ok := e.lookupNamespace(prefix); ok {
+			utilized[prefix] = true
+		}
+	}
+
+	var declarations []string
+
+	scope := make(map[string]string, len(rendered))
+	for prefix, uri := range rendered {
+		scope[prefix] = uri
+	}
+
+	for prefix := range utilized {
+		uri, ok := e.lookupNamespace(prefix)
+		if !ok || prefix == "xml" {
+			continue
+		}
+
+		if current, seen := scope[prefix]; seen && current == uri {
+			continue
+		}
+
+		scope[prefix] = uri
+		declarations = append(declarations, prefix)
+	}
+
+	sort.Strings(declarations)
+
+	b.WriteByte('<')
+	b.WriteString(qualifiedName(e.prefix, e.local))
+
+	for _, prefix := range declarations {
+		if prefix == "" {
+			b.WriteString(` xmlns="`)
+		} else {
+			b.WriteString(` xmlns:` + prefix + `="`)
+		}
+
+		b.WriteString(escapeAttr(scope[prefix]))
+		b.WriteByte('"')
+	}
+
+	attrs := slices.Clone(e.attrs)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		nsI, _ := e.lookupNamespace(attrs[i].prefix)
+		nsJ, _ := e.lookupNamespace(attrs[j].prefix)
+
+		if attrs[i].prefix == "" {
+			nsI = ""
+		}
+
+		if attrs[j].prefix == "" {
+			nsJ = ""
+		}
+
+		if nsI != nsJ {
+			return nsI < nsJ
+		}
+
+		return attrs[i].local < attrs[j].local
+	})
+
+	for _, attr := range attrs {
+		b.WriteString(" " + qualifiedName(attr.prefix, attr.local) + `="` + escapeAttr(attr.value) + `"`)
+	}
+
+	b.WriteByte('>')
+
+	for _, child := range e.children {
+		switch child := child.(type) {
+		case *element:
+			if child != skip {
+				writeCanonical(b, child, scope, inclusive, skip)
+			}
+		case string:
+			b.WriteString(escapeText(child))
+		}
+	}
+
+	b.WriteString("</" + qualifiedName(e.prefix, e.local) + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+
+	return prefix + ":" + local
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;").Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;").Replace(s)
+}
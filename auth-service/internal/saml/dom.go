@@ -0,0 +1,196 @@
+package saml
+
+import (
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Namespaces used by SAML messages.
+const (
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+)
+
+// element is a node of a parsed document. Unlike encoding/xml it keeps the
+// prefixes and the namespace declarations as written, which canonicalization
+// needs. Comments and processing instructions are dropped.
+type element struct {
+	parent *element
+	prefix string
+	local  string
+	// namespaces holds the declarations of the element, "" for the default one.
+	namespaces map[string]string
+	attrs      []attribute
+	// children holds *element and string (character data) nodes in order.
+	children []interface{}
+}
+
+type attribute struct {
+	prefix string
+	local  string
+	value  string
+}
+
+// parseDocument parses a document into a tree and returns its root element.
+// Documents with a DTD are rejected, which rules out entity expansion tricks.
+func parseDocument(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *element
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errormsg.ErrInvalidSAMLResponse, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &element{parent: current, prefix: token.Name.Space, local: token.Name.Local, namespaces: map[string]string{}}
+
+			for _, attr := range token.Attr {
+				switch {
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					node.namespaces[""] = attr.Value
+				case attr.Name.Space == "xmlns":
+					node.namespaces[attr.Name.Local] = attr.Value
+				default:
+					node.attrs = append(node.attrs, attribute{prefix: attr.Name.Space, local: attr.Name.Local, value: attr.Value})
+				}
+			}
+
+			if current == nil {
+				if root != nil {
+					return nil, fmt.Errorf("%w: several root elements", errormsg.ErrInvalidSAMLResponse)
+				}
+
+				root = node
+			} else {
+				current.children = append(current.children, node)
+			}
+
+			current = node
+		case xml.EndElement:
+			if current == nil || token.Name.Space != current.prefix || token.Name.Local != current.local {
+				return nil, fmt.Errorf("%w: mismatched end element", errormsg.ErrInvalidSAMLResponse)
+			}
+
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(token))
+			}
+		case xml.Directive:
+			return nil, fmt.Errorf("%w: DTDs are not allowed", errormsg.ErrInvalidSAMLResponse)
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, fmt.Errorf("%w: incomplete document", errormsg.ErrInvalidSAMLResponse)
+	}
+
+	return root, nil
+}
+
+// lookupNamespace returns the namespace bound to the prefix at the element.
+func (e *element) lookupNamespace(prefix string) (string, bool) {
+	switch prefix {
+	case "xml":
+		return nsXML, true
+	case "xmlns":
+		return "", false
+	}
+
+	for node := e; node != nil; node = node.parent {
+		if uri, ok := node.namespaces[prefix]; ok {
+			return uri, true
+		}
+	}
+
+	return "", prefix == ""
+}
+
+// namespace returns the namespace of the element.
+func (e *element) namespace() string {
+	uri, _ := e.lookupNamespace(e.prefix)
+
+	return uri
+}
+
+// is reports whether the element has the namespace and the local name.
+func (e *element) is(namespace, local string) bool {
+	return e.local == local && e.namespace() == namespace
+}
+
+// elements returns the child elements with the namespace and the local name.
+func (e *element) elements(namespace, local string) []*element {
+	var found []*element
+
+	for _, child := range e.children {
+		if node, ok := child.(*element); ok && node.is(namespace, local) {
+			found = append(found, node)
+		}
+	}
+
+	return found
+}
+
+// child returns the only child element with the namespace and the local name,
+// nil when there is none or several.
+func (e *element) child(namespace, local string) *element {
+	found := e.elements(namespace, local)
+	if len(found) != 1 {
+		return nil
+	}
+
+	return found[0]
+}
+
+// attr returns the value of an unqualified attribute.
+func (e *element) attr(local string) string {
+	for _, attr := range e.attrs {
+		if attr.prefix == "" && attr.local == local {
+			return attr.value
+		}
+	}
+
+	return ""
+}
+
+// text returns the character data of the element, trimmed.
+func (e *element) text() string {
+	var b strings.Builder
+
+	for _, child := range e.children {
+		if data, ok := child.(string); ok {
+			b.WriteString(data)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// path follows a chain of child elements, each given as namespace and local
+// name, and returns nil when any of them is missing or repeated.
+func (e *element) path(steps ...[2]string) *element {
+	node := e
+
+	for _, step := range steps {
+		if node = node.child(step[0], step[1]); node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,202 @@
+package saml
+
+import (
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	statusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmationBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// assertion holds what a login needs from a validated assertion.
+type assertion struct {
+	subject      string
+	nameIDFormat string
+	attributes   map[string][]string
+}
+
+// attribute returns the first value of the attribute.
+func (a *assertion) attribute(name string) string {
+	if values := a.attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// validate checks the response to the request following the Web Browser SSO
+// profile (SAML profiles section 4.1.4.3) and returns its assertion. Either
+// the response or the assertion must be signed; everything is read from the
+// verified elements only, which defeats signature wrapping.
+func (sp *ServiceProvider) validate(provider ProviderConfig, data []byte, requestID string) (*assertion, error) {
+	response, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !response.is(nsProtocol, "Response"), response.attr("Version") != samlVersion:
+		return nil, responseError("not a SAML 2.0 response")
+	case response.attr("Destination") != "" && response.attr("Destination") != sp.ACSURL():
+		return nil, responseError("sent to another destination")
+	case response.attr("InResponseTo") != requestID:
+		return nil, responseError("not a response to the request")
+	}
+
+	if issuer := response.child(nsAssertion, "Issuer"); issuer != nil && issuer.text() != provider.EntityID {
+		return nil, responseError("issued by another provider")
+	}
+
+	status := response.path([2]string{nsProtocol, "Status"}, [2]string{nsProtocol, "StatusCode"})
+	if status == nil {
+		return nil, responseError("no status")
+	}
+
+	if status.attr("Value") != statusSuccess {
+		return nil, fmt.Errorf("%w: %s", errormsg.ErrFederatedLoginDenied, status.attr("Value"))
+	}
+
+	if len(response.elements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, responseError("encrypted assertions are not supported")
+	}
+
+	element := response.child(nsAssertion, "Assertion")
+	if element == nil {
+		return nil, responseError("exactly one assertion is required")
+	}
+
+	responseSigned, err := verifySignature(response, provider.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	assertionSigned, err := verifySignature(element, provider.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	if !responseSigned && !assertionSigned {
+		return nil, signatureError("neither the response nor the assertion is signed")
+	}
+
+	return sp.validateAssertion(provider, element, requestID)
+}
+
+func (sp *ServiceProvider) validateAssertion(provider ProviderConfig, e *element, requestID string) (*assertion, error) {
+	now := sp.now()
+
+	if issuer := e.child(nsAssertion, "Issuer"); issuer == nil || issuer.text() != provider.EntityID {
+		return nil, responseError("assertion is issued by another provider")
+	}
+
+	conditions := e.child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return nil, responseError("no conditions")
+	}
+
+	if err := sp.checkValidity(now, conditions.attr("NotBefore"), conditions.attr("NotOnOrAfter")); err != nil {
+		return nil, err
+	}
+
+	restrictions := conditions.elements(nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, responseError("no audience restriction")
+	}
+
+	// Every restriction must be satisfied (SAML core section 2.5.1.4).
+	for _, restriction := range restrictions {
+		audiences := restriction.elements(nsAssertion, "Audience")
+		if !slices.ContainsFunc(audiences, func(audience *element) bool { return audience.text() == sp.EntityID() }) {
+			return nil, responseError("issued for another audience")
+		}
+	}
+
+	subject := e.child(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, responseError("no subject")
+	}
+
+	nameID := subject.child(nsAssertion, "NameID")
+	if nameID == nil || nameID.text() == "" {
+		return nil, responseError("no NameID")
+	}
+
+	if !slices.ContainsFunc(subject.elements(nsAssertion, "SubjectConfirmation"), func(confirmation *element) bool {
+		return sp.confirms(now, confirmation, requestID)
+	}) {
+		return nil, responseError("no valid bearer subject confirmation")
+	}
+
+	if len(e.elements(nsAssertion, "AuthnStatement")) == 0 {
+		return nil, responseError("no authentication statement")
+	}
+
+	result := &assertion{
+		subject:      nameID.text(),
+		nameIDFormat: nameID.attr("Format"),
+		attributes:   map[string][]string{},
+	}
+
+	for _, statement := range e.elements(nsAssertion, "AttributeStatement") {
+		for _, attribute := range statement.elements(nsAssertion, "Attribute") {
+			for _, value := range attribute.elements(nsAssertion, "AttributeValue") {
+				result.attributes[attribute.attr("Name")] = append(result.attributes[attribute.attr("Name")], value.text())
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// confirms reports whether the bearer subject confirmation is addressed to
+// this service provider, answers the request and is not expired.
+func (sp *ServiceProvider) confirms(now time.Time, confirmation *element, requestID string) bool {
+	if confirmation.attr("Method") != confirmationBearer {
+		return false
+	}
+
+	data := confirmation.child(nsAssertion, "SubjectConfirmationData")
+	if data == nil || data.attr("NotBefore") != "" || data.attr("NotOnOrAfter") == "" {
+		return false
+	}
+
+	return data.attr("Recipient") == sp.ACSURL() &&
+		data.attr("InResponseTo") == requestID &&
+		sp.checkValidity(now, "", data.attr("NotOnOrAfter")) == nil
+}
+
+// checkValidity checks the validity period given as xs:dateTime values, either
+// of which may be empty, allowing for clock skew.
+func (sp *ServiceProvider) checkValidity(now time.Time, notBefore, notOnOrAfter string) error {
+	if notBefore != "" {
+		start, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil {
+			return responseError("malformed NotBefore")
+		}
+
+		if now.Add(sp.cfg.ClockSkew).Before(start) {
+			return responseError("assertion is not yet valid")
+		}
+	}
+
+	if notOnOrAfter != "" {
+		end, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil {
+			return responseError("malformed NotOnOrAfter")
+		}
+
+		if !now.Add(-sp.cfg.ClockSkew).Before(end) {
+			return responseError("assertion has expired")
+		}
+	}
+
+	return nil
+}
+
+func responseError(reason string) error {
+	return fmt.Errorf("%w: %s", errormsg.ErrInvalidSAMLResponse, reason)
+}
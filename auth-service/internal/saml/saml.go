@@ -0,0 +1,361 @@
+// Package saml implements a SAML 2.0 service provider for SP-initiated single
+// sign-on with enterprise identity providers: the AuthnRequest is sent with
+// the HTTP-Redirect binding and the response comes back to the assertion
+// consumer service with the HTTP-POST binding. Assertions must be signed with
+// the configured certificate of the identity provider; the package verifies
+// XML signatures itself, supporting exclusive canonicalization with RSA
+// SHA-256 and SHA-512. Users are mapped through federation.Identities.
+package saml
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailaddr"
+	"auth-service/internal/federation"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Attributes mapped to users by default: the LDAP mail, givenName and sn
+// attributes with the URI names of the SAML X.500 attribute profile.
+const (
+	AttributeEmail     = "urn:oid:0.9.2342.19200300.100.1.3"
+	AttributeFirstName = "urn:oid:2.5.4.42"
+	AttributeLastName  = "urn:oid:2.5.4.4"
+)
+
+const (
+	bindingPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	nameIDEmail     = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	identityPrefix  = "saml:"
+	requestIDLength = 20
+	samlVersion     = "2.0"
+	samlTimeLayout  = "2006-01-02T15:04:05Z"
+)
+
+// ProviderConfig describes an identity provider.
+type ProviderConfig struct {
+	// Name identifies the provider in URLs, e.g. "acme".
+	Name string
+	// EntityID is the entity ID of the identity provider, the issuer of its
+	// responses and assertions.
+	EntityID string
+	// SSOURL is the location of its SingleSignOnService with the HTTP-Redirect
+	// binding.
+	SSOURL string
+	// Certificate verifies signatures of the identity provider.
+	Certificate *x509.Certificate
+	// EmailAttribute, FirstNameAttribute and LastNameAttribute name the
+	// assertion attributes mapped to the user. Without the email attribute a
+	// NameID in the email format is taken as the email.
+	EmailAttribute     string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	// EmailDomains lists the domains the provider is trusted to assert emails
+	// of, usually those of the organization it serves.
+	EmailDomains []string
+	// TrustEmail trusts the provider with emails of any domain.
+	//
+	// Emails the provider is not trusted with are taken as unverified, so they
+	// neither link to an existing user nor create one; only identities linked
+	// before can log in.
+	TrustEmail bool
+}
+
+// trusts reports whether the provider is trusted to assert the email.
+func (p ProviderConfig) trusts(email string) bool {
+	if p.TrustEmail {
+		return true
+	}
+
+	normalized, err := emailaddr.Normalize(email, false)
+	if err != nil {
+		return false
+	}
+
+	domain := normalized[strings.LastIndexByte(normalized, '@')+1:]
+
+	return slices.ContainsFunc(p.EmailDomains, func(allowed string) bool {
+		return strings.EqualFold(allowed, domain)
+	})
+}
+
+// Config holds service provider settings.
+type Config struct {
+	Providers []ProviderConfig
+	// BaseURL is the public URL of the SAML endpoints. The assertion consumer
+	// service is <BaseURL>/acs.
+	BaseURL string
+	// EntityID identifies the service provider, <BaseURL>/metadata when empty.
+	EntityID string
+	// RequestTTL is how long an AuthnRequest waits for the response.
+	RequestTTL time.Duration
+	// ClockSkew is the tolerated difference between the clocks of the service
+	// and the identity providers.
+	ClockSkew time.Duration
+}
+
+// Pending is a started login. RequestID must be stored in the requesting
+// browser and presented with the response.
+type Pending struct {
+	URL       string
+	RequestID string
+	ExpiresAt time.Time
+}
+
+// ServiceProvider runs SAML logins through the configured identity providers.
+type ServiceProvider struct {
+	repo       repository.SAMLRepository
+	identities *federation.Identities
+	cfg        Config
+	providers  map[string]ProviderConfig
+	now        func() time.Time
+}
+
+func NewServiceProvider(repo repository.SAMLRepository, identities *federation.Identities, cfg Config) *ServiceProvider {
+	providers := make(map[string]ProviderConfig, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		providers[provider.Name] = provider
+	}
+
+	return &ServiceProvider{
+		repo:       repo,
+		identities: identities,
+		cfg:        cfg,
+		providers:  providers,
+		now:        time.Now,
+	}
+}
+
+// ParseCertificate decodes a PEM encoded X.509 certificate of an identity
+// provider.
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: no PEM certificate", errormsg.ErrInvalidCertificate)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errormsg.ErrInvalidCertificate, err)
+	}
+
+	return cert, nil
+}
+
+// EntityID returns the entity ID of the service provider.
+func (sp *ServiceProvider) EntityID() string {
+	if sp.cfg.EntityID != "" {
+		return sp.cfg.EntityID
+	}
+
+	return sp.endpoint("/metadata")
+}
+
+// ACSURL returns the location of the assertion consumer service.
+func (sp *ServiceProvider) ACSURL() string {
+	return sp.endpoint("/acs")
+}
+
+// Metadata returns the SAML metadata of the service provider to be imported
+// by identity providers.
+func (sp *ServiceProvider) Metadata() ([]byte, error) {
+	metadata := entityDescriptor{
+		EntityID: sp.EntityID(),
+		SPSSODescriptor: spSSODescriptor{
+			AuthnRequestsSigned:        false,
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: nsProtocol,
+			NameIDFormats:              []string{nameIDEmail},
+			AssertionConsumerService: indexedEndpoint{
+				Binding:   bindingPost,
+				Location:  sp.ACSURL(),
+				Index:     0,
+				IsDefault: true,
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SAML metadata: %w", err)
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Begin starts a login through the identity provider and returns the URL to
+// send the browser to, carrying the AuthnRequest.
+func (sp *ServiceProvider) Begin(name string) (*Pending, error) {
+	provider, ok := sp.providers[name]
+	if !ok {
+		return nil, errormsg.ErrUnknownProvider
+	}
+
+	requestID, err := newRequestID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := sp.now()
+	expiresAt := now.Add(sp.cfg.RequestTTL)
+
+	err = sp.repo.CreateSAMLRequest(calltypes.SAMLRequest{ID: requestID, Provider: name, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := xml.Marshal(authnRequest{
+		ID:                          requestID,
+		Version:                     samlVersion,
+		IssueInstant:                now.UTC().Format(samlTimeLayout),
+		Destination:                 provider.SSOURL,
+		AssertionConsumerServiceURL: sp.ACSURL(),
+		ProtocolBinding:             bindingPost,
+		Issuer:                      issuer{Value: sp.EntityID()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode AuthnRequest: %w", err)
+	}
+
+	var deflated bytes.Buffer
+
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress AuthnRequest: %w", err)
+	}
+
+	if _, err := writer.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to compress AuthnRequest: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress AuthnRequest: %w", err)
+	}
+
+	location, err := url.Parse(provider.SSOURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SSO URL of %s", errormsg.ErrInvalidConfig, name)
+	}
+
+	query := location.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	location.RawQuery = query.Encode()
+
+	return &Pending{
+		URL:       location.String(),
+		RequestID: requestID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Complete validates the base64 encoded SAMLResponse posted to the assertion
+// consumer service. requestID is the value kept in the browser by Begin.
+func (sp *ServiceProvider) Complete(requestID, samlResponse string) (*federation.Result, error) {
+	if requestID == "" {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	request, err := sp.repo.TakeSAMLRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sp.now().After(request.ExpiresAt) {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	provider, ok := sp.providers[request.Provider]
+	if !ok {
+		return nil, errormsg.ErrUnknownProvider
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed encoding", errormsg.ErrInvalidSAMLResponse)
+	}
+
+	assertion, err := sp.validate(provider, data, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := assertion.attribute(provider.EmailAttribute)
+	if email == "" && assertion.nameIDFormat == nameIDEmail {
+		email = assertion.subject
+	}
+
+	return sp.identities.Resolve(federation.Identity{
+		Provider:      identityPrefix + provider.Name,
+		Subject:       assertion.subject,
+		Email:         email,
+		EmailVerified: provider.trusts(email),
+		FirstName:     assertion.attribute(provider.FirstNameAttribute),
+		LastName:      assertion.attribute(provider.LastNameAttribute),
+	})
+}
+
+func (sp *ServiceProvider) endpoint(path string) string {
+	return strings.TrimSuffix(sp.cfg.BaseURL, "/") + path
+}
+
+// newRequestID returns a random request ID. IDs must not start with a digit
+// (xs:ID), hence the prefix.
+func newRequestID() (string, error) {
+	b := make([]byte, requestIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return "_" + hex.EncodeToString(b), nil
+}
+
+type issuer struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Value   string   `xml:",chardata"`
+}
+
+type authnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      issuer
+}
+
+type entityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor
+}
+
+type spSSODescriptor struct {
+	XMLName                    xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata SPSSODescriptor"`
+	AuthnRequestsSigned        bool            `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool            `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string          `xml:"protocolSupportEnumeration,attr"`
+	NameIDFormats              []string        `xml:"urn:oasis:names:tc:SAML:2.0:metadata NameIDFormat"`
+	AssertionConsumerService   indexedEndpoint `xml:"urn:oasis:names:tc:SAML:2.0:metadata AssertionConsumerService"`
+}
+
+type indexedEndpoint struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     int    `xml:"index,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
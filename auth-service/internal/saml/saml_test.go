@@ -0,0 +1,724 @@
+package saml_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/federation"
+	"auth-service/internal/saml"
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProvider = "acme"
+	testIssuer   = "https://idp.acme.example/saml"
+	testSSOURL   = "https://idp.acme.example/saml/sso"
+	testBaseURL  = "https://auth.example.com/saml"
+	testEntityID = testBaseURL + "/metadata"
+	testACSURL   = testBaseURL + "/acs"
+
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nameIDEmail = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	success     = "urn:oasis:names:tc:SAML:2.0:status:Success"
+)
+
+// Keys are generated once, RSA key generation is slow.
+var (
+	idpKey   = sync.OnceValue(generateKey) //nolint: gochecknoglobals
+	otherKey = sync.OnceValue(generateKey) //nolint: gochecknoglobals
+)
+
+func generateKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// idpCertificate returns a self-signed certificate of idpKey in PEM.
+func idpCertificate(t *testing.T) []byte {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.acme.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &idpKey().PublicKey, idpKey())
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// testResponse describes a response of the identity provider. Messages are
+// written in canonical form, so the digests are computed over the text as is.
+type testResponse struct {
+	requestID    string
+	destination  string
+	issuer       string
+	status       string
+	audience     string
+	recipient    string
+	notBefore    time.Time
+	notOnOrAfter time.Time
+	nameID       string
+	attributes   [][2]string
+	// key signs the assertion and, with signResponse, the response.
+	key           *rsa.PrivateKey
+	signAssertion bool
+	signResponse  bool
+	// tamper changes the response after signing.
+	tamper func(response, assertion string) string
+}
+
+func validResponse(requestID string) testResponse {
+	return testResponse{
+		requestID:    requestID,
+		destination:  testACSURL,
+		issuer:       testIssuer,
+		status:       success,
+		audience:     testEntityID,
+		recipient:    testACSURL,
+		notBefore:    time.Now().Add(-time.Minute),
+		notOnOrAfter: time.Now().Add(5 * time.Minute),
+		nameID:       "anna@example.com",
+		attributes: [][2]string{
+			{saml.AttributeEmail, "anna@example.com"},
+			{saml.AttributeFirstName, "Anna"},
+			{saml.AttributeLastName, "Ivanova"},
+		},
+		key:           idpKey(),
+		signAssertion: true,
+	}
+}
+
+func (r testResponse) assertion() string {
+	var attributes strings.Builder
+	for _, attribute := range r.attributes {
+		attributes.WriteString(`<saml:Attribute Name="` + attribute[0] + `"><saml:AttributeValue>` + attribute[1] +
+			`</saml:AttributeValue></saml:Attribute>`)
+	}
+
+	return `<saml:Assertion xmlns:saml="` + nsAssertion + `" ID="_assertion" IssueInstant="` + timestamp(time.Now()) + `" Version="2.0">` +
+		`<saml:Issuer>` + r.issuer + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID Format="` + nameIDEmail + `">` + r.nameID + `</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">` +
+		`<saml:SubjectConfirmationData InResponseTo="` + r.requestID + `" NotOnOrAfter="` + timestamp(r.notOnOrAfter) +
+		`" Recipient="` + r.recipient + `"></saml:SubjectConfirmationData></saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + timestamp(r.notBefore) + `" NotOnOrAfter="` + timestamp(r.notOnOrAfter) + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + r.audience + `</saml:Audience></saml:AudienceRestriction></saml:Conditions>` +
+		`<saml:AuthnStatement AuthnInstant="` + timestamp(time.Now()) + `"><saml:AuthnContext><saml:AuthnContextClassRef>` +
+		`urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport</saml:AuthnContextClassRef></saml:AuthnContext></saml:AuthnStatement>` +
+		`<saml:AttributeStatement>` + attributes.String() + `</saml:AttributeStatement></saml:Assertion>`
+}
+
+// encode returns the base64 encoded response as posted by the browser.
+func (r testResponse) encode(t *testing.T) string {
+	t.Helper()
+
+	assertion := r.assertion()
+	if r.signAssertion {
+		assertion = sign(t, assertion, "_assertion", r.key)
+	}
+
+	response := `<samlp:Response xmlns:samlp="` + nsProtocol + `" Destination="` + r.destination + `" ID="_response" InResponseTo="` +
+		r.requestID + `" IssueInstant="` + timestamp(time.Now()) + `" Version="2.0">` +
+		`<saml:Issuer xmlns:saml="` + nsAssertion + `">` + r.issuer + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="` + r.status + `"></samlp:StatusCode></samlp:Status>` +
+		assertion + `</samlp:Response>`
+
+	if r.signResponse {
+		response = sign(t, response, "_response", r.key)
+	}
+
+	if r.tamper != nil {
+		response = r.tamper(response, assertion)
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(response))
+}
+
+// sign adds an enveloped signature after the issuer of the canonical element.
+func sign(t *testing.T, element, id string, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte(element))
+
+	signedInfo := `<ds:SignedInfo xmlns:ds="` + nsDSig + `">` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"></ds:Transform>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:Transform></ds:Transforms>` +
+		`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue></ds:Reference></ds:SignedInfo>`
+
+	hashed := sha256.Sum256([]byte(signedInfo))
+
+	value, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	require.NoError(t, err)
+
+	// SignedInfo inherits the namespace in the document, its canonical form
+	// declares it.
+	signature := `<ds:Signature xmlns:ds="` + nsDSig + `">` + strings.Replace(signedInfo, ` xmlns:ds="`+nsDSig+`"`, "", 1) +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</ds:SignatureValue></ds:Signature>`
+
+	return strings.Replace(element, "</saml:Issuer>", "</saml:Issuer>"+signature, 1)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+type memoryRepository struct {
+	mu         sync.Mutex
+	users      map[int]calltypes.User
+	requests   map[string]calltypes.SAMLRequest
+	identities map[string]calltypes.FederatedIdentity
+}
+
+func newMemoryRepository(users ...calltypes.User) *memoryRepository {
+	repo := &memoryRepository{
+		users:      map[int]calltypes.User{},
+		requests:   map[string]calltypes.SAMLRequest{},
+		identities: map[string]calltypes.FederatedIdentity{},
+	}
+
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+
+	return repo
+}
+
+func (m *memoryRepository) CreateSAMLRequest(request calltypes.SAMLRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[request.ID] = request
+
+	return nil
+}
+
+func (m *memoryRepository) TakeSAMLRequest(id string) (*calltypes.SAMLRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := m.requests[id]
+	if !ok {
+		return nil, errormsg.ErrInvalidFederatedLogin
+	}
+
+	delete(m.requests, id)
+
+	return &request, nil
+}
+
+func (m *memoryRepository) GetOne(id int) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, errormsg.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (m *memoryRepository) GetByEmail(email string) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, errormsg.ErrUserNotFound
+}
+
+func (m *memoryRepository) InsertExternal(user calltypes.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user.ID = len(m.users) + 100
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (m *memoryRepository) CreateFederatedLogin(calltypes.FederatedLogin) error {
+	return nil
+}
+
+func (m *memoryRepository) TakeFederatedLogin(string) (*calltypes.FederatedLogin, error) {
+	return nil, errormsg.ErrInvalidFederatedLogin
+}
+
+func (m *memoryRepository) GetFederatedIdentity(provider, subject string) (*calltypes.FederatedIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity, ok := m.identities[provider+"|"+subject]
+	if !ok {
+		return nil, errormsg.ErrIdentityNotFound
+	}
+
+	return &identity, nil
+}
+
+func (m *memoryRepository) LinkFederatedIdentity(identity calltypes.FederatedIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.identities[identity.Provider+"|"+identity.Subject] = identity
+
+	return nil
+}
+
+func (m *memoryRepository) TouchFederatedIdentity(provider, subject, email string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity := m.identities[provider+"|"+subject]
+	identity.Email = email
+	identity.LastLoginAt = at
+	m.identities[provider+"|"+subject] = identity
+
+	return nil
+}
+
+// newServiceProvider returns a service provider trusting the test identity
+// provider with emails of example.com.
+func newServiceProvider(t *testing.T, repo *memoryRepository) *saml.ServiceProvider {
+	t.Helper()
+
+	return newServiceProviderWith(t, repo, func(provider *saml.ProviderConfig) {
+		provider.EmailDomains = []string{"Example.com"}
+	})
+}
+
+func newServiceProviderWith(t *testing.T, repo *memoryRepository, configure func(provider *saml.ProviderConfig)) *saml.ServiceProvider {
+	t.Helper()
+
+	cert, err := saml.ParseCertificate(idpCertificate(t))
+	require.NoError(t, err)
+
+	provider := saml.ProviderConfig{
+		Name:               testProvider,
+		EntityID:           testIssuer,
+		SSOURL:             testSSOURL,
+		Certificate:        cert,
+		EmailAttribute:     saml.AttributeEmail,
+		FirstNameAttribute: saml.AttributeFirstName,
+		LastNameAttribute:  saml.AttributeLastName,
+	}
+	configure(&provider)
+
+	return saml.NewServiceProvider(repo, federation.NewIdentities(repo, repo), saml.Config{
+		Providers:  []saml.ProviderConfig{provider},
+		BaseURL:    testBaseURL,
+		RequestTTL: time.Minute,
+		ClockSkew:  2 * time.Minute,
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	sp := newServiceProvider(t, newMemoryRepository())
+
+	data, err := sp.Metadata()
+	require.NoError(t, err)
+
+	var metadata struct {
+		EntityID string `xml:"entityID,attr"`
+		SP       struct {
+			WantAssertionsSigned bool `xml:"WantAssertionsSigned,attr"`
+			ACS                  struct {
+				Binding  string `xml:"Binding,attr"`
+				Location string `xml:"Location,attr"`
+			} `xml:"AssertionConsumerService"`
+		} `xml:"SPSSODescriptor"`
+	}
+
+	require.NoError(t, xml.Unmarshal(data, &metadata))
+	assert.Equal(t, testEntityID, metadata.EntityID)
+	assert.True(t, metadata.SP.WantAssertionsSigned)
+	assert.Equal(t, "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST", metadata.SP.ACS.Binding)
+	assert.Equal(t, testACSURL, metadata.SP.ACS.Location)
+}
+
+func TestBegin(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	sp := newServiceProvider(t, repo)
+
+	pending, err := sp.Begin(testProvider)
+	require.NoError(t, err)
+	assert.Contains(t, repo.requests, pending.RequestID)
+
+	location, err := url.Parse(pending.URL)
+	require.NoError(t, err)
+	assert.Equal(t, testSSOURL, location.Scheme+"://"+location.Host+location.Path)
+
+	deflated, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.NoError(t, err)
+
+	var request struct {
+		XMLName     xml.Name
+		ID          string `xml:"ID,attr"`
+		Destination string `xml:"Destination,attr"`
+		ACS         string `xml:"AssertionConsumerServiceURL,attr"`
+		Issuer      string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	}
+
+	require.NoError(t, xml.Unmarshal(data, &request))
+	assert.Equal(t, xml.Name{Space: nsProtocol, Local: "AuthnRequest"}, request.XMLName)
+	assert.Equal(t, pending.RequestID, request.ID)
+	assert.Equal(t, testSSOURL, request.Destination)
+	assert.Equal(t, testACSURL, request.ACS)
+	assert.Equal(t, testEntityID, request.Issuer)
+
+	_, err = sp.Begin("unknown")
+	require.ErrorIs(t, err, errormsg.ErrUnknownProvider)
+}
+
+func TestCompleteProvisionsUser(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	sp := newServiceProvider(t, repo)
+
+	pending, err := sp.Begin(testProvider)
+	require.NoError(t, err)
+
+	result, err := sp.Complete(pending.RequestID, validResponse(pending.RequestID).encode(t))
+	require.NoError(t, err)
+
+	assert.True(t, result.Provisioned)
+	assert.Equal(t, "saml:"+testProvider, result.Provider)
+	assert.Equal(t, "anna@example.com", result.Subject)
+	assert.Equal(t, "anna@example.com", result.User.Email)
+	assert.Equal(t, "Anna", result.User.FirstName)
+	assert.Equal(t, "Ivanova", result.User.LastName)
+	assert.True(t, result.User.EmailVerified)
+
+	// The request is taken, the same response is not accepted again.
+	_, err = sp.Complete(pending.RequestID, validResponse(pending.RequestID).encode(t))
+	require.ErrorIs(t, err, errormsg.ErrInvalidFederatedLogin)
+}
+
+func TestCompleteLinksUser(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository(calltypes.User{ID: 7, Email: "anna@example.com", EmailVerified: true, Status: calltypes.UserStatusActive})
+	sp := newServiceProvider(t, repo)
+
+	pending, err := sp.Begin(testProvider)
+	require.NoError(t, err)
+
+	response := validResponse(pending.RequestID)
+	// Without the email attribute the NameID in the email format is used.
+	response.attributes = nil
+
+	result, err := sp.Complete(pending.RequestID, response.encode(t))
+	require.NoError(t, err)
+
+	assert.True(t, result.Linked)
+	assert.Equal(t, 7, result.User.ID)
+	assert.True(t, result.User.EmailVerified)
+}
+
+func TestCompleteRefusesUnverifiedLocalAccount(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository(calltypes.User{ID: 7, Email: "anna@example.com", Status: calltypes.UserStatusActive})
+	sp := newServiceProvider(t, repo)
+
+	pending, err := sp.Begin(testProvider)
+	require.NoError(t, err)
+
+	_, err = sp.Complete(pending.RequestID, validResponse(pending.RequestID).encode(t))
+	require.ErrorIs(t, err, errormsg.ErrUnverifiedLocalEmail)
+	assert.Empty(t, repo.identities)
+}
+
+func TestCompleteUntrustedEmail(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		configure func(provider *saml.ProviderConfig)
+	}{
+		{name: "no trust configured", configure: func(*saml.ProviderConfig) {}},
+		{name: "other domain", configure: func(provider *saml.ProviderConfig) {
+			provider.EmailDomains = []string{"acme.example"}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemoryRepository(calltypes.User{ID: 7, Email: "anna@example.com", Status: calltypes.UserStatusActive})
+			sp := newServiceProviderWith(t, repo, tc.configure)
+
+			pending, err := sp.Begin(testProvider)
+			require.NoError(t, err)
+
+			_, err = sp.Complete(pending.RequestID, validResponse(pending.RequestID).encode(t))
+			require.ErrorIs(t, err, errormsg.ErrUnverifiedEmail)
+			assert.Empty(t, repo.identities, "the identity must not be linked to the user")
+		})
+	}
+}
+
+func TestCompleteTrustEmail(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository()
+	sp := newServiceProviderWith(t, repo, func(provider *saml.ProviderConfig) {
+		provider.TrustEmail = true
+	})
+
+	pending, err := sp.Begin(testProvider)
+	require.NoError(t, err)
+
+	result, err := sp.Complete(pending.RequestID, validResponse(pending.RequestID).encode(t))
+	require.NoError(t, err)
+	assert.True(t, result.Provisioned)
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		modify  func(r *testResponse)
+		wantErr error
+	}{
+		{
+			name: "signed response",
+			modify: func(r *testResponse) {
+				r.signAssertion = false
+				r.signResponse = true
+			},
+		},
+		{
+			name: "response and assertion signed",
+			modify: func(r *testResponse) {
+				r.signResponse = true
+			},
+		},
+		{
+			name: "other serialization",
+			modify: func(r *testResponse) {
+				r.tamper = func(response, _ string) string {
+					response = strings.ReplaceAll(response, `"></saml:SubjectConfirmationData>`, `" />`)
+
+					return `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + strings.ReplaceAll(response, `Format="`+nameIDEmail+`"`, `Format='`+nameIDEmail+`'`)
+				}
+			},
+		},
+		{
+			name: "expired within clock skew",
+			modify: func(r *testResponse) {
+				r.notOnOrAfter = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "not yet valid within clock skew",
+			modify: func(r *testResponse) {
+				r.notBefore = time.Now().Add(time.Minute)
+			},
+		},
+		{
+			name: "tampered attribute",
+			modify: func(r *testResponse) {
+				r.tamper = func(response, _ string) string {
+					return strings.Replace(response, "Anna<", "Eve<", 1)
+				}
+			},
+			wantErr: errormsg.ErrInvalidSAMLSignature,
+		},
+		{
+			name: "unsigned",
+			modify: func(r *testResponse) {
+				r.signAssertion = false
+			},
+			wantErr: errormsg.ErrInvalidSAMLSignature,
+		},
+		{
+			name: "signed with other key",
+			modify: func(r *testResponse) {
+				r.key = otherKey()
+			},
+			wantErr: errormsg.ErrInvalidSAMLSignature,
+		},
+		{
+			name: "signed assertion wrapped beside a forged one",
+			modify: func(r *testResponse) {
+				forged := *r
+				forged.nameID = "admin@example.com"
+
+				r.tamper = func(response, assertion string) string {
+					return strings.Replace(response, assertion,
+						`<samlp:Extensions>`+assertion+`</samlp:Extensions>`+forged.assertion(), 1)
+				}
+			},
+			wantErr: errormsg.ErrInvalidSAMLSignature,
+		},
+		{
+			name: "two assertions",
+			modify: func(r *testResponse) {
+				r.tamper = func(response, assertion string) string {
+					return strings.Replace(response, assertion, assertion+assertion, 1)
+				}
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "other audience",
+			modify: func(r *testResponse) {
+				r.audience = "https://other.example.com"
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "expired",
+			modify: func(r *testResponse) {
+				r.notOnOrAfter = time.Now().Add(-5 * time.Minute)
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "not yet valid",
+			modify: func(r *testResponse) {
+				r.notBefore = time.Now().Add(5 * time.Minute)
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "response to another request",
+			modify: func(r *testResponse) {
+				r.requestID = "_other"
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "other recipient",
+			modify: func(r *testResponse) {
+				r.recipient = "https://other.example.com/acs"
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "other destination",
+			modify: func(r *testResponse) {
+				r.destination = "https://other.example.com/acs"
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "other issuer",
+			modify: func(r *testResponse) {
+				r.issuer = "https://idp.other.example"
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "DTD",
+			modify: func(r *testResponse) {
+				r.tamper = func(response, _ string) string {
+					return `<!DOCTYPE Response [<!ENTITY x "x">]>` + response
+				}
+			},
+			wantErr: errormsg.ErrInvalidSAMLResponse,
+		},
+		{
+			name: "denied",
+			modify: func(r *testResponse) {
+				r.status = "urn:oasis:names:tc:SAML:2.0:status:Responder"
+			},
+			wantErr: errormsg.ErrFederatedLoginDenied,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sp := newServiceProvider(t, newMemoryRepository())
+
+			pending, err := sp.Begin(testProvider)
+			require.NoError(t, err)
+
+			response := validResponse(pending.RequestID)
+			tc.modify(&response)
+
+			result, err := sp.Complete(pending.RequestID, response.encode(t))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "anna@example.com", result.User.Email)
+		})
+	}
+}
+
+func TestCompleteUnknownRequest(t *testing.T) {
+	t.Parallel()
+
+	sp := newServiceProvider(t, newMemoryRepository())
+
+	_, err := sp.Complete("_unknown", validResponse("_unknown").encode(t))
+	require.ErrorIs(t, err, errormsg.ErrInvalidFederatedLogin)
+
+	_, err = sp.Complete("", validResponse("").encode(t))
+	require.ErrorIs(t, err, errormsg.ErrInvalidFederatedLogin)
+}
+
+func TestParseCertificate(t *testing.T) {
+	t.Parallel()
+
+	_, err := saml.ParseCertificate([]byte("not a certificate"))
+	require.ErrorIs(t, err, errormsg.ErrInvalidCertificate)
+
+	_, err = saml.ParseCertificate(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}))
+	require.ErrorIs(t, err, errormsg.ErrInvalidCertificate)
+}
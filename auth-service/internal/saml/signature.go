@@ -0,0 +1,196 @@
+package saml
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Algorithms of XML Signature accepted in assertions. SHA-1 is not accepted.
+const (
+	algExcC14N     = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algDigest256   = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algDigest512   = "http://www.w3.org/2001/04/xmlenc#sha512"
+	nsInclusiveNS  = algExcC14N
+	defaultNSToken = "#default"
+)
+
+// verifySignature checks the enveloped signature of the element against the
+// certificate of the identity provider. It reports false when the element is
+// not signed. The signature must cover exactly this element, so the caller may
+// trust whatever it reads from it afterwards.
+func verifySignature(e *element, cert *x509.Certificate) (bool, error) {
+	signatures := e.elements(nsDSig, "Signature")
+
+	switch len(signatures) {
+	case 0:
+		return false, nil
+	case 1:
+	default:
+		return false, signatureError("several signatures")
+	}
+
+	signature := signatures[0]
+
+	signedInfo := signature.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return false, signatureError("no SignedInfo")
+	}
+
+	method := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if method == nil || method.attr("Algorithm") != algExcC14N {
+		return false, signatureError("unsupported canonicalization")
+	}
+
+	reference := signedInfo.child(nsDSig, "Reference")
+	if reference == nil || e.attr("ID") == "" || reference.attr("URI") != "#"+e.attr("ID") {
+		return false, signatureError("signature does not reference the element")
+	}
+
+	inclusive, err := referenceTransforms(reference)
+	if err != nil {
+		return false, err
+	}
+
+	digestHash, err := digestAlgorithm(reference.path([2]string{nsDSig, "DigestMethod"}))
+	if err != nil {
+		return false, err
+	}
+
+	expectedDigest, err := decodeBase64(reference.path([2]string{nsDSig, "DigestValue"}))
+	if err != nil {
+		return false, err
+	}
+
+	digest := digestHash.New()
+	digest.Write([]byte(canonicalize(e, inclusive, signature)))
+
+	if subtle.ConstantTimeCompare(digest.Sum(nil), expectedDigest) != 1 {
+		return false, signatureError("digest mismatch")
+	}
+
+	signatureHash, err := signatureAlgorithm(signedInfo.child(nsDSig, "SignatureMethod"))
+	if err != nil {
+		return false, err
+	}
+
+	value, err := decodeBase64(signature.child(nsDSig, "SignatureValue"))
+	if err != nil {
+		return false, err
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return false, signatureError("certificate has no RSA key")
+	}
+
+	hashed := signatureHash.New()
+	hashed.Write([]byte(canonicalize(signedInfo, inclusiveNamespaces(method), nil)))
+
+	if err := rsa.VerifyPKCS1v15(key, signatureHash, hashed.Sum(nil), value); err != nil {
+		return false, signatureError("bad signature")
+	}
+
+	return true, nil
+}
+
+// referenceTransforms checks that the reference is transformed with the
+// enveloped signature transform and exclusive canonicalization only, and
+// returns the inclusive prefixes of the latter.
+func referenceTransforms(reference *element) ([]string, error) {
+	transforms := reference.child(nsDSig, "Transforms")
+	if transforms == nil {
+		return nil, signatureError("no transforms")
+	}
+
+	var (
+		enveloped, canonical bool
+		inclusive            []string
+	)
+
+	for _, transform := range transforms.elements(nsDSig, "Transform") {
+		switch transform.attr("Algorithm") {
+		case algEnveloped:
+			enveloped = true
+		case algExcC14N:
+			canonical = true
+			inclusive = inclusiveNamespaces(transform)
+		default:
+			return nil, signatureError("unsupported transform " + transform.attr("Algorithm"))
+		}
+	}
+
+	if !enveloped || !canonical {
+		return nil, signatureError("enveloped signature with exclusive canonicalization is required")
+	}
+
+	return inclusive, nil
+}
+
+// inclusiveNamespaces returns the InclusiveNamespaces PrefixList of a
+// canonicalization method or transform.
+func inclusiveNamespaces(method *element) []string {
+	list := method.child(nsInclusiveNS, "InclusiveNamespaces")
+	if list == nil {
+		return nil
+	}
+
+	return strings.Fields(list.attr("PrefixList"))
+}
+
+func digestAlgorithm(method *element) (crypto.Hash, error) {
+	if method == nil {
+		return 0, signatureError("no digest method")
+	}
+
+	switch method.attr("Algorithm") {
+	case algDigest256:
+		return crypto.SHA256, nil
+	case algDigest512:
+		return crypto.SHA512, nil
+	default:
+		return 0, signatureError("unsupported digest " + method.attr("Algorithm"))
+	}
+}
+
+func signatureAlgorithm(method *element) (crypto.Hash, error) {
+	if method == nil {
+		return 0, signatureError("no signature method")
+	}
+
+	switch method.attr("Algorithm") {
+	case algRSASHA256:
+		return crypto.SHA256, nil
+	case algRSASHA512:
+		return crypto.SHA512, nil
+	default:
+		return 0, signatureError("unsupported signature method " + method.attr("Algorithm"))
+	}
+}
+
+// decodeBase64 decodes the base64 content of the element, which may be wrapped
+// over several lines.
+func decodeBase64(e *element) ([]byte, error) {
+	if e == nil {
+		return nil, signatureError("missing value")
+	}
+
+	value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.text()), ""))
+	if err != nil {
+		return nil, signatureError("malformed value")
+	}
+
+	return value, nil
+}
+
+func signatureError(reason string) error {
+	return fmt.Errorf("%w: %s", errormsg.ErrInvalidSAMLSignature, reason)
+}
@@ -0,0 +1,66 @@
+// Package secretbox encrypts small secrets, such as TOTP seeds, before they are
+// stored in the database.
+package secretbox
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+const keyLength = 32
+
+// Box encrypts and decrypts values with AES-256-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates Box from a base64 encoded 32-byte key.
+func New(encodedKey string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != keyLength {
+		return nil, errormsg.ErrInvalidEncryptionKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plain text and returns base64 encoded nonce and cipher text.
+func (b *Box) Seal(plainText []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plainText, nil)), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (b *Box) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return nil, errormsg.ErrDecrypt
+	}
+
+	nonce, cipherText := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+
+	plainText, err := b.aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, errormsg.ErrDecrypt
+	}
+
+	return plainText, nil
+}
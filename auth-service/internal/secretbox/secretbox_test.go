@@ -0,0 +1,33 @@
+package secretbox_test
+
+import (
+	"auth-service/internal/secretbox"
+	"auth-service/pkg/errormsg"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox(t *testing.T) {
+	t.Parallel()
+
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte("totp seed"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "totp seed")
+
+	opened, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("totp seed"), opened)
+
+	_, err = box.Open(sealed[:len(sealed)-4] + "AAAA")
+	require.ErrorIs(t, err, errormsg.ErrDecrypt)
+
+	_, err = secretbox.New("c2hvcnQ=")
+	require.ErrorIs(t, err, errormsg.ErrInvalidEncryptionKey)
+}
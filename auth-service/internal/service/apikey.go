@@ -0,0 +1,262 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Returns personal API keys of the current user, revoked ones included
+// @Tags API keys
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.APIKey}
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Security BearerAuth
+// @Router /apikeys [get].
+func (s *RewardService) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	s.writeAPIKeys(w, userID)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Creates a personal API key acting as the current user with the given scopes only. The key is sent in the X-API-Key header and is returned only once. Personal keys get the default quota: rateLimit is rejected. A user may have at most 10 active keys
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param request body calltypes.APIKeyRequest true "Key settings"
+// @Success 201 {object} calltypes.JSONResponse{data=calltypes.APIKeyCredentials}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid key settings"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 409 {object} calltypes.ErrorResponse "Too many active keys"
+// @Security BearerAuth
+// @Router /apikeys [post].
+func (s *RewardService) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.APIKeyRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	credentials, err := s.APIKeys.CreatePersonal(userID, requestPayload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, apiKeyErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  userID,
+		ActorID: userID,
+		Action:  audit.ActionAPIKeyCreated,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"id": credentials.ID, "scopes": requestPayload.Scopes},
+	})
+
+	writeAPIKeyCredentials(w, credentials)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revokes a personal API key of the current user
+// @Tags API keys
+// @Param id path string true "Key ID"
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 404 {object} calltypes.ErrorResponse "API key not found"
+// @Security BearerAuth
+// @Router /apikeys/{id} [delete].
+func (s *RewardService) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	key, err := s.APIKeys.RevokePersonal(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		httputils.ErrorJSON(w, err, apiKeyErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  userID,
+		ActorID: userID,
+		Action:  audit.ActionAPIKeyRevoked,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"id": key.ID},
+	})
+
+	writeAPIKeyRevoked(w)
+}
+
+// ListServiceAPIKeys godoc
+// @Summary List service API keys
+// @Description Returns API keys of integrations, revoked ones included
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.APIKey}
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/apikeys [get].
+func (s *RewardService) ListServiceAPIKeys(w http.ResponseWriter, _ *http.Request) {
+	s.writeAPIKeys(w, 0)
+}
+
+// CreateServiceAPIKey godoc
+// @Summary Create service API key
+// @Description Creates an API key of an integration. It has no user and gets the given scopes only. The key is returned only once
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body calltypes.APIKeyRequest true "Key settings"
+// @Success 201 {object} calltypes.JSONResponse{data=calltypes.APIKeyCredentials}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid key settings"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/apikeys [post].
+func (s *RewardService) CreateServiceAPIKey(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.APIKeyRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	credentials, err := s.APIKeys.CreateService(requestPayload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, apiKeyErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		Action:  audit.ActionAPIKeyCreated,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"id": credentials.ID, "scopes": requestPayload.Scopes},
+	})
+
+	writeAPIKeyCredentials(w, credentials)
+}
+
+// RevokeAnyAPIKey godoc
+// @Summary Revoke any API key
+// @Description Revokes a service key or a personal key of any user, e.g. a leaked one
+// @Tags Admin
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id path string true "Key ID"
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} calltypes.ErrorResponse "API key not found"
+// @Router /admin/apikeys/{id} [delete].
+func (s *RewardService) RevokeAnyAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := s.APIKeys.Revoke(chi.URLParam(r, "id"))
+	if err != nil {
+		httputils.ErrorJSON(w, err, apiKeyErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  key.UserID,
+		Action:  audit.ActionAPIKeyRevoked,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"id": key.ID},
+	})
+
+	writeAPIKeyRevoked(w)
+}
+
+func (s *RewardService) writeAPIKeys(w http.ResponseWriter, userID int) {
+	keys, err := s.APIKeys.List(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Fetched %d API keys", len(keys)),
+		Data:    keys,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+func writeAPIKeyCredentials(w http.ResponseWriter, credentials *calltypes.APIKeyCredentials) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "API key created, store it now: it is not shown again",
+		Data:    credentials,
+	}
+
+	err := httputils.WriteJSON(w, http.StatusCreated, payload, noStoreHeaders())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+func writeAPIKeyRevoked(w http.ResponseWriter) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "API key has been revoked",
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// apiKeyErrorStatus maps API key errors to HTTP status codes.
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidAPIKeyRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errormsg.ErrTooManyAPIKeys):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/internal/dataexport"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequestDataExport godoc
+// @Summary Export personal data
+// @Description Asks for an export of everything the service holds about the authenticated user: profile, roles, sessions, OAuth consents, passkeys, API keys, linked identities and security events. The export is generated in the background; poll it with GET /users/me/exports/{id}
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body calltypes.DataExportRequest false "Format, json by default"
+// @Success 202 {object} calltypes.JSONResponse{data=calltypes.DataExport}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid format or data export is disabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many requests"
+// @Security BearerAuth
+// @Router /users/me/exports [post].
+func (s *RewardService) RequestDataExport(w http.ResponseWriter, r *http.Request) {
+	if s.DataExport == nil {
+		httputils.ErrorJSON(w, errormsg.ErrDataExportDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.DataExportRequest
+
+	if r.ContentLength != 0 {
+		if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	export, err := s.DataExport.Request(id, requestPayload.Format)
+	if err != nil {
+		httputils.ErrorJSON(w, err, dataExportErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		Action:  audit.ActionDataExportRequested,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"exportId": export.ID, "format": export.Format},
+	})
+
+	writeDataExport(w, http.StatusAccepted, "Data export has been requested", export)
+}
+
+// GetDataExport godoc
+// @Summary Get personal data export
+// @Description Returns a data export of the authenticated user. Ready exports carry downloadUrl, a signed link valid for a few minutes
+// @Tags Users
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.DataExport}
+// @Failure 400 {object} calltypes.ErrorResponse "Data export is disabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 404 {object} calltypes.ErrorResponse "Export not found"
+// @Security BearerAuth
+// @Router /users/me/exports/{id} [get].
+func (s *RewardService) GetDataExport(w http.ResponseWriter, r *http.Request) {
+	if s.DataExport == nil {
+		httputils.ErrorJSON(w, errormsg.ErrDataExportDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	export, err := s.DataExport.Get(id, chi.URLParam(r, "id"))
+	if err != nil {
+		httputils.ErrorJSON(w, err, dataExportErrorStatus(err))
+
+		return
+	}
+
+	writeDataExport(w, http.StatusOK, "Retrieved data export", export)
+}
+
+// DownloadDataExport godoc
+// @Summary Download personal data export
+// @Description Downloads the file of a data export through the signed link from GET /users/me/exports/{id}
+// @Tags Users
+// @Produce json,application/zip
+// @Param id path string true "Export ID"
+// @Param expires query int true "Link expiry, Unix time"
+// @Param sig query string true "Link signature"
+// @Success 200 {file} file "Export file"
+// @Failure 400 {object} calltypes.ErrorResponse "Data export is disabled"
+// @Failure 403 {object} calltypes.ErrorResponse "Invalid or expired link"
+// @Failure 404 {object} calltypes.ErrorResponse "Export not found or not ready"
+// @Router /exports/{id} [get].
+func (s *RewardService) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	if s.DataExport == nil {
+		httputils.ErrorJSON(w, errormsg.ErrDataExportDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	export, file, err := s.DataExport.Open(chi.URLParam(r, "id"), r.URL.Query())
+	if err != nil {
+		httputils.ErrorJSON(w, err, dataExportErrorStatus(err))
+
+		return
+	}
+	defer file.Close()
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  export.UserID,
+		Action:  audit.ActionDataExportDownloaded,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"exportId": export.ID},
+	})
+
+	contentType := "application/json"
+	if export.Format == dataexport.FormatZIP {
+		contentType = "application/zip"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%s.%s"`, export.ID, export.Format))
+	w.Header().Set("Cache-Control", "no-store")
+
+	http.ServeContent(w, r, "", *export.CompletedAt, file)
+}
+
+func writeDataExport(w http.ResponseWriter, status int, message string, export *calltypes.DataExport) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: message,
+		Data:    export,
+	}
+
+	err := httputils.WriteJSON(w, status, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+func dataExportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidExportFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrInvalidDownloadLink):
+		return http.StatusForbidden
+	case errors.Is(err, errormsg.ErrDataExportNotFound),
+		errors.Is(err, errormsg.ErrDataExportNotReady):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
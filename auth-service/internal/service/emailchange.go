@@ -0,0 +1,173 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StartEmailChange godoc
+// @Summary Change email
+// @Description Sends a confirmation link to the new email and a cancellation link to the current one. The email changes only after confirmation; a new request replaces the pending one. Requires the current password unless the session was authenticated with a second factor or a user-verified passkey
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body calltypes.EmailChangeRequest true "New email and current password"
+// @Success 202 {object} calltypes.JSONResponse{data=calltypes.PendingEmailChange}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid email"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated, or current password is required"
+// @Failure 403 {object} calltypes.ErrorResponse "Invalid password"
+// @Failure 409 {object} calltypes.ErrorResponse "Email is already taken"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many requests or failed attempts"
+// @Security BearerAuth
+// @Router /users/me/email [post].
+func (s *RewardService) StartEmailChange(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.EmailChangeRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Repo.GetOne(id)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	if !s.reauthenticate(w, r, user, requestPayload.Password) {
+		return
+	}
+
+	pending, err := s.EmailChange.Start(user, requestPayload.Email)
+	if err != nil {
+		httputils.ErrorJSON(w, err, emailChangeErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID: id,
+		Action: audit.ActionEmailChangeRequested,
+		IP:     GetClientIP(r),
+		Details: map[string]interface{}{
+			"email":     pending.Email,
+			"expiresAt": pending.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Confirmation link has been sent to the new email",
+		Data:    pending,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusAccepted, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Changes the email with the token from the confirmation email. The user is logged out everywhere
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body calltypes.EmailChangeTokenRequest true "Confirmation token"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid or expired token"
+// @Failure 409 {object} calltypes.ErrorResponse "Email has been taken since the request"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many requests"
+// @Router /email-change/confirm [post].
+func (s *RewardService) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.EmailChangeTokenRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	change, err := s.EmailChange.Confirm(requestPayload.Token)
+	if err != nil {
+		httputils.ErrorJSON(w, err, emailChangeErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  change.UserID,
+		Action:  audit.ActionEmailChanged,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"email": change.NewEmail},
+	})
+
+	writeUserMessage(w, "Email has been changed")
+}
+
+// CancelEmailChange godoc
+// @Summary Cancel email change
+// @Description Cancels a pending email change with the token from the email sent to the current address
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body calltypes.EmailChangeTokenRequest true "Cancellation token"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid or expired token"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many requests"
+// @Router /email-change/cancel [post].
+func (s *RewardService) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.EmailChangeTokenRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	change, err := s.EmailChange.Cancel(requestPayload.Token)
+	if err != nil {
+		httputils.ErrorJSON(w, err, emailChangeErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  change.UserID,
+		Action:  audit.ActionEmailChangeCancelled,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"email": change.NewEmail},
+	})
+
+	writeUserMessage(w, "Email change has been cancelled")
+}
+
+func emailChangeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidEmail),
+		errors.Is(err, errormsg.ErrSameEmail),
+		errors.Is(err, errormsg.ErrInvalidEmailChange):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrEmailTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// emailLoginCookie binds a pending email login to the browser that started it.
+const emailLoginCookie = "emailLogin"
+
+// StartEmailLogin godoc
+// @Summary Start passwordless login
+// @Description Sends a 6-digit code or a magic link to the email. The response sets a cookie binding the login to this browser; the code or the link works only together with it. The response is the same whether the account exists or not
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.EmailLoginRequest true "Email and method"
+// @Success 202 {object} calltypes.JSONResponse
+// @Header 202 {string} Set-Cookie "emailLogin"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request data"
+// @Router /authenticate/email [post].
+func (s *RewardService) StartEmailLogin(w http.ResponseWriter, r *http.Request) {
+	if s.EmailLogin == nil {
+		httputils.ErrorJSON(w, errormsg.ErrEmailLoginDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload calltypes.EmailLoginRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if requestPayload.Email == "" {
+		httputils.ErrorJSON(w, errormsg.ErrUserNotFound, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Repo.GetByEmail(requestPayload.Email)
+	if err != nil {
+		user = nil
+	}
+
+	pending, err := s.EmailLogin.Start(user, requestPayload.Method)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errormsg.ErrInvalidLoginMethod) {
+			status = http.StatusBadRequest
+		}
+
+		httputils.ErrorJSON(w, err, status)
+
+		return
+	}
+
+	setEmailLoginCookie(w, pending.Binding, pending.ExpiresAt)
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "If the account exists, we have sent a login " + requestPayload.Method + " to " + requestPayload.Email,
+		Data:    map[string]interface{}{"expiresAt": pending.ExpiresAt},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusAccepted, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// VerifyEmailCode godoc
+// @Summary Complete passwordless login with a code
+// @Description Checks the code sent by StartEmailLogin and returns auth cookies. Must be called from the browser that started the login
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.EmailCodeRequest true "Code from the email"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request data"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid or expired code"
+// @Router /authenticate/email/verify [post].
+func (s *RewardService) VerifyEmailCode(w http.ResponseWriter, r *http.Request) {
+	if s.EmailLogin == nil {
+		httputils.ErrorJSON(w, errormsg.ErrEmailLoginDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload calltypes.EmailCodeRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	binding, err := r.Cookie(emailLoginCookie)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidEmailLogin, http.StatusUnauthorized)
+
+		return
+	}
+
+	userID, err := s.EmailLogin.VerifyCode(binding.Value, requestPayload.Code)
+	if err != nil {
+		httputils.ErrorJSON(w, err, emailLoginErrorStatus(err))
+
+		return
+	}
+
+	s.completeEmailLogin(w, r, userID)
+}
+
+// VerifyEmailLink godoc
+// @Summary Complete passwordless login with a magic link
+// @Description Target of the magic link sent by StartEmailLogin. Returns auth cookies when opened in the browser that started the login
+// @Tags Auth
+// @Produce json
+// @Param login query string true "Login ID"
+// @Param expires query int true "Expiry as Unix time"
+// @Param sig query string true "Link signature"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid or expired link"
+// @Router /authenticate/email/link [get].
+func (s *RewardService) VerifyEmailLink(w http.ResponseWriter, r *http.Request) {
+	if s.EmailLogin == nil {
+		httputils.ErrorJSON(w, errormsg.ErrEmailLoginDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	binding, err := r.Cookie(emailLoginCookie)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidEmailLogin, http.StatusUnauthorized)
+
+		return
+	}
+
+	userID, err := s.EmailLogin.VerifyLink(binding.Value, r.URL.Query())
+	if err != nil {
+		httputils.ErrorJSON(w, err, emailLoginErrorStatus(err))
+
+		return
+	}
+
+	s.completeEmailLogin(w, r, userID)
+}
+
+// completeEmailLogin drops the binding cookie and logs the user in.
+func (s *RewardService) completeEmailLogin(w http.ResponseWriter, r *http.Request, userID int) {
+	user, err := s.Repo.GetOne(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	setEmailLoginCookie(w, "", time.Unix(0, 0))
+
+	// The login code or link was delivered to the mailbox, which proves it is the user's.
+	if !user.EmailVerified {
+		if err := s.Repo.MarkEmailVerified(user.ID); err != nil {
+			log.Println("failed to mark email as verified: ", err)
+		}
+	}
+
+	s.completeLogin(w, user, GetClientIP(r), token.AMROTP)
+}
+
+// setEmailLoginCookie stores the login binding. The cookie is Lax so that it is
+// sent when the magic link is opened from a mail client.
+func setEmailLoginCookie(w http.ResponseWriter, binding string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     emailLoginCookie,
+		Value:    binding,
+		Path:     "/authenticate/email",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expires,
+	})
+}
+
+// emailLoginErrorStatus maps email login errors to HTTP status codes.
+func emailLoginErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidEmailLogin), errors.Is(err, errormsg.ErrInvalidLoginCode),
+		errors.Is(err, errormsg.ErrInvalidLoginLink):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DeleteMe godoc
+// @Summary Delete current user
+// @Description Schedules the deletion of the account of the authenticated user. When the grace period ends, the personal data of the user, its sessions and credentials are erased. Repeated requests return the scheduled deletion. Requires the current password unless the session was authenticated with a second factor or a user-verified passkey
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body calltypes.AccountDeletionRequest false "Current password"
+// @Success 202 {object} calltypes.JSONResponse{data=calltypes.AccountDeletion}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request body"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated, or current password is required"
+// @Failure 403 {object} calltypes.ErrorResponse "Invalid password"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many failed attempts"
+// @Security BearerAuth
+// @Router /users/me [delete].
+func (s *RewardService) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.AccountDeletionRequest
+
+	if r.ContentLength != 0 {
+		if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	user, err := s.Repo.GetOne(id)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	if !s.reauthenticate(w, r, user, requestPayload.Password) {
+		return
+	}
+
+	deletion, err := s.Erasure.Schedule(user)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		Action:  audit.ActionDeletionScheduled,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"eraseAt": deletion.EraseAt.Format(time.RFC3339)},
+	})
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Account deletion has been scheduled",
+		Data:    deletion,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusAccepted, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// CancelDeleteMe godoc
+// @Summary Cancel deletion of current user
+// @Description Cancels the scheduled deletion of the account of the authenticated user
+// @Tags Users
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 404 {object} calltypes.ErrorResponse "Deletion is not scheduled"
+// @Security BearerAuth
+// @Router /users/me/deletion [delete].
+func (s *RewardService) CancelDeleteMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	if err := s.Erasure.Cancel(id); err != nil {
+		httputils.ErrorJSON(w, err, erasureErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID: id,
+		Action: audit.ActionDeletionCancelled,
+		IP:     GetClientIP(r),
+	})
+
+	writeUserMessage(w, "Account deletion has been cancelled")
+}
+
+func erasureErrorStatus(err error) int {
+	if errors.Is(err, errormsg.ErrDeletionNotScheduled) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// federatedLoginCookie binds a login through an identity provider to the
+// browser that started it.
+const federatedLoginCookie = "federatedLogin"
+
+// FederationProviders godoc
+// @Summary List identity providers
+// @Description Returns names of the external OpenID providers users can log in with
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse{data=[]string}
+// @Failure 400 {object} calltypes.ErrorResponse "Federated login is disabled"
+// @Router /federation/providers [get].
+func (s *RewardService) FederationProviders(w http.ResponseWriter, _ *http.Request) {
+	if s.Federation == nil {
+		httputils.ErrorJSON(w, errormsg.ErrFederationDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Fetched identity providers",
+		Data:    s.Federation.Providers(),
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// BeginFederatedLogin godoc
+// @Summary Start login through an identity provider
+// @Description Redirects to the authorization endpoint of the external OpenID provider (authorization code flow with PKCE). The response sets a cookie binding the login to this browser
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the identity provider"
+// @Header 302 {string} Set-Cookie "federatedLogin"
+// @Failure 400 {object} calltypes.ErrorResponse "Federated login is disabled"
+// @Failure 404 {object} calltypes.ErrorResponse "Unknown provider"
+// @Failure 502 {object} calltypes.ErrorResponse "Provider is unavailable"
+// @Router /federation/{provider}/login [get].
+func (s *RewardService) BeginFederatedLogin(w http.ResponseWriter, r *http.Request) {
+	if s.Federation == nil {
+		httputils.ErrorJSON(w, errormsg.ErrFederationDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	pending, err := s.Federation.Begin(chi.URLParam(r, "provider"))
+	if err != nil {
+		log.Println("failed to start federated login: ", err)
+		httputils.ErrorJSON(w, err, federationErrorStatus(err))
+
+		return
+	}
+
+	setFederatedLoginCookie(w, pending.State, pending.ExpiresAt)
+
+	http.Redirect(w, r, pending.URL, http.StatusFound)
+}
+
+// CompleteFederatedLogin godoc
+// @Summary Complete login through an identity provider
+// @Description Redirect target of the identity provider. Verifies the ID token and returns auth cookies. On first login the identity is linked to the user with the same email, or a user is created; either way the provider must report the email as verified
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State of the login"
+// @Param error query string false "Error returned by the provider"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid login or ID token"
+// @Failure 403 {object} calltypes.ErrorResponse "Email is not verified by the provider or by the account with that email"
+// @Failure 502 {object} calltypes.ErrorResponse "Provider is unavailable"
+// @Router /federation/{provider}/callback [get].
+func (s *RewardService) CompleteFederatedLogin(w http.ResponseWriter, r *http.Request) {
+	if s.Federation == nil {
+		httputils.ErrorJSON(w, errormsg.ErrFederationDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	binding, err := r.Cookie(federatedLoginCookie)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidFederatedLogin, http.StatusUnauthorized)
+
+		return
+	}
+
+	setFederatedLoginCookie(w, "", time.Unix(0, 0))
+
+	result, err := s.Federation.Complete(chi.URLParam(r, "provider"), binding.Value, r.URL.Query())
+	if err != nil {
+		log.Println("federated login failed: ", err)
+		httputils.ErrorJSON(w, err, federationErrorStatus(err))
+
+		return
+	}
+
+	ip := GetClientIP(r)
+	details := map[string]interface{}{"provider": result.Provider, "subject": result.Subject}
+
+	switch {
+	case result.Provisioned:
+		s.Audit.Record(calltypes.AuditEvent{UserID: result.User.ID, Action: audit.ActionUserProvisioned, IP: ip, Details: details})
+	case result.Linked:
+		s.Audit.Record(calltypes.AuditEvent{UserID: result.User.ID, Action: audit.ActionIdentityLinked, IP: ip, Details: details})
+	}
+
+	s.completeLogin(w, result.User, ip)
+}
+
+// setFederatedLoginCookie stores the state of the login. The cookie is Lax so
+// that it is sent with the redirect back from the provider.
+func setFederatedLoginCookie(w http.ResponseWriter, state string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     federatedLoginCookie,
+		Value:    state,
+		Path:     "/federation",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expires,
+	})
+}
+
+// federationErrorStatus maps federated login errors to HTTP status codes.
+func federationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrUnknownProvider):
+		return http.StatusNotFound
+	case errors.Is(err, errormsg.ErrInvalidFederatedLogin), errors.Is(err, errormsg.ErrFederatedLoginDenied),
+		errors.Is(err, errormsg.ErrInvalidIDToken), errors.Is(err, errormsg.ErrInvalidSAMLResponse),
+		errors.Is(err, errormsg.ErrInvalidSAMLSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, errormsg.ErrUnverifiedEmail), errors.Is(err, errormsg.ErrUnverifiedLocalEmail):
+		return http.StatusForbidden
+	case errors.Is(err, errormsg.ErrProviderUnavailable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
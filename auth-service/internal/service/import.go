@@ -0,0 +1,100 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"net/http"
+)
+
+// ImportLegacyUsers inserts users carrying password hashes from legacy systems
+// and reports the outcome for every entry. A failed entry does not stop the import.
+// Users without a status are imported as active.
+func ImportLegacyUsers(repo repository.Repository, users []calltypes.ImportUser) []calltypes.ImportResult {
+	results := make([]calltypes.ImportResult, 0, len(users))
+
+	for _, imported := range users {
+		result := calltypes.ImportResult{Email: imported.Email}
+
+		status := imported.Status
+		if status == "" {
+			status = calltypes.UserStatusActive
+		}
+
+		if !status.Valid() {
+			result.Error = fmt.Sprintf("unknown status %q", status)
+			results = append(results, result)
+
+			continue
+		}
+
+		id, err := repo.Import(calltypes.User{
+			Email:     imported.Email,
+			FirstName: imported.FirstName,
+			LastName:  imported.LastName,
+			Password:  imported.PasswordHash,
+			Status:    status,
+		})
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		result.ID = id
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// ImportUsers godoc
+// @Summary Import users from legacy systems
+// @Description Creates users with their existing password hash (bcrypt, PBKDF2-SHA256, scrypt or SHA-512 crypt)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body calltypes.ImportRequest true "Users to import"
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.ImportResult}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request data"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/users/import [post].
+func (s *RewardService) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.ImportRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	if len(requestPayload.Users) == 0 {
+		httputils.ErrorJSON(w, errormsg.ErrEmptyImport, http.StatusBadRequest)
+
+		return
+	}
+
+	results := ImportLegacyUsers(s.Repo, requestPayload.Users)
+
+	imported := 0
+
+	for _, result := range results {
+		if result.Error == "" {
+			imported++
+		}
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Imported %d of %d users", imported, len(results)),
+		Data:    results,
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/service"
+	"auth-service/pkg/errormsg"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewardService_ImportUsers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockSetup      func(*MockRepository)
+		expectedStatus int
+		expectedResult []calltypes.ImportResult
+	}{
+		{
+			name: "Partial import",
+			requestBody: `{"users": [
+				{"email": "old@example.com", "passwordHash": "$scrypt$ln=10,r=8,p=1$c2FsdA$aGFzaA"},
+				{"email": "bad@example.com", "passwordHash": "plain"}
+			]}`,
+			mockSetup: func(m *MockRepository) {
+				m.On("Import", mock.MatchedBy(func(u calltypes.User) bool {
+					return u.Email == "old@example.com" && u.Status == calltypes.UserStatusActive
+				})).Return(7, nil)
+				m.On("Import", mock.MatchedBy(func(u calltypes.User) bool {
+					return u.Email == "bad@example.com"
+				})).Return(0, errormsg.ErrUnsupportedHash)
+			},
+			expectedStatus: http.StatusOK,
+			expectedResult: []calltypes.ImportResult{
+				{Email: "old@example.com", ID: 7},
+				{Email: "bad@example.com", Error: errormsg.ErrUnsupportedHash.Error()},
+			},
+		},
+		{
+			name: "Unknown status",
+			requestBody: `{"users": [
+				{"email": "old@example.com", "passwordHash": "$scrypt$ln=10,r=8,p=1$c2FsdA$aGFzaA", "status": "banned"}
+			]}`,
+			mockSetup:      func(_ *MockRepository) {},
+			expectedStatus: http.StatusOK,
+			expectedResult: []calltypes.ImportResult{
+				{Email: "old@example.com", Error: `unknown status "banned"`},
+			},
+		},
+		{
+			name:           "Empty import",
+			requestBody:    `{"users": []}`,
+			mockSetup:      func(_ *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockRepo := new(MockRepository)
+			tt.mockSetup(mockRepo)
+
+			svc := service.NewRewardService(mockRepo)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/import", strings.NewReader(tt.requestBody))
+			rr := httptest.NewRecorder()
+
+			svc.ImportUsers(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+
+			if tt.expectedResult != nil {
+				var response struct {
+					Data []calltypes.ImportResult `json:"data"`
+				}
+
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+				assert.Equal(t, tt.expectedResult, response.Data)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"net/http"
+)
+
+// UnlockUser godoc
+// @Summary Unlock user account
+// @Description Clears failed login attempts and the temporary lock of the account
+// @Tags Admin
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id path int true "User ID"
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or user not found"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Failure 500 {object} calltypes.ErrorResponse "Internal server error"
+// @Router /admin/users/{id}/unlock [post].
+func (s *RewardService) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := GetIDFromURL(r, "id")
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidID, http.StatusBadRequest)
+
+		return
+	}
+
+	if s.Lockout == nil {
+		httputils.ErrorJSON(w, errormsg.ErrLockoutDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Repo.GetOne(id)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	if err := s.Lockout.Unlock(user.Email); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("User %d has been unlocked", id),
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
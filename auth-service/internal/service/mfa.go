@@ -0,0 +1,365 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/internal/mfa"
+	"auth-service/internal/token"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+)
+
+// requireMFA answers a successful first factor of a user with MFA enabled with
+// a pending login challenge instead of tokens. The challenge keeps amr of the
+// first factor for the tokens issued after the second one.
+func (s *RewardService) requireMFA(w http.ResponseWriter, user *calltypes.User, ip string, amr []string) {
+	challenge, expiresAt, err := s.MFA.NewChallenge(user.ID, ip, amr)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Second factor is required",
+		Data: calltypes.MFAChallengeResponse{
+			MFARequired: true,
+			Challenge:   challenge,
+			Methods:     []string{mfa.MethodTOTP, mfa.MethodRecoveryCode},
+			ExpiresAt:   expiresAt,
+		},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// AuthenticateMFA godoc
+// @Summary Complete MFA login
+// @Description Checks TOTP code or a recovery code for the challenge returned by Authenticate and returns auth cookies
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.MFALoginRequest true "Challenge and code"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request data"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid challenge or code"
+// @Router /authenticate/mfa [post].
+func (s *RewardService) AuthenticateMFA(w http.ResponseWriter, r *http.Request) {
+	if s.MFA == nil {
+		httputils.ErrorJSON(w, errormsg.ErrMFADisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload calltypes.MFALoginRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	ip := GetClientIP(r)
+
+	challenge, err := s.MFA.Challenge(requestPayload.Challenge, ip)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidMFAChallenge, http.StatusUnauthorized)
+
+		return
+	}
+
+	if err := s.verifySecondFactor(challenge.UserID, ip, requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusUnauthorized)
+
+		return
+	}
+
+	if err := s.MFA.CompleteChallenge(challenge); err != nil {
+		log.Println("failed to delete MFA challenge: ", err)
+	}
+
+	amr := slices.Clone(challenge.AMR)
+	for _, method := range []string{token.AMROTP, token.AMRMFA} {
+		if !slices.Contains(amr, method) {
+			amr = append(amr, method)
+		}
+	}
+
+	if err := s.issueTokens(w, challenge.UserID, ip, amr...); err != nil {
+		httputils.ErrorJSON(w, err, tokenErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Second factor accepted",
+		Data:    map[string]interface{}{"user_id": challenge.UserID},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// verifySecondFactor checks TOTP code or consumes a recovery code of the user.
+// Every consumed recovery code is recorded in the audit trail.
+func (s *RewardService) verifySecondFactor(userID int, ip string, request calltypes.MFALoginRequest) error {
+	switch {
+	case request.RecoveryCode != "":
+		if err := s.MFA.UseRecoveryCode(userID, request.RecoveryCode); err != nil {
+			return err
+		}
+
+		s.Audit.Record(calltypes.AuditEvent{
+			UserID:  userID,
+			ActorID: userID,
+			Action:  audit.ActionRecoveryCodeUsed,
+			IP:      ip,
+		})
+
+		return nil
+	case request.Code != "":
+		return s.MFA.VerifyTOTP(userID, request.Code)
+	default:
+		return errormsg.ErrMFACodeRequired
+	}
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generates TOTP secret and otpauth URI for the current user. TOTP is enabled after confirmation. A session authenticated with a second factor or a user-verified passkey, e.g. after a recovery code login, may replace an enabled TOTP; the current one keeps working until the new one is confirmed
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.TOTPEnrollment}
+// @Failure 400 {object} calltypes.ErrorResponse "MFA is disabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 409 {object} calltypes.ErrorResponse "MFA is already enabled and the session is not stepped up"
+// @Security BearerAuth
+// @Router /mfa/totp/enroll [post].
+func (s *RewardService) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	if s.MFA == nil {
+		httputils.ErrorJSON(w, errormsg.ErrMFADisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	user, err := s.Repo.GetOne(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	enroll := s.MFA.Enroll
+	if middleware.SteppedUp(r.Context()) {
+		enroll = s.MFA.Reenroll
+	}
+
+	enrollment, err := enroll(user.ID, user.Email)
+	if err != nil {
+		httputils.ErrorJSON(w, err, mfaErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Scan the URI with an authenticator app and confirm with the first code",
+		Data:    enrollment,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enables TOTP for the current user after checking the first code and returns recovery codes
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body calltypes.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.RecoveryCodes}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid code"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 409 {object} calltypes.ErrorResponse "MFA is already enabled"
+// @Security BearerAuth
+// @Router /mfa/totp/confirm [post].
+func (s *RewardService) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	if s.MFA == nil {
+		httputils.ErrorJSON(w, errormsg.ErrMFADisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.TOTPCodeRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	codes, err := s.MFA.Confirm(userID, requestPayload.Code)
+	if err != nil {
+		httputils.ErrorJSON(w, err, mfaErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("TOTP has been enabled for user %d, store the recovery codes safely", userID),
+		Data:    calltypes.RecoveryCodes{RecoveryCodes: codes},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate MFA recovery codes
+// @Description Replaces all recovery codes of the current user with a new set. Requires a valid TOTP code or recovery code unless the session was authenticated with a second factor or a user-verified passkey
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body calltypes.MFACodeRequest false "TOTP code or recovery code"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.RecoveryCodes}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid code or MFA is not enabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Security BearerAuth
+// @Router /mfa/recovery-codes [post].
+func (s *RewardService) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if s.MFA == nil {
+		httputils.ErrorJSON(w, errormsg.ErrMFADisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.MFACodeRequest
+
+	if r.ContentLength != 0 {
+		if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if err := s.verifyMFAOwnership(r, userID, requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, mfaErrorStatus(err))
+
+		return
+	}
+
+	codes, err := s.MFA.GenerateRecoveryCodes(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  userID,
+		ActorID: userID,
+		Action:  audit.ActionRecoveryCodesRegenerated,
+		IP:      GetClientIP(r),
+	})
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Recovery codes have been regenerated, previous codes are no longer valid",
+		Data:    calltypes.RecoveryCodes{RecoveryCodes: codes},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// verifyMFAOwnership checks that the caller controls the second factor of the
+// user: either the session was authenticated with a strong factor or the
+// request carries a valid TOTP code or recovery code.
+func (s *RewardService) verifyMFAOwnership(r *http.Request, userID int, request calltypes.MFACodeRequest) error {
+	if middleware.SteppedUp(r.Context()) {
+		enabled, err := s.MFA.Enabled(userID)
+		if err != nil {
+			return err
+		}
+
+		if !enabled {
+			return errormsg.ErrMFANotEnrolled
+		}
+
+		return nil
+	}
+
+	return s.verifySecondFactor(userID, GetClientIP(r), calltypes.MFALoginRequest{
+		Code:         request.Code,
+		RecoveryCode: request.RecoveryCode,
+	})
+}
+
+// mfaErrorStatus maps MFA errors to HTTP status codes.
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrMFAAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, errormsg.ErrInvalidMFACode), errors.Is(err, errormsg.ErrMFANotEnrolled),
+		errors.Is(err, errormsg.ErrMFACodeReused), errors.Is(err, errormsg.ErrInvalidRecoveryCode),
+		errors.Is(err, errormsg.ErrMFACodeRequired):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
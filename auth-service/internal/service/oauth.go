@@ -0,0 +1,277 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/oauth"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// OAuthAuthorize godoc
+// @Summary OAuth 2.0 authorization endpoint
+// @Description Issues an authorization code to the logged in user and redirects back to the client. Only response_type=code with PKCE (S256) is supported and redirect_uri must exactly match one of the registered URIs. Users are not asked for consent, so only clients registered as first-party may use it; others get unauthorized_client. Users without a session are redirected to the login page when OAUTH_LOGIN_URL is set. Session cookies are SameSite=Strict and are not sent when the user comes from another site, so such users pass through the login page too. The openid scope makes it an OpenID Connect authentication request
+// @Tags OAuth
+// @Produce json
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Param scope query string false "Space separated scopes"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "OpenID Connect nonce returned in the ID token"
+// @Param prompt query string false "none to fail with login_required instead of asking to log in"
+// @Success 302 "Redirect to the client with code or error"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid client or redirect URI"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Router /oauth/authorize [get].
+func (s *RewardService) OAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	request, err := s.OAuth.ParseAuthorizeRequest(r.URL.Query())
+	if err != nil {
+		// Without a trusted redirect URI the error must not be sent anywhere.
+		if request == nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+
+		http.Redirect(w, r, s.OAuth.ErrorRedirect(request, err), http.StatusFound)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok && !request.Interactive() {
+		http.Redirect(w, r, s.OAuth.ErrorRedirect(request, errormsg.ErrLoginRequired), http.StatusFound)
+
+		return
+	}
+
+	if !ok {
+		s.redirectToLogin(w, r)
+
+		return
+	}
+
+	location, err := s.OAuth.Authorize(request, userID)
+	if err != nil {
+		log.Println("failed to issue authorization code: ", err)
+
+		location = s.OAuth.ErrorRedirect(request, err)
+	}
+
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// OAuthToken godoc
+// @Summary OAuth 2.0 token endpoint
+// @Description Exchanges an authorization code (with code_verifier) or a refresh token for tokens, or issues a token of the client itself with client_credentials. Devices poll it with the device code until the user decides (authorization_pending, slow_down). Token exchange swaps a user access token for a token restricted to one of the audiences registered for the client, with the client in the act claim. Grants with the openid scope also get an ID token. Confidential clients authenticate with HTTP Basic, client_secret in the form or a private_key_jwt assertion, as registered; public clients send client_id only. Refresh tokens are rotated on every use
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token, client_credentials, urn:ietf:params:oauth:grant-type:device_code or urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param device_code formData string false "Device code of the device authorization"
+// @Param subject_token formData string false "Access token of the user to exchange"
+// @Param subject_token_type formData string false "urn:ietf:params:oauth:token-type:access_token"
+// @Param requested_token_type formData string false "urn:ietf:params:oauth:token-type:access_token"
+// @Param audience formData string false "Service the exchanged token is restricted to"
+// @Param scope formData string false "Requested scope, or narrowed down scope for refresh and token exchange"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "Client assertion signed with a registered key"
+// @Success 200 {object} calltypes.OAuthTokenResponse
+// @Failure 400 {object} calltypes.OAuthErrorResponse "Invalid request or grant"
+// @Failure 401 {object} calltypes.OAuthErrorResponse "Client authentication failed"
+// @Router /oauth/token [post].
+func (s *RewardService) OAuthToken(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, consts.Megabyte)
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, fmt.Errorf("%w: malformed form", errormsg.ErrInvalidOAuthRequest))
+
+		return
+	}
+
+	credentials, err := oauthClientCredentials(r)
+	if err != nil {
+		writeOAuthError(w, err)
+
+		return
+	}
+
+	response, err := s.OAuth.Exchange(r.PostForm, credentials, GetClientIP(r))
+	if err != nil {
+		writeOAuthError(w, err)
+
+		return
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, response, noStoreHeaders())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// RegisterOAuthClient godoc
+// @Summary Register OAuth client
+// @Description Registers an application allowed to obtain tokens. The client secret is returned only once. Only first-party clients (firstParty) may use the authorization endpoint, which does not ask users for consent
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body calltypes.OAuthClientRequest true "Client metadata"
+// @Success 201 {object} calltypes.JSONResponse{data=calltypes.OAuthClientCredentials}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid client metadata"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/oauth/clients [post].
+func (s *RewardService) RegisterOAuthClient(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload calltypes.OAuthClientRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	credentials, err := s.OAuth.RegisterClient(requestPayload)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errormsg.ErrInvalidClientMetadata) {
+			status = http.StatusBadRequest
+		}
+
+		httputils.ErrorJSON(w, err, status)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "OAuth client registered, store the secret now: it is not shown again",
+		Data:    credentials,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusCreated, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// redirectToLogin sends a user without a session to the login page, which
+// returns to the authorization request afterwards.
+func (s *RewardService) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	loginURL, err := url.Parse(s.OAuth.Config().LoginURL)
+	if err != nil || s.OAuth.Config().LoginURL == "" {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	query := loginURL.Query()
+	query.Set("return_to", r.URL.RequestURI())
+	loginURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, loginURL.String(), http.StatusFound)
+}
+
+// oauthClientCredentials reads client credentials from HTTP Basic
+// authentication or from the form, including private_key_jwt assertions. Using
+// more than one method is an error.
+func oauthClientCredentials(r *http.Request) (oauth.ClientCredentials, error) {
+	id, secret, basic := r.BasicAuth()
+	if !basic {
+		return oauth.ClientCredentials{
+			ID:            r.PostForm.Get("client_id"),
+			Secret:        r.PostForm.Get("client_secret"),
+			AssertionType: r.PostForm.Get("client_assertion_type"),
+			Assertion:     r.PostForm.Get("client_assertion"),
+		}, nil
+	}
+
+	if r.PostForm.Has("client_secret") || r.PostForm.Has("client_assertion") {
+		return oauth.ClientCredentials{}, fmt.Errorf("%w: more than one client authentication method", errormsg.ErrInvalidOAuthRequest)
+	}
+
+	// Credentials are form-encoded before being put into the header (RFC 6749 section 2.3.1).
+	id, idErr := url.QueryUnescape(id)
+	secret, secretErr := url.QueryUnescape(secret)
+
+	if idErr != nil || secretErr != nil {
+		return oauth.ClientCredentials{}, errormsg.ErrInvalidClient
+	}
+
+	if formID := r.PostForm.Get("client_id"); formID != "" && formID != id {
+		return oauth.ClientCredentials{}, errormsg.ErrInvalidClient
+	}
+
+	return oauth.ClientCredentials{ID: id, Secret: secret, Basic: true}, nil
+}
+
+// writeOAuthError writes an error response of the token endpoint.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	code := oauth.ErrorCode(err)
+	headers := noStoreHeaders()
+	payload := calltypes.OAuthErrorResponse{Error: code, ErrorDescription: err.Error()}
+
+	status := http.StatusBadRequest
+
+	switch code {
+	case oauth.ErrorInvalidClient:
+		status = http.StatusUnauthorized
+
+		headers.Set("WWW-Authenticate", `Basic realm="oauth"`)
+	case oauth.ErrorServerError:
+		status = http.StatusInternalServerError
+
+		log.Println("OAuth token request failed: ", err)
+
+		payload.ErrorDescription = ""
+	}
+
+	if err := httputils.WriteJSON(w, status, payload, headers); err != nil {
+		log.Println("failed to write OAuth error: ", err)
+	}
+}
+
+// noStoreHeaders forbid caching of responses carrying tokens.
+func noStoreHeaders() http.Header {
+	headers := http.Header{}
+	headers.Set("Cache-Control", "no-store")
+	headers.Set("Pragma", "no-cache")
+
+	return headers
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OAuthDeviceAuthorization godoc
+// @Summary OAuth 2.0 device authorization endpoint
+// @Description Starts the device authorization grant (RFC 8628) for devices without a browser. The device shows the user code and the verification URI, then polls the token endpoint with grant_type urn:ietf:params:oauth:grant-type:device_code until the user decides. Clients authenticate as at the token endpoint
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Param scope formData string false "Space separated scopes"
+// @Success 200 {object} calltypes.OAuthDeviceAuthorizationResponse
+// @Failure 400 {object} calltypes.OAuthErrorResponse "Invalid request or scope"
+// @Failure 401 {object} calltypes.OAuthErrorResponse "Client authentication failed"
+// @Router /oauth/device_authorization [post].
+func (s *RewardService) OAuthDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, consts.Megabyte)
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, fmt.Errorf("%w: malformed form", errormsg.ErrInvalidOAuthRequest))
+
+		return
+	}
+
+	credentials, err := oauthClientCredentials(r)
+	if err != nil {
+		writeOAuthError(w, err)
+
+		return
+	}
+
+	response, err := s.OAuth.AuthorizeDevice(r.PostForm, credentials)
+	if err != nil {
+		writeOAuthError(w, err)
+
+		return
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, response, noStoreHeaders())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// OAuthDeviceRequest godoc
+// @Summary Look up a device authorization
+// @Description Returns the client and the scope a user code was issued for, to be shown to the user before approving
+// @Tags OAuth
+// @Produce json
+// @Param user_code query string true "User code shown by the device"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.OAuthDeviceRequest}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid or expired user code"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Router /oauth/device [get].
+func (s *RewardService) OAuthDeviceRequest(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	request, err := s.OAuth.DeviceRequest(r.URL.Query().Get("user_code"))
+	if err != nil {
+		httputils.ErrorJSON(w, err, deviceErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Device authorization is waiting for your decision",
+		Data:    request,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// DecideOAuthDevice godoc
+// @Summary Approve or deny a device authorization
+// @Description Approves or denies the device authorization of the user code for the logged in user. The device polling the token endpoint gets tokens or access_denied
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.OAuthDeviceDecision true "User code and decision"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid or expired user code"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many attempts"
+// @Router /oauth/device [post].
+func (s *RewardService) DecideOAuthDevice(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil {
+		httputils.ErrorJSON(w, errormsg.ErrOAuthDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.OAuthDeviceDecision
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	request, err := s.OAuth.DeviceRequest(requestPayload.UserCode)
+	if err != nil {
+		httputils.ErrorJSON(w, err, deviceErrorStatus(err))
+
+		return
+	}
+
+	if err := s.OAuth.DecideDevice(requestPayload.UserCode, userID, requestPayload.Approve); err != nil {
+		httputils.ErrorJSON(w, err, deviceErrorStatus(err))
+
+		return
+	}
+
+	action, message := audit.ActionDeviceDenied, "Device authorization denied"
+	if requestPayload.Approve {
+		action, message = audit.ActionDeviceApproved, "Device authorized, you can return to it"
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  userID,
+		ActorID: userID,
+		Action:  action,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"client_id": request.ClientID, "scope": request.Scope},
+	})
+
+	err = httputils.WriteJSON(w, http.StatusOK, calltypes.JSONResponse{Error: false, Message: message})
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// deviceErrorStatus maps device authorization errors to HTTP status codes.
+func deviceErrorStatus(err error) int {
+	if errors.Is(err, errormsg.ErrInvalidUserCode) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
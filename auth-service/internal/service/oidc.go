@@ -0,0 +1,111 @@
+package service
+
+import (
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/oauth"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// OpenIDConfiguration godoc
+// @Summary OpenID provider metadata
+// @Description Discovery document of the OpenID Connect provider (OpenID Connect Discovery 1.0)
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} calltypes.OpenIDConfiguration
+// @Failure 400 {object} calltypes.ErrorResponse "OpenID Connect is disabled"
+// @Router /.well-known/openid-configuration [get].
+func (s *RewardService) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil || !s.OAuth.OIDCEnabled() {
+		httputils.ErrorJSON(w, errormsg.ErrOIDCDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, s.OAuth.Discovery())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// OAuthJWKS godoc
+// @Summary ID token signing keys
+// @Description Public keys ID tokens are signed with, as a JWK set
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} calltypes.JSONWebKeySet
+// @Failure 400 {object} calltypes.ErrorResponse "OpenID Connect is disabled"
+// @Router /oauth/jwks [get].
+func (s *RewardService) OAuthJWKS(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil || !s.OAuth.OIDCEnabled() {
+		httputils.ErrorJSON(w, errormsg.ErrOIDCDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, s.OAuth.JWKS())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo endpoint
+// @Description Returns claims about the user of the access token. The token must be issued to an OAuth client with the openid scope; email and profile scopes release the email and names
+// @Tags OAuth
+// @Produce json
+// @Param Authorization header string true "Bearer access token"
+// @Success 200 {object} calltypes.UserInfo
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Access token lacks the openid scope"
+// @Router /userinfo [get]
+// @Router /userinfo [post].
+func (s *RewardService) UserInfo(w http.ResponseWriter, r *http.Request) {
+	if s.OAuth == nil || !s.OAuth.OIDCEnabled() {
+		httputils.ErrorJSON(w, errormsg.ErrOIDCDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		userInfoForbidden(w)
+
+		return
+	}
+
+	info, err := s.OAuth.UserInfo(userID, middleware.ScopesFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, errormsg.ErrInsufficientScope) {
+			userInfoForbidden(w)
+
+			return
+		}
+
+		log.Println("failed to fetch userinfo: ", err)
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusInternalServerError)
+
+		return
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, info, noStoreHeaders())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// userInfoForbidden rejects tokens without the openid scope (RFC 6750 section 3.1).
+func userInfoForbidden(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+oauth.ScopeOpenID+`"`)
+
+	httputils.ErrorJSON(w, errormsg.ErrInsufficientScope, http.StatusForbidden)
+}
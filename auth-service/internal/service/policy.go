@@ -0,0 +1,41 @@
+package service
+
+import (
+	"auth-service/pkg/errormsg"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PolicyResource loads the user in the URL of /users/{id}/... routes for
+// policy rules. Other routes and unknown users have no resource.
+func (s *RewardService) PolicyResource(r *http.Request) (map[string]interface{}, error) {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil || !strings.Contains(routeContext.RoutePattern(), "/users/{id}") {
+		return nil, nil //nolint: nilnil
+	}
+
+	id, err := GetIDFromURL(r, "id")
+	if err != nil {
+		return nil, nil //nolint: nilnil
+	}
+
+	user, err := s.Repo.GetOne(id)
+	if err != nil {
+		if errors.Is(err, errormsg.ErrUserNotFound) {
+			return nil, nil //nolint: nilnil
+		}
+
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"type":          "user",
+		"id":            user.ID,
+		"email":         user.Email,
+		"emailVerified": user.EmailVerified,
+		"status":        string(user.Status),
+	}, nil
+}
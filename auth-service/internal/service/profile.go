@@ -0,0 +1,141 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/pkg/errormsg"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UpdateMe godoc
+// @Summary Update current user
+// @Description Changes the profile of the authenticated user with a JSON merge patch: members left out are kept, null removes a name. Only firstName and lastName can be changed. If-Match must carry the ETag of the user from GET /users/me, or * to overwrite any version
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "ETag of the user"
+// @Param request body calltypes.ProfilePatch true "Merge patch"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.User}
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid patch"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 412 {object} calltypes.ErrorResponse "User has been changed since the ETag"
+// @Failure 415 {object} calltypes.ErrorResponse "Unsupported content type"
+// @Failure 428 {object} calltypes.ErrorResponse "If-Match header is missing"
+// @Security BearerAuth
+// @Router /users/me [patch].
+func (s *RewardService) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	if !mergePatchContentType(r.Header.Get("Content-Type")) {
+		httputils.ErrorJSON(w, errormsg.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType)
+
+		return
+	}
+
+	version, err := ifMatchVersion(r.Header.Get("If-Match"))
+	if err != nil {
+		httputils.ErrorJSON(w, err, profileErrorStatus(err))
+
+		return
+	}
+
+	var patch map[string]json.RawMessage
+
+	if err := httputils.ReadJSON(w, r, &patch); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Profile.Patch(id, version, patch)
+	if err != nil {
+		httputils.ErrorJSON(w, err, profileErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Profile has been updated",
+		Data:    user,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload, http.Header{"ETag": {userETag(user)}})
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// userETag returns the entity tag of the user.
+func userETag(user *calltypes.User) string {
+	return strconv.Quote(strconv.Itoa(user.Version))
+}
+
+// ifMatchVersion reads the version of the user from the If-Match header. The
+// wildcard matches any version and is returned as zero.
+func ifMatchVersion(header string) (int, error) {
+	header = strings.TrimSpace(header)
+
+	switch {
+	case header == "":
+		return 0, errormsg.ErrPreconditionRequired
+	case header == "*":
+		return 0, nil
+	}
+
+	// Lists of entity tags and weak tags never match the single strong tag
+	// of the user.
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, errormsg.ErrVersionConflict
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, errormsg.ErrVersionConflict
+	}
+
+	return version, nil
+}
+
+// mergePatchContentType reports whether the media type is JSON merge patch or
+// plain JSON, which is accepted for clients unable to set the former.
+func mergePatchContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "application/merge-patch+json", "application/json":
+		return true
+	default:
+		return false
+	}
+}
+
+// profileErrorStatus maps profile errors to HTTP status codes.
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidProfile):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errormsg.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errormsg.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListRoles godoc
+// @Summary List roles
+// @Description Returns all roles with the permissions they grant
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.Role}
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/roles [get].
+func (s *RewardService) ListRoles(w http.ResponseWriter, _ *http.Request) {
+	roles, err := s.RBAC.Roles()
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeRoles(w, roles)
+}
+
+// SaveRole godoc
+// @Summary Create or update role
+// @Description Creates the role or replaces its description and permissions. Permissions look like resource:action, e.g. users:read. Users get new permissions with their next access token
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param name path string true "Role name"
+// @Param request body calltypes.RoleRequest true "Role settings"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.Role}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid role"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/roles/{name} [put].
+func (s *RewardService) SaveRole(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.RoleRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	role, err := s.RBAC.SaveRole(chi.URLParam(r, "name"), requestPayload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, roleErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		Action:  audit.ActionRoleUpdated,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"role": role.Name, "permissions": role.Permissions},
+	})
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Role %s has been saved", role.Name),
+		Data:    role,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// DeleteRole godoc
+// @Summary Delete role
+// @Description Deletes the role and takes it away from its users
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param name path string true "Role name"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} calltypes.ErrorResponse "Role not found"
+// @Router /admin/roles/{name} [delete].
+func (s *RewardService) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := s.RBAC.DeleteRole(name); err != nil {
+		httputils.ErrorJSON(w, err, roleErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		Action:  audit.ActionRoleDeleted,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"role": name},
+	})
+
+	writeRoleMessage(w, fmt.Sprintf("Role %s has been deleted", name))
+}
+
+// ListUserRoles godoc
+// @Summary List user roles
+// @Description Returns roles assigned to the user
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.Role}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or user not found"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Router /admin/users/{id}/roles [get].
+func (s *RewardService) ListUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.userFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := s.RBAC.UserRoles(id)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	writeRoles(w, roles)
+}
+
+// AssignRole godoc
+// @Summary Assign role
+// @Description Assigns the role to the user. The user gets its permissions with the next access token
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or user not found"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} calltypes.ErrorResponse "Role not found"
+// @Router /admin/users/{id}/roles/{role} [put].
+func (s *RewardService) AssignRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.userFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	role := chi.URLParam(r, "role")
+
+	if err := s.RBAC.Assign(id, role); err != nil {
+		httputils.ErrorJSON(w, err, roleErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		Action:  audit.ActionRoleAssigned,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"role": role},
+	})
+
+	writeRoleMessage(w, fmt.Sprintf("Role %s has been assigned to user %d", role, id))
+}
+
+// UnassignRole godoc
+// @Summary Unassign role
+// @Description Takes the role away from the user. Access tokens already issued keep it until they expire
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or user not found"
+// @Failure 403 {object} calltypes.ErrorResponse "Admin token is invalid"
+// @Failure 404 {object} calltypes.ErrorResponse "Role is not assigned"
+// @Router /admin/users/{id}/roles/{role} [delete].
+func (s *RewardService) UnassignRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.userFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	role := chi.URLParam(r, "role")
+
+	if err := s.RBAC.Unassign(id, role); err != nil {
+		httputils.ErrorJSON(w, err, roleErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		Action:  audit.ActionRoleUnassigned,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"role": role},
+	})
+
+	writeRoleMessage(w, fmt.Sprintf("Role %s has been unassigned from user %d", role, id))
+}
+
+// userFromURL returns ID of an existing user from the URL, writing an error
+// response otherwise.
+func (s *RewardService) userFromURL(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := GetIDFromURL(r, "id")
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidID, http.StatusBadRequest)
+
+		return 0, false
+	}
+
+	if _, err := s.Repo.GetOne(id); err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+func writeRoles(w http.ResponseWriter, roles []*calltypes.Role) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Fetched %d roles", len(roles)),
+		Data:    roles,
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+func writeRoleMessage(w http.ResponseWriter, message string) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: message,
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// roleErrorStatus maps RBAC errors to HTTP status codes.
+func roleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrRoleNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,7 +1,24 @@
 package service
 
 import (
+	"auth-service/internal/apikey"
+	"auth-service/internal/audit"
+	"auth-service/internal/dataexport"
+	"auth-service/internal/emailchange"
+	"auth-service/internal/emaillogin"
+	"auth-service/internal/erasure"
+	"auth-service/internal/federation"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mfa"
+	"auth-service/internal/oauth"
+	"auth-service/internal/passwordreset"
+	"auth-service/internal/policy"
 	"auth-service/internal/postgres/repository"
+	"auth-service/internal/profile"
+	"auth-service/internal/rbac"
+	"auth-service/internal/saml"
+	"auth-service/internal/useradmin"
+	"auth-service/internal/webauthn"
 	"net/http"
 )
 
@@ -15,6 +32,23 @@ type RewardServiceInterface interface {
 
 type RewardService struct {
 	RewardServiceInterface
-	Repo   repository.Repository
-	Client *http.Client
+	Repo          repository.Repository
+	Client        *http.Client
+	Lockout       *lockout.Guard
+	MFA           *mfa.Manager
+	Audit         *audit.Logger
+	Passkey       *webauthn.RelyingParty
+	EmailLogin    *emaillogin.Manager
+	OAuth         *oauth.Server
+	Federation    *federation.Manager
+	SAML          *saml.ServiceProvider
+	APIKeys       *apikey.Manager
+	RBAC          *rbac.Manager
+	Policy        *policy.Engine
+	Users         *useradmin.Manager
+	PasswordReset *passwordreset.Manager
+	Profile       *profile.Manager
+	EmailChange   *emailchange.Manager
+	Erasure       *erasure.Manager
+	DataExport    *dataexport.Manager
 }
@@ -4,12 +4,16 @@ package service
 import (
 	"auth-service/api/calltypes"
 	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
 	"auth-service/internal/postgres/repository"
 	"auth-service/internal/token"
 	"auth-service/pkg/consts"
 	"auth-service/pkg/errormsg"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -57,13 +61,11 @@ func GetIDFromURL(r *http.Request, paramName string) (int, error) {
 	return id, nil
 }
 
+// GetClientIP returns the IP of the client without the port. Lockouts and
+// bindings of tokens to clients are keyed by it, so IPv6 clients must not
+// collapse into one value.
 func GetClientIP(r *http.Request) string {
-	ip := r.RemoteAddr
-	if strings.Contains(ip, ":") {
-		ip = strings.Split(ip, ":")[0]
-	}
-
-	return ip
+	return middleware.ClientIP(r)
 }
 
 // Registrate godoc
@@ -82,7 +84,6 @@ func (s *RewardService) Registrate(w http.ResponseWriter, r *http.Request) {
 		FirstName string `json:"firstName"`
 		LastName  string `json:"lastName"`
 		Password  string `json:"password"`
-		Active    int    `json:"active,omitempty"`
 		Score     int    `json:"score,omitempty"`
 		Referrer  string `json:"referrer,omitempty"`
 	}
@@ -105,7 +106,7 @@ func (s *RewardService) Registrate(w http.ResponseWriter, r *http.Request) {
 		FirstName: requestPayload.FirstName,
 		LastName:  requestPayload.LastName,
 		Password:  requestPayload.Password,
-		Active:    requestPayload.Active,
+		Status:    calltypes.UserStatusActive,
 	}
 
 	id, err := s.Repo.Insert(user)
@@ -169,6 +170,9 @@ func (s *RewardService) GetLeaderboard(w http.ResponseWriter, _ *http.Request) {
 // @Header 200 {string} Set-Cookie "accessToken"
 // @Header 200 {string} Set-Cookie "refreshToken"
 // @Failure 400 {object} calltypes.ErrorResponse "Invalid credentials"
+// @Failure 403 {object} calltypes.ErrorResponse "User is not active"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many failed attempts"
+// @Header 429 {integer} Retry-After "Seconds to wait before the next attempt"
 // @Router /login [post].
 func (s *RewardService) Authenticate(w http.ResponseWriter, r *http.Request) {
 	var requestPayload struct {
@@ -188,8 +192,32 @@ func (s *RewardService) Authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := GetClientIP(r)
+	if ip == "" {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidIP, http.StatusBadRequest)
+
+		return
+	}
+
+	if s.Lockout != nil {
+		wait, err := s.Lockout.Check(requestPayload.Email, ip)
+		if err != nil {
+			httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+			return
+		}
+
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			httputils.ErrorJSON(w, errormsg.ErrTooManyAttempts, http.StatusTooManyRequests)
+
+			return
+		}
+	}
+
 	user, err := s.Repo.GetByEmail(requestPayload.Email)
 	if err != nil {
+		s.registerLoginFailure(requestPayload.Email, ip, false)
 		httputils.ErrorJSON(w, errormsg.ErrUserNotExist, http.StatusBadRequest)
 
 		return
@@ -197,34 +225,140 @@ func (s *RewardService) Authenticate(w http.ResponseWriter, r *http.Request) {
 
 	valid, err := s.Repo.PasswordMatches(requestPayload.Password, *user)
 	if err != nil || !valid {
+		s.registerLoginFailure(requestPayload.Email, ip, true)
 		httputils.ErrorJSON(w, errormsg.ErrInvalidPassword, http.StatusBadRequest)
 
 		return
 	}
 
-	tokenService := token.NewTokenService()
+	if s.Lockout != nil {
+		if err := s.Lockout.RegisterSuccess(requestPayload.Email); err != nil {
+			log.Println("failed to reset login failures: ", err)
+		}
+	}
 
-	ip := GetClientIP(r)
-	if ip == "" {
-		httputils.ErrorJSON(w, errormsg.ErrInvalidIP, http.StatusBadRequest)
+	s.completeLogin(w, user, ip, token.AMRPassword)
+}
+
+// completeLogin finishes a successful first factor: users with MFA enabled get
+// a pending login challenge, others get auth cookies right away. Users who are
+// not active are refused.
+func (s *RewardService) completeLogin(w http.ResponseWriter, user *calltypes.User, ip string, amr ...string) {
+	if user.Status != calltypes.UserStatusActive {
+		httputils.ErrorJSON(w, errormsg.ErrUserInactive, http.StatusForbidden)
 
 		return
 	}
 
-	accessToken, hashedRefreshToken, err := tokenService.GenerateTokens(ip)
-	if err != nil {
-		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+	if s.MFA != nil {
+		enabled, err := s.MFA.Enabled(user.ID)
+		if err != nil {
+			httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+			return
+		}
+
+		if enabled {
+			s.requireMFA(w, user, ip, amr)
+
+			return
+		}
+	}
+
+	if err := s.issueTokens(w, user.ID, ip, amr...); err != nil {
+		httputils.ErrorJSON(w, err, tokenErrorStatus(err))
 
 		return
 	}
 
-	err = s.Repo.StoreRefreshToken(user.ID, hashedRefreshToken)
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Welcome back, %s!", user.FirstName),
+		Data:    map[string]interface{}{"user_id": user.ID},
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload, nil)
 	if err != nil {
-		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
 
 		return
 	}
+}
+
+// issueTokens generates a token pair for the user, stores the refresh token and
+// sets auth cookies. Only active users get tokens.
+func (s *RewardService) issueTokens(w http.ResponseWriter, userID int, ip string, amr ...string) error {
+	if err := s.requireActive(userID); err != nil {
+		return err
+	}
 
+	access, err := s.access(userID)
+	if err != nil {
+		return err
+	}
+
+	tokenService := token.NewTokenService()
+
+	accessToken, hashedRefreshToken, err := tokenService.GenerateTokens(userID, ip, access, amr...)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.StoreRefreshToken(userID, hashedRefreshToken); err != nil {
+		return err
+	}
+
+	setAuthCookies(w, accessToken, hashedRefreshToken)
+
+	return nil
+}
+
+// requireActive returns errormsg.ErrUserInactive unless the user is active.
+func (s *RewardService) requireActive(userID int) error {
+	status, err := s.Repo.UserStatus(userID)
+	if err != nil {
+		return err
+	}
+
+	if status != calltypes.UserStatusActive {
+		return errormsg.ErrUserInactive
+	}
+
+	return nil
+}
+
+// tokenErrorStatus maps errors of issueTokens to HTTP status codes.
+func tokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrUserNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrUserInactive):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// access returns roles and permissions of the user to put into access tokens.
+// Without RBAC tokens carry none.
+func (s *RewardService) access(userID int) (token.Access, error) {
+	if s.RBAC == nil {
+		return token.Access{}, nil
+	}
+
+	return s.RBAC.Access(userID)
+}
+
+// Permissions returns the permissions the roles of the user grant, none
+// without RBAC.
+func (s *RewardService) Permissions(userID int) ([]string, error) {
+	access, err := s.access(userID)
+
+	return access.Permissions, err
+}
+
+// setAuthCookies sets access and refresh token cookies.
+func setAuthCookies(w http.ResponseWriter, accessToken, refreshToken string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "accessToken",
 		Value:    accessToken,
@@ -237,39 +371,84 @@ func (s *RewardService) Authenticate(w http.ResponseWriter, r *http.Request) {
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refreshToken",
-		Value:    hashedRefreshToken,
+		Value:    refreshToken,
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteStrictMode,
 		Expires:  time.Now().Add(consts.RefreshTokenExpireTime),
 	})
+}
 
-	payload := calltypes.JSONResponse{
-		Error:   false,
-		Message: fmt.Sprintf("Welcome back, %s!", user.FirstName),
-		Data:    map[string]interface{}{"user_id": user.ID},
+// registerLoginFailure counts a failed login when brute-force protection is enabled.
+func (s *RewardService) registerLoginFailure(email, ip string, accountExists bool) {
+	if s.Lockout == nil {
+		return
 	}
 
-	err = httputils.WriteJSON(w, http.StatusOK, payload, nil)
-	if err != nil {
-		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+	if err := s.Lockout.RegisterFailure(email, ip, accountExists); err != nil {
+		log.Println("failed to register login failure: ", err)
+	}
+}
 
-		return
+// reauthenticate lets a sensitive action through when the session was
+// authenticated with a strong factor or the caller confirmed the current
+// password. It writes the error response otherwise; wrong passwords count
+// towards the lockout of the account like failed logins.
+func (s *RewardService) reauthenticate(w http.ResponseWriter, r *http.Request, user *calltypes.User, password string) bool {
+	if middleware.SteppedUp(r.Context()) {
+		return true
+	}
+
+	if password == "" {
+		httputils.ErrorJSON(w, errormsg.ErrReauthRequired, http.StatusUnauthorized)
+
+		return false
+	}
+
+	ip := GetClientIP(r)
+
+	if s.Lockout != nil {
+		wait, err := s.Lockout.Check(user.Email, ip)
+		if err != nil {
+			httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+			return false
+		}
+
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			httputils.ErrorJSON(w, errormsg.ErrTooManyAttempts, http.StatusTooManyRequests)
+
+			return false
+		}
 	}
+
+	valid, err := s.Repo.PasswordMatches(password, *user)
+	if err != nil || !valid {
+		s.registerLoginFailure(user.Email, ip, true)
+		httputils.ErrorJSON(w, errormsg.ErrInvalidPassword, http.StatusForbidden)
+
+		return false
+	}
+
+	return true
 }
 
 // Provide godoc
 // @Summary Provide new tokens
-// @Description Generates and returns new access and refresh tokens for user
+// @Description Generates and returns new access and refresh tokens for the authenticated user. The ID must be the caller's own
 // @Tags Auth
 // @Param id path int true "User ID"
 // @Produce json
 // @Success 200 {object} calltypes.JSONResponse
 // @Header 200 {string} Set-Cookie "accessToken"
 // @Header 200 {string} Set-Cookie "refreshToken"
-// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or IP"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or IP, or user not found"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Not the caller's own ID, or user is not active"
 // @Failure 500 {object} calltypes.ErrorResponse "Internal server error"
+// @Security BearerAuth
 // @Router /users/{id}/tokens [post].
 func (s *RewardService) Provide(w http.ResponseWriter, r *http.Request) {
 	id, err := GetIDFromURL(r, "id")
@@ -279,8 +458,6 @@ func (s *RewardService) Provide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenService := token.NewTokenService()
-
 	ip := GetClientIP(r)
 	if ip == "" {
 		httputils.ErrorJSON(w, errormsg.ErrInvalidIP, http.StatusBadRequest)
@@ -288,40 +465,12 @@ func (s *RewardService) Provide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, hashedRefreshToken, err := tokenService.GenerateTokens(ip)
-	if err != nil {
-		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
-
-		return
-	}
-
-	err = s.Repo.StoreRefreshToken(id, hashedRefreshToken)
-	if err != nil {
-		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+	if err := s.issueTokens(w, id, ip); err != nil {
+		httputils.ErrorJSON(w, err, tokenErrorStatus(err))
 
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "accessToken",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(consts.AccessTokenExpireTime),
-	})
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refreshToken",
-		Value:    hashedRefreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(consts.RefreshTokenExpireTime),
-	})
-
 	payload := calltypes.JSONResponse{
 		Error:   false,
 		Message: "Tokens has been successfully provided",
@@ -346,6 +495,7 @@ func (s *RewardService) Provide(w http.ResponseWriter, r *http.Request) {
 // @Header 200 {string} Set-Cookie "refreshToken"
 // @Failure 400 {object} calltypes.ErrorResponse "Invalid ID"
 // @Failure 401 {object} calltypes.ErrorResponse "Invalid or expired refresh token"
+// @Failure 403 {object} calltypes.ErrorResponse "User is not active"
 // @Failure 500 {object} calltypes.ErrorResponse "Internal server error"
 // @Router /users/{id}/refresh [post].
 func (s *RewardService) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -378,9 +528,22 @@ func (s *RewardService) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.requireActive(id); err != nil {
+		httputils.ErrorJSON(w, err, tokenErrorStatus(err))
+
+		return
+	}
+
+	access, err := s.access(id)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
 	tokenService := token.NewTokenService()
 
-	accessToken, hashedRefreshToken, err := tokenService.GenerateTokens(ip)
+	accessToken, hashedRefreshToken, err := tokenService.GenerateTokens(id, ip, access)
 	if err != nil {
 		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
 
@@ -394,25 +557,7 @@ func (s *RewardService) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "accessToken",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(consts.AccessTokenExpireTime),
-	})
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refreshToken",
-		Value:    hashedRefreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(consts.RefreshTokenExpireTime),
-	})
+	setAuthCookies(w, accessToken, hashedRefreshToken)
 
 	payload := calltypes.JSONResponse{
 		Error:   false,
@@ -429,7 +574,7 @@ func (s *RewardService) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // RetrieveOne godoc
 // @Summary Get user by ID
-// @Description Returns single user data
+// @Description Returns single user data. Users read only their own record unless they have the users:read permission
 // @Tags Users
 // @Param id path int true "User ID"
 // @Produce json
@@ -444,6 +589,32 @@ func (s *RewardService) RetrieveOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.writeUser(w, id)
+}
+
+// RetrieveMe godoc
+// @Summary Get current user
+// @Description Returns data of the authenticated user, so that clients need not know their own ID. The ETag header is used to update the profile
+// @Tags Users
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.User}
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 400 {object} calltypes.ErrorResponse "User not found"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Security BearerAuth
+// @Router /users/me [get].
+func (s *RewardService) RetrieveMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	s.writeUser(w, id)
+}
+
+func (s *RewardService) writeUser(w http.ResponseWriter, id int) {
 	user, err := s.Repo.GetOne(id)
 	if err != nil {
 		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
@@ -457,7 +628,7 @@ func (s *RewardService) RetrieveOne(w http.ResponseWriter, r *http.Request) {
 		Data:    user,
 	}
 
-	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	err = httputils.WriteJSON(w, http.StatusOK, payload, http.Header{"ETag": {userETag(user)}})
 	if err != nil {
 		httputils.ErrorJSON(w, err, http.StatusBadRequest)
 
@@ -42,6 +42,12 @@ func (m *MockRepository) Insert(user calltypes.User) (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockRepository) Import(user calltypes.User) (int, error) {
+	args := m.Called(user)
+
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockRepository) GetAll() ([]*calltypes.User, error) {
 	args := m.Called()
 
@@ -116,6 +122,53 @@ func (m *MockRepository) UpdateRefreshToken(id int, rawToken string) error {
 	return args.Error(0) //nolint: wrapcheck
 }
 
+func (m *MockRepository) MarkEmailVerified(id int) error {
+	args := m.Called(id)
+
+	return args.Error(0) //nolint: wrapcheck
+}
+
+func (m *MockRepository) UserStatus(id int) (calltypes.UserStatus, error) {
+	args := m.Called(id)
+
+	status, _ := args.Get(0).(calltypes.UserStatus)
+
+	return status, args.Error(1) //nolint: wrapcheck
+}
+
+func (m *MockRepository) SessionState(id int) (*calltypes.SessionState, error) {
+	args := m.Called(id)
+
+	state, _ := args.Get(0).(*calltypes.SessionState)
+
+	return state, args.Error(1) //nolint: wrapcheck
+}
+
+func TestGetClientIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		expected   string
+	}{
+		{name: "IPv4", remoteAddr: "192.0.2.1:12345", expected: "192.0.2.1"},
+		{name: "IPv6", remoteAddr: "[2001:db8::1]:443", expected: "2001:db8::1"},
+		{name: "without port", remoteAddr: "192.0.2.1", expected: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			assert.Equal(t, tt.expected, service.GetClientIP(req))
+		})
+	}
+}
+
 func TestRewardService_Registrate(t *testing.T) {
 	t.Parallel()
 
@@ -215,13 +268,33 @@ func TestRewardService_Authenticate(t *testing.T) {
 					FirstName: "Test",
 					LastName:  "User",
 					Password:  "hashedpassword",
+					Status:    calltypes.UserStatusActive,
 				}
 				m.On("GetByEmail", "test@example.com").Return(user, nil)
 				m.On("PasswordMatches", "correctpassword", *user).Return(true, nil)
+				m.On("UserStatus", user.ID).Return(calltypes.UserStatusActive, nil)
 				m.On("StoreRefreshToken", user.ID, mock.AnythingOfType("string")).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name: "Suspended user",
+			requestBody: `{
+                "email": "test@example.com",
+                "password": "correctpassword"
+            }`,
+			mockSetup: func(m *MockRepository) { //nolint:varnamelen
+				user := &calltypes.User{
+					ID:       1,
+					Email:    "test@example.com",
+					Password: "hashedpassword",
+					Status:   calltypes.UserStatusSuspended,
+				}
+				m.On("GetByEmail", "test@example.com").Return(user, nil)
+				m.On("PasswordMatches", "correctpassword", *user).Return(true, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
 		{
 			name: "Invalid credentials",
 			requestBody: `{
@@ -423,6 +496,7 @@ func TestRewardService_Provide(t *testing.T) {
 		name            string
 		urlID           string
 		ip              string
+		status          calltypes.UserStatus
 		storeTokenError error
 		expectedCode    int
 		expectedError   bool
@@ -431,10 +505,19 @@ func TestRewardService_Provide(t *testing.T) {
 			name:            "successful token provision",
 			urlID:           "123",
 			ip:              "192.168.1.1",
+			status:          calltypes.UserStatusActive,
 			storeTokenError: nil,
 			expectedCode:    http.StatusOK,
 			expectedError:   false,
 		},
+		{
+			name:          "suspended user",
+			urlID:         "123",
+			ip:            "192.168.1.1",
+			status:        calltypes.UserStatusSuspended,
+			expectedCode:  http.StatusForbidden,
+			expectedError: true,
+		},
 		{
 			name:            "invalid ID in URL",
 			urlID:           "abc",
@@ -455,6 +538,7 @@ func TestRewardService_Provide(t *testing.T) {
 			name:            "failed to store refresh token",
 			urlID:           "123",
 			ip:              "192.168.1.1",
+			status:          calltypes.UserStatusActive,
 			storeTokenError: errormsg.ErrStorage,
 			expectedCode:    http.StatusInternalServerError,
 			expectedError:   true,
@@ -467,7 +551,11 @@ func TestRewardService_Provide(t *testing.T) {
 
 			mockRepo := new(MockRepository)
 			if tc.urlID == "123" && tc.ip != "" {
-				mockRepo.On("StoreRefreshToken", 123, mock.AnythingOfType("string")).Return(tc.storeTokenError)
+				mockRepo.On("UserStatus", 123).Return(tc.status, nil)
+
+				if tc.status == calltypes.UserStatusActive {
+					mockRepo.On("StoreRefreshToken", 123, mock.AnythingOfType("string")).Return(tc.storeTokenError)
+				}
 			}
 
 			svc := &service.RewardService{Repo: mockRepo}
@@ -534,6 +622,7 @@ func TestRewardService_Refresh(t *testing.T) {
 		cookieValue        string
 		validationResult   bool
 		validationError    error
+		status             calltypes.UserStatus
 		updateTokenError   error
 		expectedCode       int
 		expectTokenRefresh bool
@@ -545,10 +634,21 @@ func TestRewardService_Refresh(t *testing.T) {
 			cookieValue:        "valid_refresh_token",
 			validationResult:   true,
 			validationError:    nil,
+			status:             calltypes.UserStatusActive,
 			updateTokenError:   nil,
 			expectedCode:       http.StatusOK,
 			expectTokenRefresh: true,
 		},
+		{
+			name:               "suspended user",
+			urlID:              "123",
+			ip:                 "192.168.1.1",
+			cookieValue:        "valid_refresh_token",
+			validationResult:   true,
+			status:             calltypes.UserStatusSuspended,
+			expectedCode:       http.StatusForbidden,
+			expectTokenRefresh: false,
+		},
 		{
 			name:               "invalid ID in URL",
 			urlID:              "abc",
@@ -582,6 +682,7 @@ func TestRewardService_Refresh(t *testing.T) {
 			cookieValue:        "valid_refresh_token",
 			validationResult:   true,
 			validationError:    nil,
+			status:             calltypes.UserStatusActive,
 			updateTokenError:   errormsg.ErrUpdate,
 			expectedCode:       http.StatusInternalServerError,
 			expectTokenRefresh: false,
@@ -597,6 +698,10 @@ func TestRewardService_Refresh(t *testing.T) {
 				mockRepo.On("ValidateRefreshToken", tc.cookieValue, tc.ip, 123).Return(tc.validationResult, tc.validationError)
 
 				if tc.validationResult && tc.validationError == nil {
+					mockRepo.On("UserStatus", 123).Return(tc.status, nil)
+				}
+
+				if tc.status == calltypes.UserStatusActive {
 					mockRepo.On("UpdateRefreshToken", 123, mock.AnythingOfType("string")).Return(tc.updateTokenError)
 				}
 			}
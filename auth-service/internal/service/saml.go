@@ -0,0 +1,142 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// samlRequestCookie binds an AuthnRequest to the browser that sent it.
+const samlRequestCookie = "samlRequest"
+
+// SAMLMetadata godoc
+// @Summary SAML service provider metadata
+// @Description Returns the SAML 2.0 metadata to import into identity providers: the entity ID and the assertion consumer service
+// @Tags Auth
+// @Produce xml
+// @Success 200 {string} string "EntityDescriptor"
+// @Failure 400 {object} calltypes.ErrorResponse "SAML login is disabled"
+// @Router /saml/metadata [get].
+func (s *RewardService) SAMLMetadata(w http.ResponseWriter, _ *http.Request) {
+	if s.SAML == nil {
+		httputils.ErrorJSON(w, errormsg.ErrSAMLDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	metadata, err := s.SAML.Metadata()
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(metadata); err != nil {
+		log.Println("failed to write SAML metadata: ", err)
+	}
+}
+
+// BeginSAMLLogin godoc
+// @Summary Start login through a SAML identity provider
+// @Description Redirects to the SSO service of the identity provider with an AuthnRequest (HTTP-Redirect binding). The response sets a cookie binding the request to this browser
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the identity provider"
+// @Header 302 {string} Set-Cookie "samlRequest"
+// @Failure 400 {object} calltypes.ErrorResponse "SAML login is disabled"
+// @Failure 404 {object} calltypes.ErrorResponse "Unknown provider"
+// @Router /saml/{provider}/login [get].
+func (s *RewardService) BeginSAMLLogin(w http.ResponseWriter, r *http.Request) {
+	if s.SAML == nil {
+		httputils.ErrorJSON(w, errormsg.ErrSAMLDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	pending, err := s.SAML.Begin(chi.URLParam(r, "provider"))
+	if err != nil {
+		log.Println("failed to start SAML login: ", err)
+		httputils.ErrorJSON(w, err, federationErrorStatus(err))
+
+		return
+	}
+
+	setSAMLRequestCookie(w, pending.RequestID, pending.ExpiresAt)
+
+	http.Redirect(w, r, pending.URL, http.StatusFound)
+}
+
+// CompleteSAMLLogin godoc
+// @Summary Assertion consumer service
+// @Description Receives the SAML response of the identity provider (HTTP-POST binding), validates the signed assertion and returns auth cookies. On first login the identity is linked to the user with the same email, or a user is created
+// @Tags Auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param SAMLResponse formData string true "Base64 encoded SAML response"
+// @Param RelayState formData string false "Relay state"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid request, response or signature"
+// @Failure 403 {object} calltypes.ErrorResponse "No trusted email in the assertion or the account with that email has not verified it"
+// @Router /saml/acs [post].
+func (s *RewardService) CompleteSAMLLogin(w http.ResponseWriter, r *http.Request) {
+	if s.SAML == nil {
+		httputils.ErrorJSON(w, errormsg.ErrSAMLDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	binding, err := r.Cookie(samlRequestCookie)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidFederatedLogin, http.StatusUnauthorized)
+
+		return
+	}
+
+	setSAMLRequestCookie(w, "", time.Unix(0, 0))
+
+	result, err := s.SAML.Complete(binding.Value, r.PostFormValue("SAMLResponse"))
+	if err != nil {
+		log.Println("SAML login failed: ", err)
+		httputils.ErrorJSON(w, err, federationErrorStatus(err))
+
+		return
+	}
+
+	ip := GetClientIP(r)
+	details := map[string]interface{}{"provider": result.Provider, "subject": result.Subject}
+
+	switch {
+	case result.Provisioned:
+		s.Audit.Record(calltypes.AuditEvent{UserID: result.User.ID, Action: audit.ActionUserProvisioned, IP: ip, Details: details})
+	case result.Linked:
+		s.Audit.Record(calltypes.AuditEvent{UserID: result.User.ID, Action: audit.ActionIdentityLinked, IP: ip, Details: details})
+	}
+
+	s.completeLogin(w, result.User, ip)
+}
+
+// setSAMLRequestCookie stores the ID of the AuthnRequest. The response is
+// posted cross-site by the identity provider, so the cookie must be SameSite
+// None, which browsers accept only with Secure (http://localhost included).
+func setSAMLRequestCookie(w http.ResponseWriter, requestID string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     samlRequestCookie,
+		Value:    requestID,
+		Path:     "/saml",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		Expires:  expires,
+	})
+}
@@ -0,0 +1,479 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/audit"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListUsers godoc
+// @Summary List users
+// @Description Returns a page of users. query matches the email and names, status filters by the status
+// @Tags Admin
+// @Produce json
+// @Param query query string false "Search text"
+// @Param status query string false "Status" Enums(pending, active, suspended, deleted)
+// @Param limit query int false "Page size, 50 by default and at most 200"
+// @Param offset query int false "Number of users to skip"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.UserPage}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid filter"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Security BearerAuth
+// @Router /admin/users [get].
+func (s *RewardService) ListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := userFilter(r.URL.Query())
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	page, err := s.Users.List(filter)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Fetched %d of %d users", len(page.Users), page.Total),
+		Data:    page,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// GetUser godoc
+// @Summary Get user
+// @Description Returns data of the user
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.User}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id} [get].
+func (s *RewardService) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := GetIDFromURL(r, "id")
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidID, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Users.Get(id)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	writeAdminUser(w, http.StatusOK, "Retrieved one user from the database", user)
+}
+
+// CreateUser godoc
+// @Summary Create user
+// @Description Creates an active user on behalf of the administrator
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body calltypes.CreateUserRequest true "User data"
+// @Success 201 {object} calltypes.JSONResponse{data=calltypes.User}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid user data"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 409 {object} calltypes.ErrorResponse "Email is taken"
+// @Security BearerAuth
+// @Router /admin/users [post].
+func (s *RewardService) CreateUser(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.CreateUserRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := s.Users.Create(requestPayload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  user.ID,
+		ActorID: actorID,
+		Action:  audit.ActionUserCreated,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"email": user.Email},
+	})
+
+	writeAdminUser(w, http.StatusCreated, fmt.Sprintf("User %d has been created", user.ID), user)
+}
+
+// UpdateUser godoc
+// @Summary Update user
+// @Description Replaces the email and names of the user
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body calltypes.UpdateUserRequest true "User data"
+// @Success 200 {object} calltypes.JSONResponse{data=calltypes.User}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid user data"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Failure 409 {object} calltypes.ErrorResponse "Email is taken"
+// @Security BearerAuth
+// @Router /admin/users/{id} [put].
+func (s *RewardService) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	actorID, id, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var requestPayload calltypes.UpdateUserRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	before, err := s.Users.Get(id)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	user, err := s.Users.Update(id, requestPayload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		ActorID: actorID,
+		Action:  audit.ActionUserUpdated,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"oldEmail": before.Email, "email": user.Email},
+	})
+
+	writeAdminUser(w, http.StatusOK, fmt.Sprintf("User %d has been updated", id), user)
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate user
+// @Description Suspends the user and revokes the sessions of the user. Suspended users cannot log in, and access tokens issued before the suspension stay refused after reactivation
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id}/deactivate [post].
+func (s *RewardService) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	s.changeUser(w, r, s.Users.Deactivate, audit.ActionUserDeactivated, "User %d has been deactivated")
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate user
+// @Description Makes a suspended or pending user active again. Deleted users cannot be reactivated
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or user is deleted"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id}/reactivate [post].
+func (s *RewardService) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	s.changeUser(w, r, s.Users.Reactivate, audit.ActionUserReactivated, "User %d has been reactivated")
+}
+
+// LogoutUser godoc
+// @Summary Log out user
+// @Description Revokes the refresh tokens of the user and of OAuth clients acting for the user. Access tokens issued until then are refused from now on
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id}/logout [post].
+func (s *RewardService) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	s.changeUser(w, r, s.Users.Logout, audit.ActionUserLoggedOut, "User %d has been logged out")
+}
+
+// ResetUserMFA godoc
+// @Summary Reset user MFA
+// @Description Removes the authenticator app, recovery codes and pending challenges of the user, e.g. after the user lost the device
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID or MFA is disabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id}/mfa/reset [post].
+func (s *RewardService) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	if s.MFA == nil {
+		httputils.ErrorJSON(w, errormsg.ErrMFADisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	reset := func(id int) error {
+		if _, err := s.Users.Get(id); err != nil {
+			return err
+		}
+
+		return s.MFA.Reset(id)
+	}
+
+	s.changeUser(w, r, reset, audit.ActionMFAReset, "MFA of user %d has been reset")
+}
+
+// SendPasswordReset godoc
+// @Summary Send password reset
+// @Description Emails the user a single-use link to set a new password
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid ID"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 403 {object} calltypes.ErrorResponse "Permission denied"
+// @Failure 404 {object} calltypes.ErrorResponse "User not found"
+// @Security BearerAuth
+// @Router /admin/users/{id}/password-reset [post].
+func (s *RewardService) SendPasswordReset(w http.ResponseWriter, r *http.Request) {
+	actorID, id, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := s.Users.Get(id)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	expiresAt, err := s.PasswordReset.Start(user)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		ActorID: actorID,
+		Action:  audit.ActionPasswordResetSent,
+		IP:      GetClientIP(r),
+		Details: map[string]interface{}{"expiresAt": expiresAt.Format(time.RFC3339)},
+	})
+
+	writeUserMessage(w, fmt.Sprintf("Password reset link has been sent to user %d", id))
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password with the token from a password reset email. The user is logged out everywhere
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.PasswordResetRequest true "Token and new password"
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid or expired token, or password is too short"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many requests"
+// @Router /password/reset [post].
+func (s *RewardService) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var requestPayload calltypes.PasswordResetRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, err := s.PasswordReset.Complete(requestPayload.Token, requestPayload.Password)
+	if err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	if err := s.Users.Logout(userID); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID: userID,
+		Action: audit.ActionPasswordReset,
+		IP:     GetClientIP(r),
+	})
+
+	writeUserMessage(w, "Password has been changed")
+}
+
+// changeUser applies the change to the user from the URL and records it in the
+// audit trail.
+func (s *RewardService) changeUser(w http.ResponseWriter, r *http.Request, change func(id int) error,
+	action, message string,
+) {
+	actorID, id, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := change(id); err != nil {
+		httputils.ErrorJSON(w, err, userAdminErrorStatus(err))
+
+		return
+	}
+
+	s.Audit.Record(calltypes.AuditEvent{
+		UserID:  id,
+		ActorID: actorID,
+		Action:  action,
+		IP:      GetClientIP(r),
+	})
+
+	writeUserMessage(w, fmt.Sprintf(message, id))
+}
+
+// adminTarget returns ID of the acting administrator and ID of the user from
+// the URL, writing an error response otherwise.
+func adminTarget(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return 0, 0, false
+	}
+
+	id, err := GetIDFromURL(r, "id")
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidID, http.StatusBadRequest)
+
+		return 0, 0, false
+	}
+
+	return actorID, id, true
+}
+
+// userFilter reads the filter of ListUsers from the query string.
+func userFilter(query url.Values) (calltypes.UserFilter, error) {
+	filter := calltypes.UserFilter{Query: query.Get("query")}
+
+	for name, target := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if !query.Has(name) {
+			continue
+		}
+
+		value, err := strconv.Atoi(query.Get(name))
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid %s", errormsg.ErrInvalidUserRequest, name)
+		}
+
+		*target = value
+	}
+
+	if query.Has("status") {
+		filter.Status = calltypes.UserStatus(query.Get("status"))
+		if !filter.Status.Valid() {
+			return filter, fmt.Errorf("%w: unknown status %q", errormsg.ErrInvalidUserRequest, filter.Status)
+		}
+	}
+
+	return filter, nil
+}
+
+func writeAdminUser(w http.ResponseWriter, status int, message string, user *calltypes.User) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: message,
+		Data:    user,
+	}
+
+	err := httputils.WriteJSON(w, status, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+func writeUserMessage(w http.ResponseWriter, message string) {
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: message,
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// userAdminErrorStatus maps user management errors to HTTP status codes.
+func userAdminErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrInvalidUserRequest),
+		errors.Is(err, errormsg.ErrPasswordLength),
+		errors.Is(err, errormsg.ErrInvalidPasswordReset):
+		return http.StatusBadRequest
+	case errors.Is(err, errormsg.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errormsg.ErrEmailTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
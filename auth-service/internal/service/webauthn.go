@@ -0,0 +1,377 @@
+package service
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/api/server/httputils"
+	"auth-service/api/server/middleware"
+	"auth-service/internal/token"
+	"auth-service/internal/webauthn"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BeginPasskeyRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns options for navigator.credentials.create() to register a passkey of the current user. Requires the current password unless the session was authenticated with a second factor or a user-verified passkey
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param request body calltypes.PasskeyRegistrationBeginRequest false "Current password"
+// @Success 200 {object} calltypes.JSONResponse{data=webauthn.CreationOptions}
+// @Failure 400 {object} calltypes.ErrorResponse "Passkeys are disabled"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated or the password is required"
+// @Failure 403 {object} calltypes.ErrorResponse "Wrong password"
+// @Failure 429 {object} calltypes.ErrorResponse "Too many failed attempts"
+// @Security BearerAuth
+// @Router /webauthn/register/begin [post].
+func (s *RewardService) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload calltypes.PasskeyRegistrationBeginRequest
+
+	if r.ContentLength != 0 {
+		if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	user, err := s.Repo.GetOne(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, errormsg.ErrFetchUser, http.StatusBadRequest)
+
+		return
+	}
+
+	// The registration ceremony is bound to this user, so confirming the
+	// password here covers FinishPasskeyRegistration as well.
+	if !s.reauthenticate(w, r, user, requestPayload.Password) {
+		return
+	}
+
+	options, err := s.Passkey.BeginRegistration(user)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Pass the options to navigator.credentials.create()",
+		Data:    options,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// FinishPasskeyRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verifies the credential created by the browser and stores it as a passkey of the current user
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param request body webauthn.RegistrationRequest true "Passkey name and created credential"
+// @Success 201 {object} calltypes.JSONResponse{data=calltypes.WebAuthnCredential}
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid credential"
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Failure 409 {object} calltypes.ErrorResponse "Credential is already registered"
+// @Security BearerAuth
+// @Router /webauthn/register/finish [post].
+func (s *RewardService) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	var requestPayload webauthn.RegistrationRequest
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	credential, err := s.Passkey.FinishRegistration(userID, requestPayload.Name, &requestPayload.Credential)
+	if err != nil {
+		httputils.ErrorJSON(w, err, passkeyErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Passkey has been registered for user %d", userID),
+		Data:    credential,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusCreated, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Description Returns passkeys registered by the current user
+// @Tags Passkeys
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse{data=[]calltypes.WebAuthnCredential}
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated"
+// @Security BearerAuth
+// @Router /webauthn/credentials [get].
+func (s *RewardService) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	credentials, err := s.Passkey.Credentials(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Fetched %d passkeys", len(credentials)),
+		Data:    credentials,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// DeletePasskey godoc
+// @Summary Delete passkey
+// @Description Removes a passkey of the current user. Requires a session authenticated with a second factor or a user-verified passkey
+// @Tags Passkeys
+// @Param id path string true "Credential ID"
+// @Produce json
+// @Success 200 {object} calltypes.JSONResponse
+// @Failure 401 {object} calltypes.ErrorResponse "Unauthenticated or step-up is required"
+// @Failure 404 {object} calltypes.ErrorResponse "Passkey not found"
+// @Security BearerAuth
+// @Router /webauthn/credentials/{id} [delete].
+func (s *RewardService) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		httputils.ErrorJSON(w, errormsg.ErrUnauthenticated, http.StatusUnauthorized)
+
+		return
+	}
+
+	if err := s.Passkey.DeleteCredential(userID, chi.URLParam(r, "id")); err != nil {
+		httputils.ErrorJSON(w, err, passkeyErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Passkey has been deleted",
+	}
+
+	err := httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// BeginPasskeyLogin godoc
+// @Summary Start passkey login
+// @Description Returns options for navigator.credentials.get(). With email only passkeys of that user are offered, otherwise any discoverable passkey is accepted
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body calltypes.PasskeyLoginRequest false "Optional email"
+// @Success 200 {object} calltypes.JSONResponse{data=webauthn.RequestOptions}
+// @Failure 400 {object} calltypes.ErrorResponse "Passkeys are disabled"
+// @Router /authenticate/passkey/begin [post].
+func (s *RewardService) BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload calltypes.PasskeyLoginRequest
+
+	if r.ContentLength != 0 {
+		if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+			httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	// Unknown emails fall back to discoverable credentials, so the response does
+	// not reveal whether an account exists.
+	var userID int
+
+	if requestPayload.Email != "" {
+		if user, err := s.Repo.GetByEmail(requestPayload.Email); err == nil {
+			userID = user.ID
+		}
+	}
+
+	options, err := s.Passkey.BeginLogin(userID)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusInternalServerError)
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Pass the options to navigator.credentials.get()",
+		Data:    options,
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// FinishPasskeyLogin godoc
+// @Summary Finish passkey login
+// @Description Verifies the assertion returned by the browser and returns auth cookies. Passkeys with user verification count as multi-factor authentication
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body webauthn.AssertionResponse true "Assertion"
+// @Success 200 {object} calltypes.JSONResponse
+// @Header 200 {string} Set-Cookie "accessToken"
+// @Header 200 {string} Set-Cookie "refreshToken"
+// @Failure 400 {object} calltypes.ErrorResponse "Invalid request data"
+// @Failure 401 {object} calltypes.ErrorResponse "Invalid assertion"
+// @Router /authenticate/passkey/finish [post].
+func (s *RewardService) FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	if s.Passkey == nil {
+		httputils.ErrorJSON(w, errormsg.ErrWebAuthnDisabled, http.StatusBadRequest)
+
+		return
+	}
+
+	var requestPayload webauthn.AssertionResponse
+
+	if err := httputils.ReadJSON(w, r, &requestPayload); err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+
+	ip := GetClientIP(r)
+	if ip == "" {
+		httputils.ErrorJSON(w, errormsg.ErrInvalidIP, http.StatusBadRequest)
+
+		return
+	}
+
+	login, err := s.Passkey.FinishLogin(&requestPayload)
+	if err != nil {
+		status := passkeyErrorStatus(err)
+		if status != http.StatusInternalServerError {
+			status = http.StatusUnauthorized
+		}
+
+		httputils.ErrorJSON(w, err, status)
+
+		return
+	}
+
+	amr := []string{token.AMRHardwareKey}
+	if login.UserVerified {
+		amr = append(amr, token.AMRMFA)
+	}
+
+	if err := s.issueTokens(w, login.UserID, ip, amr...); err != nil {
+		httputils.ErrorJSON(w, err, tokenErrorStatus(err))
+
+		return
+	}
+
+	payload := calltypes.JSONResponse{
+		Error:   false,
+		Message: "Passkey accepted",
+		Data:    map[string]interface{}{"user_id": login.UserID},
+	}
+
+	err = httputils.WriteJSON(w, http.StatusOK, payload)
+	if err != nil {
+		httputils.ErrorJSON(w, err, http.StatusBadRequest)
+
+		return
+	}
+}
+
+// passkeyErrorStatus maps WebAuthn errors to HTTP status codes.
+func passkeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errormsg.ErrCredentialExists):
+		return http.StatusConflict
+	case errors.Is(err, errormsg.ErrCredentialNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errormsg.ErrWebAuthnFailed), errors.Is(err, errormsg.ErrInvalidCeremony),
+		errors.Is(err, errormsg.ErrUnsupportedCOSEKey), errors.Is(err, errormsg.ErrUnsupportedAttStmt),
+		errors.Is(err, errormsg.ErrSignCountRegressed):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -7,9 +7,18 @@ import (
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"os"
+	"strings"
 	"time"
 )
 
+// Authentication method references used in the amr claim (RFC 8176).
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMFA         = "mfa"
+	AMRHardwareKey = "hwk"
+)
+
 type ServiceToken struct {
 	SecretKey string
 }
@@ -20,9 +29,17 @@ func NewTokenService() *ServiceToken {
 	}
 }
 
-// GenerateTokens when called generates access tokens.
-func (ts *ServiceToken) GenerateTokens(clientIP string) (string, string, error) {
-	accessToken, err := ts.GenerateAccessToken(clientIP)
+// Access describes what the user may do: the roles assigned to the user and the
+// permissions they grant.
+type Access struct {
+	Roles       []string
+	Permissions []string
+}
+
+// GenerateTokens when called generates access tokens. amr lists the
+// authentication methods used to log in, such as "pwd" and "otp".
+func (ts *ServiceToken) GenerateTokens(userID int, clientIP string, access Access, amr ...string) (string, string, error) {
+	accessToken, err := ts.GenerateAccessToken(userID, clientIP, access, amr...)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -35,14 +52,83 @@ func (ts *ServiceToken) GenerateTokens(clientIP string) (string, string, error)
 	return accessToken, refreshToken, nil
 }
 
-// GenerateAccessToken generates access tokens.
-func (ts *ServiceToken) GenerateAccessToken(clientIP string) (string, error) {
+// GenerateAccessToken generates access tokens for the user. Roles go to the
+// roles claim and permissions to the scope claim.
+func (ts *ServiceToken) GenerateAccessToken(userID int, clientIP string, access Access, amr ...string) (string, error) {
 	claims := jwt.MapClaims{
+		"sub": userID,
 		"exp": time.Now().Add(consts.AccessTokenExpireTime).Unix(),
 		"iat": time.Now().Unix(),
 		"ip":  clientIP,
 	}
 
+	if len(amr) > 0 {
+		claims["amr"] = amr
+	}
+
+	if len(access.Roles) > 0 {
+		claims["roles"] = access.Roles
+	}
+
+	if len(access.Permissions) > 0 {
+		claims["scope"] = strings.Join(access.Permissions, " ")
+	}
+
+	return sign(claims)
+}
+
+// Grant describes an access token issued to an OAuth client on behalf of a
+// user. UserID is zero for tokens of the client itself.
+type Grant struct {
+	UserID   int
+	ClientID string
+	// Scope is a space separated list of granted scopes.
+	Scope    string
+	ClientIP string
+	AMR      []string
+	// Audience restricts the token to one resource server.
+	Audience string
+	// Actor is the act claim of delegated tokens (RFC 8693 section 4.1).
+	Actor map[string]interface{}
+}
+
+// GenerateGrantToken generates an access token for an OAuth client. Besides
+// the claims of GenerateAccessToken it carries client_id and scope, and aud and
+// act for delegated tokens. Tokens of the client itself have the client ID as
+// sub (RFC 9068).
+func (ts *ServiceToken) GenerateGrantToken(grant Grant) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":       grant.UserID,
+		"client_id": grant.ClientID,
+		"exp":       time.Now().Add(consts.AccessTokenExpireTime).Unix(),
+		"iat":       time.Now().Unix(),
+		"ip":        grant.ClientIP,
+	}
+
+	if grant.UserID == 0 {
+		claims["sub"] = grant.ClientID
+	}
+
+	if grant.Scope != "" {
+		claims["scope"] = grant.Scope
+	}
+
+	if len(grant.AMR) > 0 {
+		claims["amr"] = grant.AMR
+	}
+
+	if grant.Audience != "" {
+		claims["aud"] = grant.Audience
+	}
+
+	if grant.Actor != nil {
+		claims["act"] = grant.Actor
+	}
+
+	return sign(claims)
+}
+
+func sign(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
 	signedToken, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
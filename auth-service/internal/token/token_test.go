@@ -82,7 +82,7 @@ func TestGenerateAccessToken(t *testing.T) {
 
 			testIP := consts.TestIP
 			g := token.NewTokenService()
-			tkn, err := g.GenerateAccessToken(testIP)
+			tkn, err := g.GenerateAccessToken(res.userID, testIP, token.Access{})
 
 			if res.wantErr {
 				require.NoError(t, err)
@@ -96,6 +96,32 @@ func TestGenerateAccessToken(t *testing.T) {
 	}
 }
 
+func TestAccessTokenRoles(t *testing.T) {
+	t.Parallel()
+	setup()
+
+	g := token.NewTokenService() //nolint: varnamelen
+
+	tkn, err := g.GenerateAccessToken(1, consts.TestIP, token.Access{
+		Roles:       []string{"admin"},
+		Permissions: []string{"users:read", "users:write"},
+	})
+	require.NoError(t, err)
+
+	claims, err := g.ValidateAccessToken(tkn)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"admin"}, claims["roles"])
+	assert.Equal(t, "users:read users:write", claims["scope"])
+
+	tkn, err = g.GenerateAccessToken(1, consts.TestIP, token.Access{})
+	require.NoError(t, err)
+
+	claims, err = g.ValidateAccessToken(tkn)
+	require.NoError(t, err)
+	assert.NotContains(t, claims, "roles")
+	assert.NotContains(t, claims, "scope")
+}
+
 func TestTokenExpiration(t *testing.T) {
 	t.Parallel()
 	setup()
@@ -106,7 +132,7 @@ func TestTokenExpiration(t *testing.T) {
 		t.Parallel()
 
 		testIP := consts.TestIP
-		tkn, err := g.GenerateAccessToken(testIP)
+		tkn, err := g.GenerateAccessToken(1, testIP, token.Access{})
 		require.NoError(t, err)
 
 		parser := jwt.Parser{}
@@ -136,7 +162,7 @@ func TestTokenValidation(t *testing.T) {
 		t.Parallel()
 
 		testIP := consts.TestIP
-		tkn, err := g.GenerateAccessToken(testIP)
+		tkn, err := g.GenerateAccessToken(1, testIP, token.Access{})
 		require.NoError(t, err)
 
 		tkn = tkn[:len(tkn)-2] + "xx"
@@ -148,3 +174,43 @@ func TestTokenValidation(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestGenerateGrantToken(t *testing.T) {
+	t.Parallel()
+	setup()
+
+	g := token.NewTokenService() //nolint: varnamelen
+
+	tkn, err := g.GenerateGrantToken(token.Grant{
+		UserID:   3,
+		ClientID: "client",
+		Scope:    "profile email",
+		ClientIP: consts.TestIP,
+	})
+	require.NoError(t, err)
+
+	claims, err := g.ValidateAccessToken(tkn)
+	require.NoError(t, err)
+
+	assert.InDelta(t, float64(3), claims["sub"], 0.0001)
+	assert.Equal(t, "client", claims["client_id"])
+	assert.Equal(t, "profile email", claims["scope"])
+	assert.NotContains(t, claims, "amr")
+	assert.NotContains(t, claims, "aud")
+	assert.NotContains(t, claims, "act")
+
+	tkn, err = g.GenerateGrantToken(token.Grant{
+		UserID:   3,
+		ClientID: "gateway",
+		ClientIP: consts.TestIP,
+		Audience: "https://billing.internal",
+		Actor:    map[string]interface{}{"sub": "gateway"},
+	})
+	require.NoError(t, err)
+
+	claims, err = g.ValidateAccessToken(tkn)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://billing.internal", claims["aud"])
+	assert.Equal(t, map[string]interface{}{"sub": "gateway"}, claims["act"])
+}
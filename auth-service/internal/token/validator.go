@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"strings"
-	"time"
 )
 
 type Validator struct {
@@ -32,24 +31,14 @@ func (ts *ServiceToken) ValidateAccessToken(tokenString string) (jwt.MapClaims,
 		return nil, errormsg.ErrInvalidToken
 	}
 
-	now := time.Now().Unix()
-
-	expVal, ok := claims["exp"].(float64)
-	if !ok {
+	if _, ok := claims["exp"].(float64); !ok {
 		return nil, errormsg.ErrInvalidToken
 	}
 
-	exp := int64(expVal)
-
-	iatVal, ok := claims["iat"].(float64)
-	if !ok {
+	if _, ok := claims["iat"].(float64); !ok {
 		return nil, errormsg.ErrInvalidToken
 	}
 
-	iat := int64(iatVal)
-
-	fmt.Printf("Current: %d, Issued: %d, Expires: %d\n", now, iat, exp)
-
 	if !token.Valid {
 		return nil, errormsg.ErrInvalidToken
 	}
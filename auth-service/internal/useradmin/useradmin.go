@@ -0,0 +1,167 @@
+// Package useradmin lets administrators list, create, update, deactivate and
+// log out users. Handlers record every change in the audit trail together with
+// the acting administrator.
+package useradmin
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/emailaddr"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+const (
+	maxEmailLen = 255
+	maxNameLen  = 100
+)
+
+// Manager manages users on behalf of administrators.
+type Manager struct {
+	repo repository.UserAdminRepository
+}
+
+func NewManager(repo repository.UserAdminRepository) *Manager {
+	return &Manager{repo: repo}
+}
+
+// Get returns the user.
+func (m *Manager) Get(id int) (*calltypes.User, error) {
+	return m.repo.GetOne(id)
+}
+
+// List returns a page of users matching the filter. The page size defaults to
+// consts.UserPageSize and is capped at consts.UserPageMaxSize.
+func (m *Manager) List(filter calltypes.UserFilter) (*calltypes.UserPage, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = consts.UserPageSize
+	case filter.Limit > consts.UserPageMaxSize:
+		filter.Limit = consts.UserPageMaxSize
+	}
+
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", errormsg.ErrInvalidUserRequest)
+	}
+
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	users, total, err := m.repo.SearchUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &calltypes.UserPage{
+		Users:  users,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// Create creates an active user.
+func (m *Manager) Create(request calltypes.CreateUserRequest) (*calltypes.User, error) {
+	user := calltypes.User{
+		Email:         strings.TrimSpace(request.Email),
+		EmailVerified: request.EmailVerified,
+		FirstName:     strings.TrimSpace(request.FirstName),
+		LastName:      strings.TrimSpace(request.LastName),
+		Password:      request.Password,
+		Status:        calltypes.UserStatusActive,
+	}
+
+	if err := validate(user); err != nil {
+		return nil, err
+	}
+
+	if len(request.Password) < consts.PassMinLength {
+		return nil, errormsg.ErrPasswordLength
+	}
+
+	if _, err := m.repo.GetByEmail(user.Email); err == nil {
+		return nil, errormsg.ErrEmailTaken
+	}
+
+	id, err := m.repo.Insert(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.repo.GetOne(id)
+}
+
+// Update replaces the email and names of the user.
+func (m *Manager) Update(id int, request calltypes.UpdateUserRequest) (*calltypes.User, error) {
+	user, err := m.repo.GetOne(id)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(request.Email)
+
+	if emailaddr.Key(email) != emailaddr.Key(user.Email) {
+		if existing, err := m.repo.GetByEmail(email); err == nil && existing.ID != id {
+			return nil, errormsg.ErrEmailTaken
+		}
+	}
+
+	user.Email = email
+	user.FirstName = strings.TrimSpace(request.FirstName)
+	user.LastName = strings.TrimSpace(request.LastName)
+
+	if err := validate(*user); err != nil {
+		return nil, err
+	}
+
+	if err := m.repo.Update(*user); err != nil {
+		return nil, err
+	}
+
+	return m.repo.GetOne(id)
+}
+
+// Deactivate suspends the user, which also logs the user out.
+func (m *Manager) Deactivate(id int) error {
+	return m.repo.SetUserStatus(id, calltypes.UserStatusSuspended)
+}
+
+// Reactivate makes a suspended or pending user active again. Deleted users
+// cannot be reactivated.
+func (m *Manager) Reactivate(id int) error {
+	user, err := m.repo.GetOne(id)
+	if err != nil {
+		return err
+	}
+
+	if user.Status == calltypes.UserStatusDeleted {
+		return fmt.Errorf("%w: deleted users cannot be reactivated", errormsg.ErrInvalidUserRequest)
+	}
+
+	return m.repo.SetUserStatus(id, calltypes.UserStatusActive)
+}
+
+// Logout revokes refresh tokens of the user and of OAuth clients acting for the
+// user, so that the user has to log in again once the access token expires.
+func (m *Manager) Logout(id int) error {
+	if _, err := m.repo.GetOne(id); err != nil {
+		return err
+	}
+
+	return m.repo.RevokeSessions(id)
+}
+
+func validate(user calltypes.User) error {
+	address, err := mail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email || len(user.Email) > maxEmailLen {
+		return fmt.Errorf("%w: invalid email", errormsg.ErrInvalidUserRequest)
+	}
+
+	if len(user.FirstName) > maxNameLen || len(user.LastName) > maxNameLen {
+		return fmt.Errorf("%w: names must not exceed %d bytes", errormsg.ErrInvalidUserRequest, maxNameLen)
+	}
+
+	return nil
+}
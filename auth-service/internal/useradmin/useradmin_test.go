@@ -0,0 +1,289 @@
+package useradmin_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/useradmin"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/errormsg"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository keeps users in memory.
+type memoryRepository struct {
+	mu      sync.Mutex
+	users   map[int]calltypes.User
+	revoked map[int]int
+	filter  calltypes.UserFilter
+}
+
+func newMemoryRepository(users ...calltypes.User) *memoryRepository {
+	m := &memoryRepository{
+		users:   make(map[int]calltypes.User),
+		revoked: make(map[int]int),
+	}
+
+	for _, user := range users {
+		m.users[user.ID] = user
+	}
+
+	return m
+}
+
+func (m *memoryRepository) GetOne(id int) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, errormsg.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (m *memoryRepository) GetByEmail(email string) (*calltypes.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+
+	return nil, errors.New("no rows")
+}
+
+func (m *memoryRepository) Insert(user calltypes.User) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user.ID = len(m.users) + 1
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (m *memoryRepository) Update(user calltypes.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users[user.ID] = user
+
+	return nil
+}
+
+func (m *memoryRepository) SearchUsers(filter calltypes.UserFilter) ([]*calltypes.User, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.filter = filter
+
+	return nil, len(m.users), nil
+}
+
+func (m *memoryRepository) SetUserStatus(id int, status calltypes.UserStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return errormsg.ErrUserNotFound
+	}
+
+	user.Status = status
+	m.users[id] = user
+
+	if status != calltypes.UserStatusActive {
+		m.revoked[id]++
+	}
+
+	return nil
+}
+
+func (m *memoryRepository) RevokeSessions(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revoked[userID]++
+
+	return nil
+}
+
+func testUser() calltypes.User {
+	return calltypes.User{ID: 1, Email: "user@example.com", FirstName: "Ann", Status: calltypes.UserStatusActive}
+}
+
+func TestManager_List(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		filter    calltypes.UserFilter
+		wantLimit int
+		wantErr   error
+	}{
+		{
+			name:      "default page size",
+			wantLimit: consts.UserPageSize,
+		},
+		{
+			name:      "capped page size",
+			filter:    calltypes.UserFilter{Limit: consts.UserPageMaxSize + 1},
+			wantLimit: consts.UserPageMaxSize,
+		},
+		{
+			name:      "requested page size",
+			filter:    calltypes.UserFilter{Limit: 10},
+			wantLimit: 10,
+		},
+		{
+			name:    "negative offset",
+			filter:  calltypes.UserFilter{Offset: -1},
+			wantErr: errormsg.ErrInvalidUserRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemoryRepository(testUser())
+			manager := useradmin.NewManager(repo)
+
+			page, err := manager.List(tt.filter)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLimit, page.Limit)
+			assert.Equal(t, tt.wantLimit, repo.filter.Limit)
+			assert.Equal(t, 1, page.Total)
+		})
+	}
+}
+
+func TestManager_Create(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		request calltypes.CreateUserRequest
+		wantErr error
+	}{
+		{
+			name:    "valid user",
+			request: calltypes.CreateUserRequest{Email: " new@example.com ", FirstName: "Bob", Password: "password1"},
+		},
+		{
+			name:    "taken email",
+			request: calltypes.CreateUserRequest{Email: "user@example.com", Password: "password1"},
+			wantErr: errormsg.ErrEmailTaken,
+		},
+		{
+			name:    "invalid email",
+			request: calltypes.CreateUserRequest{Email: "Bob <new@example.com>", Password: "password1"},
+			wantErr: errormsg.ErrInvalidUserRequest,
+		},
+		{
+			name:    "short password",
+			request: calltypes.CreateUserRequest{Email: "new@example.com", Password: "short"},
+			wantErr: errormsg.ErrPasswordLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			manager := useradmin.NewManager(newMemoryRepository(testUser()))
+
+			user, err := manager.Create(tt.request)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "new@example.com", user.Email)
+			assert.Equal(t, calltypes.UserStatusActive, user.Status)
+		})
+	}
+}
+
+func TestManager_Update(t *testing.T) {
+	t.Parallel()
+
+	other := calltypes.User{ID: 2, Email: "other@example.com", Status: calltypes.UserStatusActive}
+	manager := useradmin.NewManager(newMemoryRepository(testUser(), other))
+
+	user, err := manager.Update(1, calltypes.UpdateUserRequest{Email: "user@example.com", FirstName: "Anna"})
+	require.NoError(t, err)
+	assert.Equal(t, "Anna", user.FirstName)
+	assert.Equal(t, calltypes.UserStatusActive, user.Status)
+
+	user, err = manager.Update(1, calltypes.UpdateUserRequest{Email: "User@Example.com", FirstName: "Anna"})
+	require.NoError(t, err, "changing the case of one's own email is not a conflict")
+	assert.Equal(t, "User@Example.com", user.Email)
+
+	_, err = manager.Update(1, calltypes.UpdateUserRequest{Email: "other@example.com"})
+	require.ErrorIs(t, err, errormsg.ErrEmailTaken)
+
+	_, err = manager.Update(1, calltypes.UpdateUserRequest{Email: "Other@Example.com"})
+	require.ErrorIs(t, err, errormsg.ErrEmailTaken)
+
+	_, err = manager.Update(3, calltypes.UpdateUserRequest{Email: "new@example.com"})
+	require.ErrorIs(t, err, errormsg.ErrUserNotFound)
+}
+
+func TestManager_Deactivate(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository(testUser())
+	manager := useradmin.NewManager(repo)
+
+	require.NoError(t, manager.Deactivate(1))
+	assert.Equal(t, calltypes.UserStatusSuspended, repo.users[1].Status)
+	assert.Equal(t, 1, repo.revoked[1], "deactivation must revoke sessions")
+
+	require.NoError(t, manager.Reactivate(1))
+	assert.Equal(t, calltypes.UserStatusActive, repo.users[1].Status)
+
+	require.ErrorIs(t, manager.Deactivate(2), errormsg.ErrUserNotFound)
+}
+
+func TestManager_ReactivateDeleted(t *testing.T) {
+	t.Parallel()
+
+	deleted := testUser()
+	deleted.Status = calltypes.UserStatusDeleted
+
+	repo := newMemoryRepository(deleted)
+	manager := useradmin.NewManager(repo)
+
+	require.ErrorIs(t, manager.Reactivate(1), errormsg.ErrInvalidUserRequest)
+	assert.Equal(t, calltypes.UserStatusDeleted, repo.users[1].Status)
+}
+
+func TestManager_Logout(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryRepository(testUser())
+	manager := useradmin.NewManager(repo)
+
+	require.NoError(t, manager.Logout(1))
+	assert.Equal(t, 1, repo.revoked[1])
+
+	require.ErrorIs(t, manager.Logout(2), errormsg.ErrUserNotFound)
+	assert.Zero(t, repo.revoked[2])
+}
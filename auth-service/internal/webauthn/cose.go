@@ -0,0 +1,150 @@
+package webauthn
+
+import (
+	"auth-service/pkg/errormsg"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms accepted for credentials.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSE key parameters (RFC 9052, RFC 9053).
+const (
+	coseKty    = 1
+	coseAlg    = 3
+	coseCrv    = -1
+	coseX      = -2
+	coseY      = -3
+	coseRSAN   = -1
+	coseRSAE   = -2
+	ktyOKP     = 1
+	ktyEC2     = 2
+	ktyRSA     = 3
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// SupportedAlgorithms lists algorithms offered in creation options, in order of
+// preference.
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256} //nolint: gochecknoglobals
+
+// PublicKey is a credential public key.
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey decodes a COSE encoded public key.
+func ParsePublicKey(encoded []byte) (*PublicKey, error) {
+	var params map[int]interface{}
+
+	if err := cbor.Unmarshal(encoded, &params); err != nil {
+		return nil, fmt.Errorf("%w: malformed COSE key", errormsg.ErrUnsupportedCOSEKey)
+	}
+
+	kty, _ := cborInt(params[coseKty])
+	alg, _ := cborInt(params[coseAlg])
+
+	switch {
+	case kty == ktyEC2 && alg == AlgES256:
+		return parseEC2(params)
+	case kty == ktyOKP && alg == AlgEdDSA:
+		return parseOKP(params)
+	case kty == ktyRSA && alg == AlgRS256:
+		return parseRSA(params)
+	default:
+		return nil, fmt.Errorf("%w: kty %d, alg %d", errormsg.ErrUnsupportedCOSEKey, kty, alg)
+	}
+}
+
+func parseEC2(params map[int]interface{}) (*PublicKey, error) {
+	crv, _ := cborInt(params[coseCrv])
+	x, okX := params[coseX].([]byte)
+	y, okY := params[coseY].([]byte)
+
+	if crv != crvP256 || !okX || !okY {
+		return nil, fmt.Errorf("%w: malformed EC2 key", errormsg.ErrUnsupportedCOSEKey)
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	if !key.Curve.IsOnCurve(key.X, key.Y) { //nolint: staticcheck
+		return nil, fmt.Errorf("%w: point is not on curve", errormsg.ErrUnsupportedCOSEKey)
+	}
+
+	return &PublicKey{Algorithm: AlgES256, key: key}, nil
+}
+
+func parseOKP(params map[int]interface{}) (*PublicKey, error) {
+	crv, _ := cborInt(params[coseCrv])
+	x, ok := params[coseX].([]byte)
+
+	if crv != crvEd25519 || !ok || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: malformed OKP key", errormsg.ErrUnsupportedCOSEKey)
+	}
+
+	return &PublicKey{Algorithm: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+}
+
+func parseRSA(params map[int]interface{}) (*PublicKey, error) {
+	n, okN := params[coseRSAN].([]byte)
+	e, okE := params[coseRSAE].([]byte)
+
+	if !okN || !okE || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("%w: malformed RSA key", errormsg.ErrUnsupportedCOSEKey)
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+
+	return &PublicKey{Algorithm: AlgRS256, key: key}, nil
+}
+
+// publicKeyFromCertificate returns the key of an attestation certificate.
+func publicKeyFromCertificate(der []byte, alg int64) (*PublicKey, error) {
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed attestation certificate", errormsg.ErrWebAuthnFailed)
+	}
+
+	return &PublicKey{Algorithm: alg, key: certificate.PublicKey}, nil
+}
+
+// Verify checks the signature of the message.
+func (k *PublicKey) Verify(message, signature []byte) error {
+	var valid bool
+
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		valid = k.Algorithm == AlgES256 && ecdsa.VerifyASN1(key, sha256Sum(message), signature)
+	case ed25519.PublicKey:
+		valid = k.Algorithm == AlgEdDSA && ed25519.Verify(key, message, signature)
+	case *rsa.PublicKey:
+		valid = k.Algorithm == AlgRS256 && rsa.VerifyPKCS1v15(key, crypto.SHA256, sha256Sum(message), signature) == nil
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", errormsg.ErrWebAuthnFailed)
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package webauthn
+
+import (
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Client data types of the two ceremonies.
+const (
+	clientDataCreate = "webauthn.create"
+	clientDataGet    = "webauthn.get"
+)
+
+// Authenticator data flags.
+const (
+	FlagUserPresent    byte = 0x01
+	FlagUserVerified   byte = 0x04
+	FlagBackupEligible byte = 0x08
+	FlagBackupState    byte = 0x10
+	FlagAttestedData   byte = 0x40
+	FlagExtensionData  byte = 0x80
+)
+
+const (
+	rpIDHashLength = 32
+	aaguidLength   = 16
+	// authDataMinLength is rpIdHash, flags and signCount.
+	authDataMinLength = rpIDHashLength + 1 + 4
+)
+
+// URLEncodedBytes is binary data encoded in JSON as unpadded base64url, as the
+// WebAuthn JSON serialization does.
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b)) //nolint: wrapcheck
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return fmt.Errorf("%w: expected base64url string", errormsg.ErrWebAuthnFailed)
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return fmt.Errorf("%w: invalid base64url", errormsg.ErrWebAuthnFailed)
+	}
+
+	*b = decoded
+
+	return nil
+}
+
+// String returns the base64url form used as credential ID in the database.
+func (b URLEncodedBytes) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// RelyingPartyEntity describes the service to the authenticator.
+type RelyingPartyEntity struct {
+	ID   string `example:"example.com" json:"id"`
+	Name string `example:"medods"      json:"name"`
+}
+
+// UserEntity describes the account the credential is created for.
+type UserEntity struct {
+	ID          URLEncodedBytes `example:"AAAAAAAAAAE"      json:"id"          swaggertype:"string"`
+	Name        string          `example:"user@example.com" json:"name"`
+	DisplayName string          `example:"John Doe"         json:"displayName"`
+}
+
+// CredentialParameter is an accepted credential type and algorithm.
+type CredentialParameter struct {
+	Type      string `example:"public-key" json:"type"`
+	Algorithm int64  `example:"-7"         json:"alg"`
+}
+
+// CredentialDescriptor identifies an existing credential.
+type CredentialDescriptor struct {
+	Type       string          `example:"public-key" json:"type"`
+	ID         URLEncodedBytes `json:"id"            swaggertype:"string"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection lists requirements on the authenticator.
+type AuthenticatorSelection struct {
+	ResidentKey      string `example:"preferred" json:"residentKey"`
+	UserVerification string `example:"preferred" json:"userVerification"`
+}
+
+// CreationOptions is PublicKeyCredentialCreationOptions passed to
+// navigator.credentials.create()
+// @name PasskeyCreationOptions.
+type CreationOptions struct {
+	Challenge              URLEncodedBytes        `json:"challenge"                    swaggertype:"string"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `example:"300000"                    json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `example:"none"                      json:"attestation"`
+}
+
+// RequestOptions is PublicKeyCredentialRequestOptions passed to
+// navigator.credentials.get()
+// @name PasskeyRequestOptions.
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"                  swaggertype:"string"`
+	Timeout          int64                  `example:"300000"                  json:"timeout"`
+	RelyingPartyID   string                 `example:"example.com"             json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `example:"preferred"               json:"userVerification"`
+}
+
+// AttestationResponse is the PublicKeyCredential returned by
+// navigator.credentials.create()
+// @name PasskeyAttestation.
+type AttestationResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"    swaggertype:"string"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"       swaggertype:"string"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"    swaggertype:"string"`
+		Transports        []string        `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by
+// navigator.credentials.get()
+// @name PasskeyAssertion.
+type AssertionResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"    swaggertype:"string"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"       swaggertype:"string"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"    swaggertype:"string"`
+		Signature         URLEncodedBytes `json:"signature"            swaggertype:"string"`
+		UserHandle        URLEncodedBytes `json:"userHandle,omitempty" swaggertype:"string"`
+	} `json:"response"`
+}
+
+// RegistrationRequest is the body of the request finishing passkey registration
+// @name PasskeyRegistrationRequest.
+type RegistrationRequest struct {
+	Name       string              `example:"MacBook Touch ID" json:"name"`
+	Credential AttestationResponse `json:"credential"`
+}
+
+// CollectedClientData is the clientDataJSON signed by the authenticator.
+type CollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// AttestationObject is the CBOR encoded result of credential creation.
+type AttestationObject struct {
+	Format      string                 `cbor:"fmt"`
+	Statement   map[string]interface{} `cbor:"attStmt"`
+	AuthDataRaw []byte                 `cbor:"authData"`
+	authData    AuthenticatorData
+}
+
+// AuthenticatorData is the parsed authenticator data structure.
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// Has reports whether the flag is set.
+func (d *AuthenticatorData) Has(flag byte) bool {
+	return d.Flags&flag != 0
+}
+
+func parseClientData(raw []byte, expectedType string) (*CollectedClientData, error) {
+	var clientData CollectedClientData
+
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("%w: malformed client data", errormsg.ErrWebAuthnFailed)
+	}
+
+	if clientData.Type != expectedType {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", errormsg.ErrWebAuthnFailed, clientData.Type)
+	}
+
+	if clientData.Challenge == "" {
+		return nil, fmt.Errorf("%w: missing challenge", errormsg.ErrWebAuthnFailed)
+	}
+
+	return &clientData, nil
+}
+
+// ParseAuthenticatorData parses authenticator data. The attested credential
+// data is read only when the AT flag is set.
+func ParseAuthenticatorData(raw []byte) (*AuthenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, fmt.Errorf("%w: authenticator data is too short", errormsg.ErrWebAuthnFailed)
+	}
+
+	data := &AuthenticatorData{
+		RPIDHash:  raw[:rpIDHashLength],
+		Flags:     raw[rpIDHashLength],
+		SignCount: binary.BigEndian.Uint32(raw[rpIDHashLength+1 : authDataMinLength]),
+	}
+
+	if !data.Has(FlagAttestedData) {
+		return data, nil
+	}
+
+	rest := raw[authDataMinLength:]
+	if len(rest) < aaguidLength+2 {
+		return nil, fmt.Errorf("%w: attested credential data is too short", errormsg.ErrWebAuthnFailed)
+	}
+
+	data.AAGUID = rest[:aaguidLength]
+	idLength := int(binary.BigEndian.Uint16(rest[aaguidLength : aaguidLength+2]))
+	rest = rest[aaguidLength+2:]
+
+	if len(rest) < idLength {
+		return nil, fmt.Errorf("%w: credential ID is truncated", errormsg.ErrWebAuthnFailed)
+	}
+
+	data.CredentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// The COSE key is followed by optional extensions, so its length is only
+	// known after decoding it.
+	decoder := cbor.NewDecoder(bytes.NewReader(rest))
+
+	var key cbor.RawMessage
+	if err := decoder.Decode(&key); err != nil {
+		return nil, fmt.Errorf("%w: malformed credential public key", errormsg.ErrWebAuthnFailed)
+	}
+
+	data.PublicKey = key
+
+	return data, nil
+}
+
+func parseAttestationObject(raw []byte) (*AttestationObject, error) {
+	var object AttestationObject
+
+	if err := cbor.Unmarshal(raw, &object); err != nil {
+		return nil, fmt.Errorf("%w: malformed attestation object", errormsg.ErrWebAuthnFailed)
+	}
+
+	authData, err := ParseAuthenticatorData(object.AuthDataRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	object.authData = *authData
+
+	return &object, nil
+}
+
+// verifyAttestation checks the attestation statement. Only "none" and "packed"
+// formats are supported; attestation certificates are not checked against
+// trust anchors since the service does not restrict authenticator models.
+func (o *AttestationObject) verifyAttestation(clientDataHash []byte, credentialKey *PublicKey) error {
+	switch o.Format {
+	case "none":
+		if len(o.Statement) != 0 {
+			return fmt.Errorf("%w: none attestation with statement", errormsg.ErrWebAuthnFailed)
+		}
+
+		return nil
+	case "packed":
+		return o.verifyPacked(clientDataHash, credentialKey)
+	default:
+		return fmt.Errorf("%w: %q", errormsg.ErrUnsupportedAttStmt, o.Format)
+	}
+}
+
+func (o *AttestationObject) verifyPacked(clientDataHash []byte, credentialKey *PublicKey) error {
+	alg, ok := cborInt(o.Statement["alg"])
+	if !ok {
+		return fmt.Errorf("%w: packed attestation without alg", errormsg.ErrWebAuthnFailed)
+	}
+
+	signature, ok := o.Statement["sig"].([]byte)
+	if !ok {
+		return fmt.Errorf("%w: packed attestation without sig", errormsg.ErrWebAuthnFailed)
+	}
+
+	signed := append(append([]byte{}, o.AuthDataRaw...), clientDataHash...)
+
+	chain, hasCertificate := o.Statement["x5c"].([]interface{})
+	if !hasCertificate {
+		// Self attestation is signed by the credential key itself.
+		if alg != credentialKey.Algorithm {
+			return fmt.Errorf("%w: attestation algorithm mismatch", errormsg.ErrWebAuthnFailed)
+		}
+
+		return credentialKey.Verify(signed, signature)
+	}
+
+	if len(chain) == 0 {
+		return fmt.Errorf("%w: empty attestation certificate chain", errormsg.ErrWebAuthnFailed)
+	}
+
+	certificate, ok := chain[0].([]byte)
+	if !ok {
+		return fmt.Errorf("%w: malformed attestation certificate", errormsg.ErrWebAuthnFailed)
+	}
+
+	attestationKey, err := publicKeyFromCertificate(certificate, alg)
+	if err != nil {
+		return err
+	}
+
+	return attestationKey.Verify(signed, signature)
+}
+
+// cborInt returns an integer decoded by cbor as either uint64 or int64.
+func cborInt(value interface{}) (int64, bool) {
+	switch number := value.(type) {
+	case int64:
+		return number, true
+	case uint64:
+		return int64(number), true //nolint: gosec
+	default:
+		return 0, false
+	}
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+
+	return sum[:]
+}
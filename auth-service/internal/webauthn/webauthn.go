@@ -0,0 +1,385 @@
+// Package webauthn implements the relying party side of WebAuthn: passkey
+// registration and assertion ceremonies, credential storage and sign counter
+// checks.
+package webauthn
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/postgres/repository"
+	"auth-service/pkg/errormsg"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Ceremonies stored in WebAuthn sessions.
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// User verification requirements.
+const (
+	VerificationRequired    = "required"
+	VerificationPreferred   = "preferred"
+	VerificationDiscouraged = "discouraged"
+)
+
+const (
+	credentialType  = "public-key"
+	challengeLength = 32
+	userHandleSize  = 8
+)
+
+// Config holds relying party settings.
+type Config struct {
+	// RPID is the domain credentials are scoped to, e.g. "example.com".
+	RPID string
+	// RPName is the service name shown by authenticators.
+	RPName string
+	// Origins lists origins allowed to run ceremonies, e.g. "https://example.com".
+	Origins []string
+	// Timeout is how long a ceremony may take.
+	Timeout time.Duration
+	// UserVerification is "required", "preferred" or "discouraged".
+	UserVerification string
+}
+
+// Login is a successfully verified assertion.
+type Login struct {
+	UserID       int
+	CredentialID string
+	UserVerified bool
+}
+
+// RelyingParty runs WebAuthn ceremonies.
+type RelyingParty struct {
+	repo repository.WebAuthnRepository
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewRelyingParty(repo repository.WebAuthnRepository, cfg Config) *RelyingParty {
+	return &RelyingParty{
+		repo: repo,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// UserHandle returns the opaque user handle stored by authenticators for
+// discoverable credentials.
+func UserHandle(userID int) []byte {
+	handle := make([]byte, userHandleSize)
+	binary.BigEndian.PutUint64(handle, uint64(userID)) //nolint: gosec
+
+	return handle
+}
+
+// BeginRegistration starts registration of a new passkey for the user.
+func (rp *RelyingParty) BeginRegistration(user *calltypes.User) (*CreationOptions, error) {
+	existing, err := rp.repo.GetWebAuthnCredentials(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := rp.newSession(CeremonyRegistration, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: credentialType, Algorithm: alg})
+	}
+
+	return &CreationOptions{
+		Challenge:    challenge,
+		RelyingParty: RelyingPartyEntity{ID: rp.cfg.RPID, Name: rp.cfg.RPName},
+		User: UserEntity{
+			ID:          UserHandle(user.ID),
+			Name:        user.Email,
+			DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		PubKeyCredParams:   params,
+		Timeout:            rp.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.cfg.UserVerification,
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration verifies the created credential and stores it for the user.
+func (rp *RelyingParty) FinishRegistration(userID int, name string, response *AttestationResponse) (*calltypes.WebAuthnCredential, error) {
+	clientData, err := parseClientData(response.Response.ClientDataJSON, clientDataCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.takeSession(clientData.Challenge, CeremonyRegistration, userID); err != nil {
+		return nil, err
+	}
+
+	if err := rp.checkOrigin(clientData); err != nil {
+		return nil, err
+	}
+
+	object, err := parseAttestationObject(response.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	authData := &object.authData
+
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	if !authData.Has(FlagAttestedData) || len(authData.CredentialID) == 0 {
+		return nil, fmt.Errorf("%w: missing attested credential data", errormsg.ErrWebAuthnFailed)
+	}
+
+	if !bytes.Equal(authData.CredentialID, response.RawID) {
+		return nil, fmt.Errorf("%w: credential ID mismatch", errormsg.ErrWebAuthnFailed)
+	}
+
+	publicKey, err := ParsePublicKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := object.verifyAttestation(sha256Sum(response.Response.ClientDataJSON), publicKey); err != nil {
+		return nil, err
+	}
+
+	credential := calltypes.WebAuthnCredential{
+		ID:             URLEncodedBytes(authData.CredentialID).String(),
+		UserID:         userID,
+		Name:           name,
+		PublicKey:      authData.PublicKey,
+		SignCount:      authData.SignCount,
+		AAGUID:         hex.EncodeToString(authData.AAGUID),
+		Transports:     response.Response.Transports,
+		BackupEligible: authData.Has(FlagBackupEligible),
+		CreatedAt:      rp.now(),
+	}
+
+	if err := rp.repo.CreateWebAuthnCredential(credential); err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// BeginLogin starts an assertion. With zero userID any discoverable credential
+// of the relying party is accepted, otherwise only credentials of the user.
+func (rp *RelyingParty) BeginLogin(userID int) (*RequestOptions, error) {
+	var allowed []*calltypes.WebAuthnCredential
+
+	if userID != 0 {
+		var err error
+
+		allowed, err = rp.repo.GetWebAuthnCredentials(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	challenge, err := rp.newSession(CeremonyLogin, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.cfg.Timeout.Milliseconds(),
+		RelyingPartyID:   rp.cfg.RPID,
+		AllowCredentials: descriptors(allowed),
+		UserVerification: rp.cfg.UserVerification,
+	}, nil
+}
+
+// FinishLogin verifies the assertion and records the new sign counter. A counter
+// that did not increase means the credential may have been cloned and the
+// assertion is rejected.
+func (rp *RelyingParty) FinishLogin(response *AssertionResponse) (*Login, error) {
+	clientData, err := parseClientData(response.Response.ClientDataJSON, clientDataGet)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := rp.repo.TakeWebAuthnSession(hashChallenge(clientData.Challenge))
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Ceremony != CeremonyLogin || rp.now().After(session.ExpiresAt) {
+		return nil, errormsg.ErrInvalidCeremony
+	}
+
+	if err := rp.checkOrigin(clientData); err != nil {
+		return nil, err
+	}
+
+	credential, err := rp.repo.GetWebAuthnCredential(URLEncodedBytes(response.RawID).String())
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserID != 0 && session.UserID != credential.UserID {
+		return nil, errormsg.ErrCredentialNotFound
+	}
+
+	if len(response.Response.UserHandle) > 0 && !bytes.Equal(response.Response.UserHandle, UserHandle(credential.UserID)) {
+		return nil, fmt.Errorf("%w: user handle mismatch", errormsg.ErrWebAuthnFailed)
+	}
+
+	authData, err := ParseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := append(append([]byte{}, response.Response.AuthenticatorData...), sha256Sum(response.Response.ClientDataJSON)...)
+	if err := publicKey.Verify(signed, response.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	if err := rp.checkSignCount(credential, authData.SignCount); err != nil {
+		return nil, err
+	}
+
+	return &Login{
+		UserID:       credential.UserID,
+		CredentialID: credential.ID,
+		UserVerified: authData.Has(FlagUserVerified),
+	}, nil
+}
+
+// Credentials returns passkeys of the user.
+func (rp *RelyingParty) Credentials(userID int) ([]*calltypes.WebAuthnCredential, error) {
+	return rp.repo.GetWebAuthnCredentials(userID)
+}
+
+// DeleteCredential removes a passkey of the user.
+func (rp *RelyingParty) DeleteCredential(userID int, credentialID string) error {
+	return rp.repo.DeleteWebAuthnCredential(userID, credentialID)
+}
+
+// checkSignCount rejects assertions whose counter did not increase. Counters
+// staying at zero are allowed since synced passkeys do not implement them.
+func (rp *RelyingParty) checkSignCount(credential *calltypes.WebAuthnCredential, signCount uint32) error {
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return errormsg.ErrSignCountRegressed
+	}
+
+	fresh, err := rp.repo.UseWebAuthnCredential(credential.ID, signCount, rp.now())
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return errormsg.ErrSignCountRegressed
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) checkOrigin(clientData *CollectedClientData) error {
+	if clientData.CrossOrigin || !slices.Contains(rp.cfg.Origins, clientData.Origin) {
+		return fmt.Errorf("%w: origin %q is not allowed", errormsg.ErrWebAuthnFailed, clientData.Origin)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) checkAuthenticatorData(authData *AuthenticatorData) error {
+	if !bytes.Equal(authData.RPIDHash, sha256Sum([]byte(rp.cfg.RPID))) {
+		return fmt.Errorf("%w: RP ID hash mismatch", errormsg.ErrWebAuthnFailed)
+	}
+
+	if !authData.Has(FlagUserPresent) {
+		return fmt.Errorf("%w: user is not present", errormsg.ErrWebAuthnFailed)
+	}
+
+	if rp.cfg.UserVerification == VerificationRequired && !authData.Has(FlagUserVerified) {
+		return fmt.Errorf("%w: user is not verified", errormsg.ErrWebAuthnFailed)
+	}
+
+	return nil
+}
+
+// newSession stores a new ceremony and returns its challenge.
+func (rp *RelyingParty) newSession(ceremony string, userID int) ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate WebAuthn challenge: %w", err)
+	}
+
+	err := rp.repo.CreateWebAuthnSession(calltypes.WebAuthnSession{
+		ID:        hashChallenge(URLEncodedBytes(challenge).String()),
+		UserID:    userID,
+		Ceremony:  ceremony,
+		ExpiresAt: rp.now().Add(rp.cfg.Timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// takeSession consumes the ceremony of the challenge. Sessions are single-use
+// whatever the outcome of the verification.
+func (rp *RelyingParty) takeSession(challenge, ceremony string, userID int) error {
+	session, err := rp.repo.TakeWebAuthnSession(hashChallenge(challenge))
+	if err != nil {
+		return err
+	}
+
+	if session.Ceremony != ceremony || session.UserID != userID || rp.now().After(session.ExpiresAt) {
+		return errormsg.ErrInvalidCeremony
+	}
+
+	return nil
+}
+
+func descriptors(credentials []*calltypes.WebAuthnCredential) []CredentialDescriptor {
+	result := make([]CredentialDescriptor, 0, len(credentials))
+
+	for _, credential := range credentials {
+		id, err := base64.RawURLEncoding.DecodeString(credential.ID)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, CredentialDescriptor{
+			Type:       credentialType,
+			ID:         id,
+			Transports: credential.Transports,
+		})
+	}
+
+	return result
+}
+
+func hashChallenge(challenge string) string {
+	return hex.EncodeToString(sha256Sum([]byte(challenge)))
+}
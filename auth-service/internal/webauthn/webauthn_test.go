@@ -0,0 +1,404 @@
+package webauthn_test
+
+import (
+	"auth-service/api/calltypes"
+	"auth-service/internal/webauthn"
+	"auth-service/internal/webauthn/webauthntest"
+	"auth-service/pkg/errormsg"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// memoryRepository keeps credentials and sessions in memory.
+type memoryRepository struct {
+	mu          sync.Mutex
+	sessions    map[string]calltypes.WebAuthnSession
+	credentials map[string]calltypes.WebAuthnCredential
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		sessions:    make(map[string]calltypes.WebAuthnSession),
+		credentials: make(map[string]calltypes.WebAuthnCredential),
+	}
+}
+
+func (m *memoryRepository) CreateWebAuthnSession(session calltypes.WebAuthnSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[session.ID] = session
+
+	return nil
+}
+
+func (m *memoryRepository) TakeWebAuthnSession(id string) (*calltypes.WebAuthnSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, errormsg.ErrInvalidCeremony
+	}
+
+	delete(m.sessions, id)
+
+	return &session, nil
+}
+
+func (m *memoryRepository) CreateWebAuthnCredential(credential calltypes.WebAuthnCredential) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.credentials[credential.ID]; ok {
+		return errormsg.ErrCredentialExists
+	}
+
+	m.credentials[credential.ID] = credential
+
+	return nil
+}
+
+func (m *memoryRepository) GetWebAuthnCredential(id string) (*calltypes.WebAuthnCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.credentials[id]
+	if !ok {
+		return nil, errormsg.ErrCredentialNotFound
+	}
+
+	return &credential, nil
+}
+
+func (m *memoryRepository) GetWebAuthnCredentials(userID int) ([]*calltypes.WebAuthnCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var credentials []*calltypes.WebAuthnCredential
+
+	for _, credential := range m.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, &credential)
+		}
+	}
+
+	return credentials, nil
+}
+
+func (m *memoryRepository) UseWebAuthnCredential(id string, signCount uint32, usedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential := m.credentials[id]
+	if credential.SignCount >= signCount && (credential.SignCount != 0 || signCount != 0) {
+		return false, nil
+	}
+
+	credential.SignCount = signCount
+	credential.LastUsedAt = &usedAt
+	m.credentials[id] = credential
+
+	return true, nil
+}
+
+func (m *memoryRepository) DeleteWebAuthnCredential(userID int, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if credential, ok := m.credentials[id]; !ok || credential.UserID != userID {
+		return errormsg.ErrCredentialNotFound
+	}
+
+	delete(m.credentials, id)
+
+	return nil
+}
+
+func testConfig() webauthn.Config {
+	return webauthn.Config{
+		RPID:             testRPID,
+		RPName:           "medods",
+		Origins:          []string{testOrigin},
+		Timeout:          5 * time.Minute,
+		UserVerification: webauthn.VerificationPreferred,
+	}
+}
+
+func testUser() *calltypes.User {
+	return &calltypes.User{ID: 7, Email: "user@example.com", FirstName: "John", LastName: "Doe"}
+}
+
+// register creates a passkey of the test user on the authenticator.
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *calltypes.WebAuthnCredential {
+	t.Helper()
+
+	options, err := rp.BeginRegistration(testUser())
+	require.NoError(t, err)
+
+	response, err := authenticator.Create(options)
+	require.NoError(t, err)
+
+	credential, err := rp.FinishRegistration(testUser().ID, "laptop", response)
+	require.NoError(t, err)
+
+	return credential
+}
+
+func TestRelyingParty_Registration(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"none", "packed"} {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+			authenticator.Format = format
+
+			credential := register(t, rp, authenticator)
+
+			assert.Equal(t, testUser().ID, credential.UserID)
+			assert.Equal(t, "laptop", credential.Name)
+			assert.True(t, credential.BackupEligible)
+			assert.Equal(t, []string{"internal"}, credential.Transports)
+
+			options, err := rp.BeginRegistration(testUser())
+			require.NoError(t, err)
+			require.Len(t, options.ExcludeCredentials, 1, "existing passkeys must be excluded")
+
+			_, err = authenticator.Create(options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRelyingParty_RegistrationRejected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tamper  func(authenticator *webauthntest.Authenticator)
+		userID  int
+		wantErr error
+	}{
+		{
+			name: "foreign origin",
+			tamper: func(authenticator *webauthntest.Authenticator) {
+				authenticator.Origin = "https://evil.example"
+			},
+			userID:  testUser().ID,
+			wantErr: errormsg.ErrWebAuthnFailed,
+		},
+		{
+			name:    "ceremony of another user",
+			userID:  testUser().ID + 1,
+			wantErr: errormsg.ErrInvalidCeremony,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+
+			if tt.tamper != nil {
+				tt.tamper(authenticator)
+			}
+
+			options, err := rp.BeginRegistration(testUser())
+			require.NoError(t, err)
+
+			response, err := authenticator.Create(options)
+			require.NoError(t, err)
+
+			_, err = rp.FinishRegistration(tt.userID, "laptop", response)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRelyingParty_RegistrationRequiresUserVerification(t *testing.T) {
+	t.Parallel()
+
+	cfg := testConfig()
+	cfg.UserVerification = webauthn.VerificationRequired
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), cfg)
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	authenticator.UserVerified = false
+
+	options, err := rp.BeginRegistration(testUser())
+	require.NoError(t, err)
+
+	response, err := authenticator.Create(options)
+	require.NoError(t, err)
+
+	_, err = rp.FinishRegistration(testUser().ID, "laptop", response)
+	require.ErrorIs(t, err, errormsg.ErrWebAuthnFailed)
+}
+
+func TestRelyingParty_Login(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		userID int
+	}{
+		{name: "discoverable credential", userID: 0},
+		{name: "credentials of the user", userID: testUser().ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+			credential := register(t, rp, authenticator)
+
+			for range 2 {
+				options, err := rp.BeginLogin(tt.userID)
+				require.NoError(t, err)
+
+				response, err := authenticator.Get(options)
+				require.NoError(t, err)
+
+				login, err := rp.FinishLogin(response)
+				require.NoError(t, err)
+
+				assert.Equal(t, testUser().ID, login.UserID)
+				assert.Equal(t, credential.ID, login.CredentialID)
+				assert.True(t, login.UserVerified)
+			}
+		})
+	}
+}
+
+func TestRelyingParty_LoginWithoutCounters(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	authenticator.CounterStep = 0
+	register(t, rp, authenticator)
+
+	for range 2 {
+		options, err := rp.BeginLogin(0)
+		require.NoError(t, err)
+
+		response, err := authenticator.Get(options)
+		require.NoError(t, err)
+
+		_, err = rp.FinishLogin(response)
+		require.NoError(t, err)
+	}
+}
+
+func TestRelyingParty_LoginDetectsClonedAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	register(t, rp, authenticator)
+
+	clone := authenticator.Clone()
+
+	for _, device := range []*webauthntest.Authenticator{authenticator, clone} {
+		options, err := rp.BeginLogin(0)
+		require.NoError(t, err)
+
+		response, err := device.Get(options)
+		require.NoError(t, err)
+
+		_, err = rp.FinishLogin(response)
+		if device == clone {
+			require.ErrorIs(t, err, errormsg.ErrSignCountRegressed)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestRelyingParty_LoginRejectsReplay(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	register(t, rp, authenticator)
+
+	options, err := rp.BeginLogin(0)
+	require.NoError(t, err)
+
+	response, err := authenticator.Get(options)
+	require.NoError(t, err)
+
+	_, err = rp.FinishLogin(response)
+	require.NoError(t, err)
+
+	_, err = rp.FinishLogin(response)
+	require.ErrorIs(t, err, errormsg.ErrInvalidCeremony)
+}
+
+func TestRelyingParty_LoginRejectsForeignCredential(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	register(t, rp, authenticator)
+
+	// A ceremony started for another user must not accept the passkey.
+	options, err := rp.BeginLogin(testUser().ID + 1)
+	require.NoError(t, err)
+	require.Empty(t, options.AllowCredentials)
+
+	response, err := authenticator.Get(options)
+	require.NoError(t, err)
+
+	_, err = rp.FinishLogin(response)
+	require.ErrorIs(t, err, errormsg.ErrCredentialNotFound)
+}
+
+func TestRelyingParty_LoginRejectsTamperedSignature(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	register(t, rp, authenticator)
+
+	options, err := rp.BeginLogin(0)
+	require.NoError(t, err)
+
+	response, err := authenticator.Get(options)
+	require.NoError(t, err)
+
+	response.Response.AuthenticatorData[len(response.Response.AuthenticatorData)-1]++
+
+	_, err = rp.FinishLogin(response)
+	require.ErrorIs(t, err, errormsg.ErrWebAuthnFailed)
+}
+
+func TestRelyingParty_DeleteCredential(t *testing.T) {
+	t.Parallel()
+
+	rp := webauthn.NewRelyingParty(newMemoryRepository(), testConfig())
+	credential := register(t, rp, webauthntest.NewAuthenticator(testOrigin))
+
+	require.ErrorIs(t, rp.DeleteCredential(testUser().ID+1, credential.ID), errormsg.ErrCredentialNotFound)
+	require.NoError(t, rp.DeleteCredential(testUser().ID, credential.ID))
+
+	credentials, err := rp.Credentials(testUser().ID)
+	require.NoError(t, err)
+	assert.Empty(t, credentials)
+}
@@ -0,0 +1,282 @@
+// Package webauthntest provides a software WebAuthn authenticator, so passkey
+// ceremonies can be tested end-to-end without hardware or a browser.
+package webauthntest
+
+import (
+	"auth-service/internal/webauthn"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const credentialIDLength = 16
+
+var errNoCredential = errors.New("authenticator has no matching credential")
+
+// Credential is a key pair held by the authenticator.
+type Credential struct {
+	ID         []byte
+	RPID       string
+	UserHandle []byte
+	SignCount  uint32
+	key        *ecdsa.PrivateKey
+}
+
+// Authenticator is an ES256 platform authenticator acting as the browser too:
+// it builds client data for the configured origin.
+type Authenticator struct {
+	// Origin is put into client data.
+	Origin string
+	// UserVerified sets the UV flag, as after a biometric or PIN check.
+	UserVerified bool
+	// CounterStep is added to the sign counter on each assertion. Zero mimics
+	// synced passkeys that do not implement counters.
+	CounterStep uint32
+	// Format is the attestation format, "none" or "packed" (self attestation).
+	Format string
+
+	credentials map[string]*Credential
+}
+
+// NewAuthenticator returns an authenticator with user verification and
+// counters enabled.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{
+		Origin:       origin,
+		UserVerified: true,
+		CounterStep:  1,
+		Format:       "none",
+		credentials:  make(map[string]*Credential),
+	}
+}
+
+// Clone returns an authenticator holding copies of the same keys and counters,
+// as if the authenticator had been cloned.
+func (a *Authenticator) Clone() *Authenticator {
+	clone := *a
+	clone.credentials = make(map[string]*Credential, len(a.credentials))
+
+	for id, credential := range a.credentials {
+		copied := *credential
+		clone.credentials[id] = &copied
+	}
+
+	return &clone
+}
+
+// Credentials returns credentials created by the authenticator.
+func (a *Authenticator) Credentials() []*Credential {
+	return slices.Collect(maps.Values(a.credentials))
+}
+
+// Create runs navigator.credentials.create() for the options.
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.AttestationResponse, error) {
+	for _, excluded := range options.ExcludeCredentials {
+		if _, ok := a.credentials[string(excluded.ID)]; ok {
+			return nil, fmt.Errorf("credential %s is excluded", webauthn.URLEncodedBytes(excluded.ID)) //nolint: err113
+		}
+	}
+
+	if !slices.ContainsFunc(options.PubKeyCredParams, func(p webauthn.CredentialParameter) bool {
+		return p.Algorithm == webauthn.AlgES256
+	}) {
+		return nil, errors.New("ES256 is not accepted") //nolint: err113
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	id := make([]byte, credentialIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate credential ID: %w", err)
+	}
+
+	credential := &Credential{
+		ID:         id,
+		RPID:       options.RelyingParty.ID,
+		UserHandle: options.User.ID,
+		key:        key,
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := a.authenticatorData(credential, true)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := map[string]interface{}{}
+
+	if a.Format == "packed" {
+		signature, err := sign(key, authData, clientDataJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		statement["alg"] = webauthn.AlgES256
+		statement["sig"] = signature
+	}
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      a.Format,
+		"attStmt":  statement,
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation object: %w", err)
+	}
+
+	a.credentials[string(id)] = credential
+
+	response := &webauthn.AttestationResponse{
+		ID:    webauthn.URLEncodedBytes(id).String(),
+		RawID: id,
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AttestationObject = attestationObject
+	response.Response.Transports = []string{"internal"}
+
+	return response, nil
+}
+
+// Get runs navigator.credentials.get() for the options. Without allowed
+// credentials any discoverable credential of the relying party is used.
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	credential := a.find(options)
+	if credential == nil {
+		return nil, errNoCredential
+	}
+
+	credential.SignCount += a.CounterStep
+
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := a.authenticatorData(credential, false)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(credential.key, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &webauthn.AssertionResponse{
+		ID:    webauthn.URLEncodedBytes(credential.ID).String(),
+		RawID: credential.ID,
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AuthenticatorData = authData
+	response.Response.Signature = signature
+	response.Response.UserHandle = credential.UserHandle
+
+	return response, nil
+}
+
+func (a *Authenticator) find(options *webauthn.RequestOptions) *Credential {
+	if len(options.AllowCredentials) == 0 {
+		for _, credential := range a.credentials {
+			if credential.RPID == options.RelyingPartyID {
+				return credential
+			}
+		}
+
+		return nil
+	}
+
+	for _, allowed := range options.AllowCredentials {
+		if credential, ok := a.credentials[string(allowed.ID)]; ok && credential.RPID == options.RelyingPartyID {
+			return credential
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	data, err := json.Marshal(webauthn.CollectedClientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.Origin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode client data: %w", err)
+	}
+
+	return data, nil
+}
+
+func (a *Authenticator) authenticatorData(credential *Credential, attested bool) ([]byte, error) {
+	rpIDHash := sha256.Sum256([]byte(credential.RPID))
+
+	flags := webauthn.FlagUserPresent | webauthn.FlagBackupEligible | webauthn.FlagBackupState
+	if a.UserVerified {
+		flags |= webauthn.FlagUserVerified
+	}
+
+	if attested {
+		flags |= webauthn.FlagAttestedData
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, credential.SignCount)
+
+	if !attested {
+		return data, nil
+	}
+
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  webauthn.AlgES256,
+		-1: 1,
+		-2: padded(credential.key.X.Bytes()),
+		-3: padded(credential.key.Y.Bytes()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	// AAGUID of a software authenticator is all zeros.
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(credential.ID))) //nolint: gosec
+	data = append(data, credential.ID...)
+
+	return append(data, publicKey...), nil
+}
+
+func sign(key *ecdsa.PrivateKey, authData, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return signature, nil
+}
+
+// padded returns a P-256 coordinate as 32 bytes.
+func padded(coordinate []byte) []byte {
+	return append(make([]byte, 32-len(coordinate)), coordinate...)
+}
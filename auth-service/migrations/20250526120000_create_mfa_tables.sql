@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_totp(
+    user_id INT PRIMARY KEY REFERENCES medods(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS mfa_challenges(
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES medods(id) ON DELETE CASCADE,
+    ip VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS user_totp;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes(
+    id serial PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES medods(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE UNIQUE INDEX idx_mfa_recovery_codes_user_code ON mfa_recovery_codes(user_id, code_hash);
+
+CREATE TABLE IF NOT EXISTS audit_log(
+    id bigserial PRIMARY KEY,
+    user_id INT,
+    actor_id INT,
+    action VARCHAR(64) NOT NULL,
+    ip VARCHAR(64),
+    details JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX idx_audit_log_user_id ON audit_log(user_id, created_at);
+    CREATE INDEX idx_audit_log_action ON audit_log(action, created_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
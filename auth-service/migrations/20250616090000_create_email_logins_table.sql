@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS email_logins(
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES medods(id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL DEFAULT '',
+    browser_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX idx_email_logins_user_id ON email_logins(user_id);
+    CREATE INDEX idx_email_logins_expires_at ON email_logins(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS email_logins;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
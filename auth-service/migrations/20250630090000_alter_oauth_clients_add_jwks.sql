@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE oauth_clients
+ADD COLUMN jwks TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS oauth_client_assertions(
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    jti VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_id, jti)
+    );
+
+    CREATE INDEX idx_oauth_client_assertions_expires_at ON oauth_client_assertions(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_client_assertions;
+ALTER TABLE oauth_clients
+DROP COLUMN jwks;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
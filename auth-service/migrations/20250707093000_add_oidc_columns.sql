@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE medods
+ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE oauth_authorization_codes
+ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE oauth_authorization_codes
+DROP COLUMN nonce;
+
+ALTER TABLE medods
+DROP COLUMN email_verified;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
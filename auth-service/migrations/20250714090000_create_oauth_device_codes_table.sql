@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS oauth_device_codes(
+    device_code_hash VARCHAR(64) PRIMARY KEY,
+    user_code VARCHAR(16) UNIQUE NOT NULL,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL DEFAULT '',
+    user_id INT REFERENCES medods(id) ON DELETE CASCADE,
+    denied BOOLEAN NOT NULL DEFAULT FALSE,
+    interval_seconds INT NOT NULL,
+    last_polled_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX idx_oauth_device_codes_expires_at ON oauth_device_codes(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_device_codes;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE oauth_clients
+ADD COLUMN exchange_audiences TEXT NOT NULL DEFAULT '';
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE oauth_clients
+DROP COLUMN exchange_audiences;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS saml_requests(
+    id VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX idx_saml_requests_expires_at ON saml_requests(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS saml_requests;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS api_keys(
+    id VARCHAR(32) PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    user_id INT REFERENCES medods(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '',
+    rate_limit VARCHAR(32) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+    );
+
+    CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
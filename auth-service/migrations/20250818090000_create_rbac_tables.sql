@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS roles(
+    name VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(128) NOT NULL,
+    PRIMARY KEY (role, permission)
+    );
+
+CREATE TABLE IF NOT EXISTS user_roles(
+    user_id INT NOT NULL REFERENCES medods(id) ON DELETE CASCADE,
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+    );
+
+    CREATE INDEX idx_user_roles_role ON user_roles(role);
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Reads any user and the leaderboard')
+    ON CONFLICT DO NOTHING;
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'users:read')
+    ON CONFLICT DO NOTHING;
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
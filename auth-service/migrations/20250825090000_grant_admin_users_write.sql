@@ -0,0 +1,13 @@
+-- +goose Up
+INSERT INTO role_permissions (role, permission)
+    SELECT name, 'users:write' FROM roles WHERE name = 'admin'
+    ON CONFLICT DO NOTHING;
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DELETE FROM role_permissions WHERE role = 'admin' AND permission = 'users:write';
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
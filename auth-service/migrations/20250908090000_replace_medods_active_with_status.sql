@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE medods ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('pending', 'active', 'suspended', 'deleted'));
+
+UPDATE medods SET status = 'suspended' WHERE active = 0;
+
+DROP INDEX IF EXISTS idx_medods_inactive;
+ALTER TABLE medods DROP COLUMN active;
+
+    CREATE INDEX idx_medods_status ON medods(status) WHERE status <> 'active';
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE medods ADD COLUMN active INT NOT NULL DEFAULT 1;
+
+UPDATE medods SET active = 0 WHERE status <> 'active';
+
+DROP INDEX IF EXISTS idx_medods_status;
+ALTER TABLE medods DROP COLUMN status;
+
+    CREATE INDEX idx_medods_inactive ON medods(email) WHERE active = 0;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
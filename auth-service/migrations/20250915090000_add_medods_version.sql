@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE medods ADD COLUMN version INT NOT NULL DEFAULT 1;
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE medods DROP COLUMN version;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
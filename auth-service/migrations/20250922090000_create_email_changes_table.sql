@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS email_changes(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    cancel_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_id INT NOT NULL UNIQUE REFERENCES medods(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS email_changes;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
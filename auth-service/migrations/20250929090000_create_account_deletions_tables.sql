@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS account_deletions(
+    user_id INT PRIMARY KEY REFERENCES medods(id) ON DELETE CASCADE,
+    requested_at TIMESTAMP NOT NULL,
+    erase_at TIMESTAMP NOT NULL
+    );
+
+    CREATE INDEX idx_account_deletions_erase_at ON account_deletions(erase_at);
+
+CREATE TABLE IF NOT EXISTS user_tombstones(
+    user_id INT PRIMARY KEY,
+    requested_at TIMESTAMP NOT NULL,
+    erased_at TIMESTAMP NOT NULL
+    );
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS user_tombstones;
+DROP TABLE IF EXISTS account_deletions;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS data_exports(
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES medods(id) ON DELETE CASCADE,
+    format VARCHAR(8) NOT NULL CHECK (format IN ('json', 'zip')),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'ready', 'failed')),
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+    );
+
+    CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+    CREATE INDEX idx_data_exports_pending ON data_exports(created_at) WHERE status = 'pending';
+    CREATE INDEX idx_data_exports_expires_at ON data_exports(expires_at);
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS data_exports;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
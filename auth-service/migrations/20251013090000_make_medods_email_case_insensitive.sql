@@ -0,0 +1,56 @@
+-- +goose Up
+-- Emails that differ only in case or surrounding spaces belong to one person
+-- now. Such accounts cannot be merged automatically, so the migration stops
+-- and lists them for an operator to resolve. Domains are stored in ASCII now,
+-- and SQL cannot convert Unicode domains to punycode, so accounts with such
+-- domains are listed as well: an operator converts them first, as the service
+-- does (user@Bücher.Example becomes user@xn--bcher-kva.example), which may
+-- reveal more collisions.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    collisions TEXT;
+    unicode_domains TEXT;
+BEGIN
+    SELECT string_agg(format('%s (ids %s)', email, ids), '; ' ORDER BY email)
+    INTO collisions
+    FROM (
+        SELECT lower(btrim(email)) AS email, string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+        FROM medods
+        GROUP BY lower(btrim(email))
+        HAVING COUNT(*) > 1
+    ) duplicates;
+
+    SELECT string_agg(format('%s (id %s)', btrim(email), id), '; ' ORDER BY id)
+    INTO unicode_domains
+    FROM medods
+    WHERE substring(btrim(email) FROM '@([^@]*)$') ~ '[^\x01-\x7f]';
+
+    IF collisions IS NOT NULL OR unicode_domains IS NOT NULL THEN
+        RAISE EXCEPTION 'emails must be resolved first: colliding case-insensitively: %; with Unicode domains to convert to punycode: %',
+            coalesce(collisions, 'none'), coalesce(unicode_domains, 'none');
+    END IF;
+END $$;
+-- +goose StatementEnd
+
+UPDATE medods
+SET email = substring(btrim(email) FROM '^(.*)@') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$'))
+WHERE btrim(email) LIKE '%_@_%';
+
+ALTER TABLE medods DROP CONSTRAINT IF EXISTS medods_email_key;
+DROP INDEX IF EXISTS idx_medods_email;
+
+    CREATE UNIQUE INDEX idx_medods_email_lower ON medods(lower(email));
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_medods_email_lower;
+
+ALTER TABLE medods ADD CONSTRAINT medods_email_key UNIQUE (email);
+
+    CREATE UNIQUE INDEX idx_medods_email ON medods(email);
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- A confirmed TOTP keeps working while its replacement waits for the first
+-- code.
+ALTER TABLE user_totp
+ADD COLUMN pending_secret_encrypted TEXT;
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE user_totp
+DROP COLUMN pending_secret_encrypted;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
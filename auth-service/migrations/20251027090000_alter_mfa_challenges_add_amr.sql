@@ -0,0 +1,15 @@
+-- +goose Up
+-- Authentication methods of the first factor, separated by spaces. Pending
+-- logins created before always passed a password.
+ALTER TABLE mfa_challenges
+ADD COLUMN amr TEXT NOT NULL DEFAULT 'pwd';
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE mfa_challenges
+DROP COLUMN amr;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- The authorization endpoint does not ask users for consent, so only
+-- first-party clients may use it. Clients registered for authorization_code
+-- before were registered under that assumption and stay allowed.
+ALTER TABLE oauth_clients
+ADD COLUMN first_party BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE oauth_clients SET first_party = TRUE WHERE ' ' || grant_types || ' ' LIKE '% authorization_code %';
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE oauth_clients
+DROP COLUMN first_party;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Access tokens issued before this moment are refused, so revoking sessions
+-- takes effect at once instead of when the tokens expire.
+ALTER TABLE medods
+ADD COLUMN sessions_revoked_at TIMESTAMP;
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE medods
+DROP COLUMN sessions_revoked_at;
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -19,4 +19,62 @@ const (
 	ReadTimeout            = 5
 	TokenParts             = 2
 	TestIP                 = "10.10.10.10"
+	LockoutMaxFailures     = 5
+	LockoutMaxIPFailures   = 20
+	LockoutDuration        = 15 * time.Minute
+	LockoutBaseDelay       = time.Second
+	LockoutMaxDelay        = time.Minute
+	LockoutFailureWindow   = time.Hour
+	RateLimitAuthenticate  = "10/1m"
+	RateLimitRegistrate    = "5/1m"
+	RateLimitProvide       = "10/1m"
+	RateLimitStaleAge      = time.Hour
+	RateLimitAuthMFA       = "10/1m"
+	MFAIssuer              = "medods"
+	MFATOTPSkew            = 1
+	MFAChallengeTTL        = 5 * time.Minute
+	MFAChallengeAttempts   = 5
+	RateLimitAuthPasskey   = "20/1m"
+	WebAuthnRPName         = "medods"
+	WebAuthnTimeout        = 5 * time.Minute
+	WebAuthnVerification   = "preferred"
+	RateLimitAuthEmail     = "10/1m"
+	EmailLoginCodeTTL      = 10 * time.Minute
+	EmailLoginLinkTTL      = 15 * time.Minute
+	EmailLoginAttempts     = 5
+	RateLimitOAuthToken    = "30/1m"
+	OAuthCodeTTL           = time.Minute
+	OAuthRefreshTokenTTL   = 30 * 24 * time.Hour
+	OAuthAssertionMaxAge   = 5 * time.Minute
+	PermissionUsersRead    = "users:read"
+	PermissionUsersWrite   = "users:write"
+	PolicyReloadInterval   = 5 * time.Second
+	UserPageSize           = 50
+	UserPageMaxSize        = 200
+	PasswordResetTTL       = time.Hour
+	RateLimitPassReset     = "10/1m"
+	IDTokenExpireTime      = time.Hour
+	OAuthDeviceCodeTTL     = 10 * time.Minute
+	OAuthDeviceInterval    = 5 * time.Second
+	RateLimitOAuthDevice   = "10/1m"
+	RateLimitAuthFederated = "20/1m"
+	FederationLoginTTL     = 10 * time.Minute
+	FederationTimeout      = 10 * time.Second
+	SAMLRequestTTL         = 10 * time.Minute
+	SAMLClockSkew          = 2 * time.Minute
+	RateLimitAPIKey        = "60/1m"
+	APIKeyTouchInterval    = time.Minute
+	APIKeyMaxScopes        = 20
+	APIKeyMaxPersonal      = 10
+	EmailChangeTTL         = 24 * time.Hour
+	RateLimitEmailChange   = "5/1m"
+	AccountDeletionGrace   = 30 * 24 * time.Hour
+	AccountErasureInterval = 10 * time.Minute
+	AccountErasureBatch    = 100
+	DataExportDir          = "exports"
+	DataExportLinkTTL      = 5 * time.Minute
+	DataExportRetention    = 24 * time.Hour
+	DataExportInterval     = 5 * time.Second
+	RateLimitDataExport    = "3/1h"
+	SMTPPort               = 587
 )
@@ -37,4 +37,104 @@ var (
 	ErrCompareHash                   = errors.New("error during comparing hash and sotre token")
 	ErrStorage                       = errors.New("storage error")
 	ErrUpdate                        = errors.New("failed to update")
+	ErrUnsupportedHash               = errors.New("unsupported password hash format")
+	ErrMalformedHash                 = errors.New("malformed password hash")
+	ErrHashTooCostly                 = errors.New("password hash parameters exceed the supported maximum")
+	ErrEmptyImport                   = errors.New("no users to import")
+	ErrImportFailed                  = errors.New("failed to import some users")
+	ErrTooManyAttempts               = errors.New("too many failed login attempts, try again later")
+	ErrLockoutDisabled               = errors.New("brute-force protection is disabled")
+	ErrInvalidConfig                 = errors.New("invalid configuration value")
+	ErrInvalidRateLimit              = errors.New("invalid rate limit rule")
+	ErrRateLimited                   = errors.New("rate limit exceeded, try again later")
+	ErrInvalidEncryptionKey          = errors.New("encryption key must be 32 bytes encoded in base64")
+	ErrDecrypt                       = errors.New("failed to decrypt secret")
+	ErrUnauthenticated               = errors.New("authenticated user is required")
+	ErrMFADisabled                   = errors.New("MFA is disabled")
+	ErrMFAAlreadyEnabled             = errors.New("MFA is already enabled")
+	ErrMFANotEnrolled                = errors.New("MFA is not enrolled")
+	ErrInvalidMFACode                = errors.New("invalid MFA code")
+	ErrMFACodeReused                 = errors.New("MFA code has already been used")
+	ErrInvalidMFAChallenge           = errors.New("invalid or expired MFA challenge")
+	ErrInvalidRecoveryCode           = errors.New("invalid or already used recovery code")
+	ErrMFACodeRequired               = errors.New("either code or recovery code is required")
+	ErrWebAuthnDisabled              = errors.New("passkeys are disabled")
+	ErrWebAuthnFailed                = errors.New("WebAuthn verification failed")
+	ErrInvalidCeremony               = errors.New("invalid or expired WebAuthn ceremony")
+	ErrUnsupportedCOSEKey            = errors.New("unsupported credential public key")
+	ErrUnsupportedAttStmt            = errors.New("unsupported attestation format")
+	ErrCredentialExists              = errors.New("credential is already registered")
+	ErrCredentialNotFound            = errors.New("credential not found")
+	ErrSignCountRegressed            = errors.New("credential sign counter did not increase, it may be cloned")
+	ErrStepUpRequired                = errors.New("stronger authentication is required")
+	ErrReauthRequired                = errors.New("current password or stronger authentication is required")
+	ErrEmailLoginDisabled            = errors.New("email login is disabled")
+	ErrInvalidLoginMethod            = errors.New("login method must be either code or link")
+	ErrInvalidEmailLogin             = errors.New("invalid or expired email login")
+	ErrInvalidLoginCode              = errors.New("invalid login code")
+	ErrInvalidLoginLink              = errors.New("invalid or expired login link")
+	ErrOAuthDisabled                 = errors.New("OAuth is disabled")
+	ErrInvalidOAuthRequest           = errors.New("invalid OAuth request")
+	ErrInvalidClient                 = errors.New("client authentication failed")
+	ErrInvalidClientMetadata         = errors.New("invalid client metadata")
+	ErrInvalidRedirectURI            = errors.New("redirect URI is not registered for the client")
+	ErrInvalidGrant                  = errors.New("invalid or expired grant")
+	ErrUnauthorizedClient            = errors.New("client is not allowed to use this grant type")
+	ErrUnsupportedGrantType          = errors.New("unsupported grant type")
+	ErrUnsupportedResponseType       = errors.New("unsupported response type")
+	ErrInvalidScope                  = errors.New("requested scope is invalid")
+	ErrInsufficientScope             = errors.New("access token lacks the required scope")
+	ErrSessionRequired               = errors.New("user session is required")
+	ErrInvalidClientAssertion        = errors.New("invalid client assertion")
+	ErrOIDCDisabled                  = errors.New("OpenID Connect is disabled")
+	ErrLoginRequired                 = errors.New("user is not logged in")
+	ErrInvalidSigningKey             = errors.New("invalid ID token signing key")
+	ErrAuthorizationPending          = errors.New("user has not approved the device yet")
+	ErrSlowDown                      = errors.New("device polls too frequently")
+	ErrAccessDenied                  = errors.New("user denied the authorization")
+	ErrExpiredToken                  = errors.New("device code has expired")
+	ErrInvalidUserCode               = errors.New("invalid or expired user code")
+	ErrInvalidTarget                 = errors.New("client may not exchange tokens for this audience")
+	ErrForeignAudience               = errors.New("access token is issued for another audience")
+	ErrFederationDisabled            = errors.New("federated login is disabled")
+	ErrUnknownProvider               = errors.New("unknown identity provider")
+	ErrInvalidFederatedLogin         = errors.New("invalid or expired federated login")
+	ErrFederatedLoginDenied          = errors.New("identity provider rejected the login")
+	ErrInvalidIDToken                = errors.New("invalid ID token")
+	ErrProviderUnavailable           = errors.New("identity provider is unavailable")
+	ErrUnverifiedEmail               = errors.New("identity provider did not verify the email")
+	ErrUnverifiedLocalEmail          = errors.New("account with this email has not verified it")
+	ErrIdentityNotFound              = errors.New("federated identity not found")
+	ErrSAMLDisabled                  = errors.New("SAML login is disabled")
+	ErrInvalidSAMLResponse           = errors.New("invalid SAML response")
+	ErrInvalidSAMLSignature          = errors.New("invalid SAML signature")
+	ErrInvalidCertificate            = errors.New("invalid identity provider certificate")
+	ErrInvalidAPIKey                 = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound                = errors.New("API key not found")
+	ErrInvalidAPIKeyRequest          = errors.New("invalid API key request")
+	ErrTooManyAPIKeys                = errors.New("too many active API keys")
+	ErrPermissionDenied              = errors.New("permission denied")
+	ErrRoleNotFound                  = errors.New("role not found")
+	ErrInvalidRole                   = errors.New("invalid role")
+	ErrInvalidPolicy                 = errors.New("invalid policy")
+	ErrPolicyDenied                  = errors.New("denied by policy")
+	ErrInvalidUserRequest            = errors.New("invalid user request")
+	ErrEmailTaken                    = errors.New("email is already taken")
+	ErrInvalidPasswordReset          = errors.New("invalid or expired password reset")
+	ErrUserInactive                  = errors.New("user account is not active")
+	ErrSessionRevoked                = errors.New("session has been revoked")
+	ErrInvalidProfile                = errors.New("invalid profile")
+	ErrVersionConflict               = errors.New("user has been changed by another request")
+	ErrPreconditionRequired          = errors.New("If-Match header is required")
+	ErrUnsupportedMediaType          = errors.New("content type must be application/merge-patch+json")
+	ErrInvalidEmail                  = errors.New("invalid email")
+	ErrSameEmail                     = errors.New("new email is the same as the current one")
+	ErrInvalidEmailChange            = errors.New("invalid or expired email change")
+	ErrDeletionNotScheduled          = errors.New("account deletion is not scheduled")
+	ErrDataExportDisabled            = errors.New("data export is disabled")
+	ErrInvalidExportFormat           = errors.New("export format must be json or zip")
+	ErrDataExportNotFound            = errors.New("data export not found")
+	ErrDataExportNotReady            = errors.New("data export is not ready")
+	ErrInvalidDownloadLink           = errors.New("invalid or expired download link")
+	ErrInvalidMailHeader             = errors.New("mail header must not contain line breaks")
 )